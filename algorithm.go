@@ -0,0 +1,267 @@
+package cryptonight
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"ekyu.moe/cryptonight/internal/observe"
+)
+
+// Algorithm is a member of the CryptoNight family, or one of the Argon2
+// algorithms which replaced it at TurtleCoin. Its zero value is CNv0.
+type Algorithm int
+
+// Algorithms implemented by this package, with their names in xmrig.
+const (
+	CNv0     Algorithm = iota // cn/0, the original CryptoNight of CNS008
+	CNv1                      // cn/1, variant 1, also known as CryptoNight v7
+	CNv2                      // cn/2, variant 2, also known as CryptoNight v8
+	CNR                       // cn/r, variant 4, which depends on the block height
+	CNFast                    // cn/fast, also known as cn/msr
+	CNHalf                    // cn/half
+	CNXTL                     // cn/xtl
+	CNRWZ                     // cn/rwz
+	CNZLS                     // cn/zls
+	CNDouble                  // cn/double
+	CNGPU                     // cn/gpu
+	CNLite0                   // cn-lite/0
+	CNLite1                   // cn-lite/1
+	CNHeavy                   // cn-heavy/0
+	CNPico                    // cn-pico, also known as CryptoNight Turtle
+	Chukwa                    // argon2/chukwa, Argon2id of TurtleCoin
+	ChukwaV2                  // argon2/chukwav2, Argon2id of TurtleCoin with more memory
+)
+
+// algorithms are the names and the params of the algorithms, indexed by
+// Algorithm.
+var algorithms = [...]struct {
+	name string
+	p    params
+}{
+	CNv0:     {"cn/0", standard(0)},
+	CNv1:     {"cn/1", standard(1)},
+	CNv2:     {"cn/2", standard(2)},
+	CNR:      {"cn/r", standard(4)},
+	CNFast:   {"cn/fast", fast},
+	CNHalf:   {"cn/half", half},
+	CNXTL:    {"cn/xtl", xtl},
+	CNRWZ:    {"cn/rwz", rwz},
+	CNZLS:    {"cn/zls", zls},
+	CNDouble: {"cn/double", double},
+	CNGPU:    {"cn/gpu", gpu},
+	CNLite0:  {"cn-lite/0", lite(0)},
+	CNLite1:  {"cn-lite/1", lite(1)},
+	CNHeavy:  {"cn-heavy/0", heavy},
+	CNPico:   {"cn-pico", pico},
+	Chukwa:   {"argon2/chukwa", chukwa},
+	ChukwaV2: {"argon2/chukwav2", chukwaV2},
+}
+
+// algorithmAliases are the other names xmrig accepts, once "cryptonight" is
+// shortened to "cn".
+var algorithmAliases = map[string]Algorithm{
+	"cn":           CNv0,
+	"cn/msr":       CNFast,
+	"cn-heavy":     CNHeavy,
+	"cn-pico/trtl": CNPico,
+	"cn-turtle":    CNPico,
+	"chukwa":       Chukwa,
+	"chukwav2":     ChukwaV2,
+}
+
+// ParseAlgorithm returns the Algorithm named name, as in xmrig, such as "cn/2",
+// "cn/r" or "cn-lite/1". The long forms xmrig accepts, such as "cryptonight/2"
+// or "cryptonight-heavy", are recognized too, regardless of case. It returns
+// ErrUnknownAlgorithm if name is none of them.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if strings.HasPrefix(name, "cryptonight") {
+		name = "cn" + strings.TrimPrefix(name, "cryptonight")
+	}
+
+	for i, v := range algorithms {
+		if v.name == name {
+			return Algorithm(i), nil
+		}
+	}
+	if a, ok := algorithmAliases[name]; ok {
+		return a, nil
+	}
+
+	return 0, ErrUnknownAlgorithm
+}
+
+// String returns the name of a in xmrig.
+func (a Algorithm) String() string {
+	if !a.valid() {
+		return "Algorithm(" + strconv.Itoa(int(a)) + ")"
+	}
+
+	return algorithms[a].name
+}
+
+// Memory returns the size in bytes of the scratchpad of a, which is what a
+// Cache takes once it has hashed with a. It returns 0 if a is not one of the
+// constants of this package.
+func (a Algorithm) Memory() int {
+	if !a.valid() {
+		return 0
+	}
+
+	return algorithms[a].p.memory
+}
+
+func (a Algorithm) valid() bool {
+	return a >= 0 && int(a) < len(algorithms)
+}
+
+// SumAlgorithm calculate a hash digest of algo. height is only used by CNR,
+// and ignored by the other algorithms.
+//
+// SumAlgorithm panics with ErrUnknownAlgorithm if algo is not one of the
+// constants of this package. The algorithms based on variant 1, that is CNv1,
+// CNFast, CNXTL and CNLite1, require data to have at least 43 bytes, otherwise
+// SumAlgorithm panics with ErrShortInput. Chukwa and ChukwaV2 require 16 bytes,
+// otherwise SumAlgorithm panics with ErrShortSalt.
+func SumAlgorithm(data []byte, algo Algorithm, height uint64) []byte {
+	if !algo.valid() {
+		observe.Error(ErrUnknownAlgorithm)
+		panic(ErrUnknownAlgorithm)
+	}
+	if algo <= CNR {
+		return SumHeight(data, algorithms[algo].p.variant, height)
+	}
+
+	cc := cachePool.Get().(*Cache)
+	defer cachePool.Put(cc)
+
+	return cc.safeSum(nil, data, algorithms[algo].p, height)
+}
+
+// SumAlgorithm calculate a hash digest of algo with cc, the same way as
+// SumAlgorithm does, and panics the same way as Cache.Sum does.
+func (cc *Cache) SumAlgorithm(data []byte, algo Algorithm, height uint64) []byte {
+	if !algo.valid() {
+		observe.Error(ErrUnknownAlgorithm)
+		panic(ErrUnknownAlgorithm)
+	}
+	if algo <= CNR {
+		return cc.SumHeight(data, algorithms[algo].p.variant, height)
+	}
+
+	return cc.exclusiveSum(nil, data, algorithms[algo].p, height)
+}
+
+// SumInto calculate a hash digest of algo with cc into dst, the same way as
+// Cache.SumAlgorithm does, and panics the same way. It does not allocate,
+// except for the scratchpad of the first hash with cc and of any later hash
+// needing a larger one, which suits miners computing millions of hashes.
+func (cc *Cache) SumInto(dst *[32]byte, data []byte, algo Algorithm, height uint64) {
+	if !algo.valid() {
+		observe.Error(ErrUnknownAlgorithm)
+		panic(ErrUnknownAlgorithm)
+	}
+	p := algorithms[algo].p
+	cc.exclusiveSum(dst[:0], data, p, height)
+	if cc.crossChecked() && algo <= CNR {
+		crossCheck(data, p.variant, dst[:])
+	}
+}
+
+// SumMany calculate the hash digests of algo of blobs with cc into dst, which
+// must be at least as long as blobs, the same way as SumInto does. It only
+// checks once that cc is not in use, and stops at the first blob it panics
+// for, leaving the following digests unchanged. Miners hashing a blob with
+// many nonces can reuse blobs and dst between batches, without allocating.
+//
+// The blobs of CNv0, CNv1 and CNv2 are hashed two at a time, as Sum2 does,
+// which is faster where Sum2 interleaves them, at the cost of a scratchpad
+// twice as large.
+func (cc *Cache) SumMany(dst [][32]byte, blobs [][]byte, algo Algorithm, height uint64) {
+	if !algo.valid() {
+		observe.Error(ErrUnknownAlgorithm)
+		panic(ErrUnknownAlgorithm)
+	}
+	if len(dst) < len(blobs) {
+		panic("cryptonight: SumMany with fewer digests than blobs")
+	}
+	if !atomic.CompareAndSwapUint32(&cc.inUse, 0, 1) {
+		observe.Error(ErrCacheInUse)
+		panic(ErrCacheInUse)
+	}
+	defer atomic.StoreUint32(&cc.inUse, 0)
+
+	p := algorithms[algo].p
+	i := 0
+	for ; algo <= CNv2 && i+1 < len(blobs); i += 2 {
+		// a pair with an invalid blob is left to the loop below, which panics
+		// once the blobs before it are hashed
+		if ValidateAlgorithm(blobs[i], algo) != nil || ValidateAlgorithm(blobs[i+1], algo) != nil {
+			break
+		}
+		var out [2][32]byte
+		cc.safeSum2(&out, blobs[i], blobs[i+1], p, height)
+		dst[i], dst[i+1] = out[0], out[1]
+		if cc.crossChecked() {
+			crossCheck(blobs[i], p.variant, dst[i][:])
+			crossCheck(blobs[i+1], p.variant, dst[i+1][:])
+		}
+	}
+	for ; i < len(blobs); i++ {
+		cc.safeSum(dst[i][:0], blobs[i], p, height)
+		if cc.crossChecked() && algo <= CNR {
+			crossCheck(blobs[i], p.variant, dst[i][:])
+		}
+	}
+}
+
+// ValidateAlgorithm reports whether data can be hashed with algo. It returns
+// ErrUnknownAlgorithm if algo is not one of the constants of this package, and
+// ErrShortInput if algo is based on variant 1 and data is shorter than 43
+// bytes, and ErrShortSalt if algo is Chukwa or ChukwaV2 and data is shorter
+// than 16 bytes. Any other input is valid, including an empty one, and so is
+// any height of CNR.
+func ValidateAlgorithm(data []byte, algo Algorithm) error {
+	if !algo.valid() {
+		return ErrUnknownAlgorithm
+	}
+	if algorithms[algo].p.variant == 1 && len(data) < 43 {
+		return ErrShortInput
+	}
+	if algorithms[algo].p.argon2 && len(data) < chukwaSaltSize {
+		return ErrShortSalt
+	}
+
+	return nil
+}
+
+// SumChecked is like SumAlgorithm, but returns the error of ValidateAlgorithm
+// instead of panicking with it, which makes it suitable for untrusted input
+// such as the blobs submitted by miners.
+func SumChecked(data []byte, algo Algorithm, height uint64) ([]byte, error) {
+	if err := ValidateAlgorithm(data, algo); err != nil {
+		observe.Error(err)
+		return nil, err
+	}
+
+	return SumAlgorithm(data, algo, height), nil
+}
+
+// SumChecked is like Cache.SumAlgorithm, but returns the error of
+// ValidateAlgorithm instead of panicking with it. It still panics with
+// ErrCacheInUse if cc is used by another goroutine, as it is a bug of the
+// caller rather than bad input. For a Cache created by NewCacheLimit, it
+// returns ErrMemoryLimit for the algorithms needing more memory.
+func (cc *Cache) SumChecked(data []byte, algo Algorithm, height uint64) ([]byte, error) {
+	if err := ValidateAlgorithm(data, algo); err != nil {
+		observe.Error(err)
+		return nil, err
+	}
+	if !cc.fits(algo.Memory()) {
+		observe.Error(ErrMemoryLimit)
+		return nil, ErrMemoryLimit
+	}
+
+	return cc.SumAlgorithm(data, algo, height), nil
+}
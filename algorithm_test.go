@@ -0,0 +1,347 @@
+package cryptonight
+
+import (
+	"bytes"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseAlgorithm(t *testing.T) {
+	for i := range algorithms {
+		a := Algorithm(i)
+		if got, err := ParseAlgorithm(a.String()); err != nil || got != a {
+			t.Errorf("%s: got %v, %v", a, got, err)
+		}
+	}
+
+	for i, v := range []struct {
+		name string
+		algo Algorithm
+		err  error
+	}{
+		{"cn/2", CNv2, nil},
+		{"CN/R", CNR, nil},
+		{" cn-lite/1 ", CNLite1, nil},
+		{"cryptonight", CNv0, nil},
+		{"cryptonight/1", CNv1, nil},
+		{"cryptonight-lite/0", CNLite0, nil},
+		{"cryptonight-heavy", CNHeavy, nil},
+		{"cn/msr", CNFast, nil},
+		{"cn-pico/trtl", CNPico, nil},
+		{"cryptonight-turtle", CNPico, nil},
+		{"Argon2/Chukwa", Chukwa, nil},
+		{"chukwav2", ChukwaV2, nil},
+		{"", 0, ErrUnknownAlgorithm},
+		{"cn/3", 0, ErrUnknownAlgorithm},
+		{"cn-lite/2", 0, ErrUnknownAlgorithm},
+		{"rx/0", 0, ErrUnknownAlgorithm},
+	} {
+		if algo, err := ParseAlgorithm(v.name); algo != v.algo || err != v.err {
+			t.Errorf("[%d] %q: expected %v, %v, got %v, %v", i, v.name, v.algo, v.err, algo, err)
+		}
+	}
+
+	if s := Algorithm(-1).String(); s != "Algorithm(-1)" {
+		t.Errorf("unexpected name of an unknown algorithm: %s", s)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	for _, v := range []struct {
+		algo   Algorithm
+		memory int
+	}{
+		{CNPico, 256 << 10},
+		{Chukwa, 512 << 10},
+		{ChukwaV2, 1 << 20},
+		{CNLite1, 1 << 20},
+		{CNv2, 2 << 20},
+		{CNHeavy, 4 << 20},
+		{Algorithm(-1), 0},
+	} {
+		if m := v.algo.Memory(); m != v.memory {
+			t.Errorf("%s: expected %d, got %d", v.algo, v.memory, m)
+		}
+	}
+
+	// a Cache is sized to the largest algorithm hashed with it
+	cc := new(Cache)
+	for _, v := range []struct {
+		algo   Algorithm
+		memory int
+	}{
+		{CNPico, 256 << 10},
+		{CNLite0, 1 << 20},
+		{CNPico, 1 << 20},
+	} {
+		cc.SumAlgorithm(nil, v.algo, 0)
+		if m := cc.Memory(); m != v.memory {
+			t.Errorf("after %s: expected %d, got %d", v.algo, v.memory, m)
+		}
+	}
+}
+
+func TestCacheLimit(t *testing.T) {
+	cc := NewCacheLimit(CNPico.Memory())
+	v := hashSpecsPico[0]
+	in, _ := hex.DecodeString(v.input)
+	if result := cc.SumAlgorithm(in, CNPico, 0); hex.EncodeToString(result) != v.output {
+		t.Errorf("\nexpected:\n\t%s\ngot:\n\t%x\n", v.output, result)
+	}
+	if out := cc.Sum2(in, in, CNPico, 0); hex.EncodeToString(out[1][:]) != v.output {
+		t.Errorf("Sum2: unexpected digest %x", out[1])
+	}
+	if _, err := cc.SumChecked(make([]byte, 43), CNLite1, 0); err != ErrMemoryLimit {
+		t.Errorf("SumChecked: expected ErrMemoryLimit, got %v", err)
+	}
+	if m := cc.Memory(); m != CNPico.Memory() {
+		t.Errorf("expected a scratchpad of %d bytes, got %d", CNPico.Memory(), m)
+	}
+
+	// the digests of CNv0 are computed one at a time within 2 MiB
+	cc = NewCacheLimit(CNv0.Memory())
+	var dst [2][32]byte
+	cc.SumMany(dst[:], [][]byte{in, in}, CNv0, 0)
+	if sum := SumAlgorithm(in, CNv0, 0); !bytes.Equal(dst[1][:], sum) {
+		t.Errorf("SumMany: expected %x, got %x", sum, dst[1])
+	}
+
+	defer func() {
+		if err := recover(); err != ErrMemoryLimit {
+			t.Errorf("expected panic with ErrMemoryLimit, got %v", err)
+		}
+	}()
+	cc.SumAlgorithm(nil, CNHeavy, 0)
+}
+
+func TestSumAlgorithm(t *testing.T) {
+	specs := map[Algorithm]hashSpec{
+		CNv0:     hashSpecsV0[1],
+		CNv1:     hashSpecsV1[0],
+		CNv2:     hashSpecsV2[0],
+		CNFast:   hashSpecsFast[0],
+		CNHalf:   hashSpecsHalf[0],
+		CNXTL:    hashSpecsXTL[0],
+		CNRWZ:    hashSpecsRWZ[0],
+		CNZLS:    hashSpecsZLS[0],
+		CNDouble: hashSpecsDouble[0],
+		CNGPU:    hashSpecsGPU[0],
+		CNLite0:  hashSpecsLite[0],
+		CNLite1:  hashSpecsLite[1],
+		CNHeavy:  hashSpecsHeavy[0],
+		CNPico:   hashSpecsPico[0],
+		Chukwa:   hashSpecsChukwa[0],
+		ChukwaV2: hashSpecsChukwaV2[0],
+	}
+	if len(specs)+1 != len(algorithms) {
+		t.Fatal("some algorithms are not tested")
+	}
+
+	cc := new(Cache)
+	for algo, v := range specs {
+		in, _ := hex.DecodeString(v.input)
+		for name, sum := range map[string]func([]byte, Algorithm, uint64) []byte{
+			"SumAlgorithm":       SumAlgorithm,
+			"Cache.SumAlgorithm": cc.SumAlgorithm,
+		} {
+			if result := sum(in, algo, 0); hex.EncodeToString(result) != v.output {
+				t.Errorf("\n[%s %s] expected:\n\t%s\ngot:\n\t%x\n", name, algo, v.output, result)
+			}
+		}
+	}
+
+	v := hashSpecsV4[0]
+	in, _ := hex.DecodeString(v.input)
+	if result := cc.SumAlgorithm(in, CNR, v.height); hex.EncodeToString(result) != v.output {
+		t.Errorf("\n[%s] expected:\n\t%s\ngot:\n\t%x\n", CNR, v.output, result)
+	}
+
+	defer func() {
+		if err := recover(); err != ErrUnknownAlgorithm {
+			t.Errorf("expected panic with ErrUnknownAlgorithm, got %v", err)
+		}
+	}()
+	SumAlgorithm(nil, Algorithm(len(algorithms)), 0)
+}
+
+// TestCorpus runs every vector of every algorithm through SumAlgorithm and the
+// Go backend: the whole of monero's tests/hash, from testdata/monero or from the
+// tree given with -monero, including the inputs of 43 bytes of variant 1 and the
+// heights of variant 4, plus the ones of CNS008, xmrig and TurtleCoin.
+func TestCorpus(t *testing.T) {
+	type vector struct {
+		input  []byte
+		output string
+		height uint64
+	}
+	corpus := make(map[Algorithm][]vector)
+	add := func(algo Algorithm, specs ...hashSpec) {
+		for _, v := range specs {
+			in, _ := hex.DecodeString(v.input)
+			corpus[algo] = append(corpus[algo], vector{in, v.output, 0})
+		}
+	}
+	dir := filepath.Join("testdata", "monero")
+	if *moneroDir != "" {
+		dir = *moneroDir
+	}
+	monero, err := loadMoneroVectors(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range monero {
+		in, _ := hex.DecodeString(v.input)
+		algo := map[int]Algorithm{0: CNv0, 1: CNv1, 2: CNv2, 4: CNR}[v.variant]
+		corpus[algo] = append(corpus[algo], vector{in, v.output, v.height})
+	}
+	if len(corpus[CNR]) == 0 {
+		t.Fatalf("%s: no vectors of variant 4", dir)
+	}
+
+	add(CNv0, hashSpecsV0[:2]...) // CNS008
+	add(CNv1, hashSpecsV1[5:]...) // produced by cn_slow_hash, not in tests/hash
+	add(CNFast, hashSpecsFast...)
+	add(CNHalf, hashSpecsHalf...)
+	add(CNXTL, hashSpecsXTL...)
+	add(CNRWZ, hashSpecsRWZ...)
+	add(CNZLS, hashSpecsZLS...)
+	add(CNDouble, hashSpecsDouble...)
+	add(CNGPU, hashSpecsGPU...)
+	add(CNHeavy, hashSpecsHeavy...)
+	add(CNPico, hashSpecsPico...)
+	add(Chukwa, hashSpecsChukwa...)
+	add(ChukwaV2, hashSpecsChukwaV2...)
+	for _, v := range hashSpecsLite {
+		add(CNLite0+Algorithm(v.variant), v)
+	}
+	if len(corpus) != len(algorithms) {
+		t.Fatalf("expected vectors for all the %d algorithms, got %d", len(algorithms), len(corpus))
+	}
+
+	cc := new(Cache)
+	for algo, vectors := range corpus {
+		for i, v := range vectors {
+			for name, sum := range map[string]func([]byte, Algorithm, uint64) []byte{
+				"SumAlgorithm":       SumAlgorithm,
+				"Cache.SumAlgorithm": cc.SumAlgorithm,
+				"sumGo": func(data []byte, algo Algorithm, height uint64) []byte {
+					return cc.sumGo(data, algorithms[algo].p, height)
+				},
+			} {
+				if result := sum(v.input, algo, v.height); hex.EncodeToString(result) != v.output {
+					t.Errorf("\n[%s %s %d] expected:\n\t%s\ngot:\n\t%x\n", name, algo, i, v.output, result)
+				}
+			}
+		}
+	}
+}
+
+func TestSumChecked(t *testing.T) {
+	cc := new(Cache)
+	for i, v := range []struct {
+		size int
+		algo Algorithm
+		err  error
+	}{
+		{0, CNv0, nil},
+		{0, CNR, nil},
+		{42, CNv1, ErrShortInput},
+		{43, CNv1, nil},
+		{42, CNFast, ErrShortInput},
+		{42, CNXTL, ErrShortInput},
+		{0, CNLite1, ErrShortInput},
+		{0, CNPico, nil},
+		{15, Chukwa, ErrShortSalt},
+		{16, Chukwa, nil},
+		{0, ChukwaV2, ErrShortSalt},
+		{0, -1, ErrUnknownAlgorithm},
+		{43, Algorithm(len(algorithms)), ErrUnknownAlgorithm},
+	} {
+		data := make([]byte, v.size)
+		if err := ValidateAlgorithm(data, v.algo); err != v.err {
+			t.Errorf("[%d] ValidateAlgorithm: expected %v, got %v", i, v.err, err)
+		}
+		for name, sum := range map[string]func([]byte, Algorithm, uint64) ([]byte, error){
+			"SumChecked":       SumChecked,
+			"Cache.SumChecked": cc.SumChecked,
+		} {
+			out, err := sum(data, v.algo, 0)
+			if err != v.err {
+				t.Errorf("[%d] %s: expected %v, got %v", i, name, v.err, err)
+			}
+			if (err == nil) != (len(out) == 32) {
+				t.Errorf("[%d] %s: unexpected digest %x with error %v", i, name, out, err)
+			}
+		}
+	}
+}
+
+func TestSumInto(t *testing.T) {
+	cc := new(Cache)
+	in, _ := hex.DecodeString(hashSpecsV2[0].input)
+	var dst [32]byte
+	cc.SumInto(&dst, in, CNv2, 0)
+	if hex.EncodeToString(dst[:]) != hashSpecsV2[0].output {
+		t.Errorf("\nexpected:\n\t%s\ngot:\n\t%x\n", hashSpecsV2[0].output, dst)
+	}
+
+	for _, algo := range []Algorithm{CNv0, CNv1, CNv2, CNR, CNHalf, CNLite1, CNHeavy, CNPico, ChukwaV2} {
+		cc.SumInto(&dst, in, algo, 1806260) // the first CNHeavy allocates
+		if n := testing.AllocsPerRun(3, func() { cc.SumInto(&dst, in, algo, 1806260) }); n != 0 {
+			t.Errorf("%s: SumInto allocates %v times", algo, n)
+		}
+	}
+}
+
+func TestSumMany(t *testing.T) {
+	cc := new(Cache)
+	blobs := make([][]byte, len(hashSpecsV1))
+	for i, v := range hashSpecsV1 {
+		blobs[i], _ = hex.DecodeString(v.input)
+	}
+	dst := make([][32]byte, len(blobs)+1)
+	cc.SumMany(dst, blobs, CNv1, 0)
+	for i, v := range hashSpecsV1 {
+		if hex.EncodeToString(dst[i][:]) != v.output {
+			t.Errorf("\n[%d] expected:\n\t%s\ngot:\n\t%x\n", i, v.output, dst[i])
+		}
+	}
+	if dst[len(blobs)] != [32]byte{} {
+		t.Error("SumMany wrote past the digests of blobs")
+	}
+
+	if n := testing.AllocsPerRun(1, func() { cc.SumMany(dst, blobs[:2], CNv1, 0) }); n != 0 {
+		t.Errorf("SumMany allocates %v times", n)
+	}
+
+	// the digests before a short blob are computed, and the ones after are not
+	for _, n := range []int{1, 2, 3} {
+		short := append(append(append([][]byte{}, blobs[:n]...), make([]byte, 42)), blobs[0])
+		dst := make([][32]byte, len(short))
+		func() {
+			defer func() {
+				if r := recover(); r != ErrShortInput {
+					t.Errorf("[%d] expected to panic with ErrShortInput, got %v.", n, r)
+				}
+			}()
+			cc.SumMany(dst, short, CNv1, 0)
+		}()
+		for i, d := range dst {
+			if i < n && hex.EncodeToString(d[:]) != hashSpecsV1[i].output || i >= n && d != [32]byte{} {
+				t.Errorf("[%d] unexpected digest %d: %x", n, i, d)
+			}
+		}
+	}
+
+	// too few digests
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+		if cc.inUse != 0 {
+			t.Error("cc left in use")
+		}
+	}()
+	cc.SumMany(dst[:1], blobs[:2], CNv1, 0)
+}
@@ -0,0 +1,44 @@
+package cryptonight
+
+import (
+	"errors"
+)
+
+// errBadAllocation is the error of an Allocator returning too little memory,
+// or memory not aligned to 16 bytes.
+var errBadAllocation = errors.New("cryptonight: Allocator returned short or misaligned memory")
+
+// Allocator provides the scratchpad of a Cache, for users who want to place it
+// themselves, such as in locked memory, on a given NUMA node or in lazily
+// mapped memory. See NewCacheWithAllocator.
+//
+// The memory returned by an Allocator is not managed by the garbage collector,
+// and must stay valid until it is given back to Free.
+type Allocator interface {
+	// Alloc returns at least n bytes of memory, aligned to 16 bytes. It does
+	// not need to be zeroed.
+	Alloc(n int) ([]byte, error)
+
+	// Free releases mem, which was returned by Alloc.
+	Free(mem []byte) error
+}
+
+// NewCacheWithAllocator returns a new Cache whose scratchpad comes from a.
+// Alloc is called by the first hash with the size of its scratchpad, and again
+// whenever a later hash needs a larger one, the previous memory being given
+// back to Free. A hash panics if Alloc fails. The memory is given back to a by
+// Close, which must be called once cc is no longer used.
+//
+// It returns ErrAllocatorUnsupported with the purego build tag or TinyGo, as
+// placing a scratchpad in memory of its own requires unsafe.
+func NewCacheWithAllocator(a Allocator) (*Cache, error) {
+	return allocCache(a)
+}
+
+// mapper allocates and frees the scratchpad of a Cache outside of the Go
+// heap, which starts at mem[off].
+type mapper struct {
+	alloc func(n int) (mem []byte, off int, err error)
+	free  func(mem []byte) error
+	huge  bool // for NewCacheHugePages
+}
@@ -0,0 +1,11 @@
+// +build purego tinygo
+
+package cryptonight
+
+func allocCache(a Allocator) (*Cache, error) { return nil, ErrAllocatorUnsupported }
+
+func (cc *Cache) growPad(memory int) {
+	cc.scratchpad = make([]uint64, memory/8)
+}
+
+func unmapCache(cc *Cache) error { return nil }
@@ -0,0 +1,86 @@
+package cryptonight
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// heapAllocator allocates in the Go heap, keeping count of the memory it has
+// given, and offsets it by misalign bytes.
+type heapAllocator struct {
+	live     map[*byte]int
+	misalign int
+}
+
+func (a *heapAllocator) Alloc(n int) ([]byte, error) {
+	mem := make([]byte, n+a.misalign)[a.misalign:]
+	a.live[&mem[0]] = len(mem)
+
+	return mem, nil
+}
+
+func (a *heapAllocator) Free(mem []byte) error {
+	if _, ok := a.live[&mem[0]]; !ok {
+		return errors.New("unknown memory")
+	}
+	delete(a.live, &mem[0])
+
+	return nil
+}
+
+func TestNewCacheWithAllocator(t *testing.T) {
+	a := &heapAllocator{live: make(map[*byte]int)}
+	cc, err := NewCacheWithAllocator(a)
+	if err == ErrAllocatorUnsupported {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.live) != 0 || cc.HugePages() {
+		t.Fatalf("unexpected allocations %v", a.live)
+	}
+
+	for _, v := range []struct {
+		algo Algorithm
+		spec hashSpec
+		size int
+	}{
+		{CNPico, hashSpecsPico[0], pico.memory},
+		{CNv2, hashSpecsV2[0], 2 * 1024 * 1024},
+		{CNHeavy, hashSpecsHeavy[0], heavy.memory},
+		{CNv2, hashSpecsV2[1], heavy.memory},
+	} {
+		in, _ := hex.DecodeString(v.spec.input)
+		if result := cc.SumAlgorithm(in, v.algo, 0); hex.EncodeToString(result) != v.spec.output {
+			t.Errorf("\n[%s] expected:\n\t%s\ngot:\n\t%x\n", v.algo, v.spec.output, result)
+		}
+		// the scratchpad only grows, giving the previous one back
+		for _, n := range a.live {
+			if len(a.live) != 1 || n != v.size {
+				t.Errorf("[%s] unexpected allocations %v", v.algo, a.live)
+			}
+		}
+	}
+
+	if err := cc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.live) != 0 {
+		t.Errorf("memory left after Close: %v", a.live)
+	}
+
+	// the first hash panics if the allocation fails, and misaligned memory is
+	// given back
+	a.misalign = 8
+	cc, _ = NewCacheWithAllocator(a)
+	func() {
+		defer func() {
+			if r := recover(); r != errBadAllocation || len(a.live) != 0 {
+				t.Errorf("expected to panic with errBadAllocation, got %v, %v", r, a.live)
+			}
+		}()
+		cc.Sum(nil, 0)
+	}()
+}
@@ -0,0 +1,72 @@
+// +build !purego,!tinygo
+
+package cryptonight
+
+import (
+	"reflect"
+	"unsafe"
+
+	"ekyu.moe/cryptonight/internal/observe"
+)
+
+func allocCache(a Allocator) (*Cache, error) {
+	return &Cache{alloc: &mapper{
+		alloc: func(n int) ([]byte, int, error) {
+			mem, err := a.Alloc(n)
+			if err != nil {
+				return nil, 0, err
+			}
+			if len(mem) < n || uintptr(unsafe.Pointer(&mem[0]))&15 != 0 {
+				a.Free(mem)
+				return nil, 0, errBadAllocation
+			}
+			return mem, 0, nil
+		},
+		free: a.Free,
+	}}, nil
+}
+
+// growPad replaces the scratchpad of cc with one of memory bytes, from the Go
+// heap or from cc.alloc.
+func (cc *Cache) growPad(memory int) {
+	if cc.alloc == nil {
+		cc.scratchpad = make([]uint64, memory/8)
+		return
+	}
+
+	mem, off, err := cc.alloc.alloc(memory)
+	if err != nil {
+		panic(err)
+	}
+	if cc.mem != nil {
+		if err := cc.alloc.free(cc.mem); err != nil {
+			observe.Error(err)
+		}
+	}
+	cc.setPad(mem, off, memory)
+}
+
+// setPad sets the scratchpad of cc to memory bytes from mem[off].
+func (cc *Cache) setPad(mem []byte, off, memory int) {
+	var sp []uint64
+	h := (*reflect.SliceHeader)(unsafe.Pointer(&sp))
+	h.Data = uintptr(unsafe.Pointer(&mem[off]))
+	h.Len = memory / 8
+	h.Cap = memory / 8
+
+	cc.scratchpad = sp
+	cc.mem = mem
+}
+
+func unmapCache(cc *Cache) error {
+	if cc.alloc == nil {
+		return nil
+	}
+	mem, free := cc.mem, cc.alloc.free
+	cc.scratchpad, cc.mem, cc.alloc = nil, nil, nil
+	if mem == nil {
+		return nil
+	}
+
+	return free(mem)
+}
@@ -1,3 +1,5 @@
+// +build amd64,!purego,!tinygo
+
 package cryptonight
 
 //go:noescape
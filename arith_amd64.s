@@ -1,3 +1,5 @@
+// +build amd64,!purego,!tinygo
+
 #include "textflag.h"
 #include "sum_defs_amd64.h"
 
@@ -1,6 +1,8 @@
 package cryptonight
 
 import (
+	"math/big"
+	"math/rand"
 	"testing"
 )
 
@@ -53,3 +55,25 @@ func TestV2Sqrt(t *testing.T) {
 		}
 	}
 }
+
+// TestMul128 checks mul128, which is in assembly on amd64, against math/big.
+func TestMul128(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < 10000; i++ {
+		x, y := r.Uint64(), r.Uint64()
+		switch i {
+		case 0:
+			x, y = 0, ^uint64(0)
+		case 1:
+			x, y = ^uint64(0), ^uint64(0)
+		}
+
+		lo, hi := mul128(x, y)
+		expected := new(big.Int).Mul(new(big.Int).SetUint64(x), new(big.Int).SetUint64(y))
+		result := new(big.Int).Lsh(new(big.Int).SetUint64(hi), 64)
+		result.Or(result, new(big.Int).SetUint64(lo))
+		if result.Cmp(expected) != 0 {
+			t.Fatalf("%d * %d: expected %v, got %v\n", x, y, expected, result)
+		}
+	}
+}
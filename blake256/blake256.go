@@ -0,0 +1,206 @@
+// Package blake256 implements the BLAKE-256 hash algorithm, as submitted to
+// the NIST SHA-3 competition, in its final round version of 14 rounds. It is
+// one of the final hashes of CryptoNight and the seed of the random programs
+// of CryptoNight-R, and can be used on its own through New, a hash.Hash
+// accepting input in any number of writes.
+//
+// This Go implementation follows the original C implementation which is
+// included in Monero as follows:
+//     src/crypto/blake256.c
+//     src/crypto/blake256.h
+//
+// Only the parameters needed by CryptoNight are supported: the digest is 256
+// bits long and the salt is always zero. BLAKE-224 and HMAC are left out.
+package blake256 // import "ekyu.moe/cryptonight/blake256"
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// Size is the size of a BLAKE-256 digest in bytes.
+const Size = 32
+
+// BlockSize is the block size of BLAKE-256 in bytes.
+const BlockSize = 64
+
+var iv = [8]uint32{
+	0x6a09e667, 0xbb67ae85, 0x3c6ef372, 0xa54ff53a,
+	0x510e527f, 0x9b05688c, 0x1f83d9ab, 0x5be0cd19,
+}
+
+var cst = [16]uint32{
+	0x243f6a88, 0x85a308d3, 0x13198a2e, 0x03707344,
+	0xa4093822, 0x299f31d0, 0x082efa98, 0xec4e6c89,
+	0x452821e6, 0x38d01377, 0xbe5466cf, 0x34e90c6c,
+	0xc0ac29b7, 0xc97c50dd, 0x3f84d5b5, 0xb5470917,
+}
+
+var sigma = [10][16]uint8{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+	{11, 8, 12, 0, 5, 2, 15, 13, 10, 14, 3, 6, 7, 1, 9, 4},
+	{7, 9, 3, 1, 13, 12, 11, 14, 2, 6, 5, 10, 4, 0, 15, 8},
+	{9, 0, 5, 7, 2, 4, 10, 15, 14, 1, 11, 12, 6, 8, 3, 13},
+	{2, 12, 6, 10, 0, 11, 8, 3, 4, 13, 7, 5, 15, 14, 1, 9},
+	{12, 5, 1, 15, 14, 13, 4, 10, 0, 7, 6, 3, 9, 2, 8, 11},
+	{13, 11, 7, 14, 12, 1, 3, 9, 5, 0, 15, 4, 8, 6, 2, 10},
+	{6, 15, 14, 9, 11, 3, 0, 8, 12, 2, 13, 7, 1, 4, 10, 5},
+	{10, 2, 8, 4, 7, 6, 1, 5, 15, 11, 9, 14, 3, 12, 13, 0},
+}
+
+type state struct {
+	h [8]uint32 // chain value
+	t uint64    // number of message bits hashed so far
+
+	buf    [BlockSize]byte // data buffer
+	bufPtr int             // data buffer pointer
+}
+
+// Sum256 returns the BLAKE-256 digest of b.
+func Sum256(b []byte) []byte {
+	h := New()
+	h.Write(b)
+
+	return h.Sum(nil)
+}
+
+// New returns a new hash.Hash computing BLAKE-256.
+func New() hash.Hash {
+	return &state{h: iv}
+}
+
+// Reset resets the state to the one of New.
+func (s *state) Reset() {
+	*s = state{h: iv}
+}
+
+func (s *state) Size() int      { return Size }
+func (s *state) BlockSize() int { return BlockSize }
+
+// Write absorbs more data into the hash's state.
+func (s *state) Write(data []byte) (n int, err error) {
+	n = len(data)
+
+	if s.bufPtr > 0 {
+		c := copy(s.buf[s.bufPtr:], data)
+		s.bufPtr += c
+		data = data[c:]
+		if s.bufPtr < BlockSize {
+			return
+		}
+		s.t += BlockSize * 8
+		s.compress(s.buf[:], false)
+		s.bufPtr = 0
+	}
+	for len(data) > BlockSize {
+		s.t += BlockSize * 8
+		s.compress(data[:BlockSize], false)
+		data = data[BlockSize:]
+	}
+	// the last block is kept even when full, as it may be the final one
+	s.bufPtr = copy(s.buf[:], data)
+
+	return
+}
+
+// Sum appends the current hash to b and returns the resulting slice. It does
+// not change the underlying hash state.
+func (s *state) Sum(b []byte) []byte {
+	d := *s
+	return d.sum(b)
+}
+
+func (s *state) sum(b []byte) []byte {
+	length := s.t + uint64(s.bufPtr)*8
+
+	var tail [2 * BlockSize]byte
+	n := copy(tail[:], s.buf[:s.bufPtr])
+	tail[n] = 0x80
+	// the counter of a block holding no message bits is zero
+	if n < 56 {
+		n = BlockSize
+	} else {
+		n = 2 * BlockSize
+	}
+	tail[n-9] |= 0x01
+	binary.BigEndian.PutUint64(tail[n-8:], length)
+
+	for i := 0; i < n; i += BlockSize {
+		m := s.bufPtr - i
+		switch {
+		case m > BlockSize:
+			m = BlockSize
+		case m < 0:
+			m = 0
+		}
+		s.t += uint64(m) * 8
+		s.compress(tail[i:i+BlockSize], m == 0)
+	}
+
+	var out [Size]byte
+	for i, v := range s.h {
+		binary.BigEndian.PutUint32(out[4*i:], v)
+	}
+
+	return append(b, out[:]...)
+}
+
+// compress processes one block of 64 bytes, with the counter s.t unless
+// nullT is set.
+func (s *state) compress(block []byte, nullT bool) {
+	var m [16]uint32
+	for i := range m {
+		m[i] = binary.BigEndian.Uint32(block[4*i:])
+	}
+
+	v0, v1, v2, v3, v4, v5, v6, v7 := s.h[0], s.h[1], s.h[2], s.h[3], s.h[4], s.h[5], s.h[6], s.h[7]
+	v8, v9, v10, v11 := cst[0], cst[1], cst[2], cst[3]
+	v12, v13, v14, v15 := cst[4], cst[5], cst[6], cst[7]
+	if !nullT {
+		v12 ^= uint32(s.t)
+		v13 ^= uint32(s.t)
+		v14 ^= uint32(s.t >> 32)
+		v15 ^= uint32(s.t >> 32)
+	}
+
+	for r := 0; r < 14; r++ {
+		// the indices are masked so that no bounds check is needed
+		p := &sigma[r%10]
+		// columns
+		v0, v4, v8, v12 = g(v0, v4, v8, v12, m[p[0]&15]^cst[p[1]&15], m[p[1]&15]^cst[p[0]&15])
+		v1, v5, v9, v13 = g(v1, v5, v9, v13, m[p[2]&15]^cst[p[3]&15], m[p[3]&15]^cst[p[2]&15])
+		v2, v6, v10, v14 = g(v2, v6, v10, v14, m[p[4]&15]^cst[p[5]&15], m[p[5]&15]^cst[p[4]&15])
+		v3, v7, v11, v15 = g(v3, v7, v11, v15, m[p[6]&15]^cst[p[7]&15], m[p[7]&15]^cst[p[6]&15])
+		// diagonals
+		v0, v5, v10, v15 = g(v0, v5, v10, v15, m[p[8]&15]^cst[p[9]&15], m[p[9]&15]^cst[p[8]&15])
+		v1, v6, v11, v12 = g(v1, v6, v11, v12, m[p[10]&15]^cst[p[11]&15], m[p[11]&15]^cst[p[10]&15])
+		v2, v7, v8, v13 = g(v2, v7, v8, v13, m[p[12]&15]^cst[p[13]&15], m[p[13]&15]^cst[p[12]&15])
+		v3, v4, v9, v14 = g(v3, v4, v9, v14, m[p[14]&15]^cst[p[15]&15], m[p[15]&15]^cst[p[14]&15])
+	}
+
+	s.h[0] ^= v0 ^ v8
+	s.h[1] ^= v1 ^ v9
+	s.h[2] ^= v2 ^ v10
+	s.h[3] ^= v3 ^ v11
+	s.h[4] ^= v4 ^ v12
+	s.h[5] ^= v5 ^ v13
+	s.h[6] ^= v6 ^ v14
+	s.h[7] ^= v7 ^ v15
+}
+
+// g is the G function of the specification, with x and y being the message
+// words already XORed with their constants.
+func g(a, b, c, d, x, y uint32) (uint32, uint32, uint32, uint32) {
+	a += x + b
+	d = bits.RotateLeft32(d^a, -16)
+	c += d
+	b = bits.RotateLeft32(b^c, -12)
+	a += y + b
+	d = bits.RotateLeft32(d^a, -8)
+	c += d
+	b = bits.RotateLeft32(b^c, -7)
+
+	return a, b, c, d
+}
@@ -0,0 +1,95 @@
+package blake256
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+
+	"github.com/dchest/blake256"
+)
+
+var blakeSpecs = []struct {
+	input  string
+	output string
+}{
+	// as per the final round submission to NIST
+	{"", "716f6e863f744b9ac22c97ec7b76ea5f5908bc5b2f67c61510bfc4751384ea7a"},
+	{"00", "0ce8d4ef4dd7cd8d62dfded9d4edb0a774ae6a41929a74da23109e8f11139c87"},
+	{hex.EncodeToString(make([]byte, 72)), "d419bad32d504fb7d44d460c42c5593fe544fa4c135dec31e21bd9abdcc22d41"},
+}
+
+func TestSum256(t *testing.T) {
+	for i, v := range blakeSpecs {
+		in, _ := hex.DecodeString(v.input)
+		if out := hex.EncodeToString(Sum256(in)); out != v.output {
+			t.Errorf("\n[%d] expected:\n\t%s\ngot:\n\t%s\n", i, v.output, out)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	in := make([]byte, 3*BlockSize+7)
+	for i := range in {
+		in[i] = byte(i)
+	}
+	want := Sum256(in)
+
+	h := New()
+	if h.Size() != Size || h.BlockSize() != BlockSize {
+		t.Fatalf("unexpected sizes %d, %d", h.Size(), h.BlockSize())
+	}
+
+	// written in chunks of every size, across block boundaries
+	for n := 1; n <= 2*BlockSize; n++ {
+		h.Reset()
+		for p := in; len(p) > 0; {
+			m := n
+			if m > len(p) {
+				m = len(p)
+			}
+			h.Write(p[:m])
+			p = p[m:]
+		}
+		if sum := h.Sum(nil); !bytes.Equal(sum, want) {
+			t.Fatalf("chunks of %d bytes:\nexpected:\n\t%x\ngot:\n\t%x\n", n, want, sum)
+		}
+	}
+
+	// Sum does not change the state
+	h.Reset()
+	h.Write(in[:10])
+	h.Sum(nil)
+	h.Write(in[10:])
+	prefix := []byte("prefix")
+	if sum := h.Sum(prefix); !bytes.Equal(sum[:len(prefix)], prefix) || !bytes.Equal(sum[len(prefix):], want) {
+		t.Errorf("\nexpected:\n\t%x\ngot:\n\t%x\n", want, sum[len(prefix):])
+	}
+}
+
+// TestCrossCheck compares BLAKE-256 against github.com/dchest/blake256, which
+// the final hash of CryptoNight used before, on every length around the
+// padding boundaries.
+func TestCrossCheck(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	for size := 0; size <= 3*BlockSize; size++ {
+		data := make([]byte, size)
+		r.Read(data)
+
+		h := blake256.New()
+		h.Write(data)
+		expected := h.Sum(nil)
+
+		if result := Sum256(data); !bytes.Equal(result, expected) {
+			t.Errorf("\n[size %d] expected:\n\t%x\ngot:\n\t%x\n", size, expected, result)
+		}
+	}
+}
+
+func BenchmarkSum256(b *testing.B) {
+	in := make([]byte, 200)
+	b.SetBytes(int64(len(in)))
+	for i := 0; i < b.N; i++ {
+		Sum256(in)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// exitRegression is the exit code when a result regresses beyond -threshold.
+const exitRegression = 2
+
+// key identifies comparable results.
+type key struct {
+	variant, threads int
+	backend          string
+}
+
+func loadReport(name string) (*Report, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	report := new(Report)
+	if err := json.NewDecoder(f).Decode(report); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// compare writes the change of hashrate of every result of report against the
+// matching one in baseline. It returns false if any of them dropped by more
+// than threshold percent. Results without a match are listed but never
+// considered a regression.
+func compare(out io.Writer, baseline, report *Report, threshold float64) (bool, error) {
+	base := make(map[key]Result, len(baseline.Results))
+	for _, r := range baseline.Results {
+		base[key{r.Variant, r.Threads, r.Backend}] = r
+	}
+
+	ok := true
+	fmt.Fprintf(out, "\ncompared to baseline of %s (%s %s/%s), threshold %.1f%%\n\n",
+		baseline.Date.Format("2006-01-02 15:04"), baseline.GoVersion, baseline.GOOS, baseline.GOARCH, threshold)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "variant\tbackend\tthreads\tbaseline H/s\tH/s\tdelta\t\t")
+	for _, r := range report.Results {
+		b, found := base[key{r.Variant, r.Threads, r.Backend}]
+		if !found || b.Hashrate == 0 {
+			fmt.Fprintf(w, "%d\t%s\t%d\t-\t%.2f\t-\tnew\t\n", r.Variant, r.Backend, r.Threads, r.Hashrate)
+			continue
+		}
+
+		delta := (r.Hashrate - b.Hashrate) / b.Hashrate * 100
+		status := "ok"
+		if -delta > threshold {
+			status = "REGRESSION"
+			ok = false
+		}
+		fmt.Fprintf(w, "%d\t%s\t%d\t%.2f\t%.2f\t%+.1f%%\t%s\t\n", r.Variant, r.Backend, r.Threads, b.Hashrate, r.Hashrate, delta, status)
+	}
+
+	return ok, w.Flush()
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ekyu.moe/cryptonight"
+)
+
+// scratchpadSize is the memory each hashing thread works on.
+const scratchpadSize = 2 * 1024 * 1024
+
+// Rough single thread hashrate range of variant 0 on typical desktop and
+// server CPUs, by backend, see rangeKey. Outside of this range something is
+// likely off.
+var expectedRange = map[string][2]float64{
+	"amd64-aes": {30, 100},
+	"go":        {8, 40},
+	"arm64-aes": {10, 60},
+	"arm64-go":  {3, 20},
+}
+
+// rangeKey returns the key of the expected hashrate range of features. On
+// arm64, the backend is the Go one, but the AES rounds may run on the crypto
+// extension, which makes it several times faster.
+func rangeKey(features *cryptonight.FeatureSet) string {
+	if runtime.GOARCH == "arm64" && features.Backend == "go" {
+		if features.AES == "arm64-aes" {
+			return "arm64-aes"
+		}
+		return "arm64-go"
+	}
+
+	return features.Backend
+}
+
+type cacheInfo struct {
+	level  int
+	typ    string
+	size   int64 // in bytes
+	shared string
+}
+
+// runDoctor reports what matters to the hashrate on this machine.
+func runDoctor(out io.Writer) int {
+	fmt.Fprintln(out, "== Runtime")
+	fmt.Fprintf(out, "%s %s/%s, %d CPUs, GOMAXPROCS=%d\n", runtime.Version(), runtime.GOOS, runtime.GOARCH, runtime.NumCPU(), runtime.GOMAXPROCS(0))
+	features := cryptonight.Features()
+	fmt.Fprintln(out, "version:", features.Version)
+	fmt.Fprintf(out, "backend: %s, compiled in: %s\n", features.Backend, strings.Join(features.Backends, ", "))
+	fmt.Fprintf(out, "AES rounds: %s, tables %s\n", features.AES, features.AESTables)
+
+	fmt.Fprintln(out, "\n== CPU features")
+	switch {
+	case runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64":
+		fmt.Fprintln(out, "no accelerated backend for", runtime.GOARCH+", the pure Go backend is used")
+	case len(features.CPU) == 0:
+		fmt.Fprintln(out, "unavailable with this build, the pure Go backend is used")
+	default:
+		fmt.Fprintln(out, strings.Join(features.CPU, " "))
+		if runtime.GOARCH == "amd64" && features.Backend == "go" {
+			fmt.Fprintln(out, "warning: no AES-NI, the much slower pure Go backend is used")
+		} else if features.AES == "go" {
+			fmt.Fprintln(out, "warning: no AES instructions, the AES rounds run in Go")
+		}
+	}
+
+	fmt.Fprintln(out, "\n== Caches")
+	caches := readCaches()
+	var l3 int64
+	if len(caches) == 0 {
+		fmt.Fprintln(out, "unavailable")
+	}
+	for _, c := range caches {
+		fmt.Fprintf(out, "L%d %-12s %6d KiB, shared by CPUs %s\n", c.level, c.typ, c.size/1024, c.shared)
+		if c.level == 3 {
+			l3 = c.size
+		}
+	}
+	if l3 > 0 {
+		threads := int(l3 / scratchpadSize)
+		fmt.Fprintf(out, "each thread needs a %d MiB scratchpad, L3 fits %d threads\n", scratchpadSize>>20, threads)
+		if runtime.GOMAXPROCS(0) > threads {
+			fmt.Fprintf(out, "hint: more than %d threads per L3 usually doesn't increase the hashrate\n", threads)
+		}
+	}
+
+	fmt.Fprintln(out, "\n== Huge pages")
+	if thp, err := ioutil.ReadFile("/sys/kernel/mm/transparent_hugepage/enabled"); err == nil {
+		fmt.Fprintln(out, "transparent:", strings.TrimSpace(string(thp)))
+	} else {
+		fmt.Fprintln(out, "transparent: unavailable")
+	}
+	if info := readMeminfo("HugePages_Total", "HugePages_Free", "Hugepagesize"); len(info) > 0 {
+		for _, kv := range info {
+			fmt.Fprintf(out, "%s: %s\n", kv[0], kv[1])
+		}
+	} else {
+		fmt.Fprintln(out, "reserved: unavailable")
+	}
+
+	fmt.Fprintln(out, "\n== NUMA")
+	nodes, _ := filepath.Glob("/sys/devices/system/node/node[0-9]*")
+	if len(nodes) == 0 {
+		fmt.Fprintln(out, "unavailable")
+	}
+	sort.Strings(nodes)
+	for _, n := range nodes {
+		cpus, _ := ioutil.ReadFile(filepath.Join(n, "cpulist"))
+		fmt.Fprintf(out, "%s: CPUs %s\n", filepath.Base(n), strings.TrimSpace(string(cpus)))
+	}
+	if len(nodes) > 1 {
+		fmt.Fprintln(out, "hint: with several nodes, pinning the hashing threads and their scratchpads to the same node with numactl(8) or ekyu.moe/cryptonight/numa avoids remote memory access")
+	}
+
+	fmt.Fprintln(out, "\n== Hashrate")
+	r := measure(0, 1, 3*time.Second)
+	expected, ok := expectedRange[rangeKey(features)]
+	if !ok {
+		fmt.Fprintf(out, "variant 0, 1 thread: %.2f H/s, no expected range for %s\n", r.Hashrate, runtime.GOARCH)
+		return 0
+	}
+	fmt.Fprintf(out, "variant 0, 1 thread: %.2f H/s, expected %.0f to %.0f H/s\n", r.Hashrate, expected[0], expected[1])
+	if r.Hashrate < expected[0] {
+		fmt.Fprintln(out, "warning: lower than expected, check for CPU throttling, power saving or other load")
+	}
+
+	return 0
+}
+
+func readCaches() []cacheInfo {
+	dirs, _ := filepath.Glob("/sys/devices/system/cpu/cpu0/cache/index[0-9]*")
+	var caches []cacheInfo
+	for _, d := range dirs {
+		read := func(name string) string {
+			b, _ := ioutil.ReadFile(filepath.Join(d, name))
+			return strings.TrimSpace(string(b))
+		}
+
+		level, err := strconv.Atoi(read("level"))
+		if err != nil {
+			continue
+		}
+		c := cacheInfo{level: level, typ: read("type"), shared: read("shared_cpu_list")}
+		size := read("size")
+		mult := int64(1)
+		switch {
+		case strings.HasSuffix(size, "K"):
+			mult, size = 1024, strings.TrimSuffix(size, "K")
+		case strings.HasSuffix(size, "M"):
+			mult, size = 1024*1024, strings.TrimSuffix(size, "M")
+		}
+		n, _ := strconv.ParseInt(size, 10, 64)
+		c.size = n * mult
+		caches = append(caches, c)
+	}
+	sort.Slice(caches, func(i, j int) bool { return caches[i].level < caches[j].level })
+
+	return caches
+}
+
+// readMeminfo returns the given fields of /proc/meminfo, in that order.
+func readMeminfo(fields ...string) [][2]string {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	found := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), ":", 2)
+		if len(kv) == 2 {
+			found[kv[0]] = strings.TrimSpace(kv[1])
+		}
+	}
+
+	var info [][2]string
+	for _, k := range fields {
+		if v, ok := found[k]; ok {
+			info = append(info, [2]string{k, v})
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,284 @@
+package main // import "ekyu.moe/cryptonight/cmd/cnbench"
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"ekyu.moe/cryptonight"
+)
+
+// picked from cryptonight_test.go, see comment there
+var benchData = [4][]byte{
+	{0xa8, 0xab, 0xb6, 0xb, 0x87, 0xa3, 0x49, 0x26, 0x72, 0xbf, 0x9d, 0x18, 0xd4, 0xd5, 0x2c, 0x4c, 0x7b, 0x3f, 0x5a, 0xdd, 0x25, 0xdd, 0x8c, 0xd5, 0xe5, 0xd7, 0x85, 0xcd, 0x30, 0xde, 0x5f, 0x10, 0xb7, 0x32, 0xce, 0x45, 0xb8, 0x74, 0x5d, 0xf5, 0x2a, 0x87, 0x93, 0xcb, 0x51, 0x2b, 0xf7, 0x77, 0xc2, 0xa7, 0xcc, 0xc0, 0xb4, 0x96, 0x3e, 0x43, 0x8f, 0x3f, 0xbf, 0x16, 0x78, 0xf7, 0xa8, 0xb4, 0x5d, 0xb, 0x4d, 0xdf, 0xc5, 0x10, 0xbe, 0xaa, 0xd1, 0xf3, 0xef, 0x29},
+	{0xe, 0xa3, 0x74, 0x46, 0xbf, 0x65, 0x53, 0xb4, 0xab, 0xc0, 0x11, 0x3e, 0x2b, 0x5b, 0x9, 0x26, 0xb8, 0x59, 0xf6, 0xb9, 0xbf, 0x5a, 0xb, 0x43, 0x95, 0x45, 0x8a, 0xa, 0x5f, 0xed, 0xb9, 0x9c, 0x79, 0xce, 0x6c, 0xbc, 0x7f, 0xa, 0x4a, 0xe3, 0x6f, 0x67, 0xb9, 0x89, 0xe6, 0x4, 0x2f, 0xe9, 0xe0, 0xd6, 0x8a, 0x50, 0x9f, 0x44, 0x7d, 0x96, 0x3f, 0xee, 0xc2, 0x71, 0x27, 0xfc, 0xf1, 0x43, 0xcd, 0xe8, 0x36, 0x34, 0x29, 0x8e, 0xd, 0xe9, 0x89, 0xb4, 0xae, 0xfd},
+	{0xc5, 0xf0, 0x6f, 0xd5, 0x8, 0xe, 0x1d, 0x60, 0xb2, 0x6b, 0xe0, 0xd7, 0x7e, 0xa, 0x56, 0xef, 0x6c, 0xfb, 0x3b, 0xc7, 0x2d, 0xc5, 0x7b, 0x8, 0xb6, 0x54, 0x1, 0x65, 0xe1, 0x20, 0x22, 0xf2, 0x26, 0x5e, 0x4b, 0xe2, 0x49, 0x6c, 0x10, 0x1b, 0x8c, 0x43, 0xcb, 0xd5, 0xbd, 0x1e, 0x7c, 0x61, 0xd8, 0x6e, 0xe2, 0x47, 0x8c, 0x46, 0x44, 0xc3, 0x1a, 0x5, 0xb7, 0x5f, 0x85, 0x8b, 0x2a, 0x68, 0x55, 0xb0, 0x5f, 0xe4, 0xc8, 0xc3, 0xac, 0x52, 0x1e, 0x3f, 0xe3, 0x18},
+	{0xfc, 0x11, 0x56, 0x9f, 0xae, 0xe8, 0x99, 0xd3, 0x62, 0xb8, 0x1a, 0xf6, 0xd3, 0xdc, 0x29, 0x69, 0x34, 0xd3, 0x98, 0x3c, 0x7f, 0x27, 0x93, 0x3, 0x3f, 0xf4, 0x28, 0x42, 0xcb, 0xe9, 0x9d, 0x5e, 0xc6, 0xad, 0x89, 0x36, 0x61, 0x87, 0x72, 0x30, 0x3c, 0xd5, 0x57, 0x91, 0xc6, 0xca, 0x54, 0x7a, 0xa9, 0xe3, 0x5e, 0x83, 0xd0, 0x8a, 0x58, 0xa1, 0x90, 0xe5, 0x5d, 0x7e, 0x3f, 0x31, 0xc3, 0xd8, 0xad, 0x12, 0x3, 0xdd, 0xd6, 0x36, 0xf1, 0x52, 0x5d, 0x5d, 0x4a, 0x36},
+}
+
+// Report is the whole output of a cnbench run.
+type Report struct {
+	Features  *cryptonight.FeatureSet `json:"features"`
+	GoVersion string                  `json:"go_version"`
+	GOOS      string                  `json:"goos"`
+	GOARCH    string                  `json:"goarch"`
+	NumCPU    int                     `json:"num_cpu"`
+	Date      time.Time               `json:"date"`
+	Results   []Result                `json:"results"`
+}
+
+// Result is the measurement of one variant with a specific number of threads.
+type Result struct {
+	Variant  int     `json:"variant"`
+	Backend  string  `json:"backend"`
+	Threads  int     `json:"threads"`
+	Hashes   int     `json:"hashes"`
+	Seconds  float64 `json:"seconds"`
+	Hashrate float64 `json:"hashrate"` // overall H/s of all threads
+
+	// Percentiles of the hashrate of a single thread, in H/s, derived from
+	// the latency of every hash. P10 is the slow end.
+	P10 float64 `json:"p10"`
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+}
+
+func main() {
+	os.Exit(realMain())
+}
+
+func realMain() int {
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		return runDoctor(os.Stdout)
+	}
+
+	var (
+		variants string
+		threads  string
+		duration time.Duration
+		jsonOut  bool
+		outFile  string
+		save     string
+		baseFile string
+		thresh   float64
+
+		out io.Writer = os.Stdout
+
+		stderr = log.New(os.Stderr, "cnbench: ", 0)
+	)
+
+	flag.StringVar(&variants, "variants", "0,1,2", "Comma separated list of variants to benchmark.")
+	flag.StringVar(&threads, "threads", defaultThreads(), "Comma separated list of thread counts to sweep.")
+	flag.DurationVar(&duration, "duration", 10*time.Second, "Duration of each measurement.")
+	flag.BoolVar(&jsonOut, "json", false, "Produce output in JSON instead of a table.")
+	flag.StringVar(&outFile, "out-file", "", "Produce output to file instead of stdout.")
+	flag.StringVar(&save, "save", "", "Save the result to this file in JSON, to be used as a baseline later.")
+	flag.StringVar(&baseFile, "baseline", "", "Compare the result against this baseline file saved by -save.")
+	flag.Float64Var(&thresh, "threshold", 5, "Maximum hashrate drop in percent against the baseline, exit with code 2 if exceeded.")
+	flag.Parse()
+
+	variantList, err := parseInts(variants)
+	if err != nil {
+		stderr.Println("parse -variants:", err)
+		return 1
+	}
+	for _, v := range variantList {
+		// SumHeight takes any valid variant, variant 4 included
+		if err := cryptonight.Validate(benchData[0], v); err != nil && err != cryptonight.ErrHeightRequired {
+			stderr.Printf("variant %d: %v", v, err)
+			return 1
+		}
+	}
+	threadList, err := parseInts(threads)
+	if err != nil {
+		stderr.Println("parse -threads:", err)
+		return 1
+	}
+	for _, t := range threadList {
+		if t <= 0 {
+			stderr.Println("thread count must be positive.")
+			return 1
+		}
+	}
+
+	var baseline *Report
+	if baseFile != "" {
+		if baseline, err = loadReport(baseFile); err != nil {
+			stderr.Println("load baseline:", err)
+			return 1
+		}
+	}
+
+	if outFile != "" {
+		f, err := os.Create(outFile)
+		if err != nil {
+			stderr.Println("create output file:", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+
+	report := &Report{
+		Features:  cryptonight.Features(),
+		GoVersion: runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		NumCPU:    runtime.NumCPU(),
+		Date:      time.Now().UTC(),
+	}
+	for _, v := range variantList {
+		for _, t := range threadList {
+			stderr.Printf("running variant %d with %d threads for %v", v, t, duration)
+			report.Results = append(report.Results, measure(v, t, duration))
+		}
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = writeTable(out, report)
+	}
+	if err != nil {
+		stderr.Println("write output:", err)
+		return 1
+	}
+
+	if save != "" {
+		if err := saveReport(save, report); err != nil {
+			stderr.Println("save baseline:", err)
+			return 1
+		}
+	}
+
+	if baseline != nil {
+		ok, err := compare(out, baseline, report, thresh)
+		if err != nil {
+			stderr.Println("write output:", err)
+			return 1
+		}
+		if !ok {
+			return exitRegression
+		}
+	}
+
+	return 0
+}
+
+func saveReport(name string, report *Report) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// defaultThreads returns powers of 2 up to GOMAXPROCS, plus GOMAXPROCS itself.
+func defaultThreads() string {
+	max := runtime.GOMAXPROCS(0)
+	var list []string
+	for t := 1; t < max; t *= 2 {
+		list = append(list, strconv.Itoa(t))
+	}
+
+	return strings.Join(append(list, strconv.Itoa(max)), ",")
+}
+
+func parseInts(s string) ([]int, error) {
+	var list []int
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, n)
+	}
+
+	return list, nil
+}
+
+// benchHeight is the block height for variant 4, the first one of it on
+// monero.
+const benchHeight = 1806260
+
+// measure runs variant on threads goroutines for about d.
+func measure(variant, threads int, d time.Duration) Result {
+	var (
+		wg        sync.WaitGroup
+		latencies = make([][]time.Duration, threads)
+		deadline  = time.Now().Add(d)
+		start     = time.Now()
+	)
+
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; time.Now().Before(deadline); j++ {
+				t := time.Now()
+				cryptonight.SumHeight(benchData[j&0x03], variant, benchHeight)
+				latencies[i] = append(latencies[i], time.Since(t))
+			}
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start).Seconds()
+
+	var all []time.Duration
+	for _, l := range latencies {
+		all = append(all, l...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+
+	// a slower hash means a lower hashrate, hence the inversion
+	rate := func(p float64) float64 {
+		if len(all) == 0 {
+			return 0
+		}
+		return 1 / all[int(float64(len(all)-1)*(1-p))].Seconds()
+	}
+
+	return Result{
+		Variant:  variant,
+		Backend:  cryptonight.Features().Backend,
+		Threads:  threads,
+		Hashes:   len(all),
+		Seconds:  elapsed,
+		Hashrate: float64(len(all)) / elapsed,
+		P10:      rate(0.10),
+		P50:      rate(0.50),
+		P90:      rate(0.90),
+	}
+}
+
+func writeTable(out io.Writer, report *Report) error {
+	fmt.Fprintf(out, "%s %s/%s, %d CPUs\n\n", report.GoVersion, report.GOOS, report.GOARCH, report.NumCPU)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "variant\tbackend\tthreads\thashes\tH/s\tp10 H/s/thread\tp50 H/s/thread\tp90 H/s/thread\t")
+	for _, r := range report.Results {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%.2f\t%.2f\t%.2f\t%.2f\t\n", r.Variant, r.Backend, r.Threads, r.Hashes, r.Hashrate, r.P10, r.P50, r.P90)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"ekyu.moe/cryptonight"
+)
+
+// record is the result of one line in batch mode.
+type record struct {
+	Blob       string `json:"blob"`
+	Variant    *int   `json:"variant,omitempty"` // nil for an algorithm without a variant
+	Height     uint64 `json:"height"`
+	Algorithm  string `json:"algorithm,omitempty"` // if hashed by algorithm
+	Hash       string `json:"hash,omitempty"`
+	Difficulty uint64 `json:"difficulty,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// recordWriter writes records in a specific format.
+type recordWriter interface {
+	Write(r *record) error
+	Flush() error
+}
+
+type csvWriter struct{ w *csv.Writer }
+
+func (c *csvWriter) Write(r *record) error {
+	diff := ""
+	if r.Hash != "" {
+		diff = strconv.FormatUint(r.Difficulty, 10)
+	}
+	variant := ""
+	if r.Variant != nil {
+		variant = strconv.Itoa(*r.Variant)
+	}
+
+	return c.w.Write([]string{
+		r.Blob,
+		variant,
+		strconv.FormatUint(r.Height, 10),
+		r.Algorithm,
+		r.Hash,
+		diff,
+		r.Error,
+	})
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonWriter writes one JSON object per line.
+type jsonWriter struct{ enc *json.Encoder }
+
+func (j *jsonWriter) Write(r *record) error { return j.enc.Encode(r) }
+func (j *jsonWriter) Flush() error          { return nil }
+
+// runBatch reads lines in the form of "blob[,variant[,height[,algorithm]]]"
+// from files, or in if files is empty, and writes a record for each of them to
+// out. Missing or empty columns fall back to -variant, -height and -algo. A line
+// naming an algorithm is hashed with it, and must leave the variant empty.
+// Blank lines and lines starting with '#' are skipped.
+//
+// A line that can't be hashed doesn't stop the batch; its error is reported
+// in the record instead, and the exit code is set to 1 at the end.
+func runBatch(in io.Reader, files []string, out io.Writer, stderr *log.Logger) int {
+	if verify != "" || target != 0 {
+		stderr.Println("-verify and -target are not supported in batch mode.")
+		return 1
+	}
+
+	var w recordWriter
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(out)
+		if err := cw.Write([]string{"blob", "variant", "height", "algorithm", "hash", "difficulty", "error"}); err != nil {
+			stderr.Println("write output:", err)
+			return 1
+		}
+		w = &csvWriter{cw}
+	case "json":
+		w = &jsonWriter{json.NewEncoder(out)}
+	default:
+		stderr.Println("unknown format:", format)
+		return 1
+	}
+
+	var readers []io.Reader
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			stderr.Println("open input file:", err)
+			return 1
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+	if len(readers) == 0 {
+		readers = append(readers, in)
+	}
+
+	ret := 0
+	scanner := bufio.NewScanner(io.MultiReader(readers...))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		r := batchLine(line)
+		if r.Error != "" {
+			ret = 1
+		}
+		if err := w.Write(r); err != nil {
+			stderr.Println("write output:", err)
+			return 1
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		stderr.Println("read input:", err)
+		return 1
+	}
+	if err := w.Flush(); err != nil {
+		stderr.Println("write output:", err)
+		return 1
+	}
+
+	return ret
+}
+
+// batchLine parses and hashes one line of batch input.
+func batchLine(line string) *record {
+	cols := strings.Split(line, ",")
+	for i := range cols {
+		cols[i] = strings.TrimSpace(cols[i])
+	}
+	r := &record{Blob: cols[0], Height: height}
+	if len(cols) > 4 {
+		r.Error = "too many columns"
+		return r
+	}
+
+	v, a, byAlgo := variant, algo, useAlgo
+	if algoName != "" {
+		r.Algorithm = algo.String()
+	}
+	var err error
+	if len(cols) > 1 && cols[1] != "" {
+		if v, err = strconv.Atoi(cols[1]); err != nil {
+			r.Error = "invalid variant: " + err.Error()
+			return r
+		}
+		byAlgo, r.Algorithm = false, ""
+	}
+	if len(cols) > 2 && cols[2] != "" {
+		if r.Height, err = strconv.ParseUint(cols[2], 10, 64); err != nil {
+			r.Error = "invalid height: " + err.Error()
+			return r
+		}
+	}
+	if len(cols) > 3 && cols[3] != "" {
+		if cols[1] != "" {
+			r.Error = "both a variant and an algorithm"
+			return r
+		}
+		if a, err = cryptonight.ParseAlgorithm(cols[3]); err != nil {
+			r.Error = err.Error()
+			return r
+		}
+		r.Algorithm = a.String()
+		var ok bool
+		v, ok = algoVariants[a]
+		byAlgo = !ok
+	}
+	if !byAlgo {
+		r.Variant = &v
+	}
+
+	blob, err := hex.DecodeString(r.Blob)
+	if err != nil {
+		r.Error = "decode hex: " + err.Error()
+		return r
+	}
+	var sum []byte
+	if byAlgo {
+		sum, err = hashAlgo(blob, a, r.Height)
+	} else {
+		sum, err = hashVariant(blob, v, r.Height)
+	}
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.Hash = hex.EncodeToString(sum)
+	r.Difficulty = cryptonight.Difficulty(sum)
+
+	return r
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+
+	"ekyu.moe/cryptonight/cnlow"
+)
+
+// runConform implements the conform subcommand, which checks this build
+// against a corpus written by the vectors subcommand, typically generated on
+// another machine. It is meant to validate ports to other GOARCH, such as big
+// endian or 32-bit ones, e.g. by running a cross compiled cnhash under qemu.
+func runConform(args []string, out io.Writer, stderr *log.Logger) int {
+	fs := flag.NewFlagSet("conform", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() != 1 {
+		stderr.Println("usage: cnhash conform file")
+		return 1
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		stderr.Println("open corpus:", err)
+		return 1
+	}
+	c := new(corpus)
+	err = json.NewDecoder(f).Decode(c)
+	f.Close()
+	if err != nil {
+		stderr.Println("decode corpus:", err)
+		return 1
+	}
+
+	failed := 0
+	sp := new(cnlow.Scratchpad)
+	for i, v := range c.Vectors {
+		in, err := hex.DecodeString(v.Input)
+		if err != nil {
+			stderr.Printf("vector %d: decode input: %v", i, err)
+			return 1
+		}
+
+		if msg := checkVector(sp, in, v); msg != "" {
+			failed++
+			fmt.Fprintf(out, "FAIL vector %d, variant %d, %d bytes: %s\n", i, v.Variant, len(in), msg)
+		}
+	}
+	fmt.Fprintf(out, "%s/%s: %d of %d vectors failed\n", runtime.GOOS, runtime.GOARCH, failed, len(c.Vectors))
+
+	if failed > 0 {
+		return exitMismatch
+	}
+	return 0
+}
+
+// checkVector checks v with hash, then with the phases of package cnlow if
+// v has intermediate states. It returns a description of the first mismatch,
+// or an empty string.
+func checkVector(sp *cnlow.Scratchpad, in []byte, v *vector) string {
+	sum, err := hash(in, v.Variant, v.Height)
+	if err != nil {
+		return err.Error()
+	}
+	if d := hex.EncodeToString(sum); d != v.Digest {
+		return fmt.Sprintf("digest %s, expected %s", d, v.Digest)
+	}
+	if v.Absorbed == "" {
+		return ""
+	}
+
+	got := &vector{Variant: v.Variant}
+	runPhases(sp, in, got)
+	for _, p := range []struct {
+		phase         string
+		got, expected string
+	}{
+		{"absorb", got.Absorbed, v.Absorbed},
+		{"explode", got.Exploded, v.Exploded},
+		{"loop", got.Looped, v.Looped},
+		{"implode", got.Imploded, v.Imploded},
+		{"final " + got.FinalHash, got.Digest, v.Digest},
+	} {
+		if p.got != p.expected {
+			return fmt.Sprintf("phase %s gives %s, expected %s", p.phase, p.got, p.expected)
+		}
+	}
+
+	return ""
+}
@@ -11,41 +11,112 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"strconv"
 	"sync/atomic"
 	"time"
 
 	"ekyu.moe/cryptonight"
 )
 
+var (
+	bench       bool
+	inHex       bool
+	outBinary   bool
+	includeDiff bool
+	inFile      string
+	outFile     string
+	variant     int
+	algoName    string
+	height      uint64
+	strict      bool
+	verify      string
+	target      uint64
+
+	batch  bool
+	format string
+
+	diffMode bool
+	hashIn   bool
+	stream   bool
+
+	expected []byte // decoded from verify
+
+	// algo is parsed from algoName. The algorithms of a variant are hashed
+	// by variant instead, so useAlgo is only set for the others.
+	algo    cryptonight.Algorithm
+	useAlgo bool
+)
+
+// algoVariants are the variants of the algorithms having one.
+var algoVariants = map[cryptonight.Algorithm]int{
+	cryptonight.CNv0: 0,
+	cryptonight.CNv1: 1,
+	cryptonight.CNv2: 2,
+	cryptonight.CNR:  4,
+}
+
+// exitMismatch is the exit code when a result fails -verify or -target.
+const exitMismatch = 2
+
 func main() {
 	os.Exit(realMain())
 }
 
 func realMain() int {
 	var (
-		bench       bool
-		inHex       bool
-		outBinary   bool
-		includeDiff bool
-		inFile      string
-		outFile     string
-		variant     int
-
 		in  io.Reader = os.Stdin
 		out io.Writer = os.Stdout
 
 		stderr = log.New(os.Stderr, "cnhash: ", 0)
 	)
 
+	if len(os.Args) > 1 && os.Args[1] == "vectors" {
+		return runVectors(os.Args[2:], out, stderr)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "conform" {
+		return runConform(os.Args[2:], out, stderr)
+	}
+
 	flag.BoolVar(&bench, "bench", false, "Benchmark mode, don't do anything else.")
 	flag.BoolVar(&includeDiff, "include-diff", false, "Append the difficulty of the result hash to the output. If -out-binary is not given, the difficulty will be appeneded to the output in decimal with comma separated (CSV friendly), otherwise it will be appeneded to the hash binary (which is 32 bytes long) directly, in 8 bytes little endian.")
 	flag.BoolVar(&inHex, "in-hex", false, "Read input in hex instead of binary.")
+	flag.BoolVar(&inHex, "hex", false, "Alias of -in-hex.")
 	flag.BoolVar(&outBinary, "out-binary", false, "Produce output in binary (little endian) instead of hex.")
+	flag.BoolVar(&outBinary, "raw", false, "Alias of -out-binary.")
 	flag.StringVar(&inFile, "in-file", "", "Read input from file instead of stdin.")
 	flag.StringVar(&outFile, "out-file", "", "Produce output to file instead of stdout.")
 	flag.IntVar(&variant, "variant", 0, "Set CryptoNight variant, default 0. This applies to benchmark mode as well.")
+	flag.StringVar(&algoName, "algo", "", "Set CryptoNight algorithm by its name in xmrig, e.g. cn/2, cn-lite/1, cn-pico or argon2/chukwa, instead of -variant. This applies to benchmark mode as well.")
+	flag.Uint64Var(&height, "height", 0, "Set block height, for variants depending on it.")
+	flag.BoolVar(&strict, "strict", false, "Only accept the original CryptoNight of CNS008, i.e. variant 0, for conformance testing.")
+	flag.StringVar(&verify, "verify", "", "Compare the result against this hash in hex, exit with code 2 if they mismatch.")
+	flag.Uint64Var(&target, "target", 0, "Check the difficulty of the result against this value, exit with code 2 if it is not met.")
+	flag.BoolVar(&batch, "batch", false, "Batch mode, read newline-delimited hex blobs, each optionally followed by comma separated variant, height and algorithm, and output one record per line. An empty column falls back to its flag.")
+	flag.StringVar(&format, "format", "csv", "Output format of batch mode, either csv or json.")
+	flag.BoolVar(&diffMode, "difficulty", false, "Difficulty mode, print the difficulty of the result hash and whether it meets -target instead.")
+	flag.BoolVar(&hashIn, "hash", false, "Treat the input as a 32 bytes hash to check rather than data to hash. Implies -difficulty.")
+	flag.BoolVar(&stream, "stream", false, "Stream mode, serve binary frames from stdin to stdout until EOF. A request is the length of the blob in uint32, the variant in uint8, the algorithm in uint8, either "+streamAlgorithms()+" or 255 to hash by variant instead, and the height in uint64, followed by the blob. A response is the status in uint8, 0 on success, and the length of the payload in uint32, followed by the 32 bytes hash or the error message. Integers are little endian.")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [file ...]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s vectors [-variants list] [-max-len n] [-seed n] [-states] [-out-file file]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s conform file\n\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "Hash each file, or stdin if there is none.")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
+	if algoName != "" {
+		var err error
+		if algo, err = cryptonight.ParseAlgorithm(algoName); err != nil {
+			stderr.Println("parse -algo:", err)
+			return 1
+		}
+		if v, ok := algoVariants[algo]; ok {
+			variant = v
+		} else {
+			useAlgo = true
+		}
+	}
 	if bench {
 		// picked from cryptonight_test.go, see comment there
 		benchData := [4][]byte{
@@ -59,15 +130,24 @@ func realMain() int {
 		lastSnap := hashes
 		t := runtime.GOMAXPROCS(0)
 		fmt.Println("GOMAXPROCS =", t)
-		fmt.Println("variant =", variant)
+		if useAlgo {
+			fmt.Println("algo =", algo)
+		} else {
+			fmt.Println("variant =", variant)
+		}
 		fmt.Println()
-		fmt.Println("last 5 seconds (overall)")
-		fmt.Println("------------------------")
+		fmt.Println("last 5 seconds (overall), per thread")
+		fmt.Println("------------------------------------")
 
 		for i := 0; i < t; i++ {
 			go func() {
+				cc := new(cryptonight.Cache)
 				for j := 0; true; j++ {
-					cryptonight.Sum(benchData[j&0x03], variant)
+					if useAlgo {
+						cc.SumAlgorithm(benchData[j&0x03], algo, height)
+					} else {
+						cc.SumHeight(benchData[j&0x03], variant, height)
+					}
 					atomic.AddUint64(&hashes, 1)
 				}
 			}()
@@ -77,22 +157,26 @@ func realMain() int {
 		for range time.Tick(5 * time.Second) {
 			i++
 			snap := atomic.LoadUint64(&hashes)
-			fmt.Printf("%.2f H/s  (%.2f H/s)\n", float64(snap-lastSnap)/5, float64(snap)/float64(5*i))
+			rate := float64(snap-lastSnap) / 5
+			fmt.Printf("%.2f H/s  (%.2f H/s), %.2f H/s\n", rate, float64(snap)/float64(5*i), rate/float64(t))
 			lastSnap = snap
 		}
 
 		return 0
 	}
 
-	if inFile != "" {
-		f, err := os.Open(inFile)
-		if err != nil {
-			stderr.Println("open input file:", err)
+	if hashIn {
+		diffMode = true
+	}
+
+	if verify != "" {
+		var err error
+		if expected, err = hex.DecodeString(verify); err != nil || len(expected) != 32 {
+			stderr.Println("-verify requires a 32 bytes hash in hex.")
 			return 1
 		}
-		defer f.Close()
-		in = f
 	}
+
 	if outFile != "" {
 		f, err := os.Create(outFile)
 		if err != nil {
@@ -103,6 +187,115 @@ func realMain() int {
 		out = f
 	}
 
+	// positional arguments are hashed one by one, like sha256sum(1) does
+	files := flag.Args()
+	if inFile != "" {
+		files = append([]string{inFile}, files...)
+	}
+	if stream {
+		return runStream(in, out, stderr)
+	}
+	if batch {
+		return runBatch(in, files, out, stderr)
+	}
+	if len(files) == 0 {
+		return hashOne(in, "", out, stderr)
+	}
+
+	named := flag.NArg() > 0
+	ret := 0
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			stderr.Println("open input file:", err)
+			return 1
+		}
+		label := ""
+		if named {
+			label = name
+		}
+		code := hashOne(f, label, out, stderr)
+		f.Close()
+		switch code {
+		case 0:
+		case exitMismatch:
+			// keep going, so that every failed file is reported
+			ret = code
+		default:
+			return code
+		}
+	}
+
+	return ret
+}
+
+// validate checks whether blob can be hashed with the given parameters.
+func validate(blob []byte, variant int, height uint64) error {
+	if height != 0 && variant != 4 {
+		return fmt.Errorf("variant %d does not use height", variant)
+	}
+	var err error
+	if strict {
+		err = cryptonight.ValidateStrict(blob, variant)
+	} else {
+		err = cryptonight.Validate(blob, variant)
+	}
+	if err == cryptonight.ErrHeightRequired {
+		// hashed with SumHeight
+		return nil
+	}
+
+	return err
+}
+
+// validateAlgo is validate for the algorithms without a variant.
+func validateAlgo(blob []byte, a cryptonight.Algorithm, height uint64) error {
+	if height != 0 {
+		return fmt.Errorf("algorithm %s does not use height", a)
+	}
+	if strict {
+		return cryptonight.ErrNonStandard
+	}
+
+	return cryptonight.ValidateAlgorithm(blob, a)
+}
+
+// hash validates the parameters and calculates the hash of blob, with -algo if
+// it is set to an algorithm without a variant.
+func hash(blob []byte, variant int, height uint64) ([]byte, error) {
+	if useAlgo {
+		return hashAlgo(blob, algo, height)
+	}
+
+	return hashVariant(blob, variant, height)
+}
+
+// hashVariant validates the parameters and calculates the hash of blob with
+// variant.
+func hashVariant(blob []byte, variant int, height uint64) ([]byte, error) {
+	if err := validate(blob, variant, height); err != nil {
+		return nil, err
+	}
+
+	return cryptonight.SumHeight(blob, variant, height), nil
+}
+
+// hashAlgo validates the parameters and calculates the hash of blob with a,
+// by variant if it has one.
+func hashAlgo(blob []byte, a cryptonight.Algorithm, height uint64) ([]byte, error) {
+	if v, ok := algoVariants[a]; ok {
+		return hashVariant(blob, v, height)
+	}
+	if err := validateAlgo(blob, a, height); err != nil {
+		return nil, err
+	}
+
+	return cryptonight.SumAlgorithm(blob, a, 0), nil
+}
+
+// hashOne hashes everything read from in and writes the result to out. If
+// label is not empty, it is appended to the line in text mode.
+func hashOne(in io.Reader, label string, out io.Writer, stderr *log.Logger) int {
 	blob, err := ioutil.ReadAll(in)
 	if err != nil {
 		stderr.Println("read input:", err)
@@ -118,12 +311,21 @@ func realMain() int {
 		blob = h
 	}
 
-	if variant == 1 && len(blob) < 43 {
-		stderr.Println("variant 1 requires at least 43 bytes of input.")
+	var sum []byte
+	if hashIn {
+		if len(blob) != 32 {
+			stderr.Println("a hash must be 32 bytes long.")
+			return 1
+		}
+		sum = blob
+	} else if sum, err = hash(blob, variant, height); err != nil {
+		stderr.Println(err)
 		return 1
 	}
+	if diffMode {
+		return writeDifficulty(out, sum, label, stderr)
+	}
 
-	sum := cryptonight.Sum(blob, variant)
 	diff := uint64(0)
 	if includeDiff {
 		diff = cryptonight.Difficulty(sum)
@@ -141,17 +343,61 @@ func realMain() int {
 			_, err = out.Write(buf)
 		}
 	} else {
+		line := hex.EncodeToString(sum)
 		if includeDiff {
-			_, err = fmt.Fprintf(out, "%x,%v\n", sum, diff)
+			line += "," + strconv.FormatUint(diff, 10)
+		}
+		if label != "" {
+			line += "  " + label
+		}
+		_, err = fmt.Fprintln(out, line)
+	}
+	if err != nil {
+		stderr.Println("write output:", err)
+		return 1
+	}
+
+	name := "input"
+	if label != "" {
+		name = label
+	}
+	ret := 0
+	if expected != nil && !bytes.Equal(sum, expected) {
+		stderr.Printf("%s: hash mismatch, expected %x, got %x", name, expected, sum)
+		ret = exitMismatch
+	}
+	if target != 0 && !cryptonight.CheckHash(sum, target) {
+		stderr.Printf("%s: difficulty %d does not meet target %d", name, cryptonight.Difficulty(sum), target)
+		ret = exitMismatch
+	}
+
+	return ret
+}
+
+// writeDifficulty writes the difficulty of sum and whether it meets -target.
+func writeDifficulty(out io.Writer, sum []byte, label string, stderr *log.Logger) int {
+	var buf bytes.Buffer
+	if label != "" {
+		fmt.Fprintf(&buf, "file:       %s\n", label)
+	}
+	fmt.Fprintf(&buf, "hash:       %x\n", sum)
+	fmt.Fprintf(&buf, "difficulty: %d\n", cryptonight.Difficulty(sum))
+
+	ret := 0
+	if target != 0 {
+		met := cryptonight.CheckHash(sum, target)
+		if met {
+			fmt.Fprintf(&buf, "target:     %d, met\n", target)
 		} else {
-			_, err = fmt.Fprintf(out, "%x\n", sum)
+			fmt.Fprintf(&buf, "target:     %d, not met\n", target)
+			ret = exitMismatch
 		}
 	}
 
-	if err != nil {
+	if _, err := buf.WriteTo(out); err != nil {
 		stderr.Println("write output:", err)
 		return 1
 	}
 
-	return 0
+	return ret
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"ekyu.moe/cryptonight"
+)
+
+// maxFrame limits the size of a blob in stream mode.
+const maxFrame = 1 << 20
+
+// Status byte of a response frame in stream mode.
+const (
+	streamOK  = 0
+	streamErr = 1
+)
+
+// streamVariant is the algorithm byte of a request hashed by variant.
+const streamVariant = 0xff
+
+// streamAlgorithms lists the algorithm bytes of a request, for the help of
+// -stream.
+func streamAlgorithms() string {
+	var list []string
+	for a := cryptonight.Algorithm(0); ; a++ {
+		if _, err := cryptonight.ParseAlgorithm(a.String()); err != nil {
+			return strings.Join(list, ", ")
+		}
+		list = append(list, strconv.Itoa(int(a))+" for "+a.String())
+	}
+}
+
+// runStream serves framed requests from in until EOF. A request is a 14 bytes
+// header followed by the blob: the length of the blob in uint32, the variant
+// in uint8, the algorithm in uint8 and the height in uint64. The algorithm is
+// a cryptonight.Algorithm, or streamVariant to hash by variant. A response is a 5 bytes header followed
+// by the payload: the status in uint8 and the length of the payload in uint32.
+// The payload is the 32 bytes hash when status is 0, or an error message
+// otherwise. All integers are little endian.
+//
+// Responses are written in request order and flushed once no further request
+// is buffered, so a client may either pipeline requests or wait for every
+// response.
+func runStream(in io.Reader, out io.Writer, stderr *log.Logger) int {
+	r := bufio.NewReader(in)
+	w := bufio.NewWriter(out)
+
+	var hdr [14]byte
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			if err == io.EOF {
+				break
+			}
+			stderr.Println("read frame:", err)
+			return 1
+		}
+
+		n := binary.LittleEndian.Uint32(hdr[0:])
+		if n > maxFrame {
+			stderr.Println("read frame: blob too large")
+			return 1
+		}
+		v, a := int(hdr[4]), hdr[5]
+		h := binary.LittleEndian.Uint64(hdr[6:])
+
+		blob := make([]byte, n)
+		if _, err := io.ReadFull(r, blob); err != nil {
+			stderr.Println("read frame:", err)
+			return 1
+		}
+
+		var sum []byte
+		var err error
+		if a == streamVariant {
+			sum, err = hashVariant(blob, v, h)
+		} else {
+			sum, err = hashAlgo(blob, cryptonight.Algorithm(a), h)
+		}
+		status, payload := byte(streamOK), sum
+		if err != nil {
+			status, payload = streamErr, []byte(err.Error())
+		}
+
+		var resp [5]byte
+		resp[0] = status
+		binary.LittleEndian.PutUint32(resp[1:], uint32(len(payload)))
+		w.Write(resp[:])
+		w.Write(payload)
+
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				stderr.Println("write frame:", err)
+				return 1
+			}
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		stderr.Println("write frame:", err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"ekyu.moe/cryptonight/cnlow"
+)
+
+// vector is one test vector, all in the form used by cnhash -batch.
+//
+// With -states, the intermediate results of the phases of package cnlow are
+// included as well, so that a port failing a vector can tell which phase is
+// wrong. Keccak states are in hex, scratchpads are SHA-256 digests of their
+// bytes in hex.
+type vector struct {
+	Input   string `json:"input"` // in hex
+	Variant int    `json:"variant"`
+	Height  uint64 `json:"height"`
+	Digest  string `json:"digest"` // in hex
+
+	Absorbed  string `json:"absorbed,omitempty"`   // state after Absorb
+	Exploded  string `json:"exploded,omitempty"`   // scratchpad after Explode
+	Looped    string `json:"looped,omitempty"`     // scratchpad after Loop
+	Imploded  string `json:"imploded,omitempty"`   // state after Implode
+	FinalHash string `json:"final_hash,omitempty"` // function Final picks
+}
+
+type corpus struct {
+	Generator string    `json:"generator"`
+	Vectors   []*vector `json:"vectors"`
+}
+
+// extraLengths are tested on top of every length up to -max-len, crossing
+// Keccak's 136 bytes rate and then some.
+var extraLengths = []int{200, 255, 256, 272, 1000, 4096}
+
+// runVectors implements the vectors subcommand, which writes a JSON corpus of
+// test vectors for other implementations to check against.
+func runVectors(args []string, out io.Writer, stderr *log.Logger) int {
+	fs := flag.NewFlagSet("vectors", flag.ContinueOnError)
+	variants := fs.String("variants", "0,1,2", "Comma separated list of variants.")
+	maxLen := fs.Int("max-len", 137, "Generate inputs of every length from 0 to this value.")
+	seed := fs.Int64("seed", 0, "Seed of the pseudo random inputs.")
+	outFile := fs.String("out-file", "", "Produce output to file instead of stdout.")
+	states := fs.Bool("states", false, "Include the intermediate states of every phase, which is much slower.")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	var variantList []int
+	for _, f := range strings.Split(*variants, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			stderr.Println("parse -variants:", err)
+			return 1
+		}
+		variantList = append(variantList, v)
+	}
+
+	lengths := make([]int, 0, *maxLen+1+len(extraLengths))
+	for n := 0; n <= *maxLen; n++ {
+		lengths = append(lengths, n)
+	}
+	for _, n := range extraLengths {
+		if n > *maxLen {
+			lengths = append(lengths, n)
+		}
+	}
+
+	r := rand.New(rand.NewSource(*seed))
+	c := &corpus{Generator: "ekyu.moe/cryptonight/cmd/cnhash"}
+	var inputs [][]byte
+	for _, v := range variantList {
+		for _, n := range lengths {
+			in := make([]byte, n)
+			r.Read(in)
+			if validate(in, v, 0) != nil {
+				// e.g. too short for variant 1
+				continue
+			}
+			c.Vectors = append(c.Vectors, &vector{Input: hex.EncodeToString(in), Variant: v})
+			inputs = append(inputs, in)
+		}
+	}
+
+	// hash in parallel, each vector is written by one goroutine only
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var sp *cnlow.Scratchpad
+			if *states {
+				sp = new(cnlow.Scratchpad)
+			}
+			for j := range next {
+				if *states {
+					runPhases(sp, inputs[j], c.Vectors[j])
+					continue
+				}
+				sum, _ := hash(inputs[j], c.Vectors[j].Variant, c.Vectors[j].Height)
+				c.Vectors[j].Digest = hex.EncodeToString(sum)
+			}
+		}()
+	}
+	for j := range c.Vectors {
+		next <- j
+	}
+	close(next)
+	wg.Wait()
+
+	if *outFile != "" {
+		f, err := os.Create(*outFile)
+		if err != nil {
+			stderr.Println("create output file:", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(c); err != nil {
+		stderr.Println("write output:", err)
+		return 1
+	}
+
+	return 0
+}
+
+// runPhases computes v from its input and variant with the phases of package
+// cnlow, filling the digest and all the intermediate states.
+func runPhases(sp *cnlow.Scratchpad, in []byte, v *vector) {
+	var state cnlow.State
+	cnlow.Absorb(&state, in)
+	v.Absorbed = hex.EncodeToString(state.Bytes())
+
+	cnlow.Explode(sp, &state)
+	v.Exploded = scratchpadDigest(sp)
+
+	r := cnlow.NewRegisters(&state, in, v.Variant)
+	cnlow.Loop(sp, r, v.Variant)
+	v.Looped = scratchpadDigest(sp)
+
+	cnlow.Implode(sp, &state)
+	v.Imploded = hex.EncodeToString(state.Bytes())
+	v.FinalHash = cnlow.FinalHash(&state)
+
+	v.Digest = hex.EncodeToString(cnlow.Final(&state))
+}
+
+// scratchpadDigest returns the SHA-256 of the little endian bytes of sp in hex.
+func scratchpadDigest(sp *cnlow.Scratchpad) string {
+	h := sha256.New()
+	var buf [4096]byte
+	for i := 0; i < len(sp); i += len(buf) / 8 {
+		for j := range buf[:len(buf)/8] {
+			binary.LittleEndian.PutUint64(buf[8*j:], sp[i+j])
+		}
+		h.Write(buf[:])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
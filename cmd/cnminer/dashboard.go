@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ekyu.moe/cryptonight"
+)
+
+// dashboardLines is the number of log lines drawn below the dashboard.
+const dashboardLines = 8
+
+// dashboard draws the state of a miner on a terminal every interval, in place
+// of its reports. It is also the output of the logger of the miner, whose
+// latest lines are drawn below it.
+type dashboard struct {
+	m       *miner
+	pool    string
+	tempCmd []string // command printing the temperature, if any
+	out     io.Writer
+
+	mu    sync.Mutex
+	lines []string
+}
+
+// Write keeps the lines of the logger, to be drawn by the next refresh.
+func (d *dashboard) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		d.lines = append(d.lines, line)
+	}
+	if len(d.lines) > dashboardLines {
+		d.lines = append(d.lines[:0], d.lines[len(d.lines)-dashboardLines:]...)
+	}
+
+	return len(p), nil
+}
+
+// run redraws the dashboard every interval until ctx is done.
+func (d *dashboard) run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	d.draw()
+	for {
+		select {
+		case <-t.C:
+			d.draw()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *dashboard) draw() {
+	m := d.m
+	var b bytes.Buffer
+	b.WriteString("\x1b[H\x1b[2J") // cursor home, clear screen
+
+	f := cryptonight.Features()
+	fmt.Fprintf(&b, "cnminer %s, %s backend, %d threads, huge pages %s\n", f.Version, f.Backend, len(m.caches), f.HugePages)
+	fmt.Fprintf(&b, "pool        %s\n", d.pool)
+	if job := m.currentJob(); job != nil {
+		fmt.Fprintf(&b, "job         %s: %s, difficulty %d, height %d\n", job.ID, job.Algorithm, job.Target, job.Height)
+	} else {
+		fmt.Fprintln(&b, "job         waiting")
+	}
+
+	r10s, r60s, r15m := m.rates()
+	fmt.Fprintf(&b, "\nthread %12s %12s %12s H/s\n", "10s", "60s", "15m")
+	for i := range m.meters {
+		t10s, t60s, t15m := m.meters[i].Rates()
+		fmt.Fprintf(&b, "%6d %12.2f %12.2f %12.2f\n", i, t10s, t60s, t15m)
+	}
+	fmt.Fprintf(&b, "%6s %12.2f %12.2f %12.2f\n", "total", r10s, r60s, r15m)
+
+	accepted, rejected := atomic.LoadUint64(&m.accepted), atomic.LoadUint64(&m.rejected)
+	latency := "n/a"
+	if accepted+rejected > 0 {
+		latency = m.avgLatency().Round(time.Millisecond).String()
+	}
+	fmt.Fprintf(&b, "\nshares      %d accepted, %d rejected, %d stale, %d failed\n", accepted, rejected, atomic.LoadUint64(&m.stale), atomic.LoadUint64(&m.failed))
+	fmt.Fprintf(&b, "latency     %s on average\n", latency)
+	fmt.Fprintf(&b, "temperature %s\n", d.temperature())
+
+	b.WriteByte('\n')
+	d.mu.Lock()
+	for _, line := range d.lines {
+		fmt.Fprintln(&b, line)
+	}
+	d.mu.Unlock()
+
+	d.out.Write(b.Bytes())
+}
+
+// temperature returns the first line printed by d.tempCmd, or why there is
+// none.
+func (d *dashboard) temperature() string {
+	if len(d.tempCmd) == 0 {
+		return "n/a, see -temp-cmd"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, d.tempCmd[0], d.tempCmd[1:]...).Output()
+	if err != nil {
+		return "error: " + err.Error()
+	}
+
+	return strings.SplitN(strings.TrimSpace(string(out)), "\n", 2)[0]
+}
@@ -0,0 +1,362 @@
+// Command cnminer is a reference CPU miner for CryptoNight pools, built on
+// package ekyu.moe/cryptonight alone: jobs come from the stratum client of
+// ekyu.moe/cryptonight/stratum, and each thread searches nonces with its own
+// Cache by Cache.MineHeight. It reconnects when the pool hangs up, and stops
+// on SIGINT or SIGTERM.
+//
+// With -dashboard, the hashrate reports are replaced by a panel redrawn on the
+// terminal, with the hashrate of each thread, the outcome of the shares, the
+// round trip of submitting them, and the temperature printed by -temp-cmd.
+// For bug reports, -pprof serves the profiles of net/http/pprof, and -stats
+// dumps the hashrate and share stats to a JSON file every -report.
+//
+// cnminer solo -daemon http://127.0.0.1:18081 -address <wallet> mines the block
+// templates of a Monero daemon instead, polled every -refresh, with the
+// client of ekyu.moe/cryptonight/daemon, and submits the blocks found to it.
+package main // import "ekyu.moe/cryptonight/cmd/cnminer"
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"ekyu.moe/cryptonight"
+	"ekyu.moe/cryptonight/daemon"
+	"ekyu.moe/cryptonight/stratum"
+)
+
+func main() {
+	os.Exit(realMain())
+}
+
+func realMain() int {
+	var (
+		pool      string
+		user      string
+		pass      string
+		algos     string
+		daemonRPC string
+		address   string
+		refresh   time.Duration
+		threads   int
+		interval  time.Duration
+		retry     time.Duration
+		dash      bool
+		tempCmd   string
+		pprofAddr string
+		statsFile string
+
+		stderr = log.New(os.Stderr, "cnminer: ", log.LstdFlags)
+	)
+
+	fs, args := flag.CommandLine, os.Args[1:]
+	solo := len(args) > 0 && args[0] == "solo"
+	if solo {
+		fs, args = flag.NewFlagSet("solo", flag.ExitOnError), args[1:]
+		fs.StringVar(&daemonRPC, "daemon", "http://127.0.0.1:18081", "RPC address of the daemon.")
+		fs.StringVar(&address, "address", "", "Wallet address the blocks pay to.")
+		fs.DurationVar(&refresh, "refresh", 5*time.Second, "Interval of polling the daemon for a new block template.")
+	} else {
+		fs.StringVar(&pool, "pool", "", "Stratum address of the pool, e.g. pool.example.com:3333.")
+		fs.StringVar(&user, "user", "", "Login of the pool, usually a wallet address.")
+		fs.StringVar(&pass, "pass", "x", "Password of the pool.")
+		fs.StringVar(&algos, "algo", "cn/r", "Comma separated list of algorithms to advertise, by their names in xmrig. The first one is used for jobs naming none.")
+		fs.DurationVar(&retry, "retry", 10*time.Second, "Delay before reconnecting to the pool.")
+	}
+	fs.IntVar(&threads, "threads", runtime.GOMAXPROCS(0), "Number of mining threads, each taking a 2 MiB cache.")
+	fs.DurationVar(&interval, "report", 10*time.Second, "Interval of hashrate reports.")
+	fs.BoolVar(&dash, "dashboard", false, "Redraw a dashboard on the terminal every -report instead of logging reports.")
+	fs.StringVar(&tempCmd, "temp-cmd", "", "Command printing the temperature, run at each refresh of the dashboard, e.g. \"cat /sys/class/thermal/thermal_zone0/temp\".")
+	fs.StringVar(&pprofAddr, "pprof", "", "Address to serve the net/http/pprof endpoints on, e.g. localhost:6060. Empty disables them.")
+	fs.StringVar(&statsFile, "stats", "", "File to write the hashrate and share stats to as JSON every -report, and on exit.")
+	fs.Parse(args)
+
+	switch {
+	case solo && address == "":
+		stderr.Println("-address is required.")
+		return 1
+	case solo && refresh <= 0:
+		stderr.Println("-refresh must be positive.")
+		return 1
+	case !solo && pool == "":
+		stderr.Println("-pool is required.")
+		return 1
+	case !solo && retry <= 0:
+		stderr.Println("-retry must be positive.")
+		return 1
+	case threads <= 0 || interval <= 0:
+		stderr.Println("-threads and -report must be positive.")
+		return 1
+	}
+	conf := &stratum.Config{Login: user, Pass: pass, Agent: "cnminer/" + cryptonight.Features().Version}
+	for _, name := range strings.Split(algos, ",") {
+		if solo {
+			break // no pool to advertise to
+		}
+		a, err := cryptonight.ParseAlgorithm(name)
+		if err != nil {
+			stderr.Println("parse -algo:", name+":", err)
+			return 1
+		}
+		conf.Algorithms = append(conf.Algorithms, a)
+	}
+
+	m := &miner{logger: stderr, meters: make([]cryptonight.HashrateMeter, threads)}
+	for i := 0; i < threads; i++ {
+		cc := cryptonight.NewCacheHugePages()
+		defer cc.Close()
+		cc.SetHashrateMeter(&m.meters[i])
+		m.caches = append(m.caches, cc)
+	}
+	if !m.caches[0].HugePages() {
+		stderr.Println("huge pages are not available, the hashrate may be lower")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		stderr.Println("stopping on", <-sig)
+		cancel()
+	}()
+	if pprofAddr != "" {
+		go func() { stderr.Println("pprof:", http.ListenAndServe(pprofAddr, nil)) }()
+		stderr.Println("serving pprof on", pprofAddr)
+	}
+	if statsFile != "" {
+		go m.dumpStats(ctx, statsFile, interval)
+	}
+	if dash {
+		d := &dashboard{m: m, pool: pool, tempCmd: strings.Fields(tempCmd), out: os.Stdout}
+		if solo {
+			d.pool = "solo on " + daemonRPC
+		}
+		m.logger = log.New(d, "", log.LstdFlags)
+		go d.run(ctx, interval)
+	} else {
+		go m.report(ctx, interval)
+	}
+
+	if solo {
+		s := newSolo(daemon.NewClient(daemonRPC, nil), address, refresh, m.logger)
+		go s.run(ctx)
+		m.logger.Println("solo mining on", daemonRPC)
+		m.mine(ctx, s)
+	} else {
+		m.minePool(ctx, pool, conf, retry)
+	}
+
+	if statsFile != "" {
+		if err := writeStats(statsFile, m.stats()); err != nil {
+			stderr.Println("write stats:", err)
+		}
+	}
+	stderr.Printf("%d hashes, %d shares accepted, %d rejected, %d stale, %d failed", m.total(),
+		atomic.LoadUint64(&m.accepted), atomic.LoadUint64(&m.rejected), atomic.LoadUint64(&m.stale), atomic.LoadUint64(&m.failed))
+
+	return 0
+}
+
+// source is where a miner gets its jobs from and sends its shares to, i.e. a
+// *stratum.Client, or the daemon of solo mining.
+type source interface {
+	Jobs() <-chan *stratum.Job
+	Submit(ctx context.Context, job *stratum.Job, nonce uint32, hash []byte) error
+}
+
+// miner mines the jobs of a pool with one thread per cache, each of which
+// records its hashes in the meter of the same index.
+type miner struct {
+	accepted uint64 // accessed atomically
+	rejected uint64 // accessed atomically, shares the pool or daemon refused
+	stale    uint64 // accessed atomically, shares of replaced jobs not sent
+	failed   uint64 // accessed atomically, shares not answered, on network errors or timeouts
+	latency  int64  // accessed atomically, total round trip of the shares answered
+
+	meters []cryptonight.HashrateMeter
+	caches []*cryptonight.Cache
+	logger *log.Logger
+
+	mu  sync.Mutex
+	job *stratum.Job // being mined
+}
+
+// rates returns the hashrate of all the threads, averaged over 10 seconds, 60
+// seconds and 15 minutes.
+func (m *miner) rates() (r10s, r60s, r15m float64) {
+	for i := range m.meters {
+		a, b, c := m.meters[i].Rates()
+		r10s, r60s, r15m = r10s+a, r60s+b, r15m+c
+	}
+
+	return r10s, r60s, r15m
+}
+
+// total returns the number of hashes of all the threads.
+func (m *miner) total() uint64 {
+	var n uint64
+	for i := range m.meters {
+		n += m.meters[i].Total()
+	}
+
+	return n
+}
+
+// avgLatency returns the average round trip of the shares answered, or 0
+// before the first one.
+func (m *miner) avgLatency() time.Duration {
+	sent := atomic.LoadUint64(&m.accepted) + atomic.LoadUint64(&m.rejected)
+	if sent == 0 {
+		return 0
+	}
+
+	return time.Duration(atomic.LoadInt64(&m.latency)) / time.Duration(sent)
+}
+
+// currentJob returns the job being mined, or nil before the first one.
+func (m *miner) currentJob() *stratum.Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.job
+}
+
+// minePool mines the jobs of the pool at addr until ctx is done, reconnecting
+// retry after the connection is lost.
+func (m *miner) minePool(ctx context.Context, addr string, conf *stratum.Config, retry time.Duration) {
+	for ctx.Err() == nil {
+		c, err := stratum.Dial(ctx, addr, conf)
+		if err != nil {
+			m.logger.Println("connect:", err)
+		} else {
+			m.logger.Println("logged in to", addr)
+			m.mine(ctx, c)
+			c.Close()
+			if ctx.Err() == nil {
+				m.logger.Println("connection lost:", c.Err())
+			}
+		}
+
+		select {
+		case <-time.After(retry):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// mine mines the jobs of src until they end or ctx is done. A new job
+// stops the threads mining the previous one.
+func (m *miner) mine(ctx context.Context, src source) {
+	var (
+		wg   sync.WaitGroup
+		stop = func() {} // stops the threads of the current job
+	)
+	defer func() {
+		stop()
+		wg.Wait()
+	}()
+
+	for {
+		select {
+		case job, ok := <-src.Jobs():
+			if !ok {
+				return
+			}
+			stop()
+			wg.Wait()
+			m.mu.Lock()
+			m.job = job
+			m.mu.Unlock()
+
+			jobCtx, cancel := context.WithCancel(ctx)
+			stop = cancel
+			m.logger.Printf("new job %s: %s, difficulty %d, height %d", job.ID, job.Algorithm, job.Target, job.Height)
+			for i, cc := range m.caches {
+				wg.Add(1)
+				go func(cc *cryptonight.Cache, start uint32) {
+					defer wg.Done()
+					m.work(jobCtx, src, job, cc, start)
+				}(cc, uint32(i))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// work searches the nonces of job from start, interleaved with the other
+// threads, and submits every share found until ctx is done.
+func (m *miner) work(ctx context.Context, src source, job *stratum.Job, cc *cryptonight.Cache, start uint32) {
+	step := uint32(len(m.caches))
+	for {
+		nonce, sum, err := cc.MineHeight(ctx, job.Blob, job.Algorithm, job.Height, job.Target, start, step)
+		if err != nil {
+			if err != context.Canceled {
+				m.logger.Println("job", job.ID+":", err)
+			}
+			return
+		}
+		go m.submit(src, job, nonce, sum)
+
+		if nonce+step < nonce {
+			return
+		}
+		start = nonce + step
+	}
+}
+
+func (m *miner) submit(src source, job *stratum.Job, nonce uint32, sum []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err := src.Submit(ctx, job, nonce, sum)
+	if err == cryptonight.ErrStaleJob {
+		atomic.AddUint64(&m.stale, 1)
+		m.logger.Printf("share of job %s dropped, the job is stale", job.ID)
+		return
+	}
+	switch err.(type) {
+	case nil, *stratum.Error, *daemon.Error:
+		atomic.AddInt64(&m.latency, int64(time.Since(start)))
+	default:
+		atomic.AddUint64(&m.failed, 1)
+		m.logger.Printf("share of job %s failed: %v", job.ID, err)
+		return
+	}
+	if err != nil {
+		atomic.AddUint64(&m.rejected, 1)
+		m.logger.Printf("share of job %s rejected: %v", job.ID, err)
+		return
+	}
+	atomic.AddUint64(&m.accepted, 1)
+	m.logger.Printf("share of job %s accepted", job.ID)
+}
+
+// report logs the hashrate every interval until ctx is done.
+func (m *miner) report(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			r10s, r60s, r15m := m.rates()
+			m.logger.Printf("%.2f %.2f %.2f H/s over 10s/60s/15m, %.2f H/s per thread, %d shares accepted, %d rejected, %d stale, %d failed",
+				r10s, r60s, r15m, r10s/float64(len(m.caches)), atomic.LoadUint64(&m.accepted), atomic.LoadUint64(&m.rejected), atomic.LoadUint64(&m.stale), atomic.LoadUint64(&m.failed))
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"ekyu.moe/cryptonight"
+	"ekyu.moe/cryptonight/cnutil"
+	"ekyu.moe/cryptonight/daemon"
+	"ekyu.moe/cryptonight/stratum"
+)
+
+// solo is the job source of cnminer solo: the block templates of a daemon,
+// whose blocks found are submitted back to it.
+type solo struct {
+	c       *daemon.Client
+	address string
+	refresh time.Duration
+	logger  *log.Logger
+
+	jobs  chan *stratum.Job
+	found chan struct{} // asks for a new template once a block is submitted
+
+	mu       sync.Mutex
+	jobID    string
+	template *daemon.BlockTemplate // of the job jobID
+}
+
+func newSolo(c *daemon.Client, address string, refresh time.Duration, logger *log.Logger) *solo {
+	return &solo{
+		c:       c,
+		address: address,
+		refresh: refresh,
+		logger:  logger,
+		jobs:    make(chan *stratum.Job, 1),
+		found:   make(chan struct{}, 1),
+	}
+}
+
+// Jobs returns the channel of the jobs, which is closed once run returns.
+func (s *solo) Jobs() <-chan *stratum.Job { return s.jobs }
+
+// run gets a block template right away, then every s.refresh and after each
+// block submitted, and sends a job whenever the template is on top of a new
+// block, until ctx is done.
+func (s *solo) run(ctx context.Context) {
+	defer close(s.jobs)
+
+	t := time.NewTicker(s.refresh)
+	defer t.Stop()
+
+	var prevHash []byte
+	for n := 1; ; {
+		tmpl, err := s.c.GetBlockTemplate(ctx, s.address, 0)
+		if err != nil && ctx.Err() == nil {
+			s.logger.Println("get block template:", err)
+		}
+		if err == nil && !bytes.Equal(tmpl.PrevHash, prevHash) {
+			job, err := soloJob(strconv.Itoa(n), tmpl)
+			if err != nil {
+				s.logger.Println("block template:", err)
+			} else {
+				prevHash = tmpl.PrevHash
+				n++
+
+				s.mu.Lock()
+				s.jobID, s.template = job.ID, tmpl
+				s.mu.Unlock()
+				select {
+				case <-s.jobs:
+				default:
+				}
+				s.jobs <- job
+			}
+		}
+
+		select {
+		case <-t.C:
+		case <-s.found:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// soloJob returns the job of mining tmpl, of the given ID.
+func soloJob(id string, tmpl *daemon.BlockTemplate) (*stratum.Job, error) {
+	blob, err := daemon.HashingBlob(tmpl.Blob)
+	if err != nil {
+		return nil, err
+	}
+	algo, err := daemon.Algorithm(tmpl.Blob)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stratum.Job{ID: id, Blob: blob, Target: tmpl.Difficulty, Algorithm: algo, Height: tmpl.Height}, nil
+}
+
+// Submit submits the block of job with nonce to the daemon, which computes its
+// hash again. A block of a job older than the latest one is not submitted, and
+// cryptonight.ErrStaleJob is returned instead.
+func (s *solo) Submit(ctx context.Context, job *stratum.Job, nonce uint32, hash []byte) error {
+	s.mu.Lock()
+	tmpl, stale := s.template, job.ID != s.jobID
+	s.mu.Unlock()
+	if stale {
+		return cryptonight.ErrStaleJob
+	}
+
+	block := append([]byte(nil), tmpl.Blob...)
+	if err := cnutil.SetNonce(block, nonce); err != nil {
+		return err
+	}
+	err := s.c.SubmitBlock(ctx, block)
+	select {
+	case s.found <- struct{}{}:
+	default:
+	}
+
+	return err
+}
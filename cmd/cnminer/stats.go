@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"ekyu.moe/cryptonight"
+)
+
+// stats is the state of a miner, as dumped by -stats.
+type stats struct {
+	Time      time.Time `json:"time"`
+	Version   string    `json:"version"`
+	Backend   string    `json:"backend"`
+	HugePages string    `json:"huge_pages"`
+
+	Job       string  `json:"job,omitempty"`
+	Algorithm string  `json:"algorithm,omitempty"`
+	Target    uint64  `json:"target,omitempty"`
+	Height    uint64  `json:"height,omitempty"`
+	Hashes    uint64  `json:"hashes"`
+	Hashrate  rates   `json:"hashrate"`
+	Threads   []rates `json:"threads"`
+
+	Accepted uint64  `json:"accepted"`
+	Rejected uint64  `json:"rejected"`
+	Stale    uint64  `json:"stale"`
+	Failed   uint64  `json:"failed"`
+	Latency  float64 `json:"latency"` // average round trip of a share in seconds
+}
+
+// rates are hashrates over 10 seconds, 60 seconds and 15 minutes.
+type rates [3]float64
+
+func (m *miner) stats() *stats {
+	f := cryptonight.Features()
+	s := &stats{
+		Time:      time.Now(),
+		Version:   f.Version,
+		Backend:   f.Backend,
+		HugePages: f.HugePages,
+		Hashes:    m.total(),
+		Accepted:  atomic.LoadUint64(&m.accepted),
+		Rejected:  atomic.LoadUint64(&m.rejected),
+		Stale:     atomic.LoadUint64(&m.stale),
+		Failed:    atomic.LoadUint64(&m.failed),
+		Latency:   m.avgLatency().Seconds(),
+	}
+	if job := m.currentJob(); job != nil {
+		s.Job, s.Algorithm, s.Target, s.Height = job.ID, job.Algorithm.String(), job.Target, job.Height
+	}
+	s.Hashrate[0], s.Hashrate[1], s.Hashrate[2] = m.rates()
+	s.Threads = make([]rates, len(m.meters))
+	for i := range m.meters {
+		s.Threads[i][0], s.Threads[i][1], s.Threads[i][2] = m.meters[i].Rates()
+	}
+
+	return s
+}
+
+// dumpStats writes the stats of m as JSON to name every interval until ctx
+// is done. The file is replaced at once, so that it can be read at any time.
+func (m *miner) dumpStats(ctx context.Context, name string, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			if err := writeStats(name, m.stats()); err != nil {
+				m.logger.Println("write stats:", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func writeStats(name string, s *stats) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(name+".tmp", append(b, '\n'), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(name+".tmp", name)
+}
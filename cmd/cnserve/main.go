@@ -0,0 +1,267 @@
+package main // import "ekyu.moe/cryptonight/cmd/cnserve"
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"ekyu.moe/cryptonight"
+	"ekyu.moe/cryptonight/internal/share"
+)
+
+// maxBody limits the size of a request body.
+const maxBody = 1 << 20
+
+type server struct {
+	keys     [][]byte          // accepted bearer tokens, none means no auth
+	pool     *cryptonight.Pool // limits concurrent hashes, as each takes 2 MiB
+	maxBatch int               // of shares in a request to /v1/verify
+	metrics  *metrics
+	logger   *log.Logger
+}
+
+type hashRequest struct {
+	Blob    string `json:"blob"`
+	Variant int    `json:"variant"`
+	Height  uint64 `json:"height,omitempty"`
+}
+
+type hashResponse struct {
+	Hash       string `json:"hash"`
+	Difficulty uint64 `json:"difficulty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func main() {
+	os.Exit(realMain())
+}
+
+func realMain() int {
+	var (
+		listen      string
+		metricsAddr string
+		authKeys    string
+		authFile    string
+		poolSize    int
+		maxBatch    int
+
+		stderr = log.New(os.Stderr, "cnserve: ", log.LstdFlags)
+	)
+
+	flag.StringVar(&listen, "listen", ":8080", "Address to listen on for the API.")
+	flag.StringVar(&metricsAddr, "metrics", "", "Address to serve Prometheus metrics on at /metrics. If it equals -listen, metrics are served by the API listener, without auth. Empty disables metrics.")
+	flag.StringVar(&authKeys, "auth-keys", "", "Comma separated list of accepted bearer tokens. Empty disables auth unless -auth-file is given.")
+	flag.StringVar(&authFile, "auth-file", "", "File of accepted bearer tokens, one per line.")
+	flag.IntVar(&poolSize, "pool-size", runtime.GOMAXPROCS(0), "Maximum number of concurrent hashes, each taking a 2 MiB cache.")
+	flag.IntVar(&maxBatch, "max-batch", 100, "Maximum number of shares in a request to /v1/verify.")
+	flag.Parse()
+
+	if poolSize <= 0 || maxBatch <= 0 {
+		stderr.Println("-pool-size and -max-batch must be positive.")
+		return 1
+	}
+
+	s := &server{
+		pool:     cryptonight.NewPool(poolSize),
+		maxBatch: maxBatch,
+		metrics:  new(metrics),
+		logger:   stderr,
+	}
+	for _, k := range strings.Split(authKeys, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			s.keys = append(s.keys, []byte(k))
+		}
+	}
+	if authFile != "" {
+		keys, err := readKeys(authFile)
+		if err != nil {
+			stderr.Println("read auth file:", err)
+			return 1
+		}
+		s.keys = append(s.keys, keys...)
+	}
+	if len(s.keys) == 0 {
+		stderr.Println("warning: no auth key given, the API is open to anyone who can reach it")
+	}
+
+	mux := s.routes()
+
+	errc := make(chan error, 2)
+	switch metricsAddr {
+	case "":
+	case listen:
+		mux.Handle("/metrics", s.metrics)
+	default:
+		m := http.NewServeMux()
+		m.Handle("/metrics", s.metrics)
+		go func() { errc <- serve(metricsAddr, m) }()
+		stderr.Println("serving metrics on", metricsAddr)
+	}
+	go func() { errc <- serve(listen, mux) }()
+	stderr.Println("serving API on", listen)
+
+	stderr.Println(<-errc)
+	return 1
+}
+
+// routes returns the API of s, without the metrics.
+func (s *server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/v1/hash", s.endpoint("hash", s.handleHash))
+	mux.Handle("/v1/verify", s.endpoint("verify", s.handleVerify))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+
+	return mux
+}
+
+func serve(addr string, h http.Handler) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	return srv.ListenAndServe()
+}
+
+func readKeys(name string) ([][]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var keys [][]byte
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if k := strings.TrimSpace(scanner.Text()); k != "" && k[0] != '#' {
+			keys = append(keys, []byte(k))
+		}
+	}
+
+	return keys, scanner.Err()
+}
+
+// endpoint wraps h with method check, auth, body decoding and metrics.
+func (s *server) endpoint(name string, h func(body []byte) (interface{}, int)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		status := s.serveEndpoint(w, r, h)
+		s.metrics.observe(name, status, time.Since(start))
+	})
+}
+
+func (s *server) serveEndpoint(w http.ResponseWriter, r *http.Request, h func(body []byte) (interface{}, int)) int {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		return reply(w, http.StatusMethodNotAllowed, &errorResponse{"method not allowed"})
+	}
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		return reply(w, http.StatusUnauthorized, &errorResponse{"unauthorized"})
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+	if err != nil {
+		return reply(w, http.StatusRequestEntityTooLarge, &errorResponse{"read request: " + err.Error()})
+	}
+
+	resp, status := h(body)
+
+	return reply(w, status, resp)
+}
+
+func (s *server) authorized(r *http.Request) bool {
+	if len(s.keys) == 0 {
+		return true
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	token := []byte(strings.TrimPrefix(auth, "Bearer "))
+	ok := 0
+	for _, k := range s.keys {
+		ok |= subtle.ConstantTimeCompare(token, k)
+	}
+
+	return ok == 1
+}
+
+func reply(w http.ResponseWriter, status int, v interface{}) int {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+
+	return status
+}
+
+func (s *server) handleHash(body []byte) (interface{}, int) {
+	var req hashRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return &errorResponse{"decode request: " + err.Error()}, http.StatusBadRequest
+	}
+	sum, err := share.HashPool(&share.Share{Blob: req.Blob, Variant: req.Variant, Height: req.Height}, s.pool)
+	if err != nil {
+		return &errorResponse{err.Error()}, http.StatusBadRequest
+	}
+	s.metrics.addHashes(1)
+
+	return &hashResponse{hex.EncodeToString(sum), cryptonight.Difficulty(sum)}, http.StatusOK
+}
+
+// handleVerify accepts either one share or an array of at most s.maxBatch of
+// them.
+func (s *server) handleVerify(body []byte) (interface{}, int) {
+	trimmed := strings.TrimSpace(string(body))
+	if strings.HasPrefix(trimmed, "[") {
+		var shares []share.Share
+		if err := json.Unmarshal(body, &shares); err != nil {
+			return &errorResponse{"decode request: " + err.Error()}, http.StatusBadRequest
+		}
+		if len(shares) > s.maxBatch {
+			return &errorResponse{fmt.Sprintf("more than %d shares", s.maxBatch)}, http.StatusRequestEntityTooLarge
+		}
+		verdicts := make([]*share.Verdict, len(shares))
+		for i := range shares {
+			verdicts[i] = s.verify(&shares[i])
+		}
+		return verdicts, http.StatusOK
+	}
+
+	var sh share.Share
+	if err := json.Unmarshal(body, &sh); err != nil {
+		return &errorResponse{"decode request: " + err.Error()}, http.StatusBadRequest
+	}
+
+	return s.verify(&sh), http.StatusOK
+}
+
+// verify verifies sh, taking a cache of the pool for its hash only, so that a
+// batch does not hold one for longer than a single share.
+func (s *server) verify(sh *share.Share) *share.Verdict {
+	v := share.VerifyPool(sh, s.pool)
+	if v.Hash != "" {
+		s.metrics.addHashes(1)
+	}
+	s.metrics.addShare(v.Valid)
+
+	return v
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ekyu.moe/cryptonight"
+	"ekyu.moe/cryptonight/internal/share"
+)
+
+var (
+	testBlob = hex.EncodeToString([]byte("Hello, 世界"))
+	testHash = "0999794e4e20d86e6a81b54495aeb370b6a9ae795fb5af4f778afaf07c0b2e0e"
+)
+
+func newTestServer() *httptest.Server {
+	s := &server{
+		keys:     [][]byte{[]byte("secret")},
+		pool:     cryptonight.NewPool(2),
+		maxBatch: 2,
+		metrics:  new(metrics),
+		logger:   log.New(ioutil.Discard, "", 0),
+	}
+
+	return httptest.NewServer(s.routes())
+}
+
+// post sends body to path of srv, and decodes the response into v.
+func post(t *testing.T, srv *httptest.Server, path, token, body string, v interface{}) int {
+	req, err := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("%s: decode response: %v", path, err)
+	}
+
+	return resp.StatusCode
+}
+
+func TestHash(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+
+	var h hashResponse
+	if status := post(t, srv, "/v1/hash", "secret", `{"blob": "`+testBlob+`", "variant": 0}`, &h); status != http.StatusOK || h.Hash != testHash {
+		t.Errorf("unexpected response %d %+v, expected hash %s", status, h, testHash)
+	}
+
+	for _, c := range []struct {
+		token, body string
+		status      int
+	}{
+		{"", `{"blob": "` + testBlob + `"}`, http.StatusUnauthorized},
+		{"wrong", `{"blob": "` + testBlob + `"}`, http.StatusUnauthorized},
+		{"secret", `{"blob": "` + testBlob + `", "variant": 3}`, http.StatusBadRequest},
+		{"secret", `{"blob": "zz"}`, http.StatusBadRequest},
+		{"secret", `{"blob": `, http.StatusBadRequest},
+	} {
+		var e errorResponse
+		if status := post(t, srv, "/v1/hash", c.token, c.body, &e); status != c.status || e.Error == "" {
+			t.Errorf("%s with token %q: unexpected response %d %+v, expected %d", c.body, c.token, status, e, c.status)
+		}
+	}
+
+	resp, err := srv.Client().Get(srv.URL + "/v1/hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET: unexpected status %d", resp.StatusCode)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+
+	valid := `{"id": 1, "blob": "` + testBlob + `", "variant": 0, "target": 1}`
+	invalid := `{"id": 2, "blob": "` + testBlob + `", "variant": 0, "target": 1, "result": "00"}`
+
+	var v share.Verdict
+	if status := post(t, srv, "/v1/verify", "secret", valid, &v); status != http.StatusOK || !v.Valid || v.Hash != testHash {
+		t.Errorf("unexpected response %d %+v", status, v)
+	}
+
+	var vs []share.Verdict
+	if status := post(t, srv, "/v1/verify", "secret", "["+valid+","+invalid+"]", &vs); status != http.StatusOK || len(vs) != 2 {
+		t.Fatalf("unexpected response %d %+v", status, vs)
+	}
+	if !vs[0].Valid || string(vs[0].ID) != "1" || vs[1].Valid || vs[1].Error != share.ErrResultMismatch.Error() {
+		t.Errorf("unexpected verdicts %+v", vs)
+	}
+
+	// the batch limit is checked before anything is hashed
+	var e errorResponse
+	if status := post(t, srv, "/v1/verify", "secret", "["+valid+","+valid+","+valid+"]", &e); status != http.StatusRequestEntityTooLarge || e.Error != "more than 2 shares" {
+		t.Errorf("unexpected response %d %+v", status, e)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"ekyu.moe/cryptonight"
+)
+
+// metrics collects counters exposed in the Prometheus text format.
+type metrics struct {
+	mu            sync.Mutex
+	requests      map[[2]string]uint64 // endpoint, status
+	durationSum   map[string]float64   // endpoint
+	hashes        uint64
+	validShares   uint64
+	invalidShares uint64
+}
+
+func (m *metrics) observe(endpoint string, status int, d time.Duration) {
+	m.mu.Lock()
+	if m.requests == nil {
+		m.requests = make(map[[2]string]uint64)
+		m.durationSum = make(map[string]float64)
+	}
+	m.requests[[2]string{endpoint, fmt.Sprint(status)}]++
+	m.durationSum[endpoint] += d.Seconds()
+	m.mu.Unlock()
+}
+
+func (m *metrics) addHashes(n uint64) {
+	m.mu.Lock()
+	m.hashes += n
+	m.mu.Unlock()
+}
+
+func (m *metrics) addShare(valid bool) {
+	m.mu.Lock()
+	if valid {
+		m.validShares++
+	} else {
+		m.invalidShares++
+	}
+	m.mu.Unlock()
+}
+
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	f := cryptonight.Features()
+	fmt.Fprintln(w, "# HELP cnserve_build_info Configuration of the CryptoNight implementation, always 1.")
+	fmt.Fprintln(w, "# TYPE cnserve_build_info gauge")
+	fmt.Fprintf(w, "cnserve_build_info{version=%q,backend=%q,huge_pages=%q} 1\n", f.Version, f.Backend, f.HugePages)
+
+	fmt.Fprintln(w, "# HELP cnserve_requests_total Number of API requests.")
+	fmt.Fprintln(w, "# TYPE cnserve_requests_total counter")
+	keys := make([][2]string, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0] < keys[j][0] || keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1]
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "cnserve_requests_total{endpoint=%q,code=%q} %d\n", k[0], k[1], m.requests[k])
+	}
+
+	fmt.Fprintln(w, "# HELP cnserve_request_duration_seconds_sum Total time spent serving API requests.")
+	fmt.Fprintln(w, "# TYPE cnserve_request_duration_seconds_sum counter")
+	endpoints := make([]string, 0, len(m.durationSum))
+	for e := range m.durationSum {
+		endpoints = append(endpoints, e)
+	}
+	sort.Strings(endpoints)
+	for _, e := range endpoints {
+		fmt.Fprintf(w, "cnserve_request_duration_seconds_sum{endpoint=%q} %g\n", e, m.durationSum[e])
+	}
+
+	fmt.Fprintln(w, "# HELP cnserve_hashes_total Number of CryptoNight hashes computed.")
+	fmt.Fprintln(w, "# TYPE cnserve_hashes_total counter")
+	fmt.Fprintf(w, "cnserve_hashes_total %d\n", m.hashes)
+
+	fmt.Fprintln(w, "# HELP cnserve_shares_total Number of shares verified.")
+	fmt.Fprintln(w, "# TYPE cnserve_shares_total counter")
+	fmt.Fprintf(w, "cnserve_shares_total{valid=\"true\"} %d\n", m.validShares)
+	fmt.Fprintf(w, "cnserve_shares_total{valid=\"false\"} %d\n", m.invalidShares)
+}
@@ -0,0 +1,162 @@
+package main // import "ekyu.moe/cryptonight/cmd/cnverify"
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"sync"
+
+	"ekyu.moe/cryptonight/internal/share"
+)
+
+// Verdict is one output record, in the same order as the input.
+type Verdict struct {
+	Line int `json:"line"`
+	*share.Verdict
+
+	seq int
+}
+
+type job struct {
+	seq  int // position among non-blank lines, used for reordering
+	line int
+	data []byte
+}
+
+func main() {
+	os.Exit(realMain())
+}
+
+func realMain() int {
+	var (
+		workers int
+		inFile  string
+		outFile string
+		onlyBad bool
+
+		in  io.Reader = os.Stdin
+		out io.Writer = os.Stdout
+
+		stderr = log.New(os.Stderr, "cnverify: ", 0)
+	)
+
+	flag.IntVar(&workers, "workers", runtime.GOMAXPROCS(0), "Number of shares verified in parallel.")
+	flag.StringVar(&inFile, "in-file", "", "Read JSONL records from file instead of stdin.")
+	flag.StringVar(&outFile, "out-file", "", "Produce output to file instead of stdout.")
+	flag.BoolVar(&onlyBad, "only-invalid", false, "Only output records of invalid shares.")
+	flag.Parse()
+
+	if workers <= 0 {
+		stderr.Println("-workers must be positive.")
+		return 1
+	}
+	if inFile != "" {
+		f, err := os.Open(inFile)
+		if err != nil {
+			stderr.Println("open input file:", err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+	if outFile != "" {
+		f, err := os.Create(outFile)
+		if err != nil {
+			stderr.Println("create output file:", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+
+	jobs := make(chan job, workers)
+	verdicts := make(chan *Verdict, workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				verdicts <- verify(j)
+			}
+		}()
+	}
+
+	var readErr error
+	go func() {
+		defer close(jobs)
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(nil, 1024*1024)
+		seq := 0
+		for line := 1; scanner.Scan(); line++ {
+			data := bytes.TrimSpace(scanner.Bytes())
+			if len(data) == 0 {
+				continue
+			}
+			jobs <- job{seq, line, append([]byte(nil), data...)}
+			seq++
+		}
+		readErr = scanner.Err()
+	}()
+	go func() {
+		wg.Wait()
+		close(verdicts)
+	}()
+
+	// verdicts arrive out of order, write them in the order of input
+	var (
+		enc      = json.NewEncoder(out)
+		pending  = make(map[int]*Verdict)
+		next     int
+		invalid  int
+		writeErr error
+	)
+	for v := range verdicts {
+		pending[v.seq] = v
+		for {
+			v, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+
+			if !v.Valid {
+				invalid++
+			}
+			if writeErr == nil && (!onlyBad || !v.Valid) {
+				writeErr = enc.Encode(v)
+			}
+		}
+	}
+
+	if readErr != nil {
+		stderr.Println("read input:", readErr)
+		return 1
+	}
+	if writeErr != nil {
+		stderr.Println("write output:", writeErr)
+		return 1
+	}
+	stderr.Printf("%d shares verified, %d invalid", next, invalid)
+	if invalid > 0 {
+		return 2
+	}
+
+	return 0
+}
+
+func verify(j job) *Verdict {
+	var s share.Share
+	if err := json.Unmarshal(j.data, &s); err != nil {
+		return &Verdict{j.line, &share.Verdict{Error: "decode record: " + err.Error()}, j.seq}
+	}
+
+	return &Verdict{j.line, share.Verify(&s), j.seq}
+}
@@ -0,0 +1,68 @@
+// +build js,wasm,go1.13
+
+// Command cnwasm exposes CryptoNight to JavaScript when built for js/wasm, for
+// web wallets verifying proof of work in the browser, with index.html as a demo
+// page. It defines cryptonight.algorithms, the names of the algorithms, and
+// cryptonight.sum(blob, algo, height), which hashes the hex encoded blob and
+// returns an object with hash and difficulty, a decimal string, or with error.
+// Each call blocks the JavaScript thread for the duration of one hash.
+package main // import "ekyu.moe/cryptonight/cmd/cnwasm"
+
+import (
+	"encoding/hex"
+	"strconv"
+	"syscall/js"
+
+	"ekyu.moe/cryptonight"
+)
+
+// cc is reused by every call, as JavaScript runs them one at a time.
+var cc = new(cryptonight.Cache)
+
+func main() {
+	var names []interface{}
+	for algo := cryptonight.CNv0; algo.Memory() != 0; algo++ {
+		names = append(names, algo.String())
+	}
+
+	js.Global().Set("cryptonight", map[string]interface{}{
+		"algorithms": names,
+		"sum":        js.FuncOf(sum),
+	})
+
+	// the functions above are called after main returns, so never return
+	select {}
+}
+
+func sum(this js.Value, args []js.Value) interface{} {
+	if len(args) != 3 {
+		return fail("cryptonight.sum takes a blob, an algorithm and a height")
+	}
+
+	blob, err := hex.DecodeString(args[0].String())
+	if err != nil {
+		return fail("blob: " + err.Error())
+	}
+	algo, err := cryptonight.ParseAlgorithm(args[1].String())
+	if err != nil {
+		return fail(err.Error())
+	}
+	height := args[2].Float()
+	if height < 0 || height != float64(uint64(height)) {
+		return fail("height: not a non-negative integer")
+	}
+
+	hash, err := cc.SumChecked(blob, algo, uint64(height))
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	return map[string]interface{}{
+		"hash":       hex.EncodeToString(hash),
+		"difficulty": strconv.FormatUint(cryptonight.Difficulty(hash), 10),
+	}
+}
+
+func fail(msg string) interface{} {
+	return map[string]interface{}{"error": msg}
+}
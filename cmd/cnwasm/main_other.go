@@ -0,0 +1,13 @@
+// +build !js !wasm !go1.13
+
+package main // import "ekyu.moe/cryptonight/cmd/cnwasm"
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	fmt.Fprintln(os.Stderr, "cnwasm: build with GOOS=js GOARCH=wasm")
+	os.Exit(2)
+}
@@ -0,0 +1,44 @@
+// Command cnworker serves hashing requests of remote clients, so that
+// front-ends can offload CryptoNight to it. See package
+// ekyu.moe/cryptonight/remote for the protocol and the client.
+package main // import "ekyu.moe/cryptonight/cmd/cnworker"
+
+import (
+	"flag"
+	"log"
+	"os"
+	"runtime"
+
+	"ekyu.moe/cryptonight/remote"
+)
+
+func main() {
+	os.Exit(realMain())
+}
+
+func realMain() int {
+	var (
+		listen  string
+		workers int
+
+		stderr = log.New(os.Stderr, "cnworker: ", log.LstdFlags)
+	)
+
+	flag.StringVar(&listen, "listen", ":7777", "TCP address to listen on.")
+	flag.IntVar(&workers, "workers", runtime.GOMAXPROCS(0), "Maximum number of concurrent hashes, each taking a 2 MiB cache.")
+	flag.Parse()
+
+	if workers <= 0 {
+		stderr.Println("-workers must be positive.")
+		return 1
+	}
+
+	s := &remote.Server{
+		Workers:  workers,
+		ErrorLog: stderr,
+	}
+	stderr.Println("serving on", listen)
+	stderr.Println(s.ListenAndServe(listen))
+
+	return 1
+}
@@ -0,0 +1,279 @@
+// Package cnlow exposes the individual phases of CryptoNight over a caller
+// owned scratchpad, for researchers and authors of alternative engines who
+// need building blocks that exactly match ekyu.moe/cryptonight.
+//
+// A full hash is computed as
+//     Absorb -> Explode -> NewRegisters -> Loop (Iterations * Step) -> Implode -> Final
+// which is what Sum does. Each phase corresponds to a section of CNS008 at
+// https://cryptonote.org/cns/cns008.txt
+//
+// All the words are stored in little endian, i.e. the scratchpad viewed as
+// bytes has the same layout as the one in the reference C implementation.
+//
+// This package is written in pure Go for clarity and is considerably slower
+// than ekyu.moe/cryptonight. It is not meant for production hashing.
+package cnlow // import "ekyu.moe/cryptonight/cnlow"
+
+import (
+	"encoding/binary"
+	"hash"
+	"math"
+
+	"ekyu.moe/cryptonight/blake256"
+	"ekyu.moe/cryptonight/groestl"
+	"ekyu.moe/cryptonight/internal/aes"
+	"ekyu.moe/cryptonight/internal/sha3"
+	"ekyu.moe/cryptonight/jh"
+	"ekyu.moe/cryptonight/skein"
+)
+
+const (
+	// ScratchpadSize is the size of the scratchpad in bytes.
+	ScratchpadSize = 2 * 1024 * 1024
+
+	// Iterations is the number of Step in the memory hard loop.
+	Iterations = 524288
+
+	// StateSize is the size of the Keccak state in bytes.
+	StateSize = 200
+
+	// mask of a 16-byte aligned address inside the scratchpad
+	addrMask = ScratchpadSize - 16
+)
+
+// State is the Keccak-1600 state, which is kept along the whole computation.
+type State [StateSize / 8]uint64
+
+// Scratchpad is the memory used by the memory hard loop.
+type Scratchpad [ScratchpadSize / 8]uint64
+
+// Bytes returns a copy of the state in little endian.
+func (s *State) Bytes() []byte {
+	b := make([]byte, StateSize)
+	for i, v := range s {
+		binary.LittleEndian.PutUint64(b[8*i:], v)
+	}
+
+	return b
+}
+
+// Registers holds the values carried between two Step of the memory hard loop.
+type Registers struct {
+	A, B [2]uint64
+
+	// variant 1 only
+	Tweak uint64
+
+	// variant 2 only
+	E         [2]uint64
+	Div, Sqrt uint64
+}
+
+// Absorb sets state to the Keccak-1600 state of data, as per CNS008 sec.3.
+func Absorb(state *State, data []byte) {
+	sha3.Keccak1600State((*[25]uint64)(state), data)
+}
+
+// Explode fills the scratchpad from state, as per CNS008 sec.3.
+func Explode(sp *Scratchpad, state *State) {
+	var (
+		rkeys  [40]uint32
+		blocks [16]uint64
+	)
+	aes.CnExpandKey(state[:4], &rkeys)
+	copy(blocks[:], state[8:24])
+	aes.CnExplode(sp[:], blocks[:], &rkeys)
+}
+
+// NewRegisters returns the initial registers of the memory hard loop, as per
+// CNS008 sec.4.
+//
+// data is the original input, only used by variant 1, where it is required
+// to have at least 43 bytes. NewRegisters panics if this condition doesn't
+// meet.
+func NewRegisters(state *State, data []byte, variant int) *Registers {
+	r := &Registers{
+		A: [2]uint64{state[0] ^ state[4], state[1] ^ state[5]},
+		B: [2]uint64{state[2] ^ state[6], state[3] ^ state[7]},
+	}
+
+	switch variant {
+	case 1:
+		if len(data) < 43 {
+			panic("cnlow: variant 1 requires at least 43 bytes of input")
+		}
+		r.Tweak = state[24] ^ binary.LittleEndian.Uint64(data[35:43])
+
+	case 2:
+		r.E = [2]uint64{state[8] ^ state[10], state[9] ^ state[11]}
+		r.Div = state[12]
+		r.Sqrt = state[13]
+	}
+
+	return r
+}
+
+// Loop runs the whole memory hard loop, i.e. Iterations times Step.
+func Loop(sp *Scratchpad, r *Registers, variant int) {
+	for i := 0; i < Iterations; i++ {
+		Step(sp, r, variant)
+	}
+}
+
+// Step runs exactly one iteration of the memory hard loop, as per CNS008
+// sec.4, with the modifications of variant 1 and 2 applied. Any other variant
+// is treated as 0.
+func Step(sp *Scratchpad, r *Registers, variant int) {
+	var c, d [2]uint64
+
+	addr := (r.A[0] & addrMask) >> 3
+	aes.CnSingleRound(c[:], sp[addr:addr+2], &r.A)
+
+	if variant == 2 {
+		shuffle(sp, addr, r)
+	}
+
+	sp[addr+0] = r.B[0] ^ c[0]
+	sp[addr+1] = r.B[1] ^ c[1]
+
+	if variant == 1 {
+		t := sp[addr+1] >> 24
+		t = ((^t)&1)<<4 | (((^t)&1)<<4&t)<<1 | (t&32)>>1
+		sp[addr+1] ^= t << 24
+	}
+
+	addr = (c[0] & addrMask) >> 3
+	d[0] = sp[addr+0]
+	d[1] = sp[addr+1]
+
+	if variant == 2 {
+		d[0] ^= r.Div ^ (r.Sqrt << 32)
+		divisor := (c[0]+(r.Sqrt<<1))&0xffffffff | 0x80000001
+		r.Div = (c[1]/divisor)&0xffffffff | (c[1]%divisor)<<32
+		r.Sqrt = sqrt(c[0] + r.Div)
+	}
+
+	lo, hi := mul128(c[0], d[0])
+
+	if variant == 2 {
+		// VARIANT2_2
+		sp[addr^0x02+0] ^= hi
+		sp[addr^0x02+1] ^= lo
+		hi ^= sp[addr^0x04+0]
+		lo ^= sp[addr^0x04+1]
+
+		shuffle(sp, addr, r)
+		r.E = r.B
+	}
+
+	r.A[0] += hi
+	r.A[1] += lo
+
+	sp[addr+0] = r.A[0]
+	sp[addr+1] = r.A[1]
+
+	if variant == 1 {
+		sp[addr+1] ^= r.Tweak
+	}
+
+	r.A[0] ^= d[0]
+	r.A[1] ^= d[1]
+
+	r.B = c
+}
+
+// shuffle rotates the three neighbour chunks of addr, as per variant 2.
+func shuffle(sp *Scratchpad, addr uint64, r *Registers) {
+	// since the scratchpad is []uint64 instead of []uint8, the offsets apply too
+	offset0 := addr ^ 0x02
+	offset1 := addr ^ 0x04
+	offset2 := addr ^ 0x06
+
+	chunk0_0, chunk0_1 := sp[offset0+0], sp[offset0+1]
+	chunk1_0, chunk1_1 := sp[offset1+0], sp[offset1+1]
+	chunk2_0, chunk2_1 := sp[offset2+0], sp[offset2+1]
+
+	sp[offset0+0] = chunk2_0 + r.E[0]
+	sp[offset0+1] = chunk2_1 + r.E[1]
+	sp[offset2+0] = chunk1_0 + r.A[0]
+	sp[offset2+1] = chunk1_1 + r.A[1]
+	sp[offset1+0] = chunk0_0 + r.B[0]
+	sp[offset1+1] = chunk0_1 + r.B[1]
+}
+
+// mul128 returns the 128-bit product of x and y.
+func mul128(x, y uint64) (lo, hi uint64) {
+	xhi, yhi := x>>32, y>>32
+	xlo, ylo := x&0xffffffff, y&0xffffffff
+
+	hihi := xhi * yhi
+	lolo := xlo * ylo
+	lohi := xlo * yhi
+	hilo := xhi * ylo
+
+	mid := lolo>>32 + lohi&0xffffffff + hilo&0xffffffff
+	lo = mid<<32 | (lolo & 0xffffffff)
+	hi = hihi + lohi>>32 + hilo>>32 + mid>>32
+
+	return
+}
+
+// sqrt is VARIANT2_INTEGER_MATH_SQRT_STEP_FP64 with its fixup.
+func sqrt(in uint64) uint64 {
+	out := uint64(math.Sqrt(float64(in)+1<<64)*2 - 1<<33)
+
+	s := out >> 1
+	b := out & 1
+	r := s*(s+b) + (out << 32)
+	if r+b > in {
+		out--
+	}
+
+	return out
+}
+
+// Implode folds the scratchpad back into state, as per CNS008 sec.5, then
+// applies Keccak-f[1600] to it.
+func Implode(sp *Scratchpad, state *State) {
+	var rkeys [40]uint32
+	aes.CnExpandKey(state[4:8], &rkeys)
+	aes.CnImplode(state[8:24], sp[:], &rkeys)
+	sha3.Keccak1600Permute((*[25]uint64)(state))
+}
+
+// Final returns the final hash of state, which is selected by its lowest 2
+// bits, as per CNS008 sec.5. The return value is exactly 32 bytes long.
+func Final(state *State) []byte {
+	var h hash.Hash
+	switch state[0] & 0x03 {
+	case 0:
+		h = blake256.New()
+	case 1:
+		h = groestl.New256()
+	case 2:
+		h = jh.New256()
+	case 3:
+		h = skein.New256(nil)
+	}
+	h.Write(state.Bytes())
+
+	return h.Sum(nil)
+}
+
+// FinalHash returns the name of the hash function Final picks for state:
+// "blake256", "groestl", "jh" or "skein".
+func FinalHash(state *State) string {
+	return [...]string{"blake256", "groestl", "jh", "skein"}[state[0]&0x03]
+}
+
+// Sum computes a CryptoNight hash with the phases above, using sp as the
+// scratchpad. It gives the same result as cryptonight.Sum.
+func Sum(sp *Scratchpad, data []byte, variant int) []byte {
+	state := new(State)
+	Absorb(state, data)
+	Explode(sp, state)
+	Loop(sp, NewRegisters(state, data, variant), variant)
+	Implode(sp, state)
+
+	return Final(state)
+}
@@ -0,0 +1,49 @@
+package cnlow_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"ekyu.moe/cryptonight"
+	"ekyu.moe/cryptonight/cnlow"
+)
+
+func TestSum(t *testing.T) {
+	sp := new(cnlow.Scratchpad)
+	inputs := [][]byte{
+		[]byte(""),
+		[]byte("This is a test"),
+		[]byte("variant 1 requires at least 43 bytes of input."),
+		[]byte("Monero is cash for a connected world. It’s fast, private, and secure."),
+	}
+
+	for variant := 0; variant <= 2; variant++ {
+		for i, in := range inputs {
+			if variant == 1 && len(in) < 43 {
+				continue
+			}
+
+			expected := cryptonight.Sum(in, variant)
+			if result := cnlow.Sum(sp, in, variant); !bytes.Equal(result, expected) {
+				t.Errorf("\n[v%d, %d] expected:\n\t%x\ngot:\n\t%x\n", variant, i, expected, result)
+			}
+		}
+	}
+}
+
+func Example() {
+	data := []byte("Hello, 世界")
+	sp := new(cnlow.Scratchpad) // may be reused across hashes
+	state := new(cnlow.State)
+
+	cnlow.Absorb(state, data)
+	cnlow.Explode(sp, state)
+	r := cnlow.NewRegisters(state, data, 0)
+	for i := 0; i < cnlow.Iterations; i++ {
+		cnlow.Step(sp, r, 0)
+	}
+	cnlow.Implode(sp, state)
+	fmt.Printf("%x\n", cnlow.Final(state))
+	// Output: 0999794e4e20d86e6a81b54495aeb370b6a9ae795fb5af4f778afaf07c0b2e0e
+}
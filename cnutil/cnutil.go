@@ -0,0 +1,118 @@
+// Package cnutil handles Monero hashing blobs, the input of CryptoNight when
+// mining a block, so that miners and pools do not have to compute offsets.
+//
+// A hashing blob starts with the block header: the major and minor versions
+// and the timestamp as varints, the 32-byte hash of the previous block, and the
+// 4-byte nonce. It is followed by the merkle root of the transactions and their
+// count, which this package ignores.
+package cnutil // import "ekyu.moe/cryptonight/cnutil"
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// NonceOffset is the offset of the nonce in a hashing blob whose versions take
+// 1 byte each and whose timestamp takes 5 bytes, which is the case of every
+// Monero block since 2014. Use ParseHeader for other blobs.
+const NonceOffset = 39
+
+var (
+	// ErrShortBlob is returned when a blob ends before the nonce.
+	ErrShortBlob = errors.New("cnutil: blob too short to hold a block header")
+
+	// ErrBadVarint is returned when a varint of the header overflows 64 bits.
+	ErrBadVarint = errors.New("cnutil: malformed varint in block header")
+
+	// ErrUnsupportedVersion is returned for the major versions not hashed
+	// with CryptoNight, that is 0 and those from 12 on, which use RandomX.
+	ErrUnsupportedVersion = errors.New("cnutil: major version not hashed with CryptoNight")
+)
+
+// Header is the block header at the start of a hashing blob.
+type Header struct {
+	MajorVersion uint64
+	MinorVersion uint64
+	Timestamp    uint64
+	PrevID       [32]byte
+	Nonce        uint32
+
+	// NonceOffset is the offset of Nonce in the blob, usually NonceOffset.
+	NonceOffset int
+}
+
+// ParseHeader parses the block header at the start of blob.
+func ParseHeader(blob []byte) (*Header, error) {
+	h := new(Header)
+	off := 0
+	for _, v := range []*uint64{&h.MajorVersion, &h.MinorVersion, &h.Timestamp} {
+		var n int
+		*v, n = binary.Uvarint(blob[off:])
+		if n == 0 {
+			return nil, ErrShortBlob
+		}
+		if n < 0 {
+			return nil, ErrBadVarint
+		}
+		off += n
+	}
+
+	if len(blob) < off+32+4 {
+		return nil, ErrShortBlob
+	}
+	copy(h.PrevID[:], blob[off:])
+	h.NonceOffset = off + 32
+	h.Nonce = binary.LittleEndian.Uint32(blob[h.NonceOffset:])
+
+	return h, nil
+}
+
+// Nonce returns the nonce of blob.
+func Nonce(blob []byte) (uint32, error) {
+	h, err := ParseHeader(blob)
+	if err != nil {
+		return 0, err
+	}
+
+	return h.Nonce, nil
+}
+
+// SetNonce replaces the nonce of blob with nonce, in place.
+func SetNonce(blob []byte, nonce uint32) error {
+	h, err := ParseHeader(blob)
+	if err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(blob[h.NonceOffset:], nonce)
+
+	return nil
+}
+
+// Variant returns the CryptoNight variant Monero hashes the blocks of
+// majorVersion with: 0 up to 6, 1 for 7, 2 for 8 and 9, and 4, also known as
+// CryptoNight-R, for 10 and 11. Variant 4 needs the height of the block as
+// well, see cryptonight.SumHeight.
+func Variant(majorVersion uint64) (int, error) {
+	switch {
+	case majorVersion == 0 || majorVersion >= 12:
+		return 0, ErrUnsupportedVersion
+	case majorVersion >= 10:
+		return 4, nil
+	case majorVersion >= 8:
+		return 2, nil
+	case majorVersion == 7:
+		return 1, nil
+	}
+
+	return 0, nil
+}
+
+// BlobVariant returns the CryptoNight variant of blob, from its major version.
+func BlobVariant(blob []byte) (int, error) {
+	h, err := ParseHeader(blob)
+	if err != nil {
+		return 0, err
+	}
+
+	return Variant(h.MajorVersion)
+}
@@ -0,0 +1,87 @@
+package cnutil
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// blob returns a hashing blob of majorVersion mined at timestamp, with nonce.
+func blob(majorVersion, timestamp uint64, nonce uint32) []byte {
+	b := make([]byte, 0, 76)
+	var buf [binary.MaxVarintLen64]byte
+	for _, v := range []uint64{majorVersion, majorVersion, timestamp} {
+		b = append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+	}
+	for i := 0; i < 32; i++ {
+		b = append(b, byte(i))
+	}
+	binary.LittleEndian.PutUint32(buf[:], nonce)
+	b = append(b, buf[:4]...)
+	b = append(b, make([]byte, 32)...) // merkle root
+	b = append(b, 1)                   // transactions
+
+	return b
+}
+
+func TestParseHeader(t *testing.T) {
+	b := blob(10, 1555555555, 0xdeadbeef)
+	h, err := ParseHeader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.MajorVersion != 10 || h.MinorVersion != 10 || h.Timestamp != 1555555555 || h.PrevID[31] != 31 {
+		t.Errorf("unexpected header %+v", h)
+	}
+	if h.NonceOffset != NonceOffset || h.Nonce != 0xdeadbeef {
+		t.Errorf("unexpected nonce %#x at %d", h.Nonce, h.NonceOffset)
+	}
+
+	// a smaller timestamp moves the nonce
+	if h, err := ParseHeader(blob(1, 1, 0)); err != nil || h.NonceOffset != 35 {
+		t.Errorf("unexpected header %+v, %v", h, err)
+	}
+
+	for i, v := range []struct {
+		blob []byte
+		err  error
+	}{
+		{nil, ErrShortBlob},
+		{b[:NonceOffset+3], ErrShortBlob},
+		{[]byte{0x80, 0x80}, ErrShortBlob},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, ErrBadVarint},
+	} {
+		if _, err := ParseHeader(v.blob); err != v.err {
+			t.Errorf("[%d] expected %v, got %v", i, v.err, err)
+		}
+	}
+}
+
+func TestSetNonce(t *testing.T) {
+	b := blob(7, 1555555555, 0)
+	if err := SetNonce(b, 0x01020304); err != nil {
+		t.Fatal(err)
+	}
+	if b[NonceOffset] != 4 || b[NonceOffset+3] != 1 {
+		t.Errorf("nonce not set in little endian: %x", b[NonceOffset:NonceOffset+4])
+	}
+	if n, err := Nonce(b); err != nil || n != 0x01020304 {
+		t.Errorf("unexpected nonce %#x, %v", n, err)
+	}
+
+	if err := SetNonce(b[:10], 1); err != ErrShortBlob {
+		t.Errorf("expected ErrShortBlob, got %v", err)
+	}
+}
+
+func TestVariant(t *testing.T) {
+	for major, want := range map[uint64]int{1: 0, 6: 0, 7: 1, 8: 2, 9: 2, 10: 4, 11: 4} {
+		if v, err := BlobVariant(blob(major, 1555555555, 0)); err != nil || v != want {
+			t.Errorf("v%d: expected variant %d, got %d, %v", major, want, v, err)
+		}
+	}
+	for _, major := range []uint64{0, 12, 16} {
+		if _, err := Variant(major); err != ErrUnsupportedVersion {
+			t.Errorf("v%d: expected ErrUnsupportedVersion, got %v", major, err)
+		}
+	}
+}
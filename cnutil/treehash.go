@@ -0,0 +1,51 @@
+package cnutil
+
+import "ekyu.moe/cryptonight/keccak"
+
+// TreeHash returns the tree hash of hashes, i.e. the merkle root of the
+// transactions of a block, as tree_hash of CryptoNote computes it. The hashes
+// beyond the largest power of 2 below their count are paired first, so that
+// a plain binary tree with Keccak-256 as its node hash remains.
+//
+// TreeHash panics if hashes is empty, as a block always has its miner
+// transaction.
+func TreeHash(hashes [][32]byte) [32]byte {
+	switch len(hashes) {
+	case 0:
+		panic("cnutil: TreeHash of no hash")
+	case 1:
+		return hashes[0]
+	case 2:
+		return hashPair(&hashes[0], &hashes[1])
+	}
+
+	cnt := 1
+	for cnt*2 < len(hashes) {
+		cnt *= 2
+	}
+
+	ints := make([][32]byte, cnt)
+	i := copy(ints, hashes[:2*cnt-len(hashes)])
+	for j := i; j < cnt; i, j = i+2, j+1 {
+		ints[j] = hashPair(&hashes[i], &hashes[i+1])
+	}
+	for ; cnt > 1; cnt /= 2 {
+		for i, j := 0, 0; j < cnt/2; i, j = i+2, j+1 {
+			ints[j] = hashPair(&ints[i], &ints[i+1])
+		}
+	}
+
+	return ints[0]
+}
+
+// hashPair returns the Keccak-256 of a and b concatenated.
+func hashPair(a, b *[32]byte) [32]byte {
+	var buf [64]byte
+	copy(buf[:], a[:])
+	copy(buf[32:], b[:])
+
+	var sum [32]byte
+	copy(sum[:], keccak.Sum256(buf[:]))
+
+	return sum
+}
@@ -0,0 +1,96 @@
+package cnutil
+
+import (
+	"bufio"
+	"encoding/hex"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ekyu.moe/cryptonight/keccak"
+)
+
+var moneroDir = flag.String("monero", "", "monero source tree to run tests/hash/tests-tree.txt from")
+
+// node is the Keccak-256 of the concatenation of hashes.
+func node(hashes ...[32]byte) (sum [32]byte) {
+	var buf []byte
+	for _, h := range hashes {
+		buf = append(buf, h[:]...)
+	}
+	copy(sum[:], keccak.Sum256(buf))
+
+	return
+}
+
+func TestTreeHash(t *testing.T) {
+	h := make([][32]byte, 9)
+	for i := range h {
+		h[i][0] = byte(i)
+	}
+
+	// the hashes beyond the largest power of 2 below their count are paired first
+	for n, want := range map[int][32]byte{
+		1: h[0],
+		2: node(h[0], h[1]),
+		3: node(h[0], node(h[1], h[2])),
+		4: node(node(h[0], h[1]), node(h[2], h[3])),
+		5: node(node(h[0], h[1]), node(h[2], node(h[3], h[4]))),
+		8: node(node(node(h[0], h[1]), node(h[2], h[3])), node(node(h[4], h[5]), node(h[6], h[7]))),
+		9: node(node(node(h[0], h[1]), node(h[2], h[3])), node(node(h[4], h[5]), node(h[6], node(h[7], h[8])))),
+	} {
+		if got := TreeHash(h[:n]); got != want {
+			t.Errorf("%d hashes: expected %x, got %x", n, want, got)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	TreeHash(nil)
+}
+
+// TestTreeHashUpstream runs tests/hash/tests-tree.txt of monero, whose lines
+// are the root and the hashes concatenated, in hex and separated by a space.
+func TestTreeHashUpstream(t *testing.T) {
+	if *moneroDir == "" {
+		t.Skip("-monero is not given")
+	}
+
+	f, err := os.Open(filepath.Join(*moneroDir, "tests", "hash", "tests-tree.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) == 3 && fields[0] == "tree_hash" {
+			fields = fields[1:]
+		}
+		data, err := hex.DecodeString(fields[len(fields)-1])
+		if len(fields) != 2 || err != nil || len(data) == 0 || len(data)%32 != 0 {
+			t.Fatalf("line %d: malformed", line)
+		}
+
+		hashes := make([][32]byte, len(data)/32)
+		for i := range hashes {
+			copy(hashes[i][:], data[32*i:])
+		}
+		if root := TreeHash(hashes); hex.EncodeToString(root[:]) != fields[0] {
+			t.Errorf("line %d: expected %s, got %x", line, fields[0], root)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,137 @@
+package cryptonight
+
+import (
+	"encoding/hex"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// These tests are meant to be run with -race as well.
+
+func TestConcurrentSum(t *testing.T) {
+	specs := append(append(append([]hashSpec{}, hashSpecsV0[:2]...), hashSpecsV1[:2]...), hashSpecsV2[:2]...)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+
+			cc := new(Cache)
+			for i := range specs {
+				v := specs[(g+i)%len(specs)]
+				in, _ := hex.DecodeString(v.input)
+
+				// alternate between a private Cache and the pool
+				var result []byte
+				if i%2 == 0 {
+					result = cc.Sum(in, v.variant)
+				} else {
+					result = Sum(in, v.variant)
+				}
+				if hex.EncodeToString(result) != v.output {
+					t.Errorf("\n[goroutine %d, v%d] expected:\n\t%s\ngot:\n\t%x\n", g, v.variant, v.output, result)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestCacheMisuse(t *testing.T) {
+	cc := new(Cache)
+
+	// as if another goroutine is running Sum
+	atomic.StoreUint32(&cc.inUse, 1)
+	func() {
+		defer func() {
+			if r := recover(); r != ErrCacheInUse {
+				t.Fatalf("expected to panic with ErrCacheInUse, got %v.", r)
+			}
+		}()
+
+		cc.Sum(nil, 0)
+	}()
+	atomic.StoreUint32(&cc.inUse, 0)
+
+	// a panic in Sum must not leave cc marked in use
+	func() {
+		defer func() { recover() }()
+		cc.Sum(nil, 1)
+	}()
+	if out := hex.EncodeToString(cc.Sum(nil, 0)); out != hashSpecsV0[0].output {
+		t.Errorf("\nexpected:\n\t%s\ngot:\n\t%s\n", hashSpecsV0[0].output, out)
+	}
+}
+
+func TestCacheMisuseConcurrent(t *testing.T) {
+	if runtime.GOMAXPROCS(0) < 2 {
+		t.Skip("goroutines cannot overlap with GOMAXPROCS=1")
+	}
+	cc := new(Cache)
+
+	// Each hash takes milliseconds, so goroutines released at once always
+	// overlap in practice.
+	const n = 4
+	var (
+		wg     sync.WaitGroup
+		start  = make(chan struct{})
+		panics int32
+	)
+	for g := 0; g < n; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					if r != ErrCacheInUse {
+						t.Errorf("expected to panic with ErrCacheInUse, got %v.", r)
+					}
+					atomic.AddInt32(&panics, 1)
+				}
+			}()
+
+			<-start
+			cc.Sum(nil, 0)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if panics == 0 {
+		t.Error("concurrent use of a Cache not detected")
+	}
+	if panics == n {
+		t.Error("every goroutine panicked, but one of them should have won")
+	}
+}
+
+func TestPanicCleanup(t *testing.T) {
+	cc := new(Cache)
+	cc.Sum(make([]byte, 64), 2)
+
+	// variant 1 only finds out the input is too short after keccak
+	func() {
+		defer func() {
+			if r := recover(); r != ErrShortInput {
+				t.Fatalf("expected to panic with ErrShortInput, got %v.", r)
+			}
+		}()
+
+		cc.Sum(make([]byte, 42), 1)
+	}()
+
+	wiped := cc.finalState == [25]uint64{} && cc.blocks == [16]uint64{} && cc.rkeys == [40]uint32{} &&
+		cc.finalBytes == [200]byte{} && cc.digest == [32]byte{} && cc.inUse == 0
+	for _, v := range cc.scratchpad {
+		wiped = wiped && v == 0
+	}
+	if !wiped {
+		t.Error("cache not wiped after panic")
+	}
+	if out := hex.EncodeToString(cc.Sum(nil, 0)); out != hashSpecsV0[0].output {
+		t.Errorf("\nexpected:\n\t%s\ngot:\n\t%s\n", hashSpecsV0[0].output, out)
+	}
+}
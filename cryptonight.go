@@ -4,42 +4,373 @@
 package cryptonight // import "ekyu.moe/cryptonight"
 
 import (
+	"errors"
+	"hash"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"ekyu.moe/cryptonight/internal/observe"
+)
+
+// Errors of this package and of the packages built on it, which are comparable
+// with ==. Sum panics with the ones Validate returns on misuse.
+var (
+	// ErrShortInput is returned when data is too short for the variant. Only
+	// variant 1 has a minimal input size, which is 43 bytes, as its tweak is
+	// read from data[35:43].
+	ErrShortInput = errors.New("cryptonight: variant 1 requires at least 43 bytes of input")
+
+	// ErrUnknownVariant is returned when the variant is not implemented.
+	ErrUnknownVariant = errors.New("cryptonight: unknown variant")
+
+	// ErrUnknownAlgorithm is returned when an Algorithm, or the name of one,
+	// is not implemented.
+	ErrUnknownAlgorithm = errors.New("cryptonight: unknown algorithm")
+
+	// ErrHeightRequired is returned when the variant depends on the block
+	// height, which is variant 4, and must be hashed with SumHeight.
+	ErrHeightRequired = errors.New("cryptonight: variant 4 requires a block height")
+
+	// ErrNonStandard is returned by ValidateStrict when the variant is not the
+	// original CryptoNight defined in CNS008.
+	ErrNonStandard = errors.New("cryptonight: variant is not part of CNS008")
+
+	// ErrCacheInUse is the value Cache.Sum panics with when it detects the
+	// Cache is being used by another goroutine.
+	ErrCacheInUse = errors.New("cryptonight: Cache used by multiple goroutines at the same time")
+
+	// ErrLowDifficulty is reported by verifiers when a hash does not meet its
+	// target difficulty, see CheckHash.
+	ErrLowDifficulty = errors.New("cryptonight: difficulty does not meet target")
+
+	// ErrStaleJob is returned by the pool clients for a share of a job which
+	// was replaced by a newer one, and so would be rejected.
+	ErrStaleJob = errors.New("cryptonight: job is stale")
+
+	// ErrNoncesExhausted is returned by Cache.Mine when every nonce it was
+	// given was tried without meeting the target.
+	ErrNoncesExhausted = errors.New("cryptonight: no nonce left to try")
+
+	// ErrAllocatorUnsupported is returned by NewCacheWithAllocator when built
+	// with the purego tag or by TinyGo.
+	ErrAllocatorUnsupported = errors.New("cryptonight: allocators are not supported with purego or TinyGo")
+
+	// ErrShortSalt is returned when the input of Chukwa is shorter than the 16
+	// bytes of its salt.
+	ErrShortSalt = errors.New("cryptonight: Chukwa requires at least 16 bytes of input")
+
+	// ErrStoreUnsupported is the value a Cache created by NewCacheWithStore
+	// panics with when asked for CNGPU, Chukwa or ChukwaV2.
+	ErrStoreUnsupported = errors.New("cryptonight: algorithm not supported with a PadStore")
+
+	// ErrMemoryLimit is the value a Cache created by NewCacheLimit panics with
+	// when asked for an algorithm needing a larger scratchpad than its limit.
+	ErrMemoryLimit = errors.New("cryptonight: algorithm needs more memory than the limit of the Cache")
+)
+
+// maxVariant is the highest variant implemented.
+const maxVariant = 4
+
+// params describe a member of the CryptoNight family: the variant of its
+// memory hard loop, and the sizes of it.
+type params struct {
+	variant    int
+	memory     int    // size of the scratchpad in bytes, a power of 2 up to 4 MiB
+	iterations int    // iterations of the memory hard loop
+	mask       uint64 // mask of the addresses in the scratchpad
+	heavy      bool   // mixing and division steps of CryptoNight-Heavy
+	xtl        bool   // variant 1 tweak of Stellite, indexed from bit 4
+	gpu        bool   // floating point loop of CryptoNight-GPU, see sumGPU
+	reverse    bool   // variant 2 shuffle of Graft, with the chunks at 0x10 and 0x30 swapped
+	argon2     bool   // Argon2id of Chukwa instead of CryptoNight, see sumArgon2
+}
+
+// standard returns the params of variant, with the sizes of CNS008.
+func standard(variant int) params {
+	return params{variant: variant, memory: 2 * 1024 * 1024, iterations: 524288, mask: 0x1ffff0}
+}
+
+// lite returns the params of variant of CryptoNight-Lite, which halves the
+// sizes of CNS008.
+func lite(variant int) params {
+	return params{variant: variant, memory: 1024 * 1024, iterations: 262144, mask: 0xffff0}
+}
+
+// heavy is the params of CryptoNight-Heavy, which doubles the scratchpad and
+// halves the iterations of CNS008.
+var heavy = params{memory: 4 * 1024 * 1024, iterations: 262144, mask: 0x3ffff0, heavy: true}
+
+// pico is the params of CryptoNight-Pico, which runs variant 2 with a 256 KiB
+// scratchpad and an eighth of the iterations of CNS008. Its mask only covers
+// half of the scratchpad, as in TurtleCoin.
+var pico = params{variant: 2, memory: 256 * 1024, iterations: 65536, mask: 0x1fff0}
+
+// fast, half and xtl are the params of forks that keep the scratchpad of
+// CNS008 and only change the iterations, or the tweak of variant 1.
+var (
+	fast = params{variant: 1, memory: 2 * 1024 * 1024, iterations: 262144, mask: 0x1ffff0}
+	half = params{variant: 2, memory: 2 * 1024 * 1024, iterations: 262144, mask: 0x1ffff0}
+	xtl  = params{variant: 1, memory: 2 * 1024 * 1024, iterations: 524288, mask: 0x1ffff0, xtl: true}
+)
+
+// rwz and zls are the params of Graft and Zelerius, which run variant 2 with
+// 3/4 of the iterations. Graft also reverses the shuffle.
+var (
+	rwz = params{variant: 2, memory: 2 * 1024 * 1024, iterations: 393216, mask: 0x1ffff0, reverse: true}
+	zls = params{variant: 2, memory: 2 * 1024 * 1024, iterations: 393216, mask: 0x1ffff0}
+)
+
+// double is the params of CryptoNight-Double, which runs variant 2 with twice
+// the iterations.
+var double = params{variant: 2, memory: 2 * 1024 * 1024, iterations: 1048576, mask: 0x1ffff0}
+
+// gpu is the params of CryptoNight-GPU. Its addresses are aligned to 64 bytes.
+var gpu = params{memory: 2 * 1024 * 1024, iterations: 49152, mask: 0x1fffc0, gpu: true}
+
+// chukwa and chukwaV2 are the params of the Chukwa algorithms of TurtleCoin,
+// Argon2id with iterations passes over memory bytes and a single lane.
+var (
+	chukwa   = params{memory: 512 * 1024, iterations: 3, argon2: true}
+	chukwaV2 = params{memory: 1024 * 1024, iterations: 4, argon2: true}
 )
 
-type cache struct {
-	// DO NOT change the order of these fields in this struct!
-	// They are carefully placed in this order to keep at least 16-byte aligned
-	// for some fields.
-	//
-	// In the future the alignment may be set explicitly, see
-	// https://github.com/golang/go/issues/19057
+// knownVariant reports whether variant is implemented. Variant 3 is skipped,
+// as monero never used it.
+func knownVariant(variant int) bool {
+	return variant >= 0 && variant <= maxVariant && variant != 3
+}
+
+// Cache can reduce GC stress by reusing the memory a hash needs, which is
+// useful when computing many hashes in a row on the same goroutine. Its
+// scratchpad is allocated by its first hash, as large as the algorithm needs,
+// and grows when a later hash needs more, such as the 4 MiB of
+// CryptoNight-Heavy. It is then kept for the smaller ones.
+//
+// The zero value of Cache is ready to use, and takes less than 1 KiB until its
+// first hash. A Cache must not be used by multiple goroutines at the same
+// time; Cache.Sum panics if it detects so.
+type Cache struct {
+	// The assembly loads these fields without assuming any alignment, as a
+	// Cache is too small for the Go heap to align it to 16 bytes. Only the
+	// scratchpad, which is allocated on its own, is aligned.
 
-	scratchpad [2 * 1024 * 1024 / 8]uint64 // 2 MiB scratchpad for memhard loop
-	finalState [25]uint64                  // state of keccak1600
-	_          [8]byte                     // padded to keep 16-byte align (0x2000d0)
+	finalState [25]uint64 // state of keccak1600
 
 	blocks [16]uint64 // temporary chunk/pointer of data
 	rkeys  [40]uint32 // 10 rounds, instead of 14 as in standard AES-256
+
+	finalBytes [200]byte // finalState in little endian, input of the final hash
+
+	inUse uint32 // 1 while Cache.Sum is running, accessed atomically
+
+	scratchpad []uint64  // scratchpad for memhard loop, allocated on demand
+	mem        []byte    // memory of scratchpad, if it comes from alloc
+	alloc      *mapper   // allocator of scratchpad, nil for the Go heap
+	store      *pagedPad // scratchpad in a PadStore, see NewCacheWithStore
+	limit      int       // largest scratchpad in bytes, 0 for none, see NewCacheLimit
+
+	final *[4]hash.Hash  // final hashes set by SetFinalHash, nil for the default ones
+	meter *HashrateMeter // set by SetHashrateMeter, fed by Mine
+
+	digest [32]byte // result of the last hash, see finalHash
 }
 
-// cachePool is a pool of cache.
+// cachePool is a pool of Cache.
 var cachePool = sync.Pool{
 	New: func() interface{} {
-		return new(cache)
+		return new(Cache)
 	},
 }
 
+// crossCheck, if set, is called with every result of Sum. It is only set by
+// differential testing builds, see sum_cref.go.
+var crossCheck func(data []byte, variant int, sum []byte)
+
+// Validate reports whether data can be hashed with variant. It returns
+// ErrUnknownVariant if variant is not 0, 1, 2 or 4, ErrShortInput if variant
+// is 1 and data is shorter than 43 bytes, and ErrHeightRequired if variant is
+// 4, which only SumHeight accepts. Any other input is valid, including an empty
+// one.
+func Validate(data []byte, variant int) error {
+	if !knownVariant(variant) {
+		return ErrUnknownVariant
+	}
+	if variant == 1 && len(data) < 43 {
+		return ErrShortInput
+	}
+	if variant == 4 {
+		return ErrHeightRequired
+	}
+
+	return nil
+}
+
+// ValidateStrict is the strict mode of Validate, for conformance testing. It
+// only accepts the original CryptoNight defined in CNS008, that is variant 0,
+// and returns ErrNonStandard for the variants introduced later by coins.
+func ValidateStrict(data []byte, variant int) error {
+	if err := Validate(data, variant); err != nil && err != ErrHeightRequired {
+		return err
+	}
+	if variant != 0 {
+		return ErrNonStandard
+	}
+
+	return nil
+}
+
 // Sum calculate a CryptoNight hash digest. The return value is exactly 32 bytes
 // long.
 //
-// When variant is 1, data is required to have at least 43 bytes.
-// This is assumed and not checked by Sum. If this condition doesn't meet, Sum
-// will panic straightforward.
+// variant must be 0, 1 or 2, otherwise Sum panics with ErrUnknownVariant, or
+// with ErrHeightRequired for variant 4. When variant is 1, data is required to
+// have at least 43 bytes, otherwise Sum panics with ErrShortInput. Use Validate
+// to check untrusted input beforehand.
 func Sum(data []byte, variant int) []byte {
-	cc := cachePool.Get().(*cache)
-	sum := cc.sum(data, variant)
-	cachePool.Put(cc)
+	if variant == 4 {
+		observe.Error(ErrHeightRequired)
+		panic(ErrHeightRequired)
+	}
+
+	return SumHeight(data, variant, 0)
+}
+
+// SumHeight is like Sum, but also accepts variant 4, also known as CryptoNight-R
+// or CN/R, whose loop runs a random program generated from the height of the
+// block being hashed. height is ignored by the other variants.
+func SumHeight(data []byte, variant int, height uint64) []byte {
+	cc := cachePool.Get().(*Cache)
+	defer cachePool.Put(cc)
+
+	sum := cc.safeSum(nil, data, standard(variant), height)
+
+	if crossCheck != nil {
+		crossCheck(data, variant, sum)
+	}
+
+	return sum
+}
+
+// Sum calculate a CryptoNight hash digest with cc, the same way as Sum does.
+//
+// Sum panics if it finds cc already in use by another goroutine. Such misuse
+// would otherwise silently produce wrong digests. If Sum panics for any other
+// reason, cc is wiped and can be reused once the panic is recovered.
+func (cc *Cache) Sum(data []byte, variant int) []byte {
+	if variant == 4 {
+		observe.Error(ErrHeightRequired)
+		panic(ErrHeightRequired)
+	}
+
+	return cc.SumHeight(data, variant, 0)
+}
+
+// SumHeight calculate a CryptoNight hash digest with cc, the same way as
+// SumHeight does, and panics the same way as Cache.Sum does.
+func (cc *Cache) SumHeight(data []byte, variant int, height uint64) []byte {
+	sum := cc.exclusiveSum(nil, data, standard(variant), height)
+	if cc.crossChecked() {
+		crossCheck(data, variant, sum)
+	}
 
 	return sum
 }
+
+// Memory returns the size in bytes of the scratchpad of cc, which is the
+// largest Algorithm.Memory of the algorithms it has hashed with so far, or 0
+// before its first hash. For a Cache created by NewCacheWithStore, it is the
+// size of the window.
+func (cc *Cache) Memory() int {
+	if cc.store != nil {
+		return len(cc.store.window) * 8
+	}
+
+	return len(cc.scratchpad) * 8
+}
+
+// NewCacheLimit returns a new Cache whose scratchpad never grows beyond limit
+// bytes, for devices with little memory, such as the gateways built with
+// TinyGo which only verify shares of CNPico or CN-Lite. limit is usually the
+// Algorithm.Memory of the largest algorithm to hash. A hash with an algorithm
+// needing more panics with ErrMemoryLimit, which Cache.SumChecked returns
+// instead.
+func NewCacheLimit(limit int) *Cache {
+	return &Cache{limit: limit}
+}
+
+// fits reports whether a scratchpad of memory bytes is within the limit of cc.
+func (cc *Cache) fits(memory int) bool {
+	return cc.limit <= 0 || memory <= cc.limit
+}
+
+// pad returns the scratchpad of cc of memory bytes, growing it if needed.
+func (cc *Cache) pad(memory int) []uint64 {
+	if memory > len(cc.scratchpad)*8 {
+		if !cc.fits(memory) {
+			panic(ErrMemoryLimit)
+		}
+		cc.growPad(memory)
+	}
+
+	return cc.scratchpad[:memory/8]
+}
+
+// exclusiveSum calls cc.safeSum, panicking with ErrCacheInUse if cc is already
+// in use.
+func (cc *Cache) exclusiveSum(out, data []byte, p params, height uint64) []byte {
+	if !atomic.CompareAndSwapUint32(&cc.inUse, 0, 1) {
+		observe.Error(ErrCacheInUse)
+		panic(ErrCacheInUse)
+	}
+	defer atomic.StoreUint32(&cc.inUse, 0)
+
+	return cc.safeSum(out, data, p, height)
+}
+
+// safeSum calls cc.sum and appends the digest to out, wiping cc if it panics,
+// so that a Cache is never left with the half-done state of an aborted hash. It
+// also reports to the Observer, if any.
+func (cc *Cache) safeSum(out, data []byte, p params, height uint64) []byte {
+	done := false
+	defer func() {
+		if done {
+			return
+		}
+		cc.wipe()
+		if observe.Enabled() {
+			r := recover()
+			if err, ok := r.(error); ok {
+				observe.Error(err)
+			}
+			panic(r)
+		}
+	}()
+
+	var start time.Time
+	if observe.Enabled() {
+		start = time.Now()
+	}
+	sum := cc.sum(data, p, height)
+	done = true
+	if !start.IsZero() {
+		observe.HashDone(p.variant, time.Since(start))
+	}
+
+	return append(out, sum...)
+}
+
+// wipe zeroes everything of cc but inUse, keeping the scratchpad allocated.
+func (cc *Cache) wipe() {
+	cc.finalState = [len(cc.finalState)]uint64{}
+	cc.blocks = [len(cc.blocks)]uint64{}
+	cc.rkeys = [len(cc.rkeys)]uint32{}
+	cc.finalBytes = [len(cc.finalBytes)]byte{}
+	cc.digest = [len(cc.digest)]byte{}
+	for i := range cc.scratchpad {
+		cc.scratchpad[i] = 0
+	}
+	if cc.store != nil {
+		cc.store.reset()
+	}
+}
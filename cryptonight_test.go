@@ -3,12 +3,12 @@ package cryptonight
 import (
 	"encoding/hex"
 	"testing"
+	"time"
 
-	"github.com/aead/skein"
-	"github.com/dchest/blake256"
-
+	"ekyu.moe/cryptonight/blake256"
 	"ekyu.moe/cryptonight/groestl"
 	"ekyu.moe/cryptonight/jh"
+	"ekyu.moe/cryptonight/skein"
 )
 
 type hashSpec struct {
@@ -48,7 +48,7 @@ var (
 		},
 	}
 	hashSpecsV2 = []hashSpec{
-		// From monero: tests/hash/test-slow-2.txt
+		// From monero: tests/hash/tests-slow-2.txt
 		{"5468697320697320612074657374205468697320697320612074657374205468697320697320612074657374", "353fdc068fd47b03c04b9431e005e00b68c2168a3cc7335c8b9b308156591a4f", 2},
 		{"4c6f72656d20697073756d20646f6c6f722073697420616d65742c20636f6e73656374657475722061646970697363696e67", "72f134fc50880c330fe65a2cb7896d59b2e708a0221c6a9da3f69b3a702d8682", 2},
 		{"656c69742c2073656420646f20656975736d6f642074656d706f7220696e6369646964756e74207574206c61626f7265", "410919660ec540fc49d8695ff01f974226a2a28dbbac82949c12f541b9a62d2f", 2},
@@ -60,6 +60,125 @@ var (
 		{"4578636570746575722073696e74206f6363616563617420637570696461746174206e6f6e2070726f6964656e742c", "12a794c1aa13d561c9c6111cee631ca9d0a321718d67d3416add9de1693ba41e", 2},
 		{"73756e7420696e2063756c706120717569206f666669636961206465736572756e74206d6f6c6c697420616e696d20696420657374206c61626f72756d2e", "2659ff95fc74b6215c1dc741e85b7a9710101b30620212f80eb59c3c55993f9d", 2},
 	}
+	hashSpecsV4 = []struct {
+		input, output string // both in hex
+		height        uint64
+	}{
+		// From monero: tests/hash/tests-slow-4.txt
+		{"5468697320697320612074657374205468697320697320612074657374205468697320697320612074657374", "f759588ad57e758467295443a9bd71490abff8e9dad1b95b6bf2f5d0d78387bc", 1806260},
+		{"4c6f72656d20697073756d20646f6c6f722073697420616d65742c20636f6e73656374657475722061646970697363696e67", "5bb833deca2bdd7252a9ccd7b4ce0b6a4854515794b56c207262f7a5b9bdb566", 1806261},
+		{"656c69742c2073656420646f20656975736d6f642074656d706f7220696e6369646964756e74207574206c61626f7265", "1ee6728da60fbd8d7d55b2b1ade487a3cf52a2c3ac6f520db12c27d8921f6cab", 1806262},
+		{"657420646f6c6f7265206d61676e6120616c697175612e20557420656e696d206164206d696e696d2076656e69616d2c", "6969fe2ddfb758438d48049f302fc2108a4fcc93e37669170e6db4b0b9b4c4cb", 1806263},
+		{"71756973206e6f737472756420657865726369746174696f6e20756c6c616d636f206c61626f726973206e697369", "7f3048b4e90d0cbe7a57c0394f37338a01fae3adfdc0e5126d863a895eb04e02", 1806264},
+		{"757420616c697175697020657820656120636f6d6d6f646f20636f6e7365717561742e20447569732061757465", "1d290443a4b542af04a82f6b2494a6ee7f20f2754c58e0849032483a56e8e2ef", 1806265},
+		{"697275726520646f6c6f7220696e20726570726568656e646572697420696e20766f6c7570746174652076656c6974", "c43cc6567436a86afbd6aa9eaa7c276e9806830334b614b2bee23cc76634f6fd", 1806266},
+		{"657373652063696c6c756d20646f6c6f726520657520667567696174206e756c6c612070617269617475722e", "87be2479c0c4e8edfdfaa5603e93f4265b3f8224c1c5946feb424819d18990a4", 1806267},
+		{"4578636570746575722073696e74206f6363616563617420637570696461746174206e6f6e2070726f6964656e742c", "dd9d6a6d8e47465cceac0877ef889b93e7eba979557e3935d7f86dce11b070f3", 1806268},
+		{"73756e7420696e2063756c706120717569206f666669636961206465736572756e74206d6f6c6c697420616e696d20696420657374206c61626f72756d2e", "75c6f2ae49a20521de97285b431e717125847fb8935ed84a61e7f8d36a2c3d8e", 1806269},
+	}
+	hashSpecsLite = []hashSpec{
+		// From xmrig: src/crypto/CryptoNight_test.h
+		{"0305a0dbd6bf05cf16e503f3a66f78007cbf34144332ecbfc22ed95c8700383b309ace1923a0964b00000008ba939a62724c0d7581fce5761e9d8a0e6a1c3f924fdd8493d1115649c05eb601", "3695b4b53bb00358b0ad38dc160feb9e004eece09b83a72ef6ba9864d3510c88", 0},
+		{"0305a0dbd6bf05cf16e503f3a66f78007cbf34144332ecbfc22ed95c8700383b309ace1923a0964b00000008ba939a62724c0d7581fce5761e9d8a0e6a1c3f924fdd8493d1115649c05eb601", "6d8cdc444e9bbbfd68fc43fcd4855b228c8a1bd91d9d00285bec02b7ca2d6741", 1},
+	}
+	hashSpecsHeavy = []hashSpec{
+		// From xmrig: src/crypto/CryptoNight_test.h
+		{"0305a0dbd6bf05cf16e503f3a66f78007cbf34144332ecbfc22ed95c8700383b309ace1923a0964b00000008ba939a62724c0d7581fce5761e9d8a0e6a1c3f924fdd8493d1115649c05eb601", "9983f21bdf2010a8d707bb2f14d78664bbe1187f55014b39e5f3d69328e48fc2", 0},
+	}
+	hashSpecsPico = []hashSpec{
+		// From xmrig: src/crypto/CryptoNight_test.h
+		{"0305a0dbd6bf05cf16e503f3a66f78007cbf34144332ecbfc22ed95c8700383b309ace1923a0964b00000008ba939a62724c0d7581fce5761e9d8a0e6a1c3f924fdd8493d1115649c05eb601", "08f421d7833117300eda66e98f4a2569093df300500173944efc401e9a4a17af", 2},
+	}
+
+	// From xmrig: src/crypto/CryptoNight_test.h, test_output_msr,
+	// test_output_half, test_output_xtl, test_output_rwz, test_output_zls and
+	// test_output_double
+	hashSpecsFast = []hashSpec{
+		{"0305a0dbd6bf05cf16e503f3a66f78007cbf34144332ecbfc22ed95c8700383b309ace1923a0964b00000008ba939a62724c0d7581fce5761e9d8a0e6a1c3f924fdd8493d1115649c05eb601", "3c7a61084c5eb865b498ab2f5a1ac52c49c177c2d0133442d65ed514335c82c5", 1},
+	}
+	hashSpecsHalf = []hashSpec{
+		{"0305a0dbd6bf05cf16e503f3a66f78007cbf34144332ecbfc22ed95c8700383b309ace1923a0964b00000008ba939a62724c0d7581fce5761e9d8a0e6a1c3f924fdd8493d1115649c05eb601", "5d4fbc356097ea6440b0888edeb635ddc84a0e397c868456895c3f29be7312a7", 2},
+	}
+	hashSpecsXTL = []hashSpec{
+		{"0305a0dbd6bf05cf16e503f3a66f78007cbf34144332ecbfc22ed95c8700383b309ace1923a0964b00000008ba939a62724c0d7581fce5761e9d8a0e6a1c3f924fdd8493d1115649c05eb601", "8fe5f05f022a617de53f79364b25cbc3c08e0e1fe3be48570703fee1ec0eb0b1", 1},
+	}
+	hashSpecsRWZ = []hashSpec{
+		{"0305a0dbd6bf05cf16e503f3a66f78007cbf34144332ecbfc22ed95c8700383b309ace1923a0964b00000008ba939a62724c0d7581fce5761e9d8a0e6a1c3f924fdd8493d1115649c05eb601", "5f56c6b0996ba23e0bba0729c99074855a10e3087fdbfe947533547376f075b8", 2},
+	}
+	hashSpecsZLS = []hashSpec{
+		{"0305a0dbd6bf05cf16e503f3a66f78007cbf34144332ecbfc22ed95c8700383b309ace1923a0964b00000008ba939a62724c0d7581fce5761e9d8a0e6a1c3f924fdd8493d1115649c05eb601", "516e33c6e446abbccdad18c04cd9a25e64102853b20a42dfdeaa8b599ecf40e2", 2},
+	}
+	hashSpecsDouble = []hashSpec{
+		{"0305a0dbd6bf05cf16e503f3a66f78007cbf34144332ecbfc22ed95c8700383b309ace1923a0964b00000008ba939a62724c0d7581fce5761e9d8a0e6a1c3f924fdd8493d1115649c05eb601", "aefbb3f0cc88046d119f6c54b96d90c9e884ea3b5983a60d50a42d7d3ebe4821", 2},
+	}
+
+	hashSpecsGPU = []hashSpec{
+		// From xmrig: src/crypto/CryptoNight_test.h
+		{"0305a0dbd6bf05cf16e503f3a66f78007cbf34144332ecbfc22ed95c8700383b309ace1923a0964b00000008ba939a62724c0d7581fce5761e9d8a0e6a1c3f924fdd8493d1115649c05eb601", "e55cb23e51649a59b127b96b515f2bf7bfea199741a0216cf838ded06eff82df", 0},
+	}
+
+	// From TurtleCoin: the tests of turtlecoin-crypto
+	hashSpecsChukwa = []hashSpec{
+		{"0100fb8e8ac805899323371bb790db19218afd8db8e3755d8b90f39b3d5506a9abce4fa912244500000000ee8146d49fa93ee724deb57d12cbc6c6f3b924d946127c7a97418f9348828f0f02", "c0dad0eeb9c52e92a1c3aa5b76a3cb90bd7376c28dce191ceeb1096e3a390d2e", 0},
+	}
+	hashSpecsChukwaV2 = []hashSpec{
+		{"0100fb8e8ac805899323371bb790db19218afd8db8e3755d8b90f39b3d5506a9abce4fa912244500000000ee8146d49fa93ee724deb57d12cbc6c6f3b924d946127c7a97418f9348828f0f02", "3578c135261366a7bac407b8c0ff50f3ad96f096ec2813e9644e6e77a43f803d", 0},
+	}
+
+	// Inputs of lengths around the 43 bytes variant 1 requires and the 136 bytes
+	// keccak rate, plus a few KB, where data[i] = byte(i). They catch padding
+	// and tweak offset mistakes that random inputs rarely hit. The digests are
+	// not from upstream, but regression values of this package, on which its
+	// Go and assembly backends agree.
+	boundarySpecs = []struct {
+		size    int
+		output  string
+		variant int
+	}{
+		{0, "eb14e8a833fac6fe9a43b57b336789c46ffe93f2868452240720607b14387e11", 0},
+		{1, "ed9f9bf165801f2c715fa456727a90cca41237aeda545ee6eec878f4bbf5fcdc", 0},
+		{42, "6b0c18a61ccf931b370b66a91e74f36fbf8a0936e383840a1d34bc8ff574f19c", 0},
+		{43, "1a7e8b50ea7f300dc093fe2145e4e0b75f2ee7f9cdb6c3cd3b76bee0297b62c1", 0},
+		{44, "4278e01c370613d31f27c6d8b64430b5479f5ba375c0979c88319ab01e4e5ecb", 0},
+		{135, "112a364623c2be6110afb0718adfa8af82657780daae3f0d565abadd790504a4", 0},
+		{136, "d9bb0959b68ab1e0d747c3ae0a9977fca475af32a6d9d70787fbece42766f3e3", 0},
+		{137, "360b4a06db0047925839516cc886cc1fe40e07d04b5b1278ed325a41d55ab4b2", 0},
+		{4096, "e4b2c376571659c4b816c41ea9bb2c666f08f50bca40129144f4ed77261d0eca", 0},
+		{10000, "7cdd5ae7a77ae5cd7e71c110d6a9a0c14fe7f366f71f212f74b63a37beeb2d58", 0},
+		{43, "da2087692b1cd205bbc43518bce3f745a2f099ce8b8480ad2df837c3083d15e4", 1},
+		{44, "c02759f395ec0490863e8f8bc06c356a93dac3d8fbe0298b723ba900de19c34a", 1},
+		{135, "d35adf48a98a152cabe58422b26f073265e50ee0d4336146fac924a93d904472", 1},
+		{136, "0b1c565564c21e8f3c8428aa127ff0e99cdc5f9f734fc45dd9422995df16c9bd", 1},
+		{137, "d811f5b86e95d396145067eaf9fc447ac203ccbfde94e3d23fb4bb3055213337", 1},
+		{4096, "ed68cd28c6235daf56a7b700c61741d739c8843cd60569f55d61a8f3537c9696", 1},
+		{10000, "a68bfd0b8a2f32da249d646d89c04c84c7db8edd7f2c91a347a8da2046f11120", 1},
+		{0, "e34985722288be50a2068f973f02248d62e7bc6a0a0dfca2eb84909724857a72", 2},
+		{1, "3e69bbd37cc347c0d49dfaa9fd283b5af634b5a1280b7200ca2280cb6d525ec5", 2},
+		{42, "fdc7e3e739a8005d55ebe463e1136a5b57e552b72cbcd3f533b344de8e81687a", 2},
+		{43, "e4f6bdce9b0e88a9656bc2d33c089c72008ebd432e6240ae8c3decf4926e2cdd", 2},
+		{44, "f9336aa0354da733e4fd38f6b32ca1615073a5d182089763d9df9d1735f7e191", 2},
+		{135, "5a885a8bf68e307fa53e45b18fb6e510a71535baba36e51d2f053a302ebe3631", 2},
+		{136, "422d905f52995861410ba690ddd1691fb5b2c183d371f3f6e44b9693dadfb0fe", 2},
+		{137, "e10fe96315c42236d8ca965016e063b885e18408e39b8686feda650ddae101f8", 2},
+		{4096, "46f7ea7b5d346d40a0a28aeeadcbcf3f9fcda11b07e727ed1830219b1092e84a", 2},
+		{10000, "2770c7e1bf9263134eb35483a2dba4ee32d0f47e2db2a069adfbf22277a569c5", 2},
+	}
+
+	// The same inputs for variant 4 at height 1806260, also regression values.
+	boundarySpecsV4 = []struct {
+		size   int
+		output string
+	}{
+		{0, "8f7d902c5bb8d099e686370de6a5ea5e80dcddf57e8b288e4f256d4b80f428cb"},
+		{1, "afc2ff2ab49ec0eb11ea7b571b7a98d391b7128813f759220b043cc8265e8796"},
+		{42, "4d7d06d244f22a66c748e744143494ead95567e7bb2cb1654061d261ae38cd6d"},
+		{43, "c03faac698f76382851760497e4312328e46e72674561e316e9eb20ffc06667e"},
+		{44, "ce87dec48b130b038746cea7545246c5a46f9e9297cf303d174e79d1faab503a"},
+		{135, "f9c93f998011a04c01cd2a8e101be856edb3af55cd2fbbfe534b54e7be4871a6"},
+		{136, "c98e945647d5101252ba6a94fd8bf04d8acf670f0f5f6f5dd79efedda987e2cb"},
+		{137, "d5cf53e1d7db20e6f7ef071782d6d549e0673db04aab142df48d816ff370d8aa"},
+		{4096, "59a20cfc9235a7dff5491909088a547360bfe81a601ca0d74a6dc1c564ac6659"},
+	}
 
 	// This test data set is specially picked, as the final hash functions for
 	// all v0, v1, v2 when they are passed through are the same, and they cover
@@ -74,6 +193,21 @@ var (
 	}
 )
 
+// withoutHeight adapts sum for testSum, which only runs variants that do not
+// depend on the height.
+func withoutHeight(sum func(data []byte, variant int, height uint64) []byte) func(data []byte, variant int) []byte {
+	return func(data []byte, variant int) []byte {
+		return sum(data, variant, 0)
+	}
+}
+
+// withStandard adapts sum for the tests of variants of standard sizes.
+func withStandard(sum func(data []byte, p params, height uint64) []byte) func(data []byte, variant int, height uint64) []byte {
+	return func(data []byte, variant int, height uint64) []byte {
+		return sum(data, standard(variant), height)
+	}
+}
+
 func testSum(t *testing.T, sum func(data []byte, variant int) []byte) {
 	run := func(t *testing.T, hashSpecs []hashSpec) {
 		for i, v := range hashSpecs {
@@ -91,15 +225,143 @@ func testSum(t *testing.T, sum func(data []byte, variant int) []byte) {
 
 		func() {
 			defer func() {
-				if r := recover(); r == nil {
-					t.Fatal("expected to panic, got nothing.")
+				if r := recover(); r != ErrShortInput {
+					t.Fatalf("expected to panic with ErrShortInput, got %v.", r)
 				}
 			}()
 
-			sum([]byte("Obviously less than 43 bytes"), 1)
+			sum(make([]byte, 42), 1)
 		}()
+
+		// exactly 43 bytes is fine
+		sum(make([]byte, 43), 1)
 	})
 	t.Run("v2", func(t *testing.T) { run(t, hashSpecsV2) })
+	t.Run("boundary", func(t *testing.T) {
+		for _, v := range boundarySpecs {
+			if result := sum(boundaryInput(v.size), v.variant); hex.EncodeToString(result) != v.output {
+				t.Errorf("\n[v%d, %d bytes] expected:\n\t%s\ngot:\n\t%x\n", v.variant, v.size, v.output, result)
+			}
+		}
+	})
+	t.Run("unknown", func(t *testing.T) {
+		for _, variant := range []int{-1, 3, 5} {
+			func() {
+				defer func() {
+					if r := recover(); r != ErrUnknownVariant {
+						t.Fatalf("expected to panic with ErrUnknownVariant, got %v.", r)
+					}
+				}()
+
+				sum(nil, variant)
+			}()
+		}
+	})
+}
+
+// boundaryInput returns the input of size bytes of boundarySpecs.
+func boundaryInput(size int) []byte {
+	in := make([]byte, size)
+	for i := range in {
+		in[i] = byte(i)
+	}
+
+	return in
+}
+
+func testSumHeight(t *testing.T, sum func(data []byte, variant int, height uint64) []byte) {
+	for i, v := range hashSpecsV4 {
+		in, _ := hex.DecodeString(v.input)
+		if result := sum(in, 4, v.height); hex.EncodeToString(result) != v.output {
+			t.Errorf("\n[%d] expected:\n\t%s\ngot:\n\t%x\n", i, v.output, result)
+		}
+	}
+
+	for _, v := range boundarySpecsV4 {
+		if result := sum(boundaryInput(v.size), 4, 1806260); hex.EncodeToString(result) != v.output {
+			t.Errorf("\n[v4, %d bytes] expected:\n\t%s\ngot:\n\t%x\n", v.size, v.output, result)
+		}
+	}
+
+	// the height is ignored by other variants
+	in, _ := hex.DecodeString(hashSpecsV2[0].input)
+	if result := sum(in, 2, 1806260); hex.EncodeToString(result) != hashSpecsV2[0].output {
+		t.Errorf("\nexpected:\n\t%s\ngot:\n\t%x\n", hashSpecsV2[0].output, result)
+	}
+}
+
+func TestSumHeight(t *testing.T) {
+	testSumHeight(t, SumHeight)
+	testSumHeight(t, new(Cache).SumHeight)
+
+	for name, sum := range map[string]func([]byte, int) []byte{
+		"Sum":       Sum,
+		"Cache.Sum": new(Cache).Sum,
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r != ErrHeightRequired {
+					t.Fatalf("expected %s to panic with ErrHeightRequired, got %v.", name, r)
+				}
+			}()
+
+			sum(nil, 4)
+		}()
+	}
+}
+
+func TestSumHeavy(t *testing.T) {
+	cc := new(Cache)
+	in, _ := hex.DecodeString(hashSpecsHeavy[0].input)
+	if result := cc.SumAlgorithm(in, CNHeavy, 0); hex.EncodeToString(result) != hashSpecsHeavy[0].output {
+		t.Errorf("\nexpected:\n\t%s\ngot:\n\t%x\n", hashSpecsHeavy[0].output, result)
+	}
+
+	// the large scratchpad is kept, and does not affect the other variants
+	if len(cc.scratchpad) != heavy.memory/8 {
+		t.Fatalf("scratchpad of %d words", len(cc.scratchpad))
+	}
+	if out := hex.EncodeToString(cc.Sum(nil, 0)); out != hashSpecsV0[0].output {
+		t.Errorf("\nexpected:\n\t%s\ngot:\n\t%s\n", hashSpecsV0[0].output, out)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for i, v := range []struct {
+		size, variant int
+		err           error
+	}{
+		{0, 0, nil},
+		{0, 1, ErrShortInput},
+		{0, 2, nil},
+		{42, 1, ErrShortInput},
+		{43, 1, nil},
+		{42, 0, nil},
+		{43, 3, ErrUnknownVariant},
+		{43, -1, ErrUnknownVariant},
+		{0, 4, ErrHeightRequired},
+		{43, 5, ErrUnknownVariant},
+	} {
+		if err := Validate(make([]byte, v.size), v.variant); err != v.err {
+			t.Errorf("[%d] expected %v, got %v", i, v.err, err)
+		}
+	}
+
+	for i, v := range []struct {
+		size, variant int
+		err           error
+	}{
+		{0, 0, nil},
+		{43, 1, ErrNonStandard},
+		{0, 2, ErrNonStandard},
+		{42, 1, ErrShortInput},
+		{0, 3, ErrUnknownVariant},
+		{0, 4, ErrNonStandard},
+	} {
+		if err := ValidateStrict(make([]byte, v.size), v.variant); err != v.err {
+			t.Errorf("[strict %d] expected %v, got %v", i, v.err, err)
+		}
+	}
 }
 
 // Here we don't make a seperate template function, as we want the function address
@@ -107,25 +369,40 @@ func testSum(t *testing.T, sum func(data []byte, variant int) []byte) {
 func BenchmarkSum(b *testing.B) {
 	b.Run("v0", func(b *testing.B) {
 		b.N = 100
+		start := time.Now()
 		for i := 0; i < b.N; i++ {
 			Sum(benchData[i&0x03], 0)
 		}
+		reportHashrate(b, start, 1)
 	})
 	b.Run("v1", func(b *testing.B) {
 		b.N = 100
+		start := time.Now()
 		for i := 0; i < b.N; i++ {
 			Sum(benchData[i&0x03], 1)
 		}
+		reportHashrate(b, start, 1)
 	})
 	b.Run("v2", func(b *testing.B) {
 		b.N = 100
+		start := time.Now()
 		for i := 0; i < b.N; i++ {
 			Sum(benchData[i&0x03], 2)
 		}
+		reportHashrate(b, start, 1)
+	})
+	b.Run("v4", func(b *testing.B) {
+		b.N = 100
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			SumHeight(benchData[i&0x03], 4, 1806260)
+		}
+		reportHashrate(b, start, 1)
 	})
 
 	b.Run("v0-parallel", func(b *testing.B) {
 		b.N = 100
+		start := time.Now()
 		b.RunParallel(func(pb *testing.PB) {
 			i := 0
 			for pb.Next() {
@@ -133,9 +410,11 @@ func BenchmarkSum(b *testing.B) {
 				i++
 			}
 		})
+		reportHashrate(b, start, 1)
 	})
 	b.Run("v1-parallel", func(b *testing.B) {
 		b.N = 100
+		start := time.Now()
 		b.RunParallel(func(pb *testing.PB) {
 			i := 0
 			for pb.Next() {
@@ -143,9 +422,11 @@ func BenchmarkSum(b *testing.B) {
 				i++
 			}
 		})
+		reportHashrate(b, start, 1)
 	})
 	b.Run("v2-parallel", func(b *testing.B) {
 		b.N = 100
+		start := time.Now()
 		b.RunParallel(func(pb *testing.PB) {
 			i := 0
 			for pb.Next() {
@@ -153,6 +434,7 @@ func BenchmarkSum(b *testing.B) {
 				i++
 			}
 		})
+		reportHashrate(b, start, 1)
 	})
 }
 
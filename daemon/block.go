@@ -0,0 +1,184 @@
+package daemon
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"ekyu.moe/cryptonight"
+	"ekyu.moe/cryptonight/cnutil"
+	"ekyu.moe/cryptonight/keccak"
+)
+
+// ErrBadBlock is returned for a block blob which cannot be parsed.
+var ErrBadBlock = errors.New("daemon: malformed block")
+
+// Tags of the inputs and outputs of a miner transaction.
+const (
+	txinGen            = 0xff
+	txoutToKey         = 0x02
+	txoutToTaggedKey   = 0x03 // with a 1 byte view tag after the key
+	rctTypeNull        = 0    // the RingCT signature of a miner transaction
+	maxMinerTxElements = 1 << 16
+)
+
+// HashingBlob returns the hashing blob of a block blob, e.g. the Blob of a
+// BlockTemplate or a block of the chain: its header, followed by the tree hash
+// of its miner transaction and the hashes of its other transactions, and by
+// their count. It is what CryptoNight hashes, with the nonce at the same
+// offset as in block.
+//
+// Only the transaction versions 1 and 2 are supported, with the inputs and
+// outputs of a miner transaction.
+func HashingBlob(block []byte) ([]byte, error) {
+	h, err := cnutil.ParseHeader(block)
+	if err != nil {
+		return nil, err
+	}
+	off := h.NonceOffset + 4
+
+	minerTx, n, err := minerTxHash(block[off:])
+	if err != nil {
+		return nil, err
+	}
+	off += n
+	count, n := binary.Uvarint(block[off:])
+	if n <= 0 || count > uint64(len(block)-off-n)/32 {
+		return nil, ErrBadBlock
+	}
+	off += n
+	if len(block) != off+int(count)*32 {
+		return nil, ErrBadBlock
+	}
+
+	hashes := make([][32]byte, 1+count)
+	hashes[0] = minerTx
+	for i := range hashes[1:] {
+		copy(hashes[1+i][:], block[off+32*i:])
+	}
+	root := cnutil.TreeHash(hashes)
+
+	blob := make([]byte, h.NonceOffset+4, h.NonceOffset+4+32+binary.MaxVarintLen64)
+	copy(blob, block)
+	blob = append(blob, root[:]...)
+	var buf [binary.MaxVarintLen64]byte
+	blob = append(blob, buf[:binary.PutUvarint(buf[:], 1+count)]...)
+
+	return blob, nil
+}
+
+// minerTxHash parses the miner transaction at the start of b, and returns its
+// hash and its size.
+func minerTxHash(b []byte) (hash [32]byte, size int, err error) {
+	off := 0
+	varint := func() uint64 {
+		v, n := binary.Uvarint(b[off:])
+		if n <= 0 {
+			err = ErrBadBlock
+			return 0
+		}
+		off += n
+		return v
+	}
+	skip := func(n uint64) {
+		if err == nil && n > uint64(len(b)-off) {
+			err = ErrBadBlock
+		}
+		if err == nil {
+			off += int(n)
+		}
+	}
+	tag := func() byte {
+		if err != nil || off >= len(b) {
+			err = ErrBadBlock
+			return 0
+		}
+		off++
+		return b[off-1]
+	}
+
+	version := varint()
+	varint() // unlock time
+	for i, n := uint64(0), varint(); err == nil && i < n; i++ {
+		if n > maxMinerTxElements || tag() != txinGen {
+			return hash, 0, ErrBadBlock
+		}
+		varint() // height
+	}
+	for i, n := uint64(0), varint(); err == nil && i < n; i++ {
+		if n > maxMinerTxElements {
+			return hash, 0, ErrBadBlock
+		}
+		varint() // amount
+		switch tag() {
+		case txoutToKey:
+			skip(32)
+		case txoutToTaggedKey:
+			skip(33)
+		default:
+			return hash, 0, ErrBadBlock
+		}
+	}
+	skip(varint()) // extra
+	if err != nil {
+		return hash, 0, err
+	}
+	prefix := off
+
+	switch version {
+	case 1:
+		copy(hash[:], keccak.Sum256(b[:off]))
+	case 2:
+		// the hashes of the prefix, of the RingCT base and of the prunable
+		// RingCT data, which is empty
+		if tag() != rctTypeNull {
+			return hash, 0, ErrBadBlock
+		}
+		var parts [96]byte
+		copy(parts[:], keccak.Sum256(b[:prefix]))
+		copy(parts[32:], keccak.Sum256(b[prefix:off]))
+		copy(hash[:], keccak.Sum256(parts[:]))
+	default:
+		return hash, 0, ErrBadBlock
+	}
+
+	return hash, off, nil
+}
+
+// Algorithm returns the algorithm Monero hashes block with, from its major
+// version. It returns the errors of cnutil.BlobVariant, including
+// cnutil.ErrUnsupportedVersion for the blocks hashed with RandomX.
+func Algorithm(block []byte) (cryptonight.Algorithm, error) {
+	v, err := cnutil.BlobVariant(block)
+	if err != nil {
+		return 0, err
+	}
+
+	return variantAlgorithms[v], nil
+}
+
+// variantAlgorithms are the algorithms of the variants cnutil.Variant returns.
+var variantAlgorithms = map[int]cryptonight.Algorithm{
+	0: cryptonight.CNv0,
+	1: cryptonight.CNv1,
+	2: cryptonight.CNv2,
+	4: cryptonight.CNR,
+}
+
+// VerifyBlock checks the proof of work of block, the blob of the block at
+// height, against difficulty. It returns cryptonight.ErrLowDifficulty if its
+// hash does not meet difficulty, and the errors of HashingBlob and Algorithm.
+func VerifyBlock(block []byte, height, difficulty uint64) error {
+	blob, err := HashingBlob(block)
+	if err != nil {
+		return err
+	}
+	algo, err := Algorithm(block)
+	if err != nil {
+		return err
+	}
+	if !cryptonight.CheckHash(cryptonight.SumAlgorithm(blob, algo, height), difficulty) {
+		return cryptonight.ErrLowDifficulty
+	}
+
+	return nil
+}
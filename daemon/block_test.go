@@ -0,0 +1,119 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"ekyu.moe/cryptonight"
+	"ekyu.moe/cryptonight/cnutil"
+	"ekyu.moe/cryptonight/keccak"
+)
+
+// genesisTx is the miner transaction of the genesis block of Monero.
+const genesisTx = "013c01ff0001ffffffffffff03029b2e4c0281c0b02e7c53291a94d1d0cbff8883f8024f5142ee494ffbbd08807121017767aafcde9be00dcfd098715ebcf7f410daebc582fda69d24a28e9d0bc890d1"
+
+// header returns a block header of majorVersion with nonce.
+func header(majorVersion byte, nonce uint32) []byte {
+	b := append([]byte{majorVersion, 0, 0}, make([]byte, 32+4)...)
+	binary.LittleEndian.PutUint32(b[35:], nonce)
+	return b
+}
+
+func TestHashingBlobGenesis(t *testing.T) {
+	tx, _ := hex.DecodeString(genesisTx)
+	block := append(append(header(1, 10000), tx...), 0)
+
+	blob, err := HashingBlob(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the id of a block is the hash of its hashing blob, prefixed by its size
+	id := keccak.Sum256(append([]byte{byte(len(blob))}, blob...))
+	if expected := "418015bb9ae982a1975da7d79277c2705727a56894ba0fb246adaabb1f4632e3"; hex.EncodeToString(id) != expected {
+		t.Errorf("expected the id %s, got %x", expected, id)
+	}
+}
+
+func TestHashingBlob(t *testing.T) {
+	// a miner transaction of version 2 with both kinds of outputs
+	prefix := []byte{2, 70, 1, 0xff, 0xa0, 0x01, 2}
+	prefix = append(append(prefix, 0x80, 0x01, txoutToKey), bytes.Repeat([]byte{1}, 32)...)
+	prefix = append(append(prefix, 0x81, 0x01, txoutToTaggedKey), bytes.Repeat([]byte{2}, 33)...)
+	prefix = append(append(prefix, 3), 0x02, 0x01, 0x00)
+	tx := append(prefix, rctTypeNull)
+
+	var parts [96]byte
+	copy(parts[:], keccak.Sum256(prefix))
+	copy(parts[32:], keccak.Sum256([]byte{rctTypeNull}))
+	var minerTx [32]byte
+	copy(minerTx[:], keccak.Sum256(parts[:]))
+
+	for _, n := range []int{0, 1, 2, 5} {
+		hashes := [][32]byte{minerTx}
+		block := append(header(10, 7), tx...)
+		block = append(block, byte(n))
+		for i := 0; i < n; i++ {
+			var h [32]byte
+			h[0] = byte(i + 1)
+			hashes = append(hashes, h)
+			block = append(block, h[:]...)
+		}
+
+		root := cnutil.TreeHash(hashes)
+		expected := append(append(header(10, 7), root[:]...), byte(n+1))
+		blob, err := HashingBlob(block)
+		if err != nil {
+			t.Errorf("[%d] %v", n, err)
+			continue
+		}
+		if !bytes.Equal(blob, expected) {
+			t.Errorf("[%d]\nexpected:\n\t%x\ngot:\n\t%x", n, expected, blob)
+		}
+		if nonce, _ := cnutil.Nonce(blob); nonce != 7 {
+			t.Errorf("[%d] expected the nonce 7, got %d", n, nonce)
+		}
+
+		// every truncation and any trailing byte are rejected
+		for i := len(header(10, 7)); i < len(block); i++ {
+			if _, err := HashingBlob(block[:i]); err != ErrBadBlock {
+				t.Errorf("[%d] expected ErrBadBlock for %d bytes, got %v", n, i, err)
+			}
+		}
+		if _, err := HashingBlob(append(block, 0)); err != ErrBadBlock {
+			t.Errorf("[%d] expected ErrBadBlock with a trailing byte, got %v", n, err)
+		}
+	}
+
+	bad := append(header(10, 7), tx...)
+	bad[len(header(10, 7))+3] = 0x02 // an input other than the coinbase
+	if _, err := HashingBlob(append(bad, 0)); err != ErrBadBlock {
+		t.Errorf("expected ErrBadBlock for a regular input, got %v", err)
+	}
+	if _, err := HashingBlob([]byte{10, 0}); err != cnutil.ErrShortBlob {
+		t.Errorf("expected cnutil.ErrShortBlob, got %v", err)
+	}
+}
+
+func TestVerifyBlock(t *testing.T) {
+	tx, _ := hex.DecodeString(genesisTx)
+	block := append(append(header(7, 0), tx...), 0)
+	blob, _ := HashingBlob(block)
+
+	if algo, err := Algorithm(block); algo != cryptonight.CNv1 || err != nil {
+		t.Errorf("expected CNv1, got %v, %v", algo, err)
+	}
+	diff := cryptonight.Difficulty(cryptonight.SumAlgorithm(blob, cryptonight.CNv1, 0))
+	if err := VerifyBlock(block, 0, diff); err != nil {
+		t.Errorf("expected the block to meet %d, got %v", diff, err)
+	}
+	if err := VerifyBlock(block, 0, diff+1); err != cryptonight.ErrLowDifficulty {
+		t.Errorf("expected ErrLowDifficulty, got %v", err)
+	}
+
+	block[0] = 12
+	if err := VerifyBlock(block, 0, 1); err != cnutil.ErrUnsupportedVersion {
+		t.Errorf("expected cnutil.ErrUnsupportedVersion, got %v", err)
+	}
+}
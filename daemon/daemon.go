@@ -0,0 +1,246 @@
+// Package daemon is a client of the JSON-RPC interface of the Monero daemon,
+// monerod, limited to what solo mining and verifying the proof of work of the
+// chain need, so that both can be done with ekyu.moe/cryptonight alone.
+//
+// To mine a block, get a BlockTemplate, search a nonce of the hashing blob of
+// its Blob, set the nonce in Blob and submit it:
+//     t, err := c.GetBlockTemplate(ctx, address, 0)
+//     blob, err := daemon.HashingBlob(t.Blob)
+//     algo, err := daemon.Algorithm(t.Blob)
+//     nonce, _, err := cc.MineHeight(ctx, blob, algo, t.Height, t.Difficulty, 0, 1)
+//     cnutil.SetNonce(t.Blob, nonce)
+//     err = c.SubmitBlock(ctx, t.Blob)
+//
+// Only the blocks hashed with CryptoNight can be mined and verified, that is
+// the major versions 1 to 11 of Monero and of the chains forked from it.
+package daemon // import "ekyu.moe/cryptonight/daemon"
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxResponse limits the size of a response of the daemon, which holds a
+// whole block at most.
+const maxResponse = 16 << 20
+
+var errBadResponse = errors.New("daemon: malformed response")
+
+// Error is an error reported by the daemon, e.g. for a block it rejects.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return "daemon: " + e.Message + " (" + strconv.Itoa(e.Code) + ")"
+}
+
+// Config contains optional parameters of a Client. A zero field means its
+// default value.
+type Config struct {
+	HTTPClient *http.Client  // default http.DefaultClient
+	Timeout    time.Duration // timeout of a call, default 30s
+}
+
+// Client calls the daemon at an address. A Client is safe for concurrent use.
+type Client struct {
+	conf Config
+	url  string
+}
+
+// NewClient returns a Client of the daemon at addr, its RPC address such as
+// "http://127.0.0.1:18081". conf may be nil.
+func NewClient(addr string, conf *Config) *Client {
+	c := &Client{url: strings.TrimSuffix(addr, "/") + "/json_rpc"}
+	if conf != nil {
+		c.conf = *conf
+	}
+	if c.conf.HTTPClient == nil {
+		c.conf.HTTPClient = http.DefaultClient
+	}
+	if c.conf.Timeout <= 0 {
+		c.conf.Timeout = 30 * time.Second
+	}
+
+	return c
+}
+
+// BlockTemplate is a block to mine, paying its reward to the address it was
+// requested for.
+type BlockTemplate struct {
+	Blob       []byte // block blob, whose nonce is to be found
+	Difficulty uint64
+	Height     uint64
+	PrevHash   []byte
+	Reward     uint64 // expected reward, in atomic units
+
+	// ReservedOffset is the offset in Blob of the bytes reserved in the extra
+	// of the miner transaction, which a pool can fill with an extra nonce per
+	// miner. The hashing blob changes with them.
+	ReservedOffset int
+}
+
+// GetBlockTemplate returns a block template paying to address, with
+// reserveSize bytes reserved for an extra nonce, at most 255.
+func (c *Client) GetBlockTemplate(ctx context.Context, address string, reserveSize int) (*BlockTemplate, error) {
+	var res struct {
+		Blob           string `json:"blocktemplate_blob"`
+		Difficulty     uint64 `json:"difficulty"`
+		DifficultyTop  uint64 `json:"difficulty_top64"`
+		Height         uint64 `json:"height"`
+		PrevHash       string `json:"prev_hash"`
+		Reward         uint64 `json:"expected_reward"`
+		ReservedOffset int    `json:"reserved_offset"`
+	}
+	err := c.call(ctx, "get_block_template", &struct {
+		Address     string `json:"wallet_address"`
+		ReserveSize int    `json:"reserve_size"`
+	}{address, reserveSize}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &BlockTemplate{
+		Difficulty:     res.Difficulty,
+		Height:         res.Height,
+		Reward:         res.Reward,
+		ReservedOffset: res.ReservedOffset,
+	}
+	var errBlob, errPrev error
+	t.Blob, errBlob = hex.DecodeString(res.Blob)
+	t.PrevHash, errPrev = hex.DecodeString(res.PrevHash)
+	if errBlob != nil || errPrev != nil || res.DifficultyTop != 0 || res.ReservedOffset < 0 || res.ReservedOffset+reserveSize > len(t.Blob) {
+		return nil, errBadResponse
+	}
+
+	return t, nil
+}
+
+// SubmitBlock submits block, a BlockTemplate blob with its nonce set. A block
+// the daemon rejects is reported as an *Error.
+func (c *Client) SubmitBlock(ctx context.Context, block []byte) error {
+	return c.call(ctx, "submit_block", []string{hex.EncodeToString(block)}, nil)
+}
+
+// BlockHeader is the header of a block of the chain, as the daemon reports it.
+type BlockHeader struct {
+	MajorVersion uint64 `json:"major_version"`
+	MinorVersion uint64 `json:"minor_version"`
+	Timestamp    uint64 `json:"timestamp"`
+	Nonce        uint32 `json:"nonce"`
+	Height       uint64 `json:"height"`
+	Hash         string `json:"hash"`
+	PrevHash     string `json:"prev_hash"`
+	Difficulty   uint64 `json:"difficulty"`
+	Reward       uint64 `json:"reward"`
+}
+
+// LastBlockHeader returns the header of the tip of the chain.
+func (c *Client) LastBlockHeader(ctx context.Context) (*BlockHeader, error) {
+	var res struct {
+		Header *BlockHeader `json:"block_header"`
+	}
+	if err := c.call(ctx, "get_last_block_header", nil, &res); err != nil {
+		return nil, err
+	}
+	if res.Header == nil {
+		return nil, errBadResponse
+	}
+
+	return res.Header, nil
+}
+
+// Block returns the blob and the header of the block at height.
+func (c *Client) Block(ctx context.Context, height uint64) ([]byte, *BlockHeader, error) {
+	var res struct {
+		Blob   string       `json:"blob"`
+		Header *BlockHeader `json:"block_header"`
+	}
+	err := c.call(ctx, "get_block", &struct {
+		Height uint64 `json:"height"`
+	}{height}, &res)
+	if err != nil {
+		return nil, nil, err
+	}
+	blob, err := hex.DecodeString(res.Blob)
+	if err != nil || res.Header == nil {
+		return nil, nil, errBadResponse
+	}
+
+	return blob, res.Header, nil
+}
+
+// VerifyBlock fetches the block at height and checks its proof of work
+// against the difficulty the daemon reports for it, with the function
+// VerifyBlock.
+func (c *Client) VerifyBlock(ctx context.Context, height uint64) error {
+	blob, h, err := c.Block(ctx, height)
+	if err != nil {
+		return err
+	}
+
+	return VerifyBlock(blob, height, h.Difficulty)
+}
+
+// request is a JSON-RPC 2.0 request to the daemon.
+type request struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      string      `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// call calls method with params, and decodes its result into res if not nil.
+func (c *Client) call(ctx context.Context, method string, params, res interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, c.conf.Timeout)
+	defer cancel()
+
+	b, _ := json.Marshal(&request{"2.0", "0", method, params})
+	req, err := http.NewRequest("POST", c.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.conf.HTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("daemon: " + resp.Status)
+	}
+
+	var m struct {
+		Result json.RawMessage `json:"result"`
+		Error  *Error          `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponse)).Decode(&m); err != nil {
+		return errors.New("daemon: malformed response: " + err.Error())
+	}
+	if m.Error != nil {
+		return m.Error
+	}
+	var status struct {
+		Status string `json:"status"`
+	}
+	if json.Unmarshal(m.Result, &status) != nil {
+		return errBadResponse
+	}
+	if status.Status != "OK" {
+		return errors.New("daemon: status " + status.Status)
+	}
+	if res != nil && json.Unmarshal(m.Result, res) != nil {
+		return errBadResponse
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package daemon
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ekyu.moe/cryptonight"
+	"ekyu.moe/cryptonight/cnutil"
+)
+
+// fakeDaemon answers the calls of a Client with a chain of a single block, the
+// template mined on top of it, and checks the proof of work of the blocks
+// submitted.
+type fakeDaemon struct {
+	t        *testing.T
+	template []byte
+	chain    []byte
+	status   string // of every response, "OK" if empty
+	accepted int
+}
+
+func (d *fakeDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Method  string          `json:"method"`
+		Params  json.RawMessage `json:"params"`
+	}
+	b, _ := ioutil.ReadAll(r.Body)
+	if r.URL.Path != "/json_rpc" || r.Method != "POST" || json.Unmarshal(b, &req) != nil || req.JSONRPC != "2.0" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	status := d.status
+	if status == "" {
+		status = "OK"
+	}
+	header := map[string]interface{}{"major_version": 7, "height": 0, "difficulty": 1, "nonce": 10000}
+	var res interface{}
+	switch req.Method {
+	case "get_block_template":
+		var p struct {
+			Address     string `json:"wallet_address"`
+			ReserveSize int    `json:"reserve_size"`
+		}
+		if json.Unmarshal(req.Params, &p) != nil || p.Address != "4address" || p.ReserveSize != 8 {
+			d.t.Errorf("unexpected params %s", req.Params)
+		}
+		res = map[string]interface{}{
+			"blocktemplate_blob": hex.EncodeToString(d.template),
+			"difficulty":         100,
+			"height":             1,
+			"prev_hash":          "00112233",
+			"expected_reward":    uint64(17592186044415),
+			"reserved_offset":    len(d.template) - 34,
+			"status":             status,
+		}
+	case "submit_block":
+		var p []string
+		if json.Unmarshal(req.Params, &p) != nil || len(p) != 1 {
+			d.t.Errorf("unexpected params %s", req.Params)
+		}
+		block, _ := hex.DecodeString(p[0])
+		if err := VerifyBlock(block, 1, 100); err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error": map[string]interface{}{"code": -7, "message": "Block not accepted"},
+			})
+			return
+		}
+		d.accepted++
+		res = map[string]string{"status": status}
+	case "get_last_block_header":
+		res = map[string]interface{}{"block_header": header, "status": status}
+	case "get_block":
+		res = map[string]interface{}{"blob": hex.EncodeToString(d.chain), "block_header": header, "status": status}
+	default:
+		d.t.Errorf("unexpected method %s", req.Method)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": "0", "result": res})
+}
+
+func TestClient(t *testing.T) {
+	tx, _ := hex.DecodeString(genesisTx)
+	d := &fakeDaemon{
+		t:        t,
+		template: append(append(header(7, 0), tx...), 0),
+		chain:    append(append(header(7, 0), tx...), 0),
+	}
+	srv := httptest.NewServer(d)
+	defer srv.Close()
+	c := NewClient(srv.URL+"/", nil)
+	ctx := context.Background()
+
+	// solo mining, as in the documentation of the package
+	tmpl, err := c.GetBlockTemplate(ctx, "4address", 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.Difficulty != 100 || tmpl.Height != 1 || tmpl.Reward != 17592186044415 || hex.EncodeToString(tmpl.PrevHash) != "00112233" {
+		t.Errorf("unexpected template %+v", tmpl)
+	}
+	blob, err := HashingBlob(tmpl.Blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	algo, err := Algorithm(tmpl.Blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := c.SubmitBlock(ctx, tmpl.Blob).(*Error); !ok || e.Code != -7 {
+		t.Errorf("expected the unmined block to be rejected, got %v", e)
+	}
+	nonce, _, err := new(cryptonight.Cache).MineHeight(ctx, blob, algo, tmpl.Height, tmpl.Difficulty, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cnutil.SetNonce(tmpl.Blob, nonce)
+	if err := c.SubmitBlock(ctx, tmpl.Blob); err != nil || d.accepted != 1 {
+		t.Errorf("expected the block to be accepted, got %v", err)
+	}
+
+	// verifying the tip
+	h, err := c.LastBlockHeader(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.MajorVersion != 7 || h.Nonce != 10000 || h.Difficulty != 1 {
+		t.Errorf("unexpected header %+v", h)
+	}
+	if err := c.VerifyBlock(ctx, h.Height); err != nil {
+		t.Error(err)
+	}
+
+	d.status = "BUSY"
+	if _, err := c.LastBlockHeader(ctx); err == nil || err.Error() != "daemon: status BUSY" {
+		t.Errorf("expected the status BUSY, got %v", err)
+	}
+	if _, err := NewClient(srv.URL+"/nowhere", nil).LastBlockHeader(ctx); err == nil {
+		t.Error("expected an error for a missing endpoint")
+	}
+}
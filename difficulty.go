@@ -16,7 +16,8 @@ var (
 	bigZero = big.NewInt(0)
 )
 
-// Difficulty returns hash's difficulty.
+// Difficulty returns hash's difficulty. A difficulty above 2^64-1 is truncated
+// to its low 64 bits, see DifficultyBig.
 //
 // If len(hash) != 32, the return value is always 0.
 //
@@ -30,18 +31,34 @@ func Difficulty(hash []byte) uint64 {
 		return 0
 	}
 
+	return DifficultyBig(hash).Uint64()
+}
+
+// DifficultyBig is like Difficulty, but is not truncated to 64 bits, for the
+// difficulties above 2^64-1 of Monero's 128-bit difficulties. It returns a new
+// big.Int, which is 0 if len(hash) != 32 or if hash is 0.
+func DifficultyBig(hash []byte) *big.Int {
+	hashBig := bigHash(hash)
+	if hashBig == nil || hashBig.Cmp(bigZero) == 0 {
+		return new(big.Int)
+	}
+
+	return hashBig.Div(bigMaxUint256, hashBig)
+}
+
+// bigHash returns hash as a little endian number, or nil if len(hash) != 32.
+func bigHash(hash []byte) *big.Int {
+	if len(hash) != 32 {
+		return nil
+	}
+
 	// swap byte order, since SetBytes accepts big instead of little endian
 	buf := make([]byte, 32)
 	for i := 0; i < 16; i++ {
 		buf[i], buf[31-i] = hash[31-i], hash[i]
 	}
 
-	hashBig := new(big.Int).SetBytes(buf)
-	if hashBig.Cmp(bigZero) == 0 {
-		return 0
-	}
-
-	return hashBig.Div(bigMaxUint256, hashBig).Uint64()
+	return new(big.Int).SetBytes(buf)
 }
 
 // CheckHash checks hash's difficulty against diff. It returns true if hash's
@@ -87,3 +104,24 @@ func CheckHash(hash []byte, diff uint64) bool {
 
 	return !carry
 }
+
+// CheckHashBig is like CheckHash, but accepts difficulties above 2^64-1. It
+// returns true if hash * diff <= 2^256-1, and false if len(hash) != 32 or diff
+// is negative.
+//
+// This function is a port of monero: src/cryptonote_basic/difficulty.cpp:check_hash_128
+func CheckHashBig(hash []byte, diff *big.Int) bool {
+	if diff.Sign() < 0 {
+		return false
+	}
+	if diff.IsUint64() {
+		return CheckHash(hash, diff.Uint64())
+	}
+
+	hashBig := bigHash(hash)
+	if hashBig == nil {
+		return false
+	}
+
+	return hashBig.Mul(hashBig, diff).Cmp(bigMaxUint256) <= 0
+}
@@ -2,6 +2,7 @@ package cryptonight
 
 import (
 	"encoding/hex"
+	"math/big"
 	"testing"
 )
 
@@ -63,6 +64,58 @@ func TestCheckHash(t *testing.T) {
 	}
 }
 
+func TestDifficultyBig(t *testing.T) {
+	for i, v := range diffSpecs {
+		in, _ := hex.DecodeString(v.input)
+		if diff := DifficultyBig(in); !diff.IsUint64() || diff.Uint64() != v.output {
+			t.Errorf("\n[%d] expected:\n\t%v\ngot:\n\t%v\n", i, v.output, diff)
+		}
+	}
+
+	// above 2^64: 0x01 << 160, whose difficulty is 2^96-1
+	in := make([]byte, 32)
+	in[20] = 1
+	want := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 96), big.NewInt(1))
+	if diff := DifficultyBig(in); diff.Cmp(want) != 0 {
+		t.Errorf("\nexpected:\n\t%v\ngot:\n\t%v\n", want, diff)
+	}
+
+	for _, diff := range []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1009),
+		new(big.Int).Sub(want, big.NewInt(1)),
+		want,
+	} {
+		if !CheckHashBig(in, diff) {
+			t.Errorf("%v: check hash goes wrong", diff)
+		}
+	}
+	for _, diff := range []*big.Int{
+		new(big.Int).Add(want, big.NewInt(1)),
+		new(big.Int).Lsh(want, 100),
+		big.NewInt(-1),
+	} {
+		if CheckHashBig(in, diff) {
+			t.Errorf("%v: check hash goes wrong", diff)
+		}
+	}
+
+	// 64-bit difficulties agree with CheckHash
+	for i, v := range diffSpecs[:2] {
+		in, _ := hex.DecodeString(v.input)
+		if !CheckHashBig(in, new(big.Int).SetUint64(v.output)) || CheckHashBig(in, new(big.Int).SetUint64(v.output+1)) {
+			t.Errorf("\n[%d] check hash goes wrong", i)
+		}
+	}
+
+	if CheckHashBig([]byte("Obviously less than 32 bytes"), want) {
+		t.Errorf("\nexpected:\n\tfalse\ngot:\n\ttrue\n")
+	}
+	if diff := DifficultyBig([]byte("Obviously less than 32 bytes")); diff.Sign() != 0 {
+		t.Errorf("\nexpected:\n\t%v\ngot:\n\t%v\n", 0, diff)
+	}
+}
+
 func BenchmarkDifficulty(b *testing.B) {
 	in, _ := hex.DecodeString("d3c693d2083888c03bc8dfbca4f32d9692e094722d8cbf4a90aa4c1400000000")
 	b.ResetTimer()
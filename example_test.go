@@ -3,6 +3,7 @@ package cryptonight
 import (
 	"encoding/hex"
 	"fmt"
+	"io"
 )
 
 func ExampleSum() {
@@ -20,6 +21,22 @@ func ExampleSum() {
 	// abb61f40468c70234051e4bb5e8b670812473b2a71e02c9633ef94996a621b96
 }
 
+func ExampleSumHeight() {
+	blob := []byte("Monero is cash for a connected world. It’s fast, private, and secure.")
+	fmt.Printf("%x\n", SumHeight(blob, 4, 1806260)) // variant 4 at block 1806260
+	// Output:
+	// 4137667d665938fca7bd638e5ea28123f65dd78f9a012d9feb44ca7ce0c8c281
+}
+
+func ExampleNew() {
+	h := New(CNv2)
+	io.WriteString(h, "Monero is cash for a connected world. ")
+	io.WriteString(h, "It’s fast, private, and secure.")
+	fmt.Printf("%x\n", h.Sum(nil))
+	// Output:
+	// abb61f40468c70234051e4bb5e8b670812473b2a71e02c9633ef94996a621b96
+}
+
 func ExampleCheckHash() {
 	hash, _ := hex.DecodeString("8e3c1865f22801dc3df0a688da80701e2390e7838e65c142604cc00eafe34000")
 	fmt.Println("Hash difficulty greater than 1000:", CheckHash(hash, 1000))
@@ -0,0 +1,31 @@
+// Build the library first, then
+//     cc -o example main.c -L. -lcryptonight
+#include <stdio.h>
+#include <string.h>
+
+#include "libcryptonight.h"
+
+int main(void) {
+	const char *blob = "This is a test";
+	uint8_t out[CN_DIGEST_SIZE];
+
+	if (cn_abi_version() != CN_ABI_VERSION) {
+		fprintf(stderr, "unexpected ABI version %d\n", cn_abi_version());
+		return 1;
+	}
+
+	cn_cache cache = cn_cache_new();
+	if (cn_cache_sum(cache, (void *)blob, strlen(blob), 0, out) != CN_OK) {
+		fprintf(stderr, "cn_cache_sum failed\n");
+		return 1;
+	}
+	cn_cache_free(cache);
+
+	// a084f01d1437a09c6985401b60d43554ae105802c5f5d8a9b3253649c0be6605
+	for (int i = 0; i < CN_DIGEST_SIZE; i++) {
+		printf("%02x", out[i]);
+	}
+	printf("\n");
+
+	return 0;
+}
@@ -0,0 +1,164 @@
+// Command export builds CryptoNight as a C shared library, so that C, C++,
+// Rust, Python (cffi, ctypes), Ruby FFI and others can use this
+// implementation.
+//
+// "go build -buildmode=c-shared -o libcryptonight.so ekyu.moe/cryptonight/export"
+// produces the library along with its header libcryptonight.h. Use .dll
+// instead of .so on Windows and .dylib on macOS. See example/main.c for usage.
+//
+// The exported functions only take and return integers and pointers to plain
+// memory owned by the caller, so that bindings can be written without a C
+// compiler. A cache is referred to by an opaque cn_cache handle, which is
+// never 0 and never reused once freed.
+//
+// CN_ABI_VERSION is bumped on any incompatible change of the functions below,
+// and cn_abi_version returns the version the library was built with, so that
+// bindings can check it at load time. On Linux, the library can be linked with
+// cryptonight.map to tag the symbols with the version, e.g.
+// -ldflags=-extldflags=-Wl,--version-script=export/cryptonight.map
+//
+// All functions returning int return 0 on success and a negative value on
+// error, see the CN_ERR_* constants.
+//
+// All functions may be called from any thread. cn_sum can be called
+// concurrently without limit. A cache can only be used by one thread at a
+// time; cn_cache_sum and cn_cache_free return CN_ERR_BUSY rather than block
+// when the cache is in use by another thread.
+package main // import "ekyu.moe/cryptonight/export"
+
+/*
+#include <stddef.h>
+#include <stdint.h>
+
+#define CN_ABI_VERSION 1
+#define CN_DIGEST_SIZE 32
+
+enum {
+	CN_OK = 0,
+	CN_ERR_INVALID_ARGUMENT = -1,
+	CN_ERR_INVALID_HANDLE = -2,
+	CN_ERR_BUSY = -3,
+};
+
+typedef uintptr_t cn_cache;
+*/
+import "C"
+
+import (
+	"sync"
+	"unsafe"
+
+	"ekyu.moe/cryptonight"
+)
+
+// entry is a cache handed out to C.
+type entry struct {
+	cc   *cryptonight.Cache
+	busy bool
+}
+
+// Go pointers can't be kept by C, so caches are handed out as handles.
+var (
+	cachesMu   sync.Mutex
+	caches     = make(map[C.cn_cache]*entry)
+	lastHandle C.cn_cache
+)
+
+func main() {}
+
+// sum validates the arguments, then hashes with cc, or with the internal pool
+// if cc is nil.
+func sum(cc *cryptonight.Cache, data unsafe.Pointer, length C.size_t, variant C.int, out *C.uint8_t) C.int {
+	if (data == nil && length != 0) || out == nil {
+		return C.CN_ERR_INVALID_ARGUMENT
+	}
+
+	in := C.GoBytes(data, C.int(length))
+	if cryptonight.Validate(in, int(variant)) != nil {
+		return C.CN_ERR_INVALID_ARGUMENT
+	}
+	var digest []byte
+	if cc != nil {
+		digest = cc.Sum(in, int(variant))
+	} else {
+		digest = cryptonight.Sum(in, int(variant))
+	}
+	copy((*[C.CN_DIGEST_SIZE]byte)(unsafe.Pointer(out))[:], digest)
+
+	return C.CN_OK
+}
+
+// cn_abi_version returns CN_ABI_VERSION of the library.
+//
+//export cn_abi_version
+func cn_abi_version() C.int {
+	return C.CN_ABI_VERSION
+}
+
+// cn_sum hashes length bytes at data and writes the CN_DIGEST_SIZE bytes
+// digest to out. variant must be 0, 1 or 2, and length must be at least 43
+// for variant 1.
+//
+//export cn_sum
+func cn_sum(data unsafe.Pointer, length C.size_t, variant C.int, out *C.uint8_t) C.int {
+	return sum(nil, data, length, variant, out)
+}
+
+// cn_cache_new allocates a cache for repeated hashing and returns its handle,
+// which must be released by cn_cache_free.
+//
+//export cn_cache_new
+func cn_cache_new() C.cn_cache {
+	cachesMu.Lock()
+	defer cachesMu.Unlock()
+
+	lastHandle++
+	caches[lastHandle] = &entry{cc: new(cryptonight.Cache)}
+
+	return lastHandle
+}
+
+// cn_cache_sum is the same as cn_sum, using the cache of handle h.
+//
+//export cn_cache_sum
+func cn_cache_sum(h C.cn_cache, data unsafe.Pointer, length C.size_t, variant C.int, out *C.uint8_t) C.int {
+	cachesMu.Lock()
+	e := caches[h]
+	if e == nil {
+		cachesMu.Unlock()
+		return C.CN_ERR_INVALID_HANDLE
+	}
+	if e.busy {
+		cachesMu.Unlock()
+		return C.CN_ERR_BUSY
+	}
+	e.busy = true
+	cachesMu.Unlock()
+
+	ret := sum(e.cc, data, length, variant, out)
+
+	cachesMu.Lock()
+	e.busy = false
+	cachesMu.Unlock()
+
+	return ret
+}
+
+// cn_cache_free releases the cache of handle h.
+//
+//export cn_cache_free
+func cn_cache_free(h C.cn_cache) C.int {
+	cachesMu.Lock()
+	defer cachesMu.Unlock()
+
+	e := caches[h]
+	if e == nil {
+		return C.CN_ERR_INVALID_HANDLE
+	}
+	if e.busy {
+		return C.CN_ERR_BUSY
+	}
+	delete(caches, h)
+
+	return C.CN_OK
+}
@@ -0,0 +1,20 @@
+{
+  "targets": [
+    {
+      "target_name": "cryptonight",
+      "sources": ["cryptonight.c"],
+      "include_dirs": ["lib"],
+      "libraries": ["-L<(module_root_dir)/lib", "-lcryptonight"],
+      "conditions": [
+        ["OS=='linux'", {
+          "ldflags": ["-Wl,-rpath,'$$ORIGIN/../../lib'"]
+        }],
+        ["OS=='mac'", {
+          "xcode_settings": {
+            "OTHER_LDFLAGS": ["-Wl,-rpath,@loader_path/../../lib"]
+          }
+        }]
+      ]
+    }
+  ]
+}
@@ -0,0 +1,13 @@
+'use strict'
+
+// Builds libcryptonight into lib/ with the Go toolchain, before node-gyp
+// links the addon against it.
+const { execFileSync } = require('child_process')
+const path = require('path')
+
+const ext = { darwin: 'dylib', win32: 'dll' }[process.platform] || 'so'
+execFileSync('go', [
+  'build', '-buildmode=c-shared',
+  '-o', path.join(__dirname, 'lib', 'libcryptonight.' + ext),
+  'ekyu.moe/cryptonight/export'
+], { cwd: __dirname, stdio: 'inherit' })
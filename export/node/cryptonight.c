@@ -0,0 +1,167 @@
+// Node-API addon over libcryptonight, see README.adoc in this directory.
+//
+// Exports:
+//     sum(data: Buffer, variant: number): Buffer
+//     sumAsync(data: Buffer, variant: number): Promise<Buffer>
+//
+// sumAsync hashes on the libuv thread pool, so it doesn't block the event
+// loop; it is what a pool should use to verify shares.
+#define NAPI_VERSION 4
+#include <node_api.h>
+#include <stdlib.h>
+#include <string.h>
+
+#include "libcryptonight.h"
+
+#define CHECK(env, call)                                                      \
+	do {                                                                  \
+		if ((call) != napi_ok) {                                      \
+			napi_throw_error((env), NULL, "cryptonight: " #call); \
+			return NULL;                                          \
+		}                                                             \
+	} while (0)
+
+// error_message returns the message for ret returned by libcryptonight.
+static const char *error_message(int ret) {
+	switch (ret) {
+	case CN_ERR_INVALID_ARGUMENT:
+		return "cryptonight: invalid argument";
+	case CN_ERR_INVALID_HANDLE:
+		return "cryptonight: invalid handle";
+	case CN_ERR_BUSY:
+		return "cryptonight: busy";
+	default:
+		return "cryptonight: unknown error";
+	}
+}
+
+// parse_args reads (data: Buffer, variant: number) from info.
+static napi_status parse_args(napi_env env, napi_callback_info info, void **data, size_t *length, int32_t *variant) {
+	size_t argc = 2;
+	napi_value argv[2];
+	bool is_buffer;
+	napi_status status;
+
+	if ((status = napi_get_cb_info(env, info, &argc, argv, NULL, NULL)) != napi_ok) {
+		return status;
+	}
+	if (argc != 2) {
+		return napi_invalid_arg;
+	}
+	if ((status = napi_is_buffer(env, argv[0], &is_buffer)) != napi_ok) {
+		return status;
+	}
+	if (!is_buffer) {
+		return napi_invalid_arg;
+	}
+	if ((status = napi_get_buffer_info(env, argv[0], data, length)) != napi_ok) {
+		return status;
+	}
+
+	return napi_get_value_int32(env, argv[1], variant);
+}
+
+static napi_value sum(napi_env env, napi_callback_info info) {
+	void *data, *out;
+	size_t length;
+	int32_t variant;
+	napi_value result;
+
+	if (parse_args(env, info, &data, &length, &variant) != napi_ok) {
+		napi_throw_type_error(env, NULL, "cryptonight: expected (Buffer, number)");
+		return NULL;
+	}
+	CHECK(env, napi_create_buffer(env, CN_DIGEST_SIZE, &out, &result));
+
+	int ret = cn_sum(data, length, variant, out);
+	if (ret != CN_OK) {
+		napi_throw_error(env, NULL, error_message(ret));
+		return NULL;
+	}
+
+	return result;
+}
+
+// job is the state of one sumAsync call. The input is copied, since the
+// buffer may be modified by JavaScript while hashing.
+typedef struct {
+	napi_async_work work;
+	napi_deferred deferred;
+	void *data;
+	size_t length;
+	int32_t variant;
+	uint8_t out[CN_DIGEST_SIZE];
+	int ret;
+} job;
+
+static void execute(napi_env env, void *arg) {
+	job *j = arg;
+	j->ret = cn_sum(j->data, j->length, j->variant, j->out);
+}
+
+static void complete(napi_env env, napi_status status, void *arg) {
+	job *j = arg;
+	napi_value value;
+
+	if (status == napi_ok && j->ret == CN_OK) {
+		void *out;
+		napi_create_buffer_copy(env, CN_DIGEST_SIZE, j->out, &out, &value);
+		napi_resolve_deferred(env, j->deferred, value);
+	} else {
+		napi_value msg;
+		const char *s = status == napi_ok ? error_message(j->ret) : "cryptonight: cancelled";
+		napi_create_string_utf8(env, s, NAPI_AUTO_LENGTH, &msg);
+		napi_create_error(env, NULL, msg, &value);
+		napi_reject_deferred(env, j->deferred, value);
+	}
+
+	napi_delete_async_work(env, j->work);
+	free(j->data);
+	free(j);
+}
+
+static napi_value sum_async(napi_env env, napi_callback_info info) {
+	void *data;
+	size_t length;
+	int32_t variant;
+	napi_value promise, name;
+
+	if (parse_args(env, info, &data, &length, &variant) != napi_ok) {
+		napi_throw_type_error(env, NULL, "cryptonight: expected (Buffer, number)");
+		return NULL;
+	}
+
+	job *j = calloc(1, sizeof(job));
+	if (j == NULL || (j->data = malloc(length ? length : 1)) == NULL) {
+		free(j);
+		napi_throw_error(env, NULL, "cryptonight: out of memory");
+		return NULL;
+	}
+	memcpy(j->data, data, length);
+	j->length = length;
+	j->variant = variant;
+
+	CHECK(env, napi_create_promise(env, &j->deferred, &promise));
+	CHECK(env, napi_create_string_utf8(env, "cryptonight.sumAsync", NAPI_AUTO_LENGTH, &name));
+	CHECK(env, napi_create_async_work(env, NULL, name, execute, complete, j, &j->work));
+	CHECK(env, napi_queue_async_work(env, j->work));
+
+	return promise;
+}
+
+static napi_value init(napi_env env, napi_value exports) {
+	napi_property_descriptor props[] = {
+		{"sum", NULL, sum, NULL, NULL, NULL, napi_enumerable, NULL},
+		{"sumAsync", NULL, sum_async, NULL, NULL, NULL, napi_enumerable, NULL},
+	};
+
+	if (cn_abi_version() != CN_ABI_VERSION) {
+		napi_throw_error(env, NULL, "cryptonight: libcryptonight ABI version mismatch");
+		return NULL;
+	}
+	CHECK(env, napi_define_properties(env, exports, sizeof(props) / sizeof(props[0]), props));
+
+	return exports;
+}
+
+NAPI_MODULE(NODE_GYP_MODULE_NAME, init)
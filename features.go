@@ -0,0 +1,61 @@
+package cryptonight
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"ekyu.moe/cryptonight/internal/aes"
+)
+
+// FeatureSet describes the configuration this package runs with, so that bug
+// reports and dashboards can tell which code actually computed the hashes.
+type FeatureSet struct {
+	Version   string   `json:"version"`    // module version, empty if unknown
+	Backend   string   `json:"backend"`    // implementation Sum dispatches to, "go" or "amd64-aes"
+	Backends  []string `json:"backends"`   // implementations compiled in, plus "cref" with the cnref tag
+	AES       string   `json:"aes"`        // implementation of the AES rounds outside of the backend, "go", "aes-ni" or "arm64-aes"
+	AESTables string   `json:"aes_tables"` // origin of the tables of the Go AES rounds, "embedded" or "generated" with the aesgen tag
+	CPU       []string `json:"cpu"`        // detected CPU features of interest to the backends
+	HugePages string   `json:"huge_pages"` // mode of transparent huge pages on Linux, empty elsewhere
+}
+
+// modulePath is the import path of this module.
+const modulePath = "ekyu.moe/cryptonight"
+
+// backends lists the implementations compiled in, the first one being the
+// fallback.
+var backends = []string{"go"}
+
+// Features reports the configuration of this package. The scratchpad is
+// allocated by the Go runtime, so it can only be backed by huge pages when
+// transparent huge pages are enabled, i.e. HugePages is "always", unless it
+// comes from NewCacheHugePages.
+func Features() *FeatureSet {
+	return &FeatureSet{
+		Version:   moduleVersion(),
+		Backend:   backend(),
+		Backends:  append([]string(nil), backends...),
+		AES:       aes.Backend(),
+		AESTables: aes.Tables(),
+		CPU:       cpuFeatures(),
+		HugePages: transparentHugePages(),
+	}
+}
+
+// transparentHugePages returns the selected mode in
+// /sys/kernel/mm/transparent_hugepage/enabled, which looks like
+// "always [madvise] never".
+func transparentHugePages() string {
+	b, err := ioutil.ReadFile("/sys/kernel/mm/transparent_hugepage/enabled")
+	if err != nil {
+		return ""
+	}
+
+	s := string(b)
+	i, j := strings.IndexByte(s, '['), strings.IndexByte(s, ']')
+	if i < 0 || j < i {
+		return strings.TrimSpace(s)
+	}
+
+	return s[i+1 : j]
+}
@@ -0,0 +1,15 @@
+// +build !purego,!tinygo
+
+package cryptonight
+
+import "ekyu.moe/cryptonight/internal/aes"
+
+// cpuFeatures returns asimd, which arm64 always has, and aes if the crypto
+// extension runs the AES rounds, with their names in /proc/cpuinfo.
+func cpuFeatures() []string {
+	if aes.Backend() == "arm64-aes" {
+		return []string{"asimd", "aes"}
+	}
+
+	return []string{"asimd"}
+}
@@ -0,0 +1,5 @@
+// +build !amd64,!arm64 purego tinygo
+
+package cryptonight
+
+func cpuFeatures() []string { return nil }
@@ -0,0 +1,43 @@
+package cryptonight
+
+import (
+	"testing"
+)
+
+func TestFeatures(t *testing.T) {
+	f := Features()
+	if len(f.Backends) == 0 || f.Backends[0] != "go" {
+		t.Fatalf("expected the go backend first, got %v", f.Backends)
+	}
+
+	found := false
+	for _, b := range f.Backends {
+		found = found || b == f.Backend
+	}
+	if !found {
+		t.Errorf("backend %q is not one of %v", f.Backend, f.Backends)
+	}
+
+	switch f.AES {
+	case "go", "aes-ni", "arm64-aes":
+	default:
+		t.Errorf("unknown implementation of the AES rounds %q", f.AES)
+	}
+	if f.Backend == "amd64-aes" && f.AES != "aes-ni" {
+		t.Errorf("AES rounds in %s with the %s backend", f.AES, f.Backend)
+	}
+	if f.AESTables != "embedded" && f.AESTables != "generated" {
+		t.Errorf("unknown origin of the AES tables %q", f.AESTables)
+	}
+	for _, c := range f.CPU {
+		if c == "aes" && f.AES == "go" {
+			t.Error("AES rounds in Go despite the aes CPU feature")
+		}
+	}
+
+	// callers must not be able to alter backends
+	f.Backends[0] = ""
+	if Features().Backends[0] != "go" {
+		t.Error("Backends shares its memory with the package")
+	}
+}
@@ -1,13 +1,12 @@
 package cryptonight
 
 import (
+	"encoding/binary"
 	"hash"
+	"strconv"
 	"sync"
-	"unsafe"
-
-	"github.com/aead/skein"
-	"github.com/dchest/blake256"
 
+	"ekyu.moe/cryptonight/blake256"
 	"ekyu.moe/cryptonight/groestl"
 	"ekyu.moe/cryptonight/jh"
 )
@@ -16,16 +15,74 @@ var hashPool = [...]*sync.Pool{
 	{New: func() interface{} { return blake256.New() }},
 	{New: func() interface{} { return groestl.New256() }},
 	{New: func() interface{} { return jh.New256() }},
-	{New: func() interface{} { return skein.New256(nil) }},
+	{New: func() interface{} { return newSkein256() }},
+}
+
+// finalHashNames are the names of the functions of hashPool, for Intermediates.
+var finalHashNames = [...]string{"blake256", "groestl", "jh", "skein"}
+
+// SetFinalHash makes cc use the hash returned by newHash as the final hash
+// picked by selector, the 2 lowest bits of the final Keccak state, in place of
+// BLAKE-256, Grøstl-256, JH-256 or Skein-512-256 for 0 to 3. A nil newHash
+// restores the default. It lets forks which swap one of the finalists use this
+// package with a Cache of their own; CNGPU, Chukwa and ChukwaV2, which have no
+// final hash, are not affected.
+//
+// SetFinalHash calls newHash once, and panics if selector is not between 0 and
+// 3 or if the hash does not have a 32 bytes digest. It must not be called while
+// cc is hashing. The results of cc are not checked against the reference
+// implementation of the cnref tag as long as one of its final hashes is set.
+func (cc *Cache) SetFinalHash(selector int, newHash func() hash.Hash) {
+	if selector < 0 || selector > 3 {
+		panic("cryptonight: final hash selector out of range")
+	}
+	if newHash == nil {
+		if cc.final != nil {
+			cc.final[selector] = nil
+			if *cc.final == [4]hash.Hash{} {
+				cc.final = nil
+			}
+		}
+		return
+	}
+
+	h := newHash()
+	if h.Size() != 32 {
+		panic("cryptonight: final hash with a digest of " + strconv.Itoa(h.Size()) + " bytes")
+	}
+	if cc.final == nil {
+		cc.final = new([4]hash.Hash)
+	}
+	cc.final[selector] = h
 }
 
-func (cc *cache) finalHash() []byte {
-	hp := hashPool[cc.finalState[0]&0x03]
+// finalHash hashes the final state with one of the 4 hash functions it picks,
+// into cc.digest. The returned slice is only valid until the next hash with cc.
+func (cc *Cache) finalHash() []byte {
+	for i, v := range cc.finalState {
+		binary.LittleEndian.PutUint64(cc.finalBytes[8*i:], v)
+	}
+
+	selector := cc.finalState[0] & 0x03
+	if cc.final != nil && cc.final[selector] != nil {
+		h := cc.final[selector]
+		h.Reset()
+		h.Write(cc.finalBytes[:])
+		return h.Sum(cc.digest[:0])
+	}
+
+	hp := hashPool[selector]
 	h := hp.Get().(hash.Hash)
 	h.Reset()
-	h.Write((*[200]byte)(unsafe.Pointer(&cc.finalState))[:])
-	sum := h.Sum(nil)
+	h.Write(cc.finalBytes[:])
+	sum := h.Sum(cc.digest[:0])
 	hp.Put(h)
 
 	return sum
 }
+
+// crossChecked reports whether the results of cc are to be checked against the
+// reference implementation, see SetFinalHash.
+func (cc *Cache) crossChecked() bool {
+	return crossCheck != nil && cc.final == nil
+}
@@ -0,0 +1,58 @@
+package cryptonight
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"testing"
+)
+
+func TestSetFinalHash(t *testing.T) {
+	cc := new(Cache)
+	for selector := 0; selector < 4; selector++ {
+		cc.SetFinalHash(selector, sha256.New)
+		for i, data := range benchData {
+			in := cc.SumIntermediates(data, CNv2, 0)
+			if int(in.Final[0]&0x03) != selector {
+				if expected := SumAlgorithm(data, CNv2, 0); !bytes.Equal(in.Digest[:], expected) {
+					t.Errorf("[%d %d] expected %x with the default %s, got %x", selector, i, expected, in.FinalHash, in.Digest)
+				}
+				continue
+			}
+			if in.FinalHash != "custom" {
+				t.Errorf("[%d %d] FinalHash: expected custom, got %q", selector, i, in.FinalHash)
+			}
+			if expected := sha256.Sum256(in.Final[:]); in.Digest != expected {
+				t.Errorf("[%d %d] expected %x, got %x", selector, i, expected, in.Digest)
+			}
+		}
+		cc.SetFinalHash(selector, nil)
+	}
+	if cc.final != nil {
+		t.Error("expected the defaults to be restored")
+	}
+	for i, data := range benchData {
+		if expected, got := SumAlgorithm(data, CNv2, 0), cc.Sum(data, 2); !bytes.Equal(got, expected) {
+			t.Errorf("[%d] expected %x after the restore, got %x", i, expected, got)
+		}
+	}
+
+	for _, tc := range []struct {
+		selector int
+		newHash  func() hash.Hash
+	}{
+		{-1, sha256.New},
+		{4, sha256.New},
+		{0, sha512.New},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("[%d] expected a panic", tc.selector)
+				}
+			}()
+			cc.SetFinalHash(tc.selector, tc.newHash)
+		}()
+	}
+}
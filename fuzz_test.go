@@ -0,0 +1,117 @@
+// +build go1.18
+
+package cryptonight
+
+import (
+	"bytes"
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+// FuzzSum checks the default implementation against the pure Go one. With the
+// cnref tag, Sum is also checked against monero's cn_slow_hash by crossCheck.
+//
+// go test -fuzz 'FuzzSum$' -tags cnref
+func FuzzSum(f *testing.F) {
+	for _, v := range boundarySpecs {
+		f.Add(make([]byte, v.size), uint8(v.variant), uint64(0))
+	}
+	for i := range benchData {
+		f.Add(benchData[i], uint8(i%3), uint64(0))
+		f.Add(benchData[i], uint8(4), uint64(1806260+i))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte, variant uint8, height uint64) {
+		v := int(variant % (maxVariant + 1))
+		if err := Validate(data, v); err != nil && err != ErrHeightRequired {
+			defer func() {
+				if r := recover(); r != err {
+					t.Fatalf("expected to panic with %v, got %v.", err, r)
+				}
+			}()
+		}
+
+		sum := SumHeight(data, v, height)
+		if expected := new(Cache).sumGo(data, standard(v), height); !bytes.Equal(sum, expected) {
+			t.Errorf("\nvariant %d at height %d on %x\nexpected:\n\t%x\ngot:\n\t%x\n", v, height, data, expected, sum)
+		}
+	})
+}
+
+// FuzzSumAlgorithm checks every algorithm against the pure Go implementation
+// with arbitrary lengths and heights. An input ValidateAlgorithm rejects, such
+// as one shorter than 43 bytes for variant 1, must panic with its error rather
+// than index out of range, and so must no address of the scratchpad.
+//
+// go test -fuzz FuzzSumAlgorithm
+func FuzzSumAlgorithm(f *testing.F) {
+	for a := range algorithms {
+		for _, size := range []int{0, 1, 42, 43, 64, 76, 200} {
+			f.Add(make([]byte, size), uint8(a), uint64(0))
+		}
+		f.Add(benchData[a%len(benchData)], uint8(a), uint64(1806260+a))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte, algo uint8, height uint64) {
+		a := Algorithm(algo % uint8(len(algorithms)))
+		if err := ValidateAlgorithm(data, a); err != nil {
+			defer func() {
+				if r := recover(); r != err {
+					t.Fatalf("%s: expected to panic with %v, got %v.", a, err, r)
+				}
+			}()
+		}
+
+		sum := SumAlgorithm(data, a, height)
+		if expected := new(Cache).sumGo(data, algorithms[a].p, height); !bytes.Equal(sum, expected) {
+			t.Errorf("\n%s at height %d on %x\nexpected:\n\t%x\ngot:\n\t%x\n", a, height, data, expected, sum)
+		}
+	})
+}
+
+// FuzzSumAliased hashes under GC stress, with a Cache outside of the Go heap
+// when huge pages are available, into a digest that overlaps the input. The
+// assembly and the mapped caches are only correct if neither the collector
+// nor the aliasing can change what they read.
+//
+// go test -fuzz FuzzSumAliased
+func FuzzSumAliased(f *testing.F) {
+	for i := range benchData {
+		f.Add(benchData[i], uint8(i), uint64(0))
+	}
+	f.Add(make([]byte, 32), uint8(CNR), uint64(1806260))
+
+	cc := NewCacheHugePages()
+	defer cc.Close()
+	defer debug.SetGCPercent(debug.SetGCPercent(1))
+
+	f.Fuzz(func(t *testing.T, data []byte, algo uint8, height uint64) {
+		a := Algorithm(algo % uint8(len(algorithms)))
+		if ValidateAlgorithm(data, a) != nil {
+			return
+		}
+		expected := new(Cache).sumGo(data, algorithms[a].p, height)
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					runtime.GC()
+				}
+			}
+		}()
+
+		buf := make([]byte, len(data)+32)
+		copy(buf, data)
+		dst := (*[32]byte)(buf)
+		cc.SumInto(dst, buf[:len(data)], a, height)
+		if !bytes.Equal(dst[:], expected) {
+			t.Errorf("\n%s at height %d on %x\nexpected:\n\t%x\ngot:\n\t%x\n", a, height, data, expected, dst[:])
+		}
+	})
+}
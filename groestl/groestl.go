@@ -1,7 +1,7 @@
-// HEAD_PLACEHOLDER
-// +build ignore
-
-// Package groestl implements Grøstl-256 algorithm.
+// Package groestl implements the Grøstl-256 hash algorithm, as submitted to
+// the NIST SHA-3 competition, in its final round version. It is one of the
+// final hashes of CryptoNight, and can be used on its own through New256, a
+// hash.Hash accepting input in any number of writes.
 //
 // This Go implementation is a port of the original C implementation which is
 // included in Monero as follows:
@@ -15,70 +15,15 @@
 package groestl // import "ekyu.moe/cryptonight/groestl"
 
 import (
+	"encoding/binary"
 	"hash"
-	"unsafe"
 )
 
-// This field is for macro definitions.
-// We define it in a literal string so that it can trick gofmt(1).
-//
-// It should be empty after they are expanded by cpp(1).
-const _ = `
-#undef build
-#undef ignore
-
-#define U8_U32(a, begin, end) \
-    ( (*[( (end) - (begin) ) / 4]uint32)(unsafe.Pointer(&a[ (begin) ])) )
-
-#define U32_U8(a, begin, end) \
-    ( (*[( (end) - (begin) ) * 4]uint8)(unsafe.Pointer(&a[ (begin) ])) )
-
-#define COLUMN(x, y, i, c0, c1, c2, c3, c4, c5, c6, c7, tv1, tv2, tu, tl, t) \
-	tu = tab[2*uint32(x[4*c0+0])];				\
-	tl = tab[2*uint32(x[4*c0+0])+1];			\
-	tv1 = tab[2*uint32(x[4*c1+1])];				\
-	tv2 = tab[2*uint32(x[4*c1+1])+1];			\
-	ROTATE_COLUMN_DOWN(tv1, tv2, 1, t);			\
-	tu ^= tv1;									\
-	tl ^= tv2;									\
-	tv1 = tab[2*uint32(x[4*c2+2])];				\
-	tv2 = tab[2*uint32(x[4*c2+2])+1];			\
-	ROTATE_COLUMN_DOWN(tv1, tv2, 2, t);			\
-	tu ^= tv1;									\
-	tl ^= tv2;									\
-	tv1 = tab[2*uint32(x[4*c3+3])];				\
-	tv2 = tab[2*uint32(x[4*c3+3])+1];			\
-	ROTATE_COLUMN_DOWN(tv1, tv2, 3, t);			\
-	tu ^= tv1;									\
-	tl ^= tv2;									\
-	tl ^= tab[2*uint32(x[4*c4+0])];				\
-	tu ^= tab[2*uint32(x[4*c4+0])+1];			\
-	tv1 = tab[2*uint32(x[4*c5+1])];				\
-	tv2 = tab[2*uint32(x[4*c5+1])+1];			\
-	ROTATE_COLUMN_DOWN(tv1, tv2, 1, t);			\
-	tl ^= tv1;									\
-	tu ^= tv2;									\
-	tv1 = tab[2*uint32(x[4*c6+2])];				\
-	tv2 = tab[2*uint32(x[4*c6+2])+1];			\
-	ROTATE_COLUMN_DOWN(tv1, tv2, 2, t);			\
-	tl ^= tv1;									\
-	tu ^= tv2;									\
-	tv1 = tab[2*uint32(x[4*c7+3])];				\
-	tv2 = tab[2*uint32(x[4*c7+3])+1];			\
-	ROTATE_COLUMN_DOWN(tv1, tv2, 3, t);			\
-	tl ^= tv1;									\
-	tu ^= tv2;									\
-	y[i] = tu;									\
-	y[i+1] = tl;
-
-#define ROTATE_COLUMN_DOWN(v1, v2, amountBytes, tempVar) \
-	tempVar = (v1 << (8 * amountBytes)) | (v2 >> (8 * (4 - amountBytes)));	\
-	v2 = (v2 << (8 * amountBytes)) | (v1 >> (8 * (4 - amountBytes)));		\
-	v1 = tempVar;
-`
-
-// To trick goimports(1)
-var _ = unsafe.Pointer(nil)
+// Size is the size of a Grøstl-256 digest in bytes.
+const Size = hashByteLen
+
+// BlockSize is the block size of Grøstl-256 in bytes.
+const BlockSize = size512
 
 const (
 	rows           = 8
@@ -105,6 +50,7 @@ type state struct {
 	bufPtr int           // data buffer pointer
 }
 
+// Sum256 returns the Grøstl-256 digest of b.
 func Sum256(b []byte) []byte {
 	h := New256()
 	h.Write(b)
@@ -112,6 +58,7 @@ func Sum256(b []byte) []byte {
 	return h.Sum(nil)
 }
 
+// New256 returns a new hash.Hash computing Grøstl-256.
 func New256() hash.Hash {
 	s := &state{}
 	s.chaining[2*cols512-1] = 65536
@@ -119,6 +66,7 @@ func New256() hash.Hash {
 	return s
 }
 
+// Reset resets the state to the one of New256.
 func (s *state) Reset() {
 	s.chaining = zeroBuf64Word
 	s.chaining[2*cols512-1] = 65536
@@ -131,7 +79,7 @@ func (s *state) Reset() {
 func (s *state) Size() int      { return hashByteLen }
 func (s *state) BlockSize() int { return size512 }
 
-// Write updates state with databitlen bits of input
+// Write updates state with data. It never returns an error.
 func (s *state) Write(data []byte) (n int, err error) {
 	n = len(data)
 	index := 0
@@ -164,9 +112,16 @@ func (s *state) Write(data []byte) (n int, err error) {
 	return
 }
 
-// Sum process remaining data (including padding), perform
-// output transformation.
+// Sum appends the digest of the data written so far to b. It does not change
+// the underlying hash state.
 func (s *state) Sum(b []byte) []byte {
+	d := *s
+	return d.sum(b)
+}
+
+// sum process remaining data (including padding), perform
+// output transformation.
+func (s *state) sum(b []byte) []byte {
 	s.buffer[s.bufPtr] = 0x80
 	s.bufPtr++
 
@@ -202,10 +157,15 @@ func (s *state) Sum(b []byte) []byte {
 	// digest final padding block
 	s.transform(s.buffer[:size512])
 	// perform output transformation
-	s.outputTransformation()
+	output(&s.chaining)
 
 	// store hash result
-	return append(b, U32_U8(s.chaining, hashByteLen/4, size512/4)[:]...)
+	var out [hashByteLen]byte
+	for i := 0; i < hashByteLen/4; i++ {
+		binary.LittleEndian.PutUint32(out[4*i:], s.chaining[size512/4-hashByteLen/4+i])
+	}
+
+	return append(b, out[:]...)
 }
 
 // digest up to msglen bytes of input (full blocks only)
@@ -217,7 +177,7 @@ func (s *state) transform(b []byte) {
 	for n >= size512 {
 		input := b[offset:]
 		// length of input is known and constant
-		f512(&s.chaining, U8_U32(input, 0, size512))
+		compress(&s.chaining, input[:size512])
 
 		// increment block counter
 		s.blockCounter1++
@@ -231,116 +191,160 @@ func (s *state) transform(b []byte) {
 }
 
 // given state h, do h <- P(h)+h
-func (s *state) outputTransformation() {
+func outputTransformation(h *[2 * cols512]uint32) {
 	var j int
-	var temp, y, z [2 * cols512]uint32
+	var temp, y, z [size512]byte
 
 	for j = 0; j < 2*cols512; j++ {
-		temp[j] = s.chaining[j]
+		put32(&temp, j, h[j])
 	}
-	rnd512p(U32_U8(temp, 0, 2*cols512), &y, 0x00000000)
-	rnd512p(U32_U8(y, 0, 2*cols512), &z, 0x00000001)
-	rnd512p(U32_U8(z, 0, 2*cols512), &y, 0x00000002)
-	rnd512p(U32_U8(y, 0, 2*cols512), &z, 0x00000003)
-	rnd512p(U32_U8(z, 0, 2*cols512), &y, 0x00000004)
-	rnd512p(U32_U8(y, 0, 2*cols512), &z, 0x00000005)
-	rnd512p(U32_U8(z, 0, 2*cols512), &y, 0x00000006)
-	rnd512p(U32_U8(y, 0, 2*cols512), &z, 0x00000007)
-	rnd512p(U32_U8(z, 0, 2*cols512), &y, 0x00000008)
-	rnd512p(U32_U8(y, 0, 2*cols512), &temp, 0x00000009)
+	rnd512p(&temp, &y, 0x00000000)
+	rnd512p(&y, &z, 0x00000001)
+	rnd512p(&z, &y, 0x00000002)
+	rnd512p(&y, &z, 0x00000003)
+	rnd512p(&z, &y, 0x00000004)
+	rnd512p(&y, &z, 0x00000005)
+	rnd512p(&z, &y, 0x00000006)
+	rnd512p(&y, &z, 0x00000007)
+	rnd512p(&z, &y, 0x00000008)
+	rnd512p(&y, &temp, 0x00000009)
 	for j = 0; j < 2*cols512; j++ {
-		s.chaining[j] ^= temp[j]
+		h[j] ^= get32(&temp, j)
 	}
 }
 
 // compute compression function (short variants)
-func f512(h *[16]uint32, m *[size512 / 4]uint32) {
+func f512(h *[2 * cols512]uint32, m []byte) {
 	var i int
-	var Ptmp, Qtmp, y, z [2 * cols512]uint32
+	var Ptmp, Qtmp, y, z [size512]byte
 
+	copy(z[:], m)
 	for i = 0; i < 2*cols512; i++ {
-		z[i] = m[i]
-		Ptmp[i] = h[i] ^ m[i]
+		put32(&Ptmp, i, h[i]^get32(&z, i))
 	}
 
 	// compute Q(m)
-	rnd512q(U32_U8(z, 0, 2*cols512), &y, 0x00000000)
-	rnd512q(U32_U8(y, 0, 2*cols512), &z, 0x01000000)
-	rnd512q(U32_U8(z, 0, 2*cols512), &y, 0x02000000)
-	rnd512q(U32_U8(y, 0, 2*cols512), &z, 0x03000000)
-	rnd512q(U32_U8(z, 0, 2*cols512), &y, 0x04000000)
-	rnd512q(U32_U8(y, 0, 2*cols512), &z, 0x05000000)
-	rnd512q(U32_U8(z, 0, 2*cols512), &y, 0x06000000)
-	rnd512q(U32_U8(y, 0, 2*cols512), &z, 0x07000000)
-	rnd512q(U32_U8(z, 0, 2*cols512), &y, 0x08000000)
-	rnd512q(U32_U8(y, 0, 2*cols512), &Qtmp, 0x09000000)
+	rnd512q(&z, &y, 0x00000000)
+	rnd512q(&y, &z, 0x01000000)
+	rnd512q(&z, &y, 0x02000000)
+	rnd512q(&y, &z, 0x03000000)
+	rnd512q(&z, &y, 0x04000000)
+	rnd512q(&y, &z, 0x05000000)
+	rnd512q(&z, &y, 0x06000000)
+	rnd512q(&y, &z, 0x07000000)
+	rnd512q(&z, &y, 0x08000000)
+	rnd512q(&y, &Qtmp, 0x09000000)
 
 	// compute P(h+m)
-	rnd512p(U32_U8(Ptmp, 0, 2*cols512), &y, 0x00000000)
-	rnd512p(U32_U8(y, 0, 2*cols512), &z, 0x00000001)
-	rnd512p(U32_U8(z, 0, 2*cols512), &y, 0x00000002)
-	rnd512p(U32_U8(y, 0, 2*cols512), &z, 0x00000003)
-	rnd512p(U32_U8(z, 0, 2*cols512), &y, 0x00000004)
-	rnd512p(U32_U8(y, 0, 2*cols512), &z, 0x00000005)
-	rnd512p(U32_U8(z, 0, 2*cols512), &y, 0x00000006)
-	rnd512p(U32_U8(y, 0, 2*cols512), &z, 0x00000007)
-	rnd512p(U32_U8(z, 0, 2*cols512), &y, 0x00000008)
-	rnd512p(U32_U8(y, 0, 2*cols512), &Ptmp, 0x00000009)
+	rnd512p(&Ptmp, &y, 0x00000000)
+	rnd512p(&y, &z, 0x00000001)
+	rnd512p(&z, &y, 0x00000002)
+	rnd512p(&y, &z, 0x00000003)
+	rnd512p(&z, &y, 0x00000004)
+	rnd512p(&y, &z, 0x00000005)
+	rnd512p(&z, &y, 0x00000006)
+	rnd512p(&y, &z, 0x00000007)
+	rnd512p(&z, &y, 0x00000008)
+	rnd512p(&y, &Ptmp, 0x00000009)
 
 	// compute P(h+m) + Q(m) + h
 	for i = 0; i < 2*cols512; i++ {
-		h[i] ^= Ptmp[i] ^ Qtmp[i]
+		h[i] ^= get32(&Ptmp, i) ^ get32(&Qtmp, i)
 	}
 }
 
 // compute one round of Q (short variants)
-func rnd512q(x *[64]byte, y *[16]uint32, r uint32) {
-	var temp1, temp2, tempUpperValue, tempLowerValue, temp uint32
-	x32 := U8_U32(x, 0, 64)
-	x32[0] = ^x32[0]
-	x32[1] ^= 0xffffffff ^ r
-	x32[2] = ^x32[2]
-	x32[3] ^= 0xefffffff ^ r
-	x32[4] = ^x32[4]
-	x32[5] ^= 0xdfffffff ^ r
-	x32[6] = ^x32[6]
-	x32[7] ^= 0xcfffffff ^ r
-	x32[8] = ^x32[8]
-	x32[9] ^= 0xbfffffff ^ r
-	x32[10] = ^x32[10]
-	x32[11] ^= 0xafffffff ^ r
-	x32[12] = ^x32[12]
-	x32[13] ^= 0x9fffffff ^ r
-	x32[14] = ^x32[14]
-	x32[15] ^= 0x8fffffff ^ r
-	COLUMN(x, y, 0, 2, 6, 10, 14, 1, 5, 9, 13, temp1, temp2, tempUpperValue, tempLowerValue, temp)
-	COLUMN(x, y, 2, 4, 8, 12, 0, 3, 7, 11, 15, temp1, temp2, tempUpperValue, tempLowerValue, temp)
-	COLUMN(x, y, 4, 6, 10, 14, 2, 5, 9, 13, 1, temp1, temp2, tempUpperValue, tempLowerValue, temp)
-	COLUMN(x, y, 6, 8, 12, 0, 4, 7, 11, 15, 3, temp1, temp2, tempUpperValue, tempLowerValue, temp)
-	COLUMN(x, y, 8, 10, 14, 2, 6, 9, 13, 1, 5, temp1, temp2, tempUpperValue, tempLowerValue, temp)
-	COLUMN(x, y, 10, 12, 0, 4, 8, 11, 15, 3, 7, temp1, temp2, tempUpperValue, tempLowerValue, temp)
-	COLUMN(x, y, 12, 14, 2, 6, 10, 13, 1, 5, 9, temp1, temp2, tempUpperValue, tempLowerValue, temp)
-	COLUMN(x, y, 14, 0, 4, 8, 12, 15, 3, 7, 11, temp1, temp2, tempUpperValue, tempLowerValue, temp)
+func rnd512q(x, y *[size512]byte, r uint32) {
+	xor32(x, 0, 0xffffffff)
+	xor32(x, 1, 0xffffffff^r)
+	xor32(x, 2, 0xffffffff)
+	xor32(x, 3, 0xefffffff^r)
+	xor32(x, 4, 0xffffffff)
+	xor32(x, 5, 0xdfffffff^r)
+	xor32(x, 6, 0xffffffff)
+	xor32(x, 7, 0xcfffffff^r)
+	xor32(x, 8, 0xffffffff)
+	xor32(x, 9, 0xbfffffff^r)
+	xor32(x, 10, 0xffffffff)
+	xor32(x, 11, 0xafffffff^r)
+	xor32(x, 12, 0xffffffff)
+	xor32(x, 13, 0x9fffffff^r)
+	xor32(x, 14, 0xffffffff)
+	xor32(x, 15, 0x8fffffff^r)
+	column(x, y, 0, 2, 6, 10, 14, 1, 5, 9, 13)
+	column(x, y, 2, 4, 8, 12, 0, 3, 7, 11, 15)
+	column(x, y, 4, 6, 10, 14, 2, 5, 9, 13, 1)
+	column(x, y, 6, 8, 12, 0, 4, 7, 11, 15, 3)
+	column(x, y, 8, 10, 14, 2, 6, 9, 13, 1, 5)
+	column(x, y, 10, 12, 0, 4, 8, 11, 15, 3, 7)
+	column(x, y, 12, 14, 2, 6, 10, 13, 1, 5, 9)
+	column(x, y, 14, 0, 4, 8, 12, 15, 3, 7, 11)
 }
 
 // compute one round of P (short variants)
-func rnd512p(x *[64]byte, y *[16]uint32, r uint32) {
-	var temp1, temp2, tempUpperValue, tempLowerValue, temp uint32
-	x32 := U8_U32(x, 0, 64)
-	x32[0] ^= 0x00000000 ^ r
-	x32[2] ^= 0x00000010 ^ r
-	x32[4] ^= 0x00000020 ^ r
-	x32[6] ^= 0x00000030 ^ r
-	x32[8] ^= 0x00000040 ^ r
-	x32[10] ^= 0x00000050 ^ r
-	x32[12] ^= 0x00000060 ^ r
-	x32[14] ^= 0x00000070 ^ r
-	COLUMN(x, y, 0, 0, 2, 4, 6, 9, 11, 13, 15, temp1, temp2, tempUpperValue, tempLowerValue, temp)
-	COLUMN(x, y, 2, 2, 4, 6, 8, 11, 13, 15, 1, temp1, temp2, tempUpperValue, tempLowerValue, temp)
-	COLUMN(x, y, 4, 4, 6, 8, 10, 13, 15, 1, 3, temp1, temp2, tempUpperValue, tempLowerValue, temp)
-	COLUMN(x, y, 6, 6, 8, 10, 12, 15, 1, 3, 5, temp1, temp2, tempUpperValue, tempLowerValue, temp)
-	COLUMN(x, y, 8, 8, 10, 12, 14, 1, 3, 5, 7, temp1, temp2, tempUpperValue, tempLowerValue, temp)
-	COLUMN(x, y, 10, 10, 12, 14, 0, 3, 5, 7, 9, temp1, temp2, tempUpperValue, tempLowerValue, temp)
-	COLUMN(x, y, 12, 12, 14, 0, 2, 5, 7, 9, 11, temp1, temp2, tempUpperValue, tempLowerValue, temp)
-	COLUMN(x, y, 14, 14, 0, 2, 4, 7, 9, 11, 13, temp1, temp2, tempUpperValue, tempLowerValue, temp)
+func rnd512p(x, y *[size512]byte, r uint32) {
+	xor32(x, 0, 0x00000000^r)
+	xor32(x, 2, 0x00000010^r)
+	xor32(x, 4, 0x00000020^r)
+	xor32(x, 6, 0x00000030^r)
+	xor32(x, 8, 0x00000040^r)
+	xor32(x, 10, 0x00000050^r)
+	xor32(x, 12, 0x00000060^r)
+	xor32(x, 14, 0x00000070^r)
+	column(x, y, 0, 0, 2, 4, 6, 9, 11, 13, 15)
+	column(x, y, 2, 2, 4, 6, 8, 11, 13, 15, 1)
+	column(x, y, 4, 4, 6, 8, 10, 13, 15, 1, 3)
+	column(x, y, 6, 6, 8, 10, 12, 15, 1, 3, 5)
+	column(x, y, 8, 8, 10, 12, 14, 1, 3, 5, 7)
+	column(x, y, 10, 10, 12, 14, 0, 3, 5, 7, 9)
+	column(x, y, 12, 12, 14, 0, 2, 5, 7, 9, 11)
+	column(x, y, 14, 14, 0, 2, 4, 7, 9, 11, 13)
+}
+
+func get32(a *[size512]byte, i int) uint32 {
+	return binary.LittleEndian.Uint32(a[4*i:])
+}
+
+func put32(a *[size512]byte, i int, v uint32) {
+	binary.LittleEndian.PutUint32(a[4*i:], v)
+}
+
+func xor32(a *[size512]byte, i int, v uint32) {
+	put32(a, i, get32(a, i)^v)
+}
+
+// column computes the column i and i+1 of y from the bytes of x at the
+// columns c0 to c7.
+func column(x, y *[size512]byte, i, c0, c1, c2, c3, c4, c5, c6, c7 int) {
+	var tv1, tv2 uint32
+
+	tu := tab[2*uint32(x[4*c0+0])]
+	tl := tab[2*uint32(x[4*c0+0])+1]
+	tv1, tv2 = rotateColumnDown(tab[2*uint32(x[4*c1+1])], tab[2*uint32(x[4*c1+1])+1], 1)
+	tu ^= tv1
+	tl ^= tv2
+	tv1, tv2 = rotateColumnDown(tab[2*uint32(x[4*c2+2])], tab[2*uint32(x[4*c2+2])+1], 2)
+	tu ^= tv1
+	tl ^= tv2
+	tv1, tv2 = rotateColumnDown(tab[2*uint32(x[4*c3+3])], tab[2*uint32(x[4*c3+3])+1], 3)
+	tu ^= tv1
+	tl ^= tv2
+	tl ^= tab[2*uint32(x[4*c4+0])]
+	tu ^= tab[2*uint32(x[4*c4+0])+1]
+	tv1, tv2 = rotateColumnDown(tab[2*uint32(x[4*c5+1])], tab[2*uint32(x[4*c5+1])+1], 1)
+	tl ^= tv1
+	tu ^= tv2
+	tv1, tv2 = rotateColumnDown(tab[2*uint32(x[4*c6+2])], tab[2*uint32(x[4*c6+2])+1], 2)
+	tl ^= tv1
+	tu ^= tv2
+	tv1, tv2 = rotateColumnDown(tab[2*uint32(x[4*c7+3])], tab[2*uint32(x[4*c7+3])+1], 3)
+	tl ^= tv1
+	tu ^= tv2
+	put32(y, i, tu)
+	put32(y, i+1, tl)
+}
+
+func rotateColumnDown(v1, v2 uint32, amountBytes uint) (uint32, uint32) {
+	return v1<<(8*amountBytes) | v2>>(8*(4-amountBytes)),
+		v2<<(8*amountBytes) | v1>>(8*(4-amountBytes))
 }
@@ -0,0 +1,51 @@
+// +build amd64,!purego,!tinygo
+
+package groestl
+
+import "golang.org/x/sys/cpu"
+
+var hasAVX2 = cpu.X86.HasAVX2
+
+// pq512AVX2 runs P on p and Q on q side by side, their states being given row
+// by row rather than column by column.
+//
+//go:noescape
+func pq512AVX2(p, q *[size512]byte)
+
+// compress and output run the permutations with AVX2 where the CPU has it,
+// rather than with the tables.
+func compress(h *[2 * cols512]uint32, m []byte) {
+	if !hasAVX2 {
+		f512(h, m)
+		return
+	}
+
+	var p, q [size512]byte
+	for i := 0; i < size512; i++ {
+		j := i%rows*cols512 + i/rows
+		q[j] = m[i]
+		p[j] = byte(h[i/4]>>(8*uint(i%4))) ^ m[i]
+	}
+	pq512AVX2(&p, &q)
+	for i := 0; i < size512; i++ {
+		j := i%rows*cols512 + i/rows
+		h[i/4] ^= uint32(p[j]^q[j]) << (8 * uint(i%4))
+	}
+}
+
+func output(h *[2 * cols512]uint32) {
+	if !hasAVX2 {
+		outputTransformation(h)
+		return
+	}
+
+	// Q runs on zeros alongside P, and is dropped.
+	var p, q [size512]byte
+	for i := 0; i < size512; i++ {
+		p[i%rows*cols512+i/rows] = byte(h[i/4] >> (8 * uint(i%4)))
+	}
+	pq512AVX2(&p, &q)
+	for i := 0; i < size512; i++ {
+		h[i/4] ^= uint32(p[i%rows*cols512+i/rows]) << (8 * uint(i%4))
+	}
+}
@@ -0,0 +1,376 @@
+// +build amd64,!purego,!tinygo
+
+#include "textflag.h"
+
+// The states of P and Q are kept row by row, each row being a quadword: rows 0
+// to 3 of P are in Y0 and rows 4 to 7 in Y1, and those of Q in Y2 and Y3.
+// ShiftBytes is then a rotation of every quadword, and MixBytes a sum of
+// rotations of the rows. Y15 is zero, Y13 is 0x10 and Y14 is 0x70 in every
+// byte.
+
+// sbox holds the S-box in 16 parts of 16 bytes, each twice so as to fill a
+// 256-bit register for VPSHUFB: part h maps the bytes of high nibble h.
+DATA sbox<>+0x000(SB)/8, $0xc56f6bf27b777c63
+DATA sbox<>+0x008(SB)/8, $0x76abd7fe2b670130
+DATA sbox<>+0x010(SB)/8, $0xc56f6bf27b777c63
+DATA sbox<>+0x018(SB)/8, $0x76abd7fe2b670130
+DATA sbox<>+0x020(SB)/8, $0xf04759fa7dc982ca
+DATA sbox<>+0x028(SB)/8, $0xc072a49cafa2d4ad
+DATA sbox<>+0x030(SB)/8, $0xf04759fa7dc982ca
+DATA sbox<>+0x038(SB)/8, $0xc072a49cafa2d4ad
+DATA sbox<>+0x040(SB)/8, $0xccf73f362693fdb7
+DATA sbox<>+0x048(SB)/8, $0x1531d871f1e5a534
+DATA sbox<>+0x050(SB)/8, $0xccf73f362693fdb7
+DATA sbox<>+0x058(SB)/8, $0x1531d871f1e5a534
+DATA sbox<>+0x060(SB)/8, $0x9a059618c323c704
+DATA sbox<>+0x068(SB)/8, $0x75b227ebe2801207
+DATA sbox<>+0x070(SB)/8, $0x9a059618c323c704
+DATA sbox<>+0x078(SB)/8, $0x75b227ebe2801207
+DATA sbox<>+0x080(SB)/8, $0xa05a6e1b1a2c8309
+DATA sbox<>+0x088(SB)/8, $0x842fe329b3d63b52
+DATA sbox<>+0x090(SB)/8, $0xa05a6e1b1a2c8309
+DATA sbox<>+0x098(SB)/8, $0x842fe329b3d63b52
+DATA sbox<>+0x0a0(SB)/8, $0x5bb1fc20ed00d153
+DATA sbox<>+0x0a8(SB)/8, $0xcf584c4a39becb6a
+DATA sbox<>+0x0b0(SB)/8, $0x5bb1fc20ed00d153
+DATA sbox<>+0x0b8(SB)/8, $0xcf584c4a39becb6a
+DATA sbox<>+0x0c0(SB)/8, $0x85334d43fbaaefd0
+DATA sbox<>+0x0c8(SB)/8, $0xa89f3c507f02f945
+DATA sbox<>+0x0d0(SB)/8, $0x85334d43fbaaefd0
+DATA sbox<>+0x0d8(SB)/8, $0xa89f3c507f02f945
+DATA sbox<>+0x0e0(SB)/8, $0xf5389d928f40a351
+DATA sbox<>+0x0e8(SB)/8, $0xd2f3ff1021dab6bc
+DATA sbox<>+0x0f0(SB)/8, $0xf5389d928f40a351
+DATA sbox<>+0x0f8(SB)/8, $0xd2f3ff1021dab6bc
+DATA sbox<>+0x100(SB)/8, $0x1744975fec130ccd
+DATA sbox<>+0x108(SB)/8, $0x73195d643d7ea7c4
+DATA sbox<>+0x110(SB)/8, $0x1744975fec130ccd
+DATA sbox<>+0x118(SB)/8, $0x73195d643d7ea7c4
+DATA sbox<>+0x120(SB)/8, $0x88902a22dc4f8160
+DATA sbox<>+0x128(SB)/8, $0xdb0b5ede14b8ee46
+DATA sbox<>+0x130(SB)/8, $0x88902a22dc4f8160
+DATA sbox<>+0x138(SB)/8, $0xdb0b5ede14b8ee46
+DATA sbox<>+0x140(SB)/8, $0x5c2406490a3a32e0
+DATA sbox<>+0x148(SB)/8, $0x79e4959162acd3c2
+DATA sbox<>+0x150(SB)/8, $0x5c2406490a3a32e0
+DATA sbox<>+0x158(SB)/8, $0x79e4959162acd3c2
+DATA sbox<>+0x160(SB)/8, $0xa94ed58d6d37c8e7
+DATA sbox<>+0x168(SB)/8, $0x08ae7a65eaf4566c
+DATA sbox<>+0x170(SB)/8, $0xa94ed58d6d37c8e7
+DATA sbox<>+0x178(SB)/8, $0x08ae7a65eaf4566c
+DATA sbox<>+0x180(SB)/8, $0xc6b4a61c2e2578ba
+DATA sbox<>+0x188(SB)/8, $0x8a8bbd4b1f74dde8
+DATA sbox<>+0x190(SB)/8, $0xc6b4a61c2e2578ba
+DATA sbox<>+0x198(SB)/8, $0x8a8bbd4b1f74dde8
+DATA sbox<>+0x1a0(SB)/8, $0x0ef6034866b53e70
+DATA sbox<>+0x1a8(SB)/8, $0x9e1dc186b9573561
+DATA sbox<>+0x1b0(SB)/8, $0x0ef6034866b53e70
+DATA sbox<>+0x1b8(SB)/8, $0x9e1dc186b9573561
+DATA sbox<>+0x1c0(SB)/8, $0x948ed9691198f8e1
+DATA sbox<>+0x1c8(SB)/8, $0xdf2855cee9871e9b
+DATA sbox<>+0x1d0(SB)/8, $0x948ed9691198f8e1
+DATA sbox<>+0x1d8(SB)/8, $0xdf2855cee9871e9b
+DATA sbox<>+0x1e0(SB)/8, $0x6842e6bf0d89a18c
+DATA sbox<>+0x1e8(SB)/8, $0x16bb54b00f2d9941
+DATA sbox<>+0x1f0(SB)/8, $0x6842e6bf0d89a18c
+DATA sbox<>+0x1f8(SB)/8, $0x16bb54b00f2d9941
+GLOBL sbox<>(SB), RODATA, $0x200
+
+// roundP and roundQ are the round constants of P and Q, for rows 0 to 3 of P
+// and 4 to 7 of Q, one round every 32 bytes. Rows 0 to 3 of Q take ones.
+DATA roundP<>+0x000(SB)/8, $0x7060504030201000
+DATA roundP<>+0x008(SB)/8, $0x0000000000000000
+DATA roundP<>+0x010(SB)/8, $0x0000000000000000
+DATA roundP<>+0x018(SB)/8, $0x0000000000000000
+DATA roundP<>+0x020(SB)/8, $0x7161514131211101
+DATA roundP<>+0x028(SB)/8, $0x0000000000000000
+DATA roundP<>+0x030(SB)/8, $0x0000000000000000
+DATA roundP<>+0x038(SB)/8, $0x0000000000000000
+DATA roundP<>+0x040(SB)/8, $0x7262524232221202
+DATA roundP<>+0x048(SB)/8, $0x0000000000000000
+DATA roundP<>+0x050(SB)/8, $0x0000000000000000
+DATA roundP<>+0x058(SB)/8, $0x0000000000000000
+DATA roundP<>+0x060(SB)/8, $0x7363534333231303
+DATA roundP<>+0x068(SB)/8, $0x0000000000000000
+DATA roundP<>+0x070(SB)/8, $0x0000000000000000
+DATA roundP<>+0x078(SB)/8, $0x0000000000000000
+DATA roundP<>+0x080(SB)/8, $0x7464544434241404
+DATA roundP<>+0x088(SB)/8, $0x0000000000000000
+DATA roundP<>+0x090(SB)/8, $0x0000000000000000
+DATA roundP<>+0x098(SB)/8, $0x0000000000000000
+DATA roundP<>+0x0a0(SB)/8, $0x7565554535251505
+DATA roundP<>+0x0a8(SB)/8, $0x0000000000000000
+DATA roundP<>+0x0b0(SB)/8, $0x0000000000000000
+DATA roundP<>+0x0b8(SB)/8, $0x0000000000000000
+DATA roundP<>+0x0c0(SB)/8, $0x7666564636261606
+DATA roundP<>+0x0c8(SB)/8, $0x0000000000000000
+DATA roundP<>+0x0d0(SB)/8, $0x0000000000000000
+DATA roundP<>+0x0d8(SB)/8, $0x0000000000000000
+DATA roundP<>+0x0e0(SB)/8, $0x7767574737271707
+DATA roundP<>+0x0e8(SB)/8, $0x0000000000000000
+DATA roundP<>+0x0f0(SB)/8, $0x0000000000000000
+DATA roundP<>+0x0f8(SB)/8, $0x0000000000000000
+DATA roundP<>+0x100(SB)/8, $0x7868584838281808
+DATA roundP<>+0x108(SB)/8, $0x0000000000000000
+DATA roundP<>+0x110(SB)/8, $0x0000000000000000
+DATA roundP<>+0x118(SB)/8, $0x0000000000000000
+DATA roundP<>+0x120(SB)/8, $0x7969594939291909
+DATA roundP<>+0x128(SB)/8, $0x0000000000000000
+DATA roundP<>+0x130(SB)/8, $0x0000000000000000
+DATA roundP<>+0x138(SB)/8, $0x0000000000000000
+GLOBL roundP<>(SB), RODATA, $0x140
+
+DATA roundQ<>+0x000(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x008(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x010(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x018(SB)/8, $0x8f9fafbfcfdfefff
+DATA roundQ<>+0x020(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x028(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x030(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x038(SB)/8, $0x8e9eaebecedeeefe
+DATA roundQ<>+0x040(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x048(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x050(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x058(SB)/8, $0x8d9dadbdcdddedfd
+DATA roundQ<>+0x060(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x068(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x070(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x078(SB)/8, $0x8c9cacbcccdcecfc
+DATA roundQ<>+0x080(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x088(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x090(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x098(SB)/8, $0x8b9babbbcbdbebfb
+DATA roundQ<>+0x0a0(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x0a8(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x0b0(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x0b8(SB)/8, $0x8a9aaabacadaeafa
+DATA roundQ<>+0x0c0(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x0c8(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x0d0(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x0d8(SB)/8, $0x8999a9b9c9d9e9f9
+DATA roundQ<>+0x0e0(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x0e8(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x0f0(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x0f8(SB)/8, $0x8898a8b8c8d8e8f8
+DATA roundQ<>+0x100(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x108(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x110(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x118(SB)/8, $0x8797a7b7c7d7e7f7
+DATA roundQ<>+0x120(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x128(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x130(SB)/8, $0xffffffffffffffff
+DATA roundQ<>+0x138(SB)/8, $0x8696a6b6c6d6e6f6
+GLOBL roundQ<>(SB), RODATA, $0x140
+
+DATA ones<>+0x000(SB)/8, $0xffffffffffffffff
+DATA ones<>+0x008(SB)/8, $0xffffffffffffffff
+DATA ones<>+0x010(SB)/8, $0xffffffffffffffff
+DATA ones<>+0x018(SB)/8, $0xffffffffffffffff
+GLOBL ones<>(SB), RODATA, $0x020
+
+// shiftP and shiftQ are the right then left shifts rotating the rows by their
+// ShiftBytes offsets.
+DATA shiftP<>+0x000(SB)/8, $0x0000000000000000
+DATA shiftP<>+0x008(SB)/8, $0x0000000000000008
+DATA shiftP<>+0x010(SB)/8, $0x0000000000000010
+DATA shiftP<>+0x018(SB)/8, $0x0000000000000018
+DATA shiftP<>+0x020(SB)/8, $0x0000000000000020
+DATA shiftP<>+0x028(SB)/8, $0x0000000000000028
+DATA shiftP<>+0x030(SB)/8, $0x0000000000000030
+DATA shiftP<>+0x038(SB)/8, $0x0000000000000038
+DATA shiftP<>+0x040(SB)/8, $0x0000000000000040
+DATA shiftP<>+0x048(SB)/8, $0x0000000000000038
+DATA shiftP<>+0x050(SB)/8, $0x0000000000000030
+DATA shiftP<>+0x058(SB)/8, $0x0000000000000028
+DATA shiftP<>+0x060(SB)/8, $0x0000000000000020
+DATA shiftP<>+0x068(SB)/8, $0x0000000000000018
+DATA shiftP<>+0x070(SB)/8, $0x0000000000000010
+DATA shiftP<>+0x078(SB)/8, $0x0000000000000008
+GLOBL shiftP<>(SB), RODATA, $0x080
+
+DATA shiftQ<>+0x000(SB)/8, $0x0000000000000008
+DATA shiftQ<>+0x008(SB)/8, $0x0000000000000018
+DATA shiftQ<>+0x010(SB)/8, $0x0000000000000028
+DATA shiftQ<>+0x018(SB)/8, $0x0000000000000038
+DATA shiftQ<>+0x020(SB)/8, $0x0000000000000000
+DATA shiftQ<>+0x028(SB)/8, $0x0000000000000010
+DATA shiftQ<>+0x030(SB)/8, $0x0000000000000020
+DATA shiftQ<>+0x038(SB)/8, $0x0000000000000030
+DATA shiftQ<>+0x040(SB)/8, $0x0000000000000038
+DATA shiftQ<>+0x048(SB)/8, $0x0000000000000028
+DATA shiftQ<>+0x050(SB)/8, $0x0000000000000018
+DATA shiftQ<>+0x058(SB)/8, $0x0000000000000008
+DATA shiftQ<>+0x060(SB)/8, $0x0000000000000040
+DATA shiftQ<>+0x068(SB)/8, $0x0000000000000030
+DATA shiftQ<>+0x070(SB)/8, $0x0000000000000020
+DATA shiftQ<>+0x078(SB)/8, $0x0000000000000010
+GLOBL shiftQ<>(SB), RODATA, $0x080
+
+DATA c10<>+0x000(SB)/8, $0x1010101010101010
+DATA c10<>+0x008(SB)/8, $0x1010101010101010
+DATA c10<>+0x010(SB)/8, $0x1010101010101010
+DATA c10<>+0x018(SB)/8, $0x1010101010101010
+GLOBL c10<>(SB), RODATA, $0x020
+
+DATA c70<>+0x000(SB)/8, $0x7070707070707070
+DATA c70<>+0x008(SB)/8, $0x7070707070707070
+DATA c70<>+0x010(SB)/8, $0x7070707070707070
+DATA c70<>+0x018(SB)/8, $0x7070707070707070
+GLOBL c70<>(SB), RODATA, $0x020
+
+DATA c1b<>+0x000(SB)/8, $0x1b1b1b1b1b1b1b1b
+DATA c1b<>+0x008(SB)/8, $0x1b1b1b1b1b1b1b1b
+DATA c1b<>+0x010(SB)/8, $0x1b1b1b1b1b1b1b1b
+DATA c1b<>+0x018(SB)/8, $0x1b1b1b1b1b1b1b1b
+GLOBL c1b<>(SB), RODATA, $0x020
+
+// SUBBYTES adds to the accumulators Y4 to Y7 the S-box of the bytes of Y0 to
+// Y3 of high nibble h, whose bytes are y - 16h at this step. Such a byte is
+// below 16 exactly when the high nibble of y is h, and it is below 0x80 once
+// 0x70 is added with saturation, where VPSHUFB gives 0 for any other byte.
+#define SUBBYTES(h) \
+	VMOVDQU  sbox<>+(32*h)(SB), Y8; \
+	VPADDUSB Y14, Y0, Y9;           \
+	VPADDUSB Y14, Y1, Y10;          \
+	VPADDUSB Y14, Y2, Y11;          \
+	VPADDUSB Y14, Y3, Y12;          \
+	VPSHUFB  Y9, Y8, Y9;            \
+	VPSHUFB  Y10, Y8, Y10;          \
+	VPSHUFB  Y11, Y8, Y11;          \
+	VPSHUFB  Y12, Y8, Y12;          \
+	VPXOR    Y9, Y4, Y4;            \
+	VPXOR    Y10, Y5, Y5;           \
+	VPXOR    Y11, Y6, Y6;           \
+	VPXOR    Y12, Y7, Y7;           \
+	VPSUBB   Y13, Y0, Y0;           \
+	VPSUBB   Y13, Y1, Y1;           \
+	VPSUBB   Y13, Y2, Y2;           \
+	VPSUBB   Y13, Y3, Y3
+
+// SHIFTBYTES rotates the rows in x by the shifts at off in table.
+#define SHIFTBYTES(x, table, off) \
+	VPSRLVQ table<>+off(SB), x, Y8;      \
+	VPSLLVQ table<>+(off+64)(SB), x, x;  \
+	VPOR    Y8, x, x
+
+// XTIME multiplies every byte of x by 2 into y, with temp.
+#define XTIME(x, y, temp) \
+	VPCMPGTB x, Y15, temp;        \
+	VPAND    c1b<>(SB), temp, temp; \
+	VPADDB   x, x, y;             \
+	VPXOR    temp, y, y
+
+// ROTATE adds to Y4 and Y5 the rows of lo and hi rotated by n < 4 rows, which
+// the permutation perm and the blend mask mask give.
+#define ROTATE(lo, hi, perm, mask) \
+	VPERMQ   $perm, lo, Y10;      \
+	VPERMQ   $perm, hi, Y11;      \
+	VPBLENDD $mask, Y11, Y10, lo; \
+	VPBLENDD $mask, Y10, Y11, hi; \
+	VPXOR    lo, Y4, Y4;          \
+	VPXOR    hi, Y5, Y5
+
+// MIXBYTES multiplies the rows in lo and hi by the circulant matrix of
+// coefficients 2, 2, 3, 4, 5, 3, 5, 7. Output row i is the sum over n of the
+// coefficient n times row i+n, that is with R the rows, D = 2R, F = 4R,
+// X = R+D, Y = R+F and Z = X+F, and rot(n) a rotation by n rows:
+//
+//	D + rot(1)D + rot(2)X + rot(3)F + rot(4)Y + rot(5)X + rot(6)Y + rot(7)Z
+//
+// where rot(4) swaps the halves for free, so that it comes to
+//
+//	D + rot(4)Y + rot(1)(D + rot(4)X) + rot(2)(X + rot(4)Y) + rot(3)(F + rot(4)Z)
+#define MIXBYTES(lo, hi) \
+	XTIME(lo, Y4, Y12);           \
+	XTIME(hi, Y5, Y12);           \
+	XTIME(Y4, Y6, Y12);           \
+	XTIME(Y5, Y7, Y12);           \
+	VPXOR    lo, Y4, Y8;          \
+	VPXOR    hi, Y5, Y9;          \
+	VPXOR    Y6, lo, lo;          \
+	VPXOR    Y7, hi, hi;          \
+	VPXOR    Y8, Y6, Y10;         \
+	VPXOR    Y9, Y7, Y11;         \
+	VPXOR    Y4, Y9, Y12;         \
+	VPXOR    Y5, Y8, Y13;         \
+	VPXOR    hi, Y4, Y4;          \
+	VPXOR    lo, Y5, Y5;          \
+	VPXOR    hi, Y8, Y8;          \
+	VPXOR    lo, Y9, Y9;          \
+	VPXOR    Y11, Y6, Y6;         \
+	VPXOR    Y10, Y7, Y7;         \
+	ROTATE(Y12, Y13, 0x39, 0xc0); \
+	ROTATE(Y8, Y9, 0x4e, 0xf0);   \
+	ROTATE(Y6, Y7, 0x93, 0xfc);   \
+	VMOVDQA  Y4, lo;              \
+	VMOVDQA  Y5, hi
+
+// func pq512AVX2(p, q *[64]byte)
+TEXT ·pq512AVX2(SB), NOSPLIT, $0
+	MOVQ p+0(FP), AX
+	MOVQ q+8(FP), BX
+
+	VMOVDQU 0(AX), Y0
+	VMOVDQU 32(AX), Y1
+	VMOVDQU 0(BX), Y2
+	VMOVDQU 32(BX), Y3
+	VPXOR   Y15, Y15, Y15
+	LEAQ    roundP<>(SB), SI
+	LEAQ    roundQ<>(SB), DI
+	MOVQ    $10, CX
+
+loop:
+	// AddRoundConstant
+	VPXOR 0(SI), Y0, Y0
+	VPXOR ones<>(SB), Y2, Y2
+	VPXOR 0(DI), Y3, Y3
+	ADDQ  $32, SI
+	ADDQ  $32, DI
+
+	// SubBytes
+	VMOVDQU c10<>(SB), Y13
+	VMOVDQU c70<>(SB), Y14
+	VPXOR   Y4, Y4, Y4
+	VPXOR   Y5, Y5, Y5
+	VPXOR   Y6, Y6, Y6
+	VPXOR   Y7, Y7, Y7
+	SUBBYTES(0)
+	SUBBYTES(1)
+	SUBBYTES(2)
+	SUBBYTES(3)
+	SUBBYTES(4)
+	SUBBYTES(5)
+	SUBBYTES(6)
+	SUBBYTES(7)
+	SUBBYTES(8)
+	SUBBYTES(9)
+	SUBBYTES(10)
+	SUBBYTES(11)
+	SUBBYTES(12)
+	SUBBYTES(13)
+	SUBBYTES(14)
+	SUBBYTES(15)
+
+	// ShiftBytes
+	SHIFTBYTES(Y4, shiftP, 0)
+	SHIFTBYTES(Y5, shiftP, 32)
+	SHIFTBYTES(Y6, shiftQ, 0)
+	SHIFTBYTES(Y7, shiftQ, 32)
+	VMOVDQA Y4, Y0
+	VMOVDQA Y5, Y1
+	VMOVDQA Y6, Y2
+	VMOVDQA Y7, Y3
+
+	// MixBytes
+	MIXBYTES(Y0, Y1)
+	MIXBYTES(Y2, Y3)
+
+	DECQ CX
+	JNZ  loop
+
+	VMOVDQU Y0, 0(AX)
+	VMOVDQU Y1, 32(AX)
+	VMOVDQU Y2, 0(BX)
+	VMOVDQU Y3, 32(BX)
+	VZEROUPPER
+	RET
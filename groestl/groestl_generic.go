@@ -0,0 +1,7 @@
+// +build !amd64 purego tinygo
+
+package groestl
+
+func compress(h *[2 * cols512]uint32, m []byte) { f512(h, m) }
+
+func output(h *[2 * cols512]uint32) { outputTransformation(h) }
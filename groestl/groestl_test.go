@@ -0,0 +1,93 @@
+package groestl
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+)
+
+var groestlSpecs = []struct {
+	input  string
+	output string
+}{
+	// as computed by the reference implementation of the NIST submission
+	{"", "1a52d11d550039be16107f9c58db9ebcc417f16f736adb2502567119f0083467"},
+	{"The quick brown fox jumps over the lazy dog", "8c7ad62eb26a21297bc39c2d7293b4bd4d3399fa8afab29e970471739e28b301"},
+}
+
+func TestSum256(t *testing.T) {
+	for i, v := range groestlSpecs {
+		if out := hex.EncodeToString(Sum256([]byte(v.input))); out != v.output {
+			t.Errorf("\n[%d] expected:\n\t%s\ngot:\n\t%s\n", i, v.output, out)
+		}
+	}
+}
+
+func TestNew256(t *testing.T) {
+	in := make([]byte, 3*BlockSize+7)
+	for i := range in {
+		in[i] = byte(i)
+	}
+	want := Sum256(in)
+
+	h := New256()
+	if h.Size() != Size || h.BlockSize() != BlockSize {
+		t.Fatalf("unexpected sizes %d, %d", h.Size(), h.BlockSize())
+	}
+
+	// written in chunks of every size, across block boundaries
+	for n := 1; n <= 2*BlockSize; n++ {
+		h.Reset()
+		for p := in; len(p) > 0; {
+			m := n
+			if m > len(p) {
+				m = len(p)
+			}
+			h.Write(p[:m])
+			p = p[m:]
+		}
+		if sum := h.Sum(nil); !bytes.Equal(sum, want) {
+			t.Fatalf("chunks of %d bytes:\nexpected:\n\t%x\ngot:\n\t%x\n", n, want, sum)
+		}
+	}
+
+	// Sum does not change the state
+	h.Reset()
+	h.Write(in[:10])
+	h.Sum(nil)
+	h.Write(in[10:])
+	prefix := []byte("prefix")
+	if sum := h.Sum(prefix); !bytes.Equal(sum[:len(prefix)], prefix) || !bytes.Equal(sum[len(prefix):], want) {
+		t.Errorf("\nexpected:\n\t%x\ngot:\n\t%x\n", want, sum[len(prefix):])
+	}
+}
+
+func TestCompress(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < 100; i++ {
+		var h, want [2 * cols512]uint32
+		m := make([]byte, size512)
+		for j := range h {
+			h[j] = r.Uint32()
+		}
+		r.Read(m)
+
+		want = h
+		f512(&want, m)
+		outputTransformation(&want)
+		compress(&h, m)
+		output(&h)
+		if h != want {
+			t.Fatalf("[%d] compress and f512 disagree", i)
+		}
+	}
+}
+
+func BenchmarkSum256(b *testing.B) {
+	in := make([]byte, 200)
+	b.SetBytes(int64(len(in)))
+	for i := 0; i < b.N; i++ {
+		Sum256(in)
+	}
+}
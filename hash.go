@@ -0,0 +1,66 @@
+package cryptonight
+
+import (
+	"hash"
+
+	"ekyu.moe/cryptonight/internal/observe"
+)
+
+// digest is the hash.Hash of an Algorithm. It buffers everything written to it,
+// as CryptoNight hashes its input at once, and hashes it with its own Cache,
+// allocated by the first call to Sum.
+type digest struct {
+	algo   Algorithm
+	height uint64
+	buf    []byte
+	cc     *Cache
+}
+
+// New returns a new hash.Hash computing the digest of algo. It panics with
+// ErrUnknownAlgorithm if algo is not one of the constants of this package, and
+// with ErrHeightRequired if algo is CNR, which needs NewHeight.
+//
+// Its Sum panics the same way as SumAlgorithm does, including when the data
+// written so far is too short for variant 1. Like most hash.Hash, it must not
+// be used by multiple goroutines at the same time.
+func New(algo Algorithm) hash.Hash {
+	if algo == CNR {
+		observe.Error(ErrHeightRequired)
+		panic(ErrHeightRequired)
+	}
+
+	return NewHeight(algo, 0)
+}
+
+// NewHeight is like New, but also accepts CNR, which hashes with height.
+// height is ignored by the other algorithms.
+func NewHeight(algo Algorithm, height uint64) hash.Hash {
+	if !algo.valid() {
+		observe.Error(ErrUnknownAlgorithm)
+		panic(ErrUnknownAlgorithm)
+	}
+
+	return &digest{algo: algo, height: height}
+}
+
+func (d *digest) Write(p []byte) (int, error) {
+	d.buf = append(d.buf, p...)
+	return len(p), nil
+}
+
+// Sum appends the digest of the data written so far to b. It does not change
+// the underlying hash state.
+func (d *digest) Sum(b []byte) []byte {
+	if d.cc == nil {
+		d.cc = new(Cache)
+	}
+
+	return append(b, d.cc.SumAlgorithm(d.buf, d.algo, d.height)...)
+}
+
+func (d *digest) Reset() { d.buf = d.buf[:0] }
+
+func (d *digest) Size() int { return 32 }
+
+// BlockSize returns the rate of keccak, through which the input goes first.
+func (d *digest) BlockSize() int { return 136 }
@@ -0,0 +1,62 @@
+package cryptonight
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	in, _ := hex.DecodeString(hashSpecsV1[0].input)
+	h := New(CNv1)
+	if h.Size() != 32 || h.BlockSize() != 136 {
+		t.Fatalf("unexpected sizes %d, %d", h.Size(), h.BlockSize())
+	}
+
+	// written in pieces, through io.MultiWriter
+	var copied bytes.Buffer
+	io.Copy(io.MultiWriter(h, &copied), io.LimitReader(bytes.NewReader(in), 10))
+	h.Write(in[10:])
+	if !bytes.Equal(copied.Bytes(), in[:10]) {
+		t.Fatal("MultiWriter did not copy the input")
+	}
+
+	prefix := []byte("prefix")
+	out := h.Sum(prefix)
+	if !bytes.HasPrefix(out, prefix) || hex.EncodeToString(out[len(prefix):]) != hashSpecsV1[0].output {
+		t.Errorf("\nexpected:\n\t%s\ngot:\n\t%x\n", hashSpecsV1[0].output, out[len(prefix):])
+	}
+
+	// Sum does not change the state, and Reset does
+	if out := hex.EncodeToString(h.Sum(nil)); out != hashSpecsV1[0].output {
+		t.Errorf("\nexpected:\n\t%s\ngot:\n\t%s\n", hashSpecsV1[0].output, out)
+	}
+	h.Reset()
+	h.Write(in)
+	if out := hex.EncodeToString(h.Sum(nil)); out != hashSpecsV1[0].output {
+		t.Errorf("\nexpected:\n\t%s\ngot:\n\t%s\n", hashSpecsV1[0].output, out)
+	}
+
+	v := hashSpecsV4[0]
+	in, _ = hex.DecodeString(v.input)
+	h = NewHeight(CNR, v.height)
+	h.Write(in)
+	if out := hex.EncodeToString(h.Sum(nil)); out != v.output {
+		t.Errorf("\nexpected:\n\t%s\ngot:\n\t%s\n", v.output, out)
+	}
+
+	for algo, err := range map[Algorithm]error{
+		CNR:           ErrHeightRequired,
+		Algorithm(-1): ErrUnknownAlgorithm,
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r != err {
+					t.Errorf("%s: expected panic with %v, got %v", algo, err, r)
+				}
+			}()
+			New(algo)
+		}()
+	}
+}
@@ -0,0 +1,14 @@
+// +build go1.13
+
+package cryptonight
+
+import (
+	"testing"
+	"time"
+)
+
+// reportHashrate reports the hashes per second of b since start, each of its
+// b.N iterations computing n hashes, to compare with miners such as xmrig.
+func reportHashrate(b *testing.B, start time.Time, n int) {
+	b.ReportMetric(float64(b.N*n)/time.Since(start).Seconds(), "H/s")
+}
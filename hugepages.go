@@ -0,0 +1,46 @@
+package cryptonight
+
+import (
+	"errors"
+
+	"ekyu.moe/cryptonight/internal/observe"
+)
+
+// errNoHugePages is reported to the Observer when NewCacheHugePages falls
+// back to new(Cache).
+var errNoHugePages = errors.New("cryptonight: huge pages are not available")
+
+// NewCacheHugePages returns a new Cache backed by huge pages, which saves most
+// of the TLB misses of the memory-hard loop, as the 2 MiB scratchpad fits in a
+// single huge page. On Linux, it uses the huge pages reserved in
+// /proc/sys/vm/nr_hugepages, and else asks for transparent huge pages. On
+// Windows, it uses large pages, which requires the "Lock pages in memory"
+// privilege.
+//
+// When huge pages are not available, including on other systems and with the
+// purego build tag, it reports why to the Observer and returns new(Cache).
+//
+// The scratchpad of a Cache backed by huge pages is not managed by the garbage
+// collector, and must be released by Close.
+func NewCacheHugePages() *Cache {
+	cc, err := mapCache()
+	if err != nil {
+		observe.Error(err)
+		return new(Cache)
+	}
+
+	return cc
+}
+
+// HugePages reports whether cc is backed by huge pages, i.e. was allocated by
+// NewCacheHugePages and not closed yet.
+func (cc *Cache) HugePages() bool {
+	return cc.alloc != nil && cc.alloc.huge
+}
+
+// Close releases the scratchpad of cc if it was allocated by NewCacheHugePages
+// or NewCacheWithAllocator, and does nothing otherwise. cc must not be used after
+// Close.
+func (cc *Cache) Close() error {
+	return unmapCache(cc)
+}
@@ -0,0 +1,52 @@
+// +build !purego,!tinygo
+
+package cryptonight
+
+import (
+	"errors"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// hugePageSize is the size of the huge pages of amd64 and of arm64 with 4 KiB
+// pages, which most systems use.
+const hugePageSize = 2 << 20
+
+const (
+	mmapProt  = unix.PROT_READ | unix.PROT_WRITE
+	mmapFlags = unix.MAP_PRIVATE | unix.MAP_ANONYMOUS
+)
+
+// mapHuge maps at least n bytes backed by huge pages, which start at mem[off],
+// aligned to a huge page.
+func mapHuge(n int) (mem []byte, off int, err error) {
+	size := (n + hugePageSize - 1) &^ (hugePageSize - 1)
+	if mem, err = unix.Mmap(-1, 0, size, mmapProt, mmapFlags|unix.MAP_HUGETLB); err == nil {
+		return mem, 0, nil
+	}
+
+	// no huge pages reserved, ask for transparent huge pages instead
+	if mode := transparentHugePages(); mode != "always" && mode != "madvise" {
+		return nil, 0, errNoHugePages
+	}
+	if mem, err = unix.Mmap(-1, 0, size+hugePageSize, mmapProt, mmapFlags); err != nil {
+		return nil, 0, errors.New("cryptonight: mmap: " + err.Error())
+	}
+	off = int(-uintptr(unsafe.Pointer(&mem[0])) & (hugePageSize - 1))
+	if err = unix.Madvise(mem[off:off+size], unix.MADV_HUGEPAGE); err != nil {
+		unix.Munmap(mem)
+		return nil, 0, errors.New("cryptonight: madvise: " + err.Error())
+	}
+
+	return mem, off, nil
+}
+
+func mapPlain(n int) ([]byte, int, error) {
+	mem, err := unix.Mmap(-1, 0, n, mmapProt, mmapFlags)
+	return mem, 0, err
+}
+
+func unmap(mem []byte) error {
+	return unix.Munmap(mem)
+}
@@ -0,0 +1,30 @@
+// +build linux windows
+// +build !purego,!tinygo
+
+package cryptonight
+
+// hugePages is the mapper of NewCacheHugePages. A scratchpad larger than the
+// first one is at least outside of the Go heap, if not on huge pages.
+var hugePages = &mapper{
+	alloc: func(n int) ([]byte, int, error) {
+		if mem, off, err := mapHuge(n); err == nil {
+			return mem, off, nil
+		}
+		return mapPlain(n)
+	},
+	free: unmap,
+	huge: true,
+}
+
+// mapCache returns a Cache with a scratchpad of 2 MiB on huge pages.
+func mapCache() (*Cache, error) {
+	const memory = 2 * 1024 * 1024
+	mem, off, err := mapHuge(memory)
+	if err != nil {
+		return nil, err
+	}
+	cc := &Cache{alloc: hugePages}
+	cc.setPad(mem, off, memory)
+
+	return cc, nil
+}
@@ -0,0 +1,5 @@
+// +build !linux,!windows purego tinygo
+
+package cryptonight
+
+func mapCache() (*Cache, error) { return nil, errNoHugePages }
@@ -0,0 +1,37 @@
+package cryptonight
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestNewCacheHugePages(t *testing.T) {
+	cc := NewCacheHugePages()
+	t.Logf("huge pages: %t", cc.HugePages())
+
+	for _, v := range []struct {
+		algo Algorithm
+		spec hashSpec
+	}{
+		{CNv2, hashSpecsV2[0]},
+		{CNHeavy, hashSpecsHeavy[0]},
+		{CNv2, hashSpecsV2[1]},
+	} {
+		in, _ := hex.DecodeString(v.spec.input)
+		if result := cc.SumAlgorithm(in, v.algo, 0); hex.EncodeToString(result) != v.spec.output {
+			t.Errorf("\n[%s] expected:\n\t%s\ngot:\n\t%x\n", v.algo, v.spec.output, result)
+		}
+	}
+
+	if err := cc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if cc.HugePages() {
+		t.Error("closed Cache still backed by huge pages")
+	}
+
+	// a Cache of the Go heap is not affected
+	if cc := new(Cache); cc.HugePages() || cc.Close() != nil {
+		t.Error("new(Cache) is not a regular Cache")
+	}
+}
@@ -0,0 +1,115 @@
+// +build !purego,!tinygo
+
+package cryptonight
+
+import (
+	"errors"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	_MEM_COMMIT      = 0x1000
+	_MEM_RESERVE     = 0x2000
+	_MEM_RELEASE     = 0x8000
+	_MEM_LARGE_PAGES = 0x20000000
+	_PAGE_READWRITE  = 0x04
+
+	_SE_PRIVILEGE_ENABLED   = 0x02
+	_ERROR_NOT_ALL_ASSIGNED = 1300
+)
+
+var (
+	kernel32                  = syscall.NewLazyDLL("kernel32.dll")
+	advapi32                  = syscall.NewLazyDLL("advapi32.dll")
+	procVirtualAlloc          = kernel32.NewProc("VirtualAlloc")
+	procVirtualFree           = kernel32.NewProc("VirtualFree")
+	procGetLargePageMinimum   = kernel32.NewProc("GetLargePageMinimum")
+	procLookupPrivilegeValueW = advapi32.NewProc("LookupPrivilegeValueW")
+	procAdjustTokenPrivileges = advapi32.NewProc("AdjustTokenPrivileges")
+
+	lockMemoryOnce sync.Once
+	lockMemoryErr  error
+)
+
+// enableLockMemory enables SeLockMemoryPrivilege in the token of the process,
+// which large pages require. The privilege must have been granted to the user
+// beforehand, as "Lock pages in memory" in the local security policy.
+func enableLockMemory() error {
+	p, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return err
+	}
+	var token syscall.Token
+	if err := syscall.OpenProcessToken(p, syscall.TOKEN_ADJUST_PRIVILEGES|syscall.TOKEN_QUERY, &token); err != nil {
+		return err
+	}
+	defer token.Close()
+
+	name, err := syscall.UTF16PtrFromString("SeLockMemoryPrivilege")
+	if err != nil {
+		return err
+	}
+	// TOKEN_PRIVILEGES with a single LUID_AND_ATTRIBUTES
+	var privileges struct {
+		count      uint32
+		luid       [2]uint32
+		attributes uint32
+	}
+	if r, _, err := procLookupPrivilegeValueW.Call(0, uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(&privileges.luid))); r == 0 {
+		return err
+	}
+	privileges.count = 1
+	privileges.attributes = _SE_PRIVILEGE_ENABLED
+	r, _, err := procAdjustTokenPrivileges.Call(uintptr(token), 0, uintptr(unsafe.Pointer(&privileges)), 0, 0, 0)
+	if r == 0 {
+		return err
+	}
+	if err == syscall.Errno(_ERROR_NOT_ALL_ASSIGNED) {
+		return errors.New("cryptonight: the user lacks the Lock pages in memory privilege")
+	}
+
+	return nil
+}
+
+// mapHuge allocates at least n bytes on large pages, which start at mem[0].
+func mapHuge(n int) (mem []byte, off int, err error) {
+	lockMemoryOnce.Do(func() { lockMemoryErr = enableLockMemory() })
+	if lockMemoryErr != nil {
+		return nil, 0, lockMemoryErr
+	}
+	page, _, _ := procGetLargePageMinimum.Call()
+	if page == 0 {
+		return nil, 0, errNoHugePages
+	}
+
+	size := (uintptr(n) + page - 1) &^ (page - 1)
+	mem, err = virtualAlloc(size, _MEM_RESERVE|_MEM_COMMIT|_MEM_LARGE_PAGES)
+	return mem, 0, err
+}
+
+func mapPlain(n int) ([]byte, int, error) {
+	mem, err := virtualAlloc(uintptr(n), _MEM_RESERVE|_MEM_COMMIT)
+	return mem, 0, err
+}
+
+func virtualAlloc(size uintptr, flags uintptr) ([]byte, error) {
+	addr, _, err := procVirtualAlloc.Call(0, size, flags, _PAGE_READWRITE)
+	if addr == 0 {
+		return nil, errors.New("cryptonight: VirtualAlloc: " + err.Error())
+	}
+
+	// addr is outside of the Go heap, so it can be converted, through a
+	// pointer to keep go vet quiet
+	p := *(*unsafe.Pointer)(unsafe.Pointer(&addr))
+	return (*[1 << 30]byte)(p)[:size:size], nil
+}
+
+func unmap(mem []byte) error {
+	if r, _, err := procVirtualFree.Call(uintptr(unsafe.Pointer(&mem[0])), 0, _MEM_RELEASE); r == 0 {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package cryptonight
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+
+	"ekyu.moe/cryptonight/internal/observe"
+	"ekyu.moe/cryptonight/internal/sha3"
+)
+
+// Intermediates are the values computed on the way to a digest, for authors of
+// other implementations localizing where theirs diverges from this one.
+type Intermediates struct {
+	// Initial is the Keccak state of the input, which the scratchpad and the
+	// memory hard loop start from.
+	Initial [200]byte
+
+	// Final is the Keccak state after the result calculation, which is the
+	// input of the final hash.
+	Final [200]byte
+
+	// FinalHash is the function picked by the 2 lowest bits of Final:
+	// "blake256", "groestl", "jh" or "skein", or "custom" if it was set with
+	// Cache.SetFinalHash. It is empty for CNGPU, whose digest is the first 32
+	// bytes of Final, and for Chukwa and ChukwaV2, which have no Keccak state,
+	// so Initial and Final are zero too.
+	FinalHash string
+
+	// Digest is the result, the same as the one of SumAlgorithm.
+	Digest [32]byte
+}
+
+// SumIntermediates calculates a hash digest of algo with cc along with its
+// intermediates, the same way as Cache.SumAlgorithm does, and panics the same
+// way. It is meant for tests and debugging, as it allocates its result.
+func (cc *Cache) SumIntermediates(data []byte, algo Algorithm, height uint64) *Intermediates {
+	if !algo.valid() {
+		observe.Error(ErrUnknownAlgorithm)
+		panic(ErrUnknownAlgorithm)
+	}
+	if !atomic.CompareAndSwapUint32(&cc.inUse, 0, 1) {
+		observe.Error(ErrCacheInUse)
+		panic(ErrCacheInUse)
+	}
+	defer atomic.StoreUint32(&cc.inUse, 0)
+
+	in := new(Intermediates)
+	p := algorithms[algo].p
+
+	cc.safeSum(in.Digest[:0], data, p, height)
+	if p.argon2 {
+		return in
+	}
+
+	var st [25]uint64
+	sha3.Keccak1600State(&st, data)
+	stateBytes(&in.Initial, &st)
+	stateBytes(&in.Final, &cc.finalState)
+	selector := cc.finalState[0] & 0x03
+	switch {
+	case p.gpu:
+	case cc.final != nil && cc.final[selector] != nil:
+		in.FinalHash = "custom"
+	default:
+		in.FinalHash = finalHashNames[selector]
+	}
+	if cc.crossChecked() && algo <= CNR {
+		crossCheck(data, p.variant, in.Digest[:])
+	}
+
+	return in
+}
+
+// stateBytes stores st into b in little endian.
+func stateBytes(b *[200]byte, st *[25]uint64) {
+	for i, v := range st {
+		binary.LittleEndian.PutUint64(b[8*i:], v)
+	}
+}
@@ -0,0 +1,60 @@
+package cryptonight
+
+import (
+	"bytes"
+	"testing"
+
+	"ekyu.moe/cryptonight/blake256"
+	"ekyu.moe/cryptonight/groestl"
+	"ekyu.moe/cryptonight/jh"
+	"ekyu.moe/cryptonight/keccak"
+	"ekyu.moe/cryptonight/skein"
+)
+
+func TestSumIntermediates(t *testing.T) {
+	finalHashes := map[string]func([]byte) []byte{
+		"blake256": blake256.Sum256,
+		"groestl":  groestl.Sum256,
+		"jh":       jh.Sum256,
+		"skein":    skein.Sum256,
+	}
+
+	cc := new(Cache)
+	seen := make(map[string]bool)
+	for a := range algorithms {
+		algo := Algorithm(a)
+		for i, data := range benchData {
+			in := cc.SumIntermediates(data, algo, 1806260)
+
+			if expected := SumAlgorithm(data, algo, 1806260); !bytes.Equal(in.Digest[:], expected) {
+				t.Errorf("[%s %d] Digest: expected %x, got %x", algo, i, expected, in.Digest)
+			}
+			if algorithms[a].p.argon2 {
+				if in.Initial != [200]byte{} || in.Final != [200]byte{} || in.FinalHash != "" {
+					t.Errorf("[%s %d] expected no Keccak state, got %+v", algo, i, in)
+				}
+				continue
+			}
+			if expected := keccak.Sum256(data); !bytes.Equal(in.Initial[:32], expected) {
+				t.Errorf("[%s %d] Initial: expected to start with %x, got %x", algo, i, expected, in.Initial[:32])
+			}
+
+			var final []byte
+			if algo == CNGPU {
+				if in.FinalHash != "" {
+					t.Errorf("[%s %d] FinalHash: expected none, got %q", algo, i, in.FinalHash)
+				}
+				final = in.Final[:32]
+			} else {
+				seen[in.FinalHash] = true
+				final = finalHashes[in.FinalHash](in.Final[:])
+			}
+			if !bytes.Equal(final, in.Digest[:]) {
+				t.Errorf("[%s %d] Final: %s of it is %x instead of the digest %x", algo, i, in.FinalHash, final, in.Digest)
+			}
+		}
+	}
+	if len(seen) != len(finalHashes) {
+		t.Errorf("only %d final hashes picked, the vectors should cover all of them", len(seen))
+	}
+}
@@ -7,9 +7,22 @@
 // project that's not CryptoNight associated.
 package aes // import "ekyu.moe/cryptonight/internal/aes"
 
-// CnExpandKey expands exactly 10 round keys.
+// Backend returns the implementation of CnRounds and CnSingleRound: "aes-ni",
+// "arm64-aes" for the crypto extension of arm64, or "go".
+func Backend() string {
+	return backend()
+}
+
+// Tables returns where the tables of the Go rounds come from: "embedded" in
+// the binary, or "generated" at init with the aesgen build tag.
+func Tables() string {
+	return tables
+}
+
+// CnExpandKey expands exactly 10 round keys, with AES-NI when the CPU supports
+// it, like CnRounds and CnSingleRound.
 //
-// key must have at least 2 elements.
+// key must have at least 4 elements.
 //
 // The result may vary from different architecture, but the output parameter
 // rkeys is guranteed to give correct result when used as input in CnRounds.
@@ -17,7 +30,7 @@ package aes // import "ekyu.moe/cryptonight/internal/aes"
 // Note that this is CryptoNight specific.
 // This is non-standard AES!
 func CnExpandKey(key []uint64, rkeys *[40]uint32) {
-	CnExpandKeyGo(key, rkeys)
+	cnExpandKey(key, rkeys)
 }
 
 // CnRounds = (SubBytes, ShiftRows, MixColumns, AddRoundKey) * 10,
@@ -27,7 +40,7 @@ func CnExpandKey(key []uint64, rkeys *[40]uint32) {
 // Note that this is CryptoNight specific.
 // This is non-standard AES!
 func CnRounds(dst, src []uint64, rkeys *[40]uint32) {
-	CnRoundsGo(dst, src, rkeys)
+	cnRounds(dst, src, rkeys)
 }
 
 // CnSingleRound performs exactly one AES round, i.e.
@@ -38,5 +51,51 @@ func CnRounds(dst, src []uint64, rkeys *[40]uint32) {
 // Note that this is CryptoNight specific.
 // CnSingleRound * 10 might not be equivalent to one CnRounds.
 func CnSingleRound(dst, src []uint64, rkey *[2]uint64) {
-	CnSingleRoundGo(dst, src, rkey)
+	cnSingleRound(dst, src, rkey)
+}
+
+// CnExplode encrypts the 8 blocks of 16 bytes in blocks with CnRounds again
+// and again, storing them after each pass into the next 16 elements of dst,
+// which is the scratchpad initialization of CNS008 sec.3. blocks is left with
+// the last pass.
+//
+// blocks must have at least 16 elements, and the length of dst must be a
+// multiple of 16.
+func CnExplode(dst, blocks []uint64, rkeys *[40]uint32) {
+	cnExplode(dst, blocks, rkeys)
+}
+
+// CnImplode xors blocks with the next 16 elements of src, then encrypts its 8
+// blocks of 16 bytes with CnRounds, until src is exhausted, which is the result
+// calculation of CNS008 sec.5. Unlike the reference implementation, it leaves
+// src unchanged.
+//
+// blocks must have at least 16 elements, and the length of src must be a
+// multiple of 16.
+func CnImplode(blocks, src []uint64, rkeys *[40]uint32) {
+	cnImplode(blocks, src, rkeys)
+}
+
+// explodeRounds and implodeRounds are CnExplode and CnImplode one CnRounds at a
+// time, for the backends that only accelerate the rounds.
+func explodeRounds(dst, blocks []uint64, rkeys *[40]uint32) {
+	blocks = blocks[:16]
+	for i := 0; i+16 <= len(dst); i += 16 {
+		for j := 0; j < 16; j += 2 {
+			cnRounds(blocks[j:j+2], blocks[j:j+2], rkeys)
+		}
+		copy(dst[i:i+16], blocks)
+	}
+}
+
+func implodeRounds(blocks, src []uint64, rkeys *[40]uint32) {
+	blocks = blocks[:16]
+	for i := 0; i+16 <= len(src); i += 16 {
+		for j := 0; j < 16; j++ {
+			blocks[j] ^= src[i+j]
+		}
+		for j := 0; j < 16; j += 2 {
+			cnRounds(blocks[j:j+2], blocks[j:j+2], rkeys)
+		}
+	}
 }
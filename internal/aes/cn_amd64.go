@@ -1,7 +1,82 @@
+// +build amd64,!purego,!tinygo
+
 package aes
 
+import "golang.org/x/sys/cpu"
+
+var hasAES = cpu.X86.HasAES
+
 //go:noescape
 func CnExpandKeyAsm(src *uint64, rkey *[40]uint32)
 
 //go:noescape
 func CnRoundsAsm(dst, src *uint64, rkeys *[40]uint32)
+
+//go:noescape
+func CnSingleRoundAsm(dst, src *uint64, rkey *[2]uint64)
+
+// CnExplodeAsm and CnImplodeAsm process n chunks of 128 bytes, keeping the 8
+// blocks in registers so that their rounds are pipelined.
+
+//go:noescape
+func CnExplodeAsm(dst *uint64, n int, blocks *uint64, rkeys *[40]uint32)
+
+//go:noescape
+func CnImplodeAsm(blocks, src *uint64, n int, rkeys *[40]uint32)
+
+func backend() string {
+	if hasAES {
+		return "aes-ni"
+	}
+
+	return "go"
+}
+
+func cnExpandKey(key []uint64, rkeys *[40]uint32) {
+	if !hasAES {
+		CnExpandKeyGo(key, rkeys)
+		return
+	}
+	_ = key[3] // bounds check, the assembly reads 32 bytes
+	CnExpandKeyAsm(&key[0], rkeys)
+}
+
+func cnRounds(dst, src []uint64, rkeys *[40]uint32) {
+	if !hasAES {
+		CnRoundsGo(dst, src, rkeys)
+		return
+	}
+	_, _ = src[1], dst[1]
+	CnRoundsAsm(&dst[0], &src[0], rkeys)
+}
+
+func cnSingleRound(dst, src []uint64, rkey *[2]uint64) {
+	if !hasAES {
+		CnSingleRoundGo(dst, src, rkey)
+		return
+	}
+	_, _ = src[1], dst[1]
+	CnSingleRoundAsm(&dst[0], &src[0], rkey)
+}
+
+func cnExplode(dst, blocks []uint64, rkeys *[40]uint32) {
+	if !hasAES {
+		explodeRounds(dst, blocks, rkeys)
+		return
+	}
+	_ = blocks[15]
+	if n := len(dst) / 16; n > 0 {
+		CnExplodeAsm(&dst[0], n, &blocks[0], rkeys)
+	}
+}
+
+func cnImplode(blocks, src []uint64, rkeys *[40]uint32) {
+	if !hasAES {
+		implodeRounds(blocks, src, rkeys)
+		return
+	}
+	_ = blocks[15]
+	if n := len(src) / 16; n > 0 {
+		CnImplodeAsm(&blocks[0], &src[0], n, rkeys)
+	}
+}
@@ -1,46 +1,63 @@
+// +build amd64,!purego,!tinygo
+
 #include "textflag.h"
 
+// Operands are loaded and stored with MOVOU, as the slices given to the
+// functions of cn.go are not necessarily 16-byte aligned.
+
 // func CnRoundsAsm(dst, src *uint64, rkeys *uint32)
 TEXT ·CnRoundsAsm(SB), NOSPLIT, $0
 	MOVQ    dst+0(FP), AX
 	MOVQ    src+8(FP), BX
 	MOVQ    rkeys+16(FP), CX
-	MOVO    0(BX), X0
-	MOVO    0(CX), X1
+	MOVOU   0(BX), X0
+	MOVOU   0(CX), X1
 	AESENC  X1, X0
-	MOVO    16(CX), X1
+	MOVOU   16(CX), X1
 	AESENC  X1, X0
-	MOVO    32(CX), X1
+	MOVOU   32(CX), X1
 	AESENC  X1, X0
-	MOVO    48(CX), X1
+	MOVOU   48(CX), X1
 	AESENC  X1, X0
-	MOVO    64(CX), X1
+	MOVOU   64(CX), X1
 	AESENC  X1, X0
-	MOVO    80(CX), X1
+	MOVOU   80(CX), X1
 	AESENC  X1, X0
-	MOVO    96(CX), X1
+	MOVOU   96(CX), X1
 	AESENC  X1, X0
-	MOVO    112(CX), X1
+	MOVOU   112(CX), X1
 	AESENC  X1, X0
-	MOVO    128(CX), X1
+	MOVOU   128(CX), X1
 	AESENC  X1, X0
-	MOVO    144(CX), X1
+	MOVOU   144(CX), X1
 	AESENC  X1, X0
-	MOVO    X0, 0(AX)
+	MOVOU   X0, 0(AX)
 	RET
 
-// func CnExpandKeyAsm(key *uint64, rkey *uint32)
-// Note that round keys are stored in uint128 format, not uint32
+// func CnSingleRoundAsm(dst, src *uint64, rkey *[2]uint64)
+TEXT ·CnSingleRoundAsm(SB), NOSPLIT, $0
+	MOVQ    dst+0(FP), AX
+	MOVQ    src+8(FP), BX
+	MOVQ    rkey+16(FP), CX
+	MOVOU   0(BX), X0
+	MOVOU   0(CX), X1
+	AESENC  X1, X0
+	MOVOU   X0, 0(AX)
+	RET
+
+// func CnExpandKeyAsm(src *uint64, rkey *[40]uint32)
+// Note that round keys are stored in uint128 format, not uint32, and that only
+// the 10 of them CnRoundsAsm uses are expanded, which fill rkey exactly.
 TEXT ·CnExpandKeyAsm(SB), NOSPLIT, $0
-	MOVQ    key+0(FP), AX
+	MOVQ    src+0(FP), AX
 	MOVQ    rkey+8(FP), BX
-	MOVO    (AX), X0
-	MOVO    X0, (BX)
+	MOVOU   (AX), X0
+	MOVOU   X0, (BX)
 	ADDQ    $16, BX
 	PXOR    X4, X4 // _expand_key_* expect X4 to be zero
 
-	MOVO    16(AX), X2
-	MOVO    X2, (BX)
+	MOVOU   16(AX), X2
+	MOVOU   X2, (BX)
 	ADDQ    $16, BX
 	AESKEYGENASSIST $0x01, X2, X1
 	CALL    _expand_key_256a<>(SB)
@@ -58,8 +75,6 @@ TEXT ·CnExpandKeyAsm(SB), NOSPLIT, $0
 	CALL    _expand_key_256a<>(SB)
 	AESKEYGENASSIST $0x08, X0, X1
 	CALL    _expand_key_256b<>(SB)
-	AESKEYGENASSIST $0x10, X2, X1
-	CALL    _expand_key_256a<>(SB)
 	RET
 
 TEXT _expand_key_256a<>(SB), NOSPLIT, $0
@@ -69,7 +84,7 @@ TEXT _expand_key_256a<>(SB), NOSPLIT, $0
 	SHUFPS  $0x8c, X0, X4
 	PXOR    X4, X0
 	PXOR    X1, X0
-	MOVO    X0, (BX)
+	MOVOU   X0, (BX)
 	ADDQ    $16, BX
 	RET
 
@@ -80,6 +95,105 @@ TEXT _expand_key_256b<>(SB), NOSPLIT, $0
 	SHUFPS  $0x8c, X2, X4
 	PXOR    X4, X2
 	PXOR    X1, X2
-	MOVO    X2, (BX)
+	MOVOU   X2, (BX)
 	ADDQ    $16, BX
 	RET
+
+// ROUND8 applies the round key at off(DX) to the 8 blocks in X0 to X7.
+#define ROUND8(off) \
+	MOVOU   off(DX), X8; \
+	AESENC  X8, X0; \
+	AESENC  X8, X1; \
+	AESENC  X8, X2; \
+	AESENC  X8, X3; \
+	AESENC  X8, X4; \
+	AESENC  X8, X5; \
+	AESENC  X8, X6; \
+	AESENC  X8, X7
+
+#define ROUNDS8 \
+	ROUND8(0); \
+	ROUND8(16); \
+	ROUND8(32); \
+	ROUND8(48); \
+	ROUND8(64); \
+	ROUND8(80); \
+	ROUND8(96); \
+	ROUND8(112); \
+	ROUND8(128); \
+	ROUND8(144)
+
+#define LOAD8(r) \
+	MOVOU   0(r), X0; \
+	MOVOU   16(r), X1; \
+	MOVOU   32(r), X2; \
+	MOVOU   48(r), X3; \
+	MOVOU   64(r), X4; \
+	MOVOU   80(r), X5; \
+	MOVOU   96(r), X6; \
+	MOVOU   112(r), X7
+
+#define STORE8(r) \
+	MOVOU   X0, 0(r); \
+	MOVOU   X1, 16(r); \
+	MOVOU   X2, 32(r); \
+	MOVOU   X3, 48(r); \
+	MOVOU   X4, 64(r); \
+	MOVOU   X5, 80(r); \
+	MOVOU   X6, 96(r); \
+	MOVOU   X7, 112(r)
+
+// XOR8 xors the 8 blocks in X0 to X7 with the 128 bytes at r.
+#define XOR8(r) \
+	MOVOU   0(r), X8; \
+	MOVOU   16(r), X9; \
+	MOVOU   32(r), X10; \
+	MOVOU   48(r), X11; \
+	MOVOU   64(r), X12; \
+	MOVOU   80(r), X13; \
+	MOVOU   96(r), X14; \
+	MOVOU   112(r), X15; \
+	PXOR    X8, X0; \
+	PXOR    X9, X1; \
+	PXOR    X10, X2; \
+	PXOR    X11, X3; \
+	PXOR    X12, X4; \
+	PXOR    X13, X5; \
+	PXOR    X14, X6; \
+	PXOR    X15, X7
+
+// func CnExplodeAsm(dst *uint64, n int, blocks *uint64, rkeys *[40]uint32)
+TEXT ·CnExplodeAsm(SB), NOSPLIT, $0
+	MOVQ    dst+0(FP), AX
+	MOVQ    n+8(FP), CX
+	MOVQ    blocks+16(FP), BX
+	MOVQ    rkeys+24(FP), DX
+	LOAD8(BX)
+
+explode_loop:
+	ROUNDS8
+	STORE8(AX)
+	ADDQ    $128, AX
+	DECQ    CX
+	JNZ     explode_loop
+
+	STORE8(BX)
+	RET
+
+// func CnImplodeAsm(blocks, src *uint64, n int, rkeys *[40]uint32)
+TEXT ·CnImplodeAsm(SB), NOSPLIT, $0
+	MOVQ    blocks+0(FP), BX
+	MOVQ    src+8(FP), AX
+	MOVQ    n+16(FP), CX
+	MOVQ    rkeys+24(FP), DX
+	LOAD8(BX)
+
+implode_loop:
+	XOR8(AX)
+	ROUNDS8
+	ADDQ    $128, AX
+	DECQ    CX
+	JNZ     implode_loop
+
+	STORE8(BX)
+	RET
@@ -0,0 +1,81 @@
+// +build amd64,!purego,!tinygo
+
+package aes
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"golang.org/x/sys/cpu"
+)
+
+// asmBuf holds the operands of the assembly functions, 16-byte aligned like the
+// scratchpad of the memory-hard loop. Every field is at a multiple of 16 within the struct, and
+// the struct is 8 mod 16 bytes long, so one of two consecutive asmBufs is
+// always aligned.
+type asmBuf struct {
+	key      [4]uint64
+	src, dst [2]uint64
+	rkeys    [40]uint32
+	_        [8]byte
+}
+
+func newAsmBuf() *asmBuf {
+	bufs := make([]asmBuf, 2)
+	if reflect.ValueOf(&bufs[0]).Pointer()%16 != 0 {
+		return &bufs[1]
+	}
+
+	return &bufs[0]
+}
+
+// TestCnRoundsAsm checks the AES-NI key expansion and rounds against the pure
+// Go ones on random keys and blocks. The expanded keys may differ in layout,
+// so they are only compared through the rounds.
+func TestCnRoundsAsm(t *testing.T) {
+	if !cpu.X86.HasAES {
+		t.Skip("host does not support AES-NI")
+	}
+
+	r := rand.New(rand.NewSource(0))
+	b := newAsmBuf()
+	for i := 0; i < 1000; i++ {
+		for j := range b.key {
+			b.key[j] = r.Uint64()
+		}
+		b.src = [2]uint64{r.Uint64(), r.Uint64()}
+
+		var rkeys [40]uint32
+		expected := make([]uint64, 2)
+		CnExpandKeyGo(b.key[:], &rkeys)
+		CnRoundsGo(expected, b.src[:], &rkeys)
+
+		CnExpandKeyAsm(&b.key[0], &b.rkeys)
+		CnRoundsAsm(&b.dst[0], &b.src[0], &b.rkeys)
+		if b.dst[0] != expected[0] || b.dst[1] != expected[1] {
+			t.Errorf("\n[%d] key %x, block %x\nexpected:\n\t%x\ngot:\n\t%x\n", i, b.key, b.src, expected, b.dst)
+		}
+	}
+}
+
+func TestCnSingleRoundAsm(t *testing.T) {
+	if !cpu.X86.HasAES {
+		t.Skip("host does not support AES-NI")
+	}
+
+	r := rand.New(rand.NewSource(0))
+	b := newAsmBuf()
+	for i := 0; i < 1000; i++ {
+		rkey := [2]uint64{r.Uint64(), r.Uint64()}
+		b.src = [2]uint64{r.Uint64(), r.Uint64()}
+
+		expected := make([]uint64, 2)
+		CnSingleRoundGo(expected, b.src[:], &rkey)
+
+		CnSingleRoundAsm(&b.dst[0], &b.src[0], &rkey)
+		if b.dst[0] != expected[0] || b.dst[1] != expected[1] {
+			t.Errorf("\n[%d] key %x, block %x\nexpected:\n\t%x\ngot:\n\t%x\n", i, rkey, b.src, expected, b.dst)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// +build arm64,!purego,!tinygo
+
+package aes
+
+import "math/bits"
+
+var hasAES = detectAES()
+
+//go:noescape
+func CnRoundsAsm(dst, src *uint64, rkeys *[40]uint32)
+
+//go:noescape
+func CnSingleRoundAsm(dst, src *uint64, rkey *[2]uint64)
+
+func backend() string {
+	if hasAES {
+		return "arm64-aes"
+	}
+
+	return "go"
+}
+
+// cnExpandKey stores the round keys as bytes when the rounds run on the
+// crypto extension, as AESE takes them, instead of big endian words.
+func cnExpandKey(key []uint64, rkeys *[40]uint32) {
+	CnExpandKeyGo(key, rkeys)
+	if hasAES {
+		for i, k := range rkeys {
+			rkeys[i] = bits.ReverseBytes32(k)
+		}
+	}
+}
+
+func cnRounds(dst, src []uint64, rkeys *[40]uint32) {
+	if !hasAES {
+		CnRoundsGo(dst, src, rkeys)
+		return
+	}
+	_, _ = src[1], dst[1]
+	CnRoundsAsm(&dst[0], &src[0], rkeys)
+}
+
+func cnSingleRound(dst, src []uint64, rkey *[2]uint64) {
+	if !hasAES {
+		CnSingleRoundGo(dst, src, rkey)
+		return
+	}
+	_, _ = src[1], dst[1]
+	CnSingleRoundAsm(&dst[0], &src[0], rkey)
+}
+
+func cnExplode(dst, blocks []uint64, rkeys *[40]uint32) {
+	explodeRounds(dst, blocks, rkeys)
+}
+
+func cnImplode(blocks, src []uint64, rkeys *[40]uint32) {
+	implodeRounds(blocks, src, rkeys)
+}
@@ -0,0 +1,56 @@
+// +build arm64,!purego,!tinygo
+
+#include "textflag.h"
+
+// AESE is AddRoundKey, ShiftRows and SubBytes, while AESENC of amd64 is
+// ShiftRows, SubBytes, MixColumns and AddRoundKey. A CryptoNight round is
+// thus AESE with a zero key then AESMC, and the key is added by the AESE of
+// the next round, or by VEOR after the last one.
+
+// func CnRoundsAsm(dst, src *uint64, rkeys *[40]uint32)
+TEXT ·CnRoundsAsm(SB), NOSPLIT, $0
+	MOVD    dst+0(FP), R0
+	MOVD    src+8(FP), R1
+	MOVD    rkeys+16(FP), R2
+	VLD1    (R1), [V0.B16]
+	VLD1.P  64(R2), [V1.B16, V2.B16, V3.B16, V4.B16]
+	VLD1.P  64(R2), [V5.B16, V6.B16, V7.B16, V8.B16]
+	VLD1    (R2), [V9.B16, V10.B16]
+	VEOR    V11.B16, V11.B16, V11.B16
+	AESE    V11.B16, V0.B16
+	AESMC   V0.B16, V0.B16
+	AESE    V1.B16, V0.B16
+	AESMC   V0.B16, V0.B16
+	AESE    V2.B16, V0.B16
+	AESMC   V0.B16, V0.B16
+	AESE    V3.B16, V0.B16
+	AESMC   V0.B16, V0.B16
+	AESE    V4.B16, V0.B16
+	AESMC   V0.B16, V0.B16
+	AESE    V5.B16, V0.B16
+	AESMC   V0.B16, V0.B16
+	AESE    V6.B16, V0.B16
+	AESMC   V0.B16, V0.B16
+	AESE    V7.B16, V0.B16
+	AESMC   V0.B16, V0.B16
+	AESE    V8.B16, V0.B16
+	AESMC   V0.B16, V0.B16
+	AESE    V9.B16, V0.B16
+	AESMC   V0.B16, V0.B16
+	VEOR    V10.B16, V0.B16, V0.B16
+	VST1    [V0.B16], (R0)
+	RET
+
+// func CnSingleRoundAsm(dst, src *uint64, rkey *[2]uint64)
+TEXT ·CnSingleRoundAsm(SB), NOSPLIT, $0
+	MOVD    dst+0(FP), R0
+	MOVD    src+8(FP), R1
+	MOVD    rkey+16(FP), R2
+	VLD1    (R1), [V0.B16]
+	VLD1    (R2), [V1.B16]
+	VEOR    V2.B16, V2.B16, V2.B16
+	AESE    V2.B16, V0.B16
+	AESMC   V0.B16, V0.B16
+	VEOR    V1.B16, V0.B16, V0.B16
+	VST1    [V0.B16], (R0)
+	RET
@@ -0,0 +1,30 @@
+// +build arm64,!purego,!tinygo
+
+package aes
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+)
+
+const (
+	_AT_HWCAP  = 16
+	_HWCAP_AES = 1 << 3
+)
+
+// detectAES reads AT_HWCAP from the auxiliary vector, as the version of
+// golang.org/x/sys/cpu this module requires knows nothing about arm64.
+func detectAES() bool {
+	auxv, err := ioutil.ReadFile("/proc/self/auxv")
+	if err != nil {
+		return false
+	}
+
+	for i := 0; i+16 <= len(auxv); i += 16 {
+		if binary.LittleEndian.Uint64(auxv[i:]) == _AT_HWCAP {
+			return binary.LittleEndian.Uint64(auxv[i+8:])&_HWCAP_AES != 0
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,11 @@
+// +build arm64,!linux,!purego,!tinygo
+
+package aes
+
+import "runtime"
+
+// detectAES trusts Apple's arm64 chips, which all have the crypto extension.
+// The other systems keep the Go rounds.
+func detectAES() bool {
+	return runtime.GOOS == "darwin" || runtime.GOOS == "ios"
+}
@@ -0,0 +1,55 @@
+// +build arm64,!purego,!tinygo
+
+package aes
+
+import (
+	"math/bits"
+	"math/rand"
+	"testing"
+)
+
+// TestCnRoundsAsm checks the rounds on the crypto extension against the pure Go
+// ones on random keys and blocks, and so does TestCnSingleRoundAsm.
+func TestCnRoundsAsm(t *testing.T) {
+	if !hasAES {
+		t.Skip("host does not support the AES instructions")
+	}
+
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < 1000; i++ {
+		key := []uint64{r.Uint64(), r.Uint64(), r.Uint64(), r.Uint64()}
+		src := []uint64{r.Uint64(), r.Uint64()}
+
+		var rkeys, rbytes [40]uint32
+		expected, dst := make([]uint64, 2), make([]uint64, 2)
+		CnExpandKeyGo(key, &rkeys)
+		CnRoundsGo(expected, src, &rkeys)
+
+		for j, k := range rkeys {
+			rbytes[j] = bits.ReverseBytes32(k)
+		}
+		CnRoundsAsm(&dst[0], &src[0], &rbytes)
+		if dst[0] != expected[0] || dst[1] != expected[1] {
+			t.Errorf("\n[%d] key %x, block %x\nexpected:\n\t%x\ngot:\n\t%x\n", i, key, src, expected, dst)
+		}
+	}
+}
+
+func TestCnSingleRoundAsm(t *testing.T) {
+	if !hasAES {
+		t.Skip("host does not support the AES instructions")
+	}
+
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < 1000; i++ {
+		rkey := [2]uint64{r.Uint64(), r.Uint64()}
+		src := []uint64{r.Uint64(), r.Uint64()}
+
+		expected, dst := make([]uint64, 2), make([]uint64, 2)
+		CnSingleRoundGo(expected, src, &rkey)
+		CnSingleRoundAsm(&dst[0], &src[0], &rkey)
+		if dst[0] != expected[0] || dst[1] != expected[1] {
+			t.Errorf("\n[%d] key %x, block %x\nexpected:\n\t%x\ngot:\n\t%x\n", i, rkey, src, expected, dst)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// +build amd64 arm64
+// +build !purego,!tinygo
+
+package aes
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// TestCnDispatch checks the exported functions on operands that are not 16-byte
+// aligned, with and without the AES instructions of the CPU, in place as the
+// memory-hard loop uses them.
+func TestCnDispatch(t *testing.T) {
+	defer func(v bool) { hasAES = v }(hasAES)
+
+	r := rand.New(rand.NewSource(0))
+	for _, hasAES = range []bool{hasAES, false} {
+		for i := 0; i < 100; i++ {
+			key := make([]uint64, 5)[1:]
+			block := make([]uint64, 3)[1:]
+			for j := range key {
+				key[j] = r.Uint64()
+			}
+			block[0], block[1] = r.Uint64(), r.Uint64()
+			rkey := [2]uint64{r.Uint64(), r.Uint64()}
+
+			var rkeys struct {
+				k     [40]uint32
+				guard [4]uint32 // must not be written by CnExpandKey
+			}
+			expected := make([]uint64, 2)
+			CnExpandKeyGo(key, &rkeys.k)
+			CnRoundsGo(expected, block, &rkeys.k)
+			CnSingleRoundGo(expected, expected, &rkey)
+
+			CnExpandKey(key, &rkeys.k)
+			CnRounds(block, block, &rkeys.k)
+			CnSingleRound(block, block, &rkey)
+			if block[0] != expected[0] || block[1] != expected[1] {
+				t.Errorf("\n[AES %t, %d] expected:\n\t%x\ngot:\n\t%x\n", hasAES, i, expected, block)
+			}
+			if rkeys.guard != [4]uint32{} {
+				t.Fatalf("[AES %t, %d] CnExpandKey wrote past the round keys", hasAES, i)
+			}
+		}
+	}
+}
+
+// TestCnExplode checks CnExplode and CnImplode against CnRoundsGo, the way
+// CNS008 describes them.
+func TestCnExplode(t *testing.T) {
+	defer func(v bool) { hasAES = v }(hasAES)
+
+	r := rand.New(rand.NewSource(0))
+	key := make([]uint64, 4)
+	init := make([]uint64, 16)
+	for i := range key {
+		key[i] = r.Uint64()
+	}
+	for i := range init {
+		init[i] = r.Uint64()
+	}
+
+	var rkeys [40]uint32
+	CnExpandKeyGo(key, &rkeys)
+	expected := make([]uint64, 16*5)
+	blocks := append([]uint64(nil), init...)
+	for i := 0; i < len(expected); i += 16 {
+		for j := 0; j < 16; j += 2 {
+			CnRoundsGo(blocks[j:j+2], blocks[j:j+2], &rkeys)
+		}
+		copy(expected[i:], blocks)
+	}
+	folded := append([]uint64(nil), init...)
+	for i := 0; i < len(expected); i += 16 {
+		for j := 0; j < 16; j += 2 {
+			folded[j] ^= expected[i+j]
+			folded[j+1] ^= expected[i+j+1]
+			CnRoundsGo(folded[j:j+2], folded[j:j+2], &rkeys)
+		}
+	}
+
+	for _, hasAES = range []bool{hasAES, false} {
+		CnExpandKey(key, &rkeys)
+		sp := make([]uint64, len(expected)+1)[1:]
+		blocks := append([]uint64(nil), init...)
+		CnExplode(sp, blocks, &rkeys)
+		if !reflect.DeepEqual(sp, expected) {
+			t.Errorf("AES %t: CnExplode filled\n\t%x\nexpected\n\t%x", hasAES, sp, expected)
+		}
+		if !reflect.DeepEqual(blocks, expected[len(expected)-16:]) {
+			t.Errorf("AES %t: CnExplode left blocks\n\t%x", hasAES, blocks)
+		}
+
+		copy(blocks, init)
+		CnImplode(blocks, sp, &rkeys)
+		if !reflect.DeepEqual(blocks, folded) {
+			t.Errorf("AES %t: CnImplode folded\n\t%x\nexpected\n\t%x", hasAES, blocks, folded)
+		}
+		if !reflect.DeepEqual(sp, expected) {
+			t.Errorf("AES %t: CnImplode changed src", hasAES)
+		}
+	}
+}
@@ -1,5 +1,14 @@
+// +build !aesgen
+
 package aes
 
+// The tables below are precomputed for speed. Their derivation from GF(2^8)
+// arithmetic is spelled out in cn_const_test.go, which checks every entry
+// against FIPS-197. The aesgen build tag computes them at init instead, see
+// cn_const_gen.go.
+
+const tables = "embedded"
+
 // Powers of x mod poly in GF(2).
 var powx = [16]byte{
 	0x01,
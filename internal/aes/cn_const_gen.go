@@ -0,0 +1,67 @@
+// +build aesgen
+
+package aes
+
+import "math/bits"
+
+// The tables are computed at init rather than embedded, which keeps their 9
+// KiB out of the binary for TinyGo and other small targets, at the cost of a
+// few microseconds on startup. cn_const_test.go checks them the same way as
+// the embedded ones.
+
+const tables = "generated"
+
+var (
+	powx                   [16]byte
+	sbox0, sbox1           [256]byte
+	te0, te1, te2, te3     [256]uint32
+	ter0, ter1, ter2, ter3 [256]uint32
+)
+
+func init() {
+	// p runs through the powers of 3, a generator of GF(2^8)*, and q through
+	// those of its inverse, so that q is the inverse of p.
+	p, q := byte(1), byte(1)
+	for {
+		p = xtime(p) ^ p
+		q ^= q << 1
+		q ^= q << 2
+		q ^= q << 4
+		if q&0x80 != 0 {
+			q ^= 0x09
+		}
+		sbox0[p] = q ^ bits.RotateLeft8(q, 1) ^ bits.RotateLeft8(q, 2) ^
+			bits.RotateLeft8(q, 3) ^ bits.RotateLeft8(q, 4) ^ 0x63
+		if p == 1 {
+			break
+		}
+	}
+	sbox0[0] = 0x63 // 0 has no inverse
+
+	for i, s := range sbox0 {
+		sbox1[s] = byte(i)
+		w := uint32(xtime(s))<<24 | uint32(s)<<16 | uint32(s)<<8 | uint32(xtime(s)^s)
+		te0[i], ter0[i] = w, bits.ReverseBytes32(w)
+		w = bits.RotateLeft32(w, -8)
+		te1[i], ter1[i] = w, bits.ReverseBytes32(w)
+		w = bits.RotateLeft32(w, -8)
+		te2[i], ter2[i] = w, bits.ReverseBytes32(w)
+		w = bits.RotateLeft32(w, -8)
+		te3[i], ter3[i] = w, bits.ReverseBytes32(w)
+	}
+
+	x := byte(1)
+	for i := range powx {
+		powx[i] = x
+		x = xtime(x)
+	}
+}
+
+// xtime multiplies b by x in GF(2^8), as per FIPS-197 sec.4.2.1.
+func xtime(b byte) byte {
+	if b&0x80 != 0 {
+		return b<<1 ^ 0x1b
+	}
+
+	return b << 1
+}
@@ -0,0 +1,260 @@
+package aes
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"math/rand"
+	"testing"
+)
+
+// The tables in cn_const.go are embedded for speed. The functions below derive
+// all of them from scratch with GF(2^8) arithmetic, as described in FIPS-197,
+// so that the embedded values can be audited against the specification.
+
+// gfMul multiplies a and b in GF(2^8) modulo x^8 + x^4 + x^3 + x + 1, as per
+// FIPS-197 sec.4.2.
+func gfMul(a, b byte) byte {
+	var p byte
+	for b != 0 {
+		if b&1 != 0 {
+			p ^= a
+		}
+		hi := a & 0x80
+		a <<= 1
+		if hi != 0 {
+			a ^= 0x1b
+		}
+		b >>= 1
+	}
+
+	return p
+}
+
+// gfInv returns the multiplicative inverse of a in GF(2^8), where the inverse
+// of 0 is defined as 0.
+func gfInv(a byte) byte {
+	if a == 0 {
+		return 0
+	}
+
+	// a^254 = a^-1, since the multiplicative group has order 255
+	r := byte(1)
+	for i := 0; i < 254; i++ {
+		r = gfMul(r, a)
+	}
+
+	return r
+}
+
+// deriveSbox computes the S-box as per FIPS-197 sec.5.1.1, i.e. the
+// multiplicative inverse followed by the affine transformation.
+func deriveSbox() (sbox, inv [256]byte) {
+	for i := 0; i < 256; i++ {
+		b := gfInv(byte(i))
+		s := b ^ bits.RotateLeft8(b, 1) ^ bits.RotateLeft8(b, 2) ^
+			bits.RotateLeft8(b, 3) ^ bits.RotateLeft8(b, 4) ^ 0x63
+		sbox[i] = s
+		inv[s] = byte(i)
+	}
+
+	return
+}
+
+// derivePowx computes the powers of x, as used for Rcon in FIPS-197 sec.5.2.
+func derivePowx() (powx [16]byte) {
+	p := byte(1)
+	for i := range powx {
+		powx[i] = p
+		p = gfMul(p, 2)
+	}
+
+	return
+}
+
+// deriveTe computes the combined SubBytes and MixColumns tables, with each
+// column stored in big endian. te[1], te[2] and te[3] are te[0] rotated.
+func deriveTe(sbox *[256]byte) (te [4][256]uint32) {
+	for i := 0; i < 256; i++ {
+		s := sbox[i]
+		w := uint32(gfMul(s, 2))<<24 | uint32(s)<<16 | uint32(s)<<8 | uint32(gfMul(s, 3))
+		for j := 0; j < 4; j++ {
+			te[j][i] = bits.RotateLeft32(w, -8*j)
+		}
+	}
+
+	return
+}
+
+func TestTables(t *testing.T) {
+	sbox, inv := deriveSbox()
+
+	// spot values from FIPS-197 Figure 7, Figure 14 and the example of sec.5.1.1
+	for _, v := range []struct {
+		table    *[256]byte
+		in, want byte
+	}{
+		{&sbox, 0x00, 0x63},
+		{&sbox, 0x01, 0x7c},
+		{&sbox, 0x53, 0xed},
+		{&sbox, 0xff, 0x16},
+		{&inv, 0x00, 0x52},
+		{&inv, 0xed, 0x53},
+		{&inv, 0xff, 0x7d},
+	} {
+		if got := v.table[v.in]; got != v.want {
+			t.Fatalf("derivation broken, expected %#02x for %#02x, got %#02x", v.want, v.in, got)
+		}
+	}
+
+	if sbox != sbox0 {
+		t.Error("sbox0 does not match FIPS-197 S-box")
+	}
+	if inv != sbox1 {
+		t.Error("sbox1 does not match FIPS-197 inverse S-box")
+	}
+	if p := derivePowx(); p != powx {
+		t.Errorf("powx does not match, expected %x, got %x", p, powx)
+	}
+
+	te := deriveTe(&sbox)
+	for j, table := range []*[256]uint32{&te0, &te1, &te2, &te3} {
+		if *table != te[j] {
+			t.Errorf("te%d does not match", j)
+		}
+	}
+
+	// ter are te in little endian, used when the state is loaded as is
+	for j, table := range []*[256]uint32{&ter0, &ter1, &ter2, &ter3} {
+		for i := range table {
+			if table[i] != bits.ReverseBytes32(te[j][i]) {
+				t.Errorf("ter%d does not match at %d", j, i)
+				break
+			}
+		}
+	}
+}
+
+// TestCnExpandKey checks that CnExpandKey produces the first 10 round keys of
+// the standard AES-256 key schedule, as described in CNS008 sec.3.
+func TestCnExpandKey(t *testing.T) {
+	// FIPS-197 Appendix A.3, w[0] to w[39]
+	key := []byte{
+		0x60, 0x3d, 0xeb, 0x10, 0x15, 0xca, 0x71, 0xbe, 0x2b, 0x73, 0xae, 0xf0, 0x85, 0x7d, 0x77, 0x81,
+		0x1f, 0x35, 0x2c, 0x07, 0x3b, 0x61, 0x08, 0xd7, 0x2d, 0x98, 0x10, 0xa3, 0x09, 0x14, 0xdf, 0xf4,
+	}
+	expected := [40]uint32{
+		0x603deb10, 0x15ca71be, 0x2b73aef0, 0x857d7781, 0x1f352c07, 0x3b6108d7, 0x2d9810a3, 0x0914dff4,
+		0x9ba35411, 0x8e6925af, 0xa51a8b5f, 0x2067fcde, 0xa8b09c1a, 0x93d194cd, 0xbe49846e, 0xb75d5b9a,
+		0xd59aecb8, 0x5bf3c917, 0xfee94248, 0xde8ebe96, 0xb5a9328a, 0x2678a647, 0x98312229, 0x2f6c79b3,
+		0x812c81ad, 0xdadf48ba, 0x24360af2, 0xfab8b464, 0x98c5bfc9, 0xbebd198e, 0x268c3ba7, 0x09e04214,
+		0x68007bac, 0xb2df3316, 0x96e939e4, 0x6c518d80, 0xc814e204, 0x76a9fb8a, 0x5025c02d, 0x59c58239,
+	}
+
+	var rkeys [40]uint32
+	CnExpandKeyGo(bytesToWords(key), &rkeys)
+	for i := range expected {
+		if rkeys[i] != expected[i] {
+			t.Errorf("\n[w%d] expected:\n\t%08x\ngot:\n\t%08x\n", i, expected[i], rkeys[i])
+		}
+	}
+}
+
+// refRound performs one standard AES round on state in place: SubBytes,
+// ShiftRows, MixColumns and AddRoundKey, as per FIPS-197 sec.5.1.
+func refRound(state *[16]byte, rkey []byte) {
+	var t [16]byte
+	for c := 0; c < 4; c++ {
+		for r := 0; r < 4; r++ {
+			t[4*c+r] = sbox0[state[4*((c+r)%4)+r]]
+		}
+	}
+
+	for c := 0; c < 4; c++ {
+		a0, a1, a2, a3 := t[4*c], t[4*c+1], t[4*c+2], t[4*c+3]
+		state[4*c+0] = gfMul(a0, 2) ^ gfMul(a1, 3) ^ a2 ^ a3 ^ rkey[4*c+0]
+		state[4*c+1] = a0 ^ gfMul(a1, 2) ^ gfMul(a2, 3) ^ a3 ^ rkey[4*c+1]
+		state[4*c+2] = a0 ^ a1 ^ gfMul(a2, 2) ^ gfMul(a3, 3) ^ rkey[4*c+2]
+		state[4*c+3] = gfMul(a0, 3) ^ a1 ^ a2 ^ gfMul(a3, 2) ^ rkey[4*c+3]
+	}
+}
+
+// TestCnRounds checks the CryptoNight specific AES rounds against a naive
+// byte oriented implementation built from FIPS-197 primitives. As per CNS008
+// sec.3, there are exactly 10 full rounds, without the initial AddRoundKey and
+// without skipping MixColumns in the last round.
+func TestCnRounds(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+
+	for i := 0; i < 100; i++ {
+		key := make([]byte, 32)
+		r.Read(key)
+		var rkeys [40]uint32
+		CnExpandKeyGo(bytesToWords(key), &rkeys)
+
+		var block [16]byte
+		r.Read(block[:])
+		src := bytesToWords(block[:])
+		dst := make([]uint64, 2)
+
+		CnRoundsGo(dst, src, &rkeys)
+		for j := 0; j < 10; j++ {
+			rkey := make([]byte, 16)
+			for k := 0; k < 4; k++ {
+				binary.BigEndian.PutUint32(rkey[4*k:], rkeys[4*j+k])
+			}
+			refRound(&block, rkey)
+		}
+		if expected := bytesToWords(block[:]); dst[0] != expected[0] || dst[1] != expected[1] {
+			t.Fatalf("\n[%d] expected:\n\t%016x\ngot:\n\t%016x\n", i, expected, dst)
+		}
+	}
+}
+
+func TestCnSingleRound(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+
+	for i := 0; i < 100; i++ {
+		var block, rkey [16]byte
+		r.Read(block[:])
+		r.Read(rkey[:])
+		src := bytesToWords(block[:])
+		rkeyWords := bytesToWords(rkey[:])
+		dst := make([]uint64, 2)
+
+		CnSingleRoundGo(dst, src, &[2]uint64{rkeyWords[0], rkeyWords[1]})
+		refRound(&block, rkey[:])
+		if expected := bytesToWords(block[:]); dst[0] != expected[0] || dst[1] != expected[1] {
+			t.Fatalf("\n[%d] expected:\n\t%016x\ngot:\n\t%016x\n", i, expected, dst)
+		}
+	}
+}
+
+// bytesToWords loads b as little endian uint64s, which is how CryptoNight
+// stores its state.
+func bytesToWords(b []byte) []uint64 {
+	w := make([]uint64, len(b)/8)
+	for i := range w {
+		w[i] = binary.LittleEndian.Uint64(b[8*i:])
+	}
+
+	return w
+}
+
+func BenchmarkCnRoundsGo(b *testing.B) {
+	var rkeys [40]uint32
+	CnExpandKeyGo([]uint64{1, 2, 3, 4}, &rkeys)
+	blocks := make([]uint64, 2)
+	b.SetBytes(16)
+	for i := 0; i < b.N; i++ {
+		CnRoundsGo(blocks, blocks, &rkeys)
+	}
+}
+
+func BenchmarkCnSingleRoundGo(b *testing.B) {
+	rkey := [2]uint64{1, 2}
+	blocks := make([]uint64, 2)
+	b.SetBytes(16)
+	for i := 0; i < b.N; i++ {
+		CnSingleRoundGo(blocks, blocks, &rkey)
+	}
+}
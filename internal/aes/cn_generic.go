@@ -0,0 +1,25 @@
+// +build !amd64,!arm64 purego tinygo
+
+package aes
+
+func backend() string { return "go" }
+
+func cnExpandKey(key []uint64, rkeys *[40]uint32) {
+	CnExpandKeyGo(key, rkeys)
+}
+
+func cnRounds(dst, src []uint64, rkeys *[40]uint32) {
+	CnRoundsGo(dst, src, rkeys)
+}
+
+func cnSingleRound(dst, src []uint64, rkey *[2]uint64) {
+	CnSingleRoundGo(dst, src, rkey)
+}
+
+func cnExplode(dst, blocks []uint64, rkeys *[40]uint32) {
+	explodeRounds(dst, blocks, rkeys)
+}
+
+func cnImplode(blocks, src []uint64, rkeys *[40]uint32) {
+	implodeRounds(blocks, src, rkeys)
+}
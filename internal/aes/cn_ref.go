@@ -2,7 +2,6 @@ package aes
 
 import (
 	"math/bits"
-	"unsafe"
 )
 
 func CnExpandKeyGo(key []uint64, rkeys *[40]uint32) {
@@ -22,16 +21,15 @@ func CnExpandKeyGo(key []uint64, rkeys *[40]uint32) {
 	}
 }
 
+// CnRoundsGo is the pure Go version of CnRounds. The blocks are kept as
+// little endian words, so no byte view of them is needed.
 func CnRoundsGo(dst, src []uint64, rkeys *[40]uint32) {
-	src8 := (*[16]byte)(unsafe.Pointer(&src[0]))
-	dst8 := (*[16]byte)(unsafe.Pointer(&dst[0]))
-
 	var s0, s1, s2, s3, t0, t1, t2, t3 uint32
 
-	s0 = uint32(src8[0])<<24 | uint32(src8[1])<<16 | uint32(src8[2])<<8 | uint32(src8[3])
-	s1 = uint32(src8[4])<<24 | uint32(src8[5])<<16 | uint32(src8[6])<<8 | uint32(src8[7])
-	s2 = uint32(src8[8])<<24 | uint32(src8[9])<<16 | uint32(src8[10])<<8 | uint32(src8[11])
-	s3 = uint32(src8[12])<<24 | uint32(src8[13])<<16 | uint32(src8[14])<<8 | uint32(src8[15])
+	s0 = bits.ReverseBytes32(uint32(src[0]))
+	s1 = bits.ReverseBytes32(uint32(src[0] >> 32))
+	s2 = bits.ReverseBytes32(uint32(src[1]))
+	s3 = bits.ReverseBytes32(uint32(src[1] >> 32))
 
 	for r := 0; r < 10; r++ {
 		t0 = rkeys[4*r+0] ^ te0[uint8(s0>>24)] ^ te1[uint8(s1>>16)] ^ te2[uint8(s2>>8)] ^ te3[uint8(s3)]
@@ -41,28 +39,25 @@ func CnRoundsGo(dst, src []uint64, rkeys *[40]uint32) {
 		s0, s1, s2, s3 = t0, t1, t2, t3
 	}
 
-	dst8[0], dst8[1], dst8[2], dst8[3] = byte(s0>>24), byte(s0>>16), byte(s0>>8), byte(s0)
-	dst8[4], dst8[5], dst8[6], dst8[7] = byte(s1>>24), byte(s1>>16), byte(s1>>8), byte(s1)
-	dst8[8], dst8[9], dst8[10], dst8[11] = byte(s2>>24), byte(s2>>16), byte(s2>>8), byte(s2)
-	dst8[12], dst8[13], dst8[14], dst8[15] = byte(s3>>24), byte(s3>>16), byte(s3>>8), byte(s3)
+	dst[0] = uint64(bits.ReverseBytes32(s0)) | uint64(bits.ReverseBytes32(s1))<<32
+	dst[1] = uint64(bits.ReverseBytes32(s2)) | uint64(bits.ReverseBytes32(s3))<<32
 }
 
+// CnSingleRoundGo is the pure Go version of CnSingleRound.
 func CnSingleRoundGo(dst, src []uint64, rkey *[2]uint64) {
-	src8 := (*[16]byte)(unsafe.Pointer(&src[0]))
-	dst8 := (*[16]byte)(unsafe.Pointer(&dst[0]))
-	rkey32 := (*[4]uint32)(unsafe.Pointer(&rkey[0]))
-
 	var t0, t1, t2, t3 uint32
 
-	t0 = rkey32[0] ^ ter0[src8[0]] ^ ter1[src8[5]] ^ ter2[src8[10]] ^ ter3[src8[15]]
-	t1 = rkey32[1] ^ ter0[src8[4]] ^ ter1[src8[9]] ^ ter2[src8[14]] ^ ter3[src8[3]]
-	t2 = rkey32[2] ^ ter0[src8[8]] ^ ter1[src8[13]] ^ ter2[src8[2]] ^ ter3[src8[7]]
-	t3 = rkey32[3] ^ ter0[src8[12]] ^ ter1[src8[1]] ^ ter2[src8[6]] ^ ter3[src8[11]]
+	_, _ = src[1], dst[1] // bounds check hint
+	s0, s1 := uint32(src[0]), uint32(src[0]>>32)
+	s2, s3 := uint32(src[1]), uint32(src[1]>>32)
+
+	t0 = uint32(rkey[0]) ^ ter0[uint8(s0)] ^ ter1[uint8(s1>>8)] ^ ter2[uint8(s2>>16)] ^ ter3[uint8(s3>>24)]
+	t1 = uint32(rkey[0]>>32) ^ ter0[uint8(s1)] ^ ter1[uint8(s2>>8)] ^ ter2[uint8(s3>>16)] ^ ter3[uint8(s0>>24)]
+	t2 = uint32(rkey[1]) ^ ter0[uint8(s2)] ^ ter1[uint8(s3>>8)] ^ ter2[uint8(s0>>16)] ^ ter3[uint8(s1>>24)]
+	t3 = uint32(rkey[1]>>32) ^ ter0[uint8(s3)] ^ ter1[uint8(s0>>8)] ^ ter2[uint8(s1>>16)] ^ ter3[uint8(s2>>24)]
 
-	dst8[0], dst8[1], dst8[2], dst8[3] = byte(t0), byte(t0>>8), byte(t0>>16), byte(t0>>24)
-	dst8[4], dst8[5], dst8[6], dst8[7] = byte(t1), byte(t1>>8), byte(t1>>16), byte(t1>>24)
-	dst8[8], dst8[9], dst8[10], dst8[11] = byte(t2), byte(t2>>8), byte(t2>>16), byte(t2>>24)
-	dst8[12], dst8[13], dst8[14], dst8[15] = byte(t3), byte(t3>>8), byte(t3>>16), byte(t3>>24)
+	dst[0] = uint64(t0) | uint64(t1)<<32
+	dst[1] = uint64(t2) | uint64(t3)<<32
 }
 
 // Apply sbox0 to each byte in w.
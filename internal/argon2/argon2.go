@@ -0,0 +1,328 @@
+// Package argon2 implements Argon2id of RFC 9106, version 1.3, as used by the
+// Chukwa algorithms of TurtleCoin, over memory owned by the caller.
+//
+// It is written after the reference implementation, with the lanes computed
+// one after the other, so it only suits the small memory and single lane of
+// the hashes of proof of work, not password hashing at scale.
+package argon2 // import "ekyu.moe/cryptonight/internal/argon2"
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// BlockWords is the size of a block of memory in 64-bit words, i.e. 1 KiB.
+const BlockWords = 128
+
+const (
+	version     = 0x13
+	syncPoints  = 4 // slices of a pass
+	addrPerLoad = BlockWords
+)
+
+// modes of Argon2
+const (
+	argon2d  = 0
+	argon2i  = 1
+	argon2id = 2
+)
+
+// IDKey derives len(out) bytes from password and salt with Argon2id, in time
+// passes over mem and threads lanes. The memory size is len(mem)/BlockWords
+// KiB, rounded down to a multiple of 4*threads, and must be at least
+// 8*threads KiB.
+func IDKey(out, password, salt []byte, time uint32, mem []uint64, threads uint32) {
+	deriveKey(out, password, salt, nil, nil, time, mem, threads, argon2id)
+}
+
+func deriveKey(out, password, salt, secret, data []byte, time uint32, mem []uint64, threads uint32, mode int) {
+	if time < 1 {
+		panic("argon2: number of rounds too small")
+	}
+	if threads < 1 {
+		panic("argon2: parallelism degree too low")
+	}
+	memory := uint32(len(mem)/BlockWords) / (syncPoints * threads) * (syncPoints * threads)
+	if memory < 2*syncPoints*threads {
+		panic("argon2: memory too small")
+	}
+	B := mem[:memory*BlockWords]
+
+	var h0 [blake2bSize + 8]byte
+	initHash(&h0, out, password, salt, secret, data, time, memory, threads, mode)
+	initBlocks(&h0, B, threads)
+	processBlocks(B, time, memory, threads, mode)
+	extractKey(out, B, memory, threads)
+}
+
+const blake2bSize = 64
+
+// blockAt returns the block of B at index i.
+func blockAt(B []uint64, i uint32) []uint64 {
+	return B[i*BlockWords : (i+1)*BlockWords]
+}
+
+// initHash writes H0 into the first 64 bytes of h0.
+func initHash(h0 *[blake2bSize + 8]byte, out, password, salt, secret, data []byte, time, memory, threads uint32, mode int) {
+	d := newBlake2b(blake2bSize)
+	d.writeUint32(threads)
+	d.writeUint32(uint32(len(out)))
+	d.writeUint32(memory)
+	d.writeUint32(time)
+	d.writeUint32(version)
+	d.writeUint32(uint32(mode))
+	for _, b := range [][]byte{password, salt, secret, data} {
+		d.writeUint32(uint32(len(b)))
+		d.Write(b)
+	}
+	d.sum(h0[:blake2bSize])
+}
+
+// initBlocks computes the first two blocks of every lane from H0.
+func initBlocks(h0 *[blake2bSize + 8]byte, B []uint64, threads uint32) {
+	laneLength := uint32(len(B)/BlockWords) / threads
+	var buf [1024]byte
+	for lane := uint32(0); lane < threads; lane++ {
+		for i := uint32(0); i < 2; i++ {
+			binary.LittleEndian.PutUint32(h0[blake2bSize:], i)
+			binary.LittleEndian.PutUint32(h0[blake2bSize+4:], lane)
+			hashLong(buf[:], h0[:])
+			b := blockAt(B, lane*laneLength+i)
+			for j := range b {
+				b[j] = binary.LittleEndian.Uint64(buf[8*j:])
+			}
+		}
+	}
+}
+
+func processBlocks(B []uint64, time, memory, threads uint32, mode int) {
+	laneLength := memory / threads
+	segmentLength := laneLength / syncPoints
+
+	for n := uint32(0); n < time; n++ {
+		for slice := uint32(0); slice < syncPoints; slice++ {
+			for lane := uint32(0); lane < threads; lane++ {
+				processSegment(B, n, slice, lane, time, memory, threads, laneLength, segmentLength, mode)
+			}
+		}
+	}
+}
+
+// processSegment fills one segment, the blocks of slice in lane during pass n.
+func processSegment(B []uint64, n, slice, lane, time, memory, threads, laneLength, segmentLength uint32, mode int) {
+	var addresses, in, zero [BlockWords]uint64
+	independent := mode == argon2i || mode == argon2id && n == 0 && slice < syncPoints/2
+	if independent {
+		in[0] = uint64(n)
+		in[1] = uint64(lane)
+		in[2] = uint64(slice)
+		in[3] = uint64(memory)
+		in[4] = uint64(time)
+		in[5] = uint64(mode)
+	}
+
+	index := uint32(0)
+	if n == 0 && slice == 0 {
+		index = 2 // the first two blocks are set by initBlocks
+		if independent {
+			in[6]++
+			processBlock(addresses[:], in[:], zero[:])
+			processBlock(addresses[:], addresses[:], zero[:])
+		}
+	}
+
+	offset := lane*laneLength + slice*segmentLength + index
+	for index < segmentLength {
+		prev := offset - 1
+		if index == 0 && slice == 0 {
+			prev += laneLength // last block of the lane
+		}
+
+		var random uint64
+		if independent {
+			if index%addrPerLoad == 0 {
+				in[6]++
+				processBlock(addresses[:], in[:], zero[:])
+				processBlock(addresses[:], addresses[:], zero[:])
+			}
+			random = addresses[index%addrPerLoad]
+		} else {
+			random = B[prev*BlockWords]
+		}
+
+		refLane := uint32(random>>32) % threads
+		if n == 0 && slice == 0 {
+			refLane = lane
+		}
+		refIndex := indexAlpha(random, laneLength, segmentLength, n, slice, index, refLane == lane)
+
+		ref := blockAt(B, refLane*laneLength+refIndex)
+		if n == 0 {
+			processBlock(blockAt(B, offset), blockAt(B, prev), ref)
+		} else {
+			processBlockXOR(blockAt(B, offset), blockAt(B, prev), ref)
+		}
+		index, offset = index+1, offset+1
+	}
+}
+
+// indexAlpha maps the 32 lowest bits of random to the index in its lane of the
+// block referenced by the block at index.
+func indexAlpha(random uint64, laneLength, segmentLength, n, slice, index uint32, sameLane bool) uint32 {
+	var area uint32
+	switch {
+	case n == 0 && sameLane:
+		area = slice*segmentLength + index - 1
+	case n == 0:
+		area = slice * segmentLength
+		if index == 0 {
+			area--
+		}
+	case sameLane:
+		area = laneLength - segmentLength + index - 1
+	default:
+		area = laneLength - segmentLength
+		if index == 0 {
+			area--
+		}
+	}
+
+	var start uint32
+	if n != 0 && slice != syncPoints-1 {
+		start = (slice + 1) * segmentLength
+	}
+
+	x := random & 0xffffffff
+	x = x * x >> 32
+	y := uint64(area) * x >> 32
+	pos := uint64(area) - 1 - y
+
+	return uint32((uint64(start) + pos) % uint64(laneLength))
+}
+
+// extractKey writes the hash of the XOR of the last blocks of all lanes into
+// out.
+func extractKey(out []byte, B []uint64, memory, threads uint32) {
+	laneLength := memory / threads
+	var final [BlockWords]uint64
+	copy(final[:], blockAt(B, laneLength-1))
+	for lane := uint32(1); lane < threads; lane++ {
+		b := blockAt(B, lane*laneLength+laneLength-1)
+		for i := range final {
+			final[i] ^= b[i]
+		}
+	}
+
+	var buf [1024]byte
+	for i, v := range final {
+		binary.LittleEndian.PutUint64(buf[8*i:], v)
+	}
+	hashLong(out, buf[:])
+}
+
+// hashLong is the variable length hash function H' of Argon2, filling out.
+func hashLong(out, in []byte) {
+	if len(out) <= blake2bSize {
+		d := newBlake2b(len(out))
+		d.writeUint32(uint32(len(out)))
+		d.Write(in)
+		d.sum(out)
+		return
+	}
+
+	var v [blake2bSize]byte
+	d := newBlake2b(blake2bSize)
+	d.writeUint32(uint32(len(out)))
+	d.Write(in)
+	d.sum(v[:])
+	n := copy(out, v[:32])
+	for len(out)-n > blake2bSize {
+		d = newBlake2b(blake2bSize)
+		d.Write(v[:])
+		d.sum(v[:])
+		n += copy(out[n:], v[:32])
+	}
+	d = newBlake2b(len(out) - n)
+	d.Write(v[:])
+	d.sum(out[n:])
+}
+
+// processBlock sets out to the compression G of x and y. out may be x.
+func processBlock(out, x, y []uint64) {
+	var r [BlockWords]uint64
+	for i := range r {
+		r[i] = x[i] ^ y[i]
+	}
+	q := r
+	blamkaBlock(&q)
+	for i := range out {
+		out[i] = q[i] ^ r[i]
+	}
+}
+
+// processBlockXOR xors the compression G of x and y into out, as version 1.3
+// does on the passes after the first one.
+func processBlockXOR(out, x, y []uint64) {
+	var r [BlockWords]uint64
+	for i := range r {
+		r[i] = x[i] ^ y[i]
+	}
+	q := r
+	blamkaBlock(&q)
+	for i := range out {
+		out[i] ^= q[i] ^ r[i]
+	}
+}
+
+// blamkaBlock applies the permutation P to the rows of b, seen as 8x8 words
+// of 16 bytes, then to its columns.
+func blamkaBlock(b *[BlockWords]uint64) {
+	for i := 0; i < 128; i += 16 {
+		blamka(
+			&b[i+0], &b[i+1], &b[i+2], &b[i+3], &b[i+4], &b[i+5], &b[i+6], &b[i+7],
+			&b[i+8], &b[i+9], &b[i+10], &b[i+11], &b[i+12], &b[i+13], &b[i+14], &b[i+15],
+		)
+	}
+	for i := 0; i < 16; i += 2 {
+		blamka(
+			&b[i], &b[i+1], &b[i+16], &b[i+17], &b[i+32], &b[i+33], &b[i+48], &b[i+49],
+			&b[i+64], &b[i+65], &b[i+80], &b[i+81], &b[i+96], &b[i+97], &b[i+112], &b[i+113],
+		)
+	}
+}
+
+// blamka is the permutation P, a round of BLAKE2b with the additions replaced
+// by fBlaMka.
+func blamka(t00, t01, t02, t03, t04, t05, t06, t07, t08, t09, t10, t11, t12, t13, t14, t15 *uint64) {
+	v00, v01, v02, v03 := *t00, *t01, *t02, *t03
+	v04, v05, v06, v07 := *t04, *t05, *t06, *t07
+	v08, v09, v10, v11 := *t08, *t09, *t10, *t11
+	v12, v13, v14, v15 := *t12, *t13, *t14, *t15
+
+	v00, v04, v08, v12 = gb(v00, v04, v08, v12)
+	v01, v05, v09, v13 = gb(v01, v05, v09, v13)
+	v02, v06, v10, v14 = gb(v02, v06, v10, v14)
+	v03, v07, v11, v15 = gb(v03, v07, v11, v15)
+	v00, v05, v10, v15 = gb(v00, v05, v10, v15)
+	v01, v06, v11, v12 = gb(v01, v06, v11, v12)
+	v02, v07, v08, v13 = gb(v02, v07, v08, v13)
+	v03, v04, v09, v14 = gb(v03, v04, v09, v14)
+
+	*t00, *t01, *t02, *t03 = v00, v01, v02, v03
+	*t04, *t05, *t06, *t07 = v04, v05, v06, v07
+	*t08, *t09, *t10, *t11 = v08, v09, v10, v11
+	*t12, *t13, *t14, *t15 = v12, v13, v14, v15
+}
+
+func gb(a, b, c, d uint64) (uint64, uint64, uint64, uint64) {
+	a += b + 2*uint64(uint32(a))*uint64(uint32(b))
+	d = bits.RotateLeft64(d^a, -32)
+	c += d + 2*uint64(uint32(c))*uint64(uint32(d))
+	b = bits.RotateLeft64(b^c, -24)
+	a += b + 2*uint64(uint32(a))*uint64(uint32(b))
+	d = bits.RotateLeft64(d^a, -16)
+	c += d + 2*uint64(uint32(c))*uint64(uint32(d))
+	b = bits.RotateLeft64(b^c, -63)
+
+	return a, b, c, d
+}
@@ -0,0 +1,74 @@
+package argon2
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestBlake2b(t *testing.T) {
+	for i, v := range []struct {
+		size   int
+		input  string
+		output string
+	}{
+		// From RFC 7693, appendix A
+		{64, "abc", "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923"},
+		{32, "", "0e5751c026e543b2e8ab2eb06099daa1d1e5df47778f7787faab45cdf12fe3a8"},
+	} {
+		d := newBlake2b(v.size)
+		d.Write([]byte(v.input))
+		out := make([]byte, v.size)
+		d.sum(out)
+		if hex.EncodeToString(out) != v.output {
+			t.Errorf("[%d] expected %s, got %x", i, v.output, out)
+		}
+	}
+
+	// a long input, in writes of every size across the block boundaries
+	in := make([]byte, 1000)
+	for i := range in {
+		in[i] = byte(i)
+	}
+	d := newBlake2b(64)
+	d.Write(in)
+	var expected [64]byte
+	d.sum(expected[:])
+	for n := 1; n < 300; n++ {
+		d := newBlake2b(64)
+		for i := 0; i < len(in); i += n {
+			end := i + n
+			if end > len(in) {
+				end = len(in)
+			}
+			d.Write(in[i:end])
+		}
+		var out [64]byte
+		d.sum(out[:])
+		if out != expected {
+			t.Fatalf("writes of %d bytes: expected %x, got %x", n, expected, out)
+		}
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	// From RFC 9106, section 5
+	password := bytes.Repeat([]byte{0x01}, 32)
+	salt := bytes.Repeat([]byte{0x02}, 16)
+	secret := bytes.Repeat([]byte{0x03}, 8)
+	data := bytes.Repeat([]byte{0x04}, 12)
+	for _, v := range []struct {
+		mode int
+		tag  string
+	}{
+		{argon2d, "512b391b6f1162975371d30919734294f868e3be3984f3c1a13a4db9fabe4acb"},
+		{argon2i, "c814d9d1dc7f37aa13f0d77f2494bda1c8de6b016dd388d29952a4c4672b6ce8"},
+		{argon2id, "0d640df58d78766c08c037a34a8b53c9d01ef0452d75b65eb52520e96b01e659"},
+	} {
+		out := make([]byte, 32)
+		deriveKey(out, password, salt, secret, data, 3, make([]uint64, 32*BlockWords), 4, v.mode)
+		if hex.EncodeToString(out) != v.tag {
+			t.Errorf("[mode %d] expected %s, got %x", v.mode, v.tag, out)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package argon2
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// blake2bBlockSize is the block size of BLAKE2b in bytes.
+const blake2bBlockSize = 128
+
+var blake2bIV = [8]uint64{
+	0x6a09e667f3bcc908, 0xbb67ae8584caa73b, 0x3c6ef372fe94f82b, 0xa54ff53a5f1d36f1,
+	0x510e527fade682d1, 0x9b05688c2b3e6c1f, 0x1f83d9abfb41bd6b, 0x5be0cd19137e2179,
+}
+
+var blake2bSigma = [12][16]uint8{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+	{11, 8, 12, 0, 5, 2, 15, 13, 10, 14, 3, 6, 7, 1, 9, 4},
+	{7, 9, 3, 1, 13, 12, 11, 14, 2, 6, 5, 10, 4, 0, 15, 8},
+	{9, 0, 5, 7, 2, 4, 10, 15, 14, 1, 11, 12, 6, 8, 3, 13},
+	{2, 12, 6, 10, 0, 11, 8, 3, 4, 13, 7, 5, 15, 14, 1, 9},
+	{12, 5, 1, 15, 14, 13, 4, 10, 0, 7, 6, 3, 9, 2, 8, 11},
+	{13, 11, 7, 14, 12, 1, 3, 9, 5, 0, 15, 4, 8, 6, 2, 10},
+	{6, 15, 14, 9, 11, 3, 0, 8, 12, 2, 13, 7, 1, 4, 10, 5},
+	{10, 2, 8, 4, 7, 6, 1, 5, 15, 11, 9, 14, 3, 12, 13, 0},
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+}
+
+// blake2b is unkeyed BLAKE2b of RFC 7693, with a digest of 1 to 64 bytes,
+// which is all Argon2 needs of it. Its zero value is not usable, see
+// newBlake2b.
+type blake2b struct {
+	h    [8]uint64
+	t    uint64 // number of bytes hashed so far
+	size int
+
+	buf    [blake2bBlockSize]byte
+	bufPtr int
+}
+
+func newBlake2b(size int) blake2b {
+	d := blake2b{h: blake2bIV, size: size}
+	d.h[0] ^= 0x01010000 ^ uint64(size)
+
+	return d
+}
+
+func (d *blake2b) Write(data []byte) {
+	if d.bufPtr > 0 {
+		c := copy(d.buf[d.bufPtr:], data)
+		d.bufPtr += c
+		data = data[c:]
+		if d.bufPtr < blake2bBlockSize || len(data) == 0 {
+			return
+		}
+		d.t += blake2bBlockSize
+		d.compress(d.buf[:], false)
+		d.bufPtr = 0
+	}
+	for len(data) > blake2bBlockSize {
+		d.t += blake2bBlockSize
+		d.compress(data[:blake2bBlockSize], false)
+		data = data[blake2bBlockSize:]
+	}
+	// the last block is kept even when full, as it may be the final one
+	d.bufPtr = copy(d.buf[:], data)
+}
+
+// writeUint32 writes v in little endian, as Argon2 encodes its lengths.
+func (d *blake2b) writeUint32(v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	d.Write(b[:])
+}
+
+// sum writes the digest into out, which must be d.size bytes long. d must not
+// be used afterwards.
+func (d *blake2b) sum(out []byte) {
+	for i := d.bufPtr; i < blake2bBlockSize; i++ {
+		d.buf[i] = 0
+	}
+	d.t += uint64(d.bufPtr)
+	d.compress(d.buf[:], true)
+
+	var b [64]byte
+	for i, v := range d.h {
+		binary.LittleEndian.PutUint64(b[8*i:], v)
+	}
+	copy(out, b[:d.size])
+}
+
+// compress processes one block of 128 bytes, with the counter d.t.
+func (d *blake2b) compress(block []byte, last bool) {
+	var m [16]uint64
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint64(block[8*i:])
+	}
+
+	v0, v1, v2, v3, v4, v5, v6, v7 := d.h[0], d.h[1], d.h[2], d.h[3], d.h[4], d.h[5], d.h[6], d.h[7]
+	v8, v9, v10, v11 := blake2bIV[0], blake2bIV[1], blake2bIV[2], blake2bIV[3]
+	v12, v13, v14, v15 := blake2bIV[4], blake2bIV[5], blake2bIV[6], blake2bIV[7]
+	v12 ^= d.t // the counter never exceeds 64 bits here
+	if last {
+		v14 = ^v14
+	}
+
+	for r := 0; r < 12; r++ {
+		// the indices are masked so that no bounds check is needed
+		s := &blake2bSigma[r]
+		// columns
+		v0, v4, v8, v12 = g(v0, v4, v8, v12, m[s[0]&15], m[s[1]&15])
+		v1, v5, v9, v13 = g(v1, v5, v9, v13, m[s[2]&15], m[s[3]&15])
+		v2, v6, v10, v14 = g(v2, v6, v10, v14, m[s[4]&15], m[s[5]&15])
+		v3, v7, v11, v15 = g(v3, v7, v11, v15, m[s[6]&15], m[s[7]&15])
+		// diagonals
+		v0, v5, v10, v15 = g(v0, v5, v10, v15, m[s[8]&15], m[s[9]&15])
+		v1, v6, v11, v12 = g(v1, v6, v11, v12, m[s[10]&15], m[s[11]&15])
+		v2, v7, v8, v13 = g(v2, v7, v8, v13, m[s[12]&15], m[s[13]&15])
+		v3, v4, v9, v14 = g(v3, v4, v9, v14, m[s[14]&15], m[s[15]&15])
+	}
+
+	d.h[0] ^= v0 ^ v8
+	d.h[1] ^= v1 ^ v9
+	d.h[2] ^= v2 ^ v10
+	d.h[3] ^= v3 ^ v11
+	d.h[4] ^= v4 ^ v12
+	d.h[5] ^= v5 ^ v13
+	d.h[6] ^= v6 ^ v14
+	d.h[7] ^= v7 ^ v15
+}
+
+// g is the mixing function G of BLAKE2b.
+func g(a, b, c, d, x, y uint64) (uint64, uint64, uint64, uint64) {
+	a += b + x
+	d = bits.RotateLeft64(d^a, -32)
+	c += d
+	b = bits.RotateLeft64(b^c, -24)
+	a += b + y
+	d = bits.RotateLeft64(d^a, -16)
+	c += d
+	b = bits.RotateLeft64(b^c, -63)
+
+	return a, b, c, d
+}
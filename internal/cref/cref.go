@@ -0,0 +1,19 @@
+// +build cnref,cgo
+
+// Package cref links the reference implementation of CryptoNight from monero,
+// for differential testing only.
+//
+// It is enabled by the cnref build tag and requires libcncrypto, or any
+// library providing cn_slow_hash with the signature of monero v0.14 or later,
+// to be passed via CGO_LDFLAGS. For example:
+// CGO_LDFLAGS="-L/path/to/monero/build/src/crypto -lcncrypto" go test -tags cnref
+//
+// The cn_slow_hash of monero v0.13 has no height parameter, and thus no
+// variant 4. It can be linked with the cnref013 tag in addition, and
+// HasHeight then reports false.
+package cref // import "ekyu.moe/cryptonight/internal/cref"
+
+// Sum calculates the hash with monero's cn_slow_hash, at height 0.
+func Sum(data []byte, variant int) []byte {
+	return SumHeight(data, variant, 0)
+}
@@ -0,0 +1,31 @@
+// +build cnref,cgo,!cnref013
+
+package cref
+
+/*
+#include <stddef.h>
+#include <stdint.h>
+
+void cn_slow_hash(const void *data, size_t length, char *hash, int variant, int prehashed, uint64_t height);
+*/
+import "C"
+
+import (
+	"unsafe"
+)
+
+// HasHeight reports whether the linked cn_slow_hash takes the block height,
+// which variant 4 depends on.
+const HasHeight = true
+
+// SumHeight calculates the hash with monero's cn_slow_hash at height.
+func SumHeight(data []byte, variant int, height uint64) []byte {
+	sum := make([]byte, 32)
+	var p unsafe.Pointer
+	if len(data) > 0 {
+		p = unsafe.Pointer(&data[0])
+	}
+	C.cn_slow_hash(p, C.size_t(len(data)), (*C.char)(unsafe.Pointer(&sum[0])), C.int(variant), 0, C.uint64_t(height))
+
+	return sum
+}
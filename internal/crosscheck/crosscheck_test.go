@@ -0,0 +1,89 @@
+// +build cnref,cgo
+
+package crosscheck
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"ekyu.moe/cryptonight"
+	"ekyu.moe/cryptonight/internal/cref"
+)
+
+var (
+	count = flag.Int("crosscheck.n", 200, "number of random inputs per algorithm")
+	seed  = flag.Int64("crosscheck.seed", 0, "seed of the random inputs, 0 for the current time")
+)
+
+// refVariants are the variants of cn_slow_hash computing the algorithms it
+// implements.
+var refVariants = map[cryptonight.Algorithm]int{
+	cryptonight.CNv0: 0,
+	cryptonight.CNv1: 1,
+	cryptonight.CNv2: 2,
+	cryptonight.CNR:  4,
+}
+
+func TestCrossCheck(t *testing.T) {
+	s := *seed
+	if s == 0 {
+		s = time.Now().UnixNano()
+	}
+	t.Logf("-crosscheck.seed %d", s)
+	r := rand.New(rand.NewSource(s))
+
+	for algo := cryptonight.CNv0; algo <= cryptonight.CNPico; algo++ {
+		variant, ok := refVariants[algo]
+		t.Run(algo.String(), func(t *testing.T) {
+			if !ok {
+				t.Skip("not implemented by cn_slow_hash")
+			}
+			if variant == 4 && !cref.HasHeight {
+				t.Skip("cn_slow_hash has no height, see the cnref013 tag")
+			}
+			crossCheck(t, r, algo, variant)
+		})
+	}
+}
+
+func crossCheck(t *testing.T, r *rand.Rand, algo cryptonight.Algorithm, variant int) {
+	cc := new(cryptonight.Cache)
+	for i := 0; i < *count; i++ {
+		data := make([]byte, r.Intn(256))
+		for cryptonight.ValidateAlgorithm(data, algo) != nil {
+			data = make([]byte, 43+r.Intn(213))
+		}
+		r.Read(data)
+		height := uint64(r.Int63n(1 << 32))
+
+		expected := cref.SumHeight(data, variant, height)
+		for name, sum := range map[string]func() []byte{
+			"SumAlgorithm": func() []byte { return cryptonight.SumAlgorithm(data, algo, height) },
+			"Cache.SumInto": func() []byte {
+				var dst [32]byte
+				cc.SumInto(&dst, data, algo, height)
+				return dst[:]
+			},
+		} {
+			if result, err := call(sum); err != nil || !bytes.Equal(result, expected) {
+				t.Fatalf("\n%s at height %d on %x\nexpected:\n\t%x\ngot:\n\t%x, %v\n", name, height, data, expected, result, err)
+			}
+		}
+	}
+}
+
+// call calls sum, turning a panic into an error, as package cryptonight
+// panics itself on a mismatch of variants 0 to 2 with the cnref tag.
+func call(sum func() []byte) (result []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return sum(), nil
+}
@@ -0,0 +1,11 @@
+// Package crosscheck holds the differential test of package cryptonight
+// against the reference implementation of monero, and has no API.
+//
+// The test is only built with the cnref tag and cgo, linking cn_slow_hash as
+// described in package ekyu.moe/cryptonight/internal/cref. For every
+// algorithm the reference implements, it hashes random inputs, and random
+// heights for CNR, through both and fails on any mismatch. The other
+// algorithms are skipped.
+//
+// CGO_LDFLAGS="-L/path/to/monero/build/src/crypto -lcncrypto" go test -tags cnref ./internal/crosscheck -crosscheck.n 10000
+package crosscheck // import "ekyu.moe/cryptonight/internal/crosscheck"
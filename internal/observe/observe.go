@@ -0,0 +1,59 @@
+// Package observe holds the Observer set by cryptonight.SetObserver, so that
+// every package of this module can report events to it.
+package observe // import "ekyu.moe/cryptonight/internal/observe"
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Observer has the same methods as cryptonight.Observer, see there.
+type Observer interface {
+	OnHashDone(variant int, elapsed time.Duration)
+	OnShareFound(hash []byte, target uint64)
+	OnError(err error)
+}
+
+// holder allows storing a nil Observer in an atomic.Value.
+type holder struct {
+	o Observer
+}
+
+var current atomic.Value
+
+// Set sets the Observer, or removes it if o is nil.
+func Set(o Observer) {
+	current.Store(holder{o})
+}
+
+func get() Observer {
+	h, _ := current.Load().(holder)
+	return h.o
+}
+
+// Enabled reports whether an Observer is set, so that callers can skip
+// preparing events nobody receives.
+func Enabled() bool {
+	return get() != nil
+}
+
+// HashDone reports a computed hash.
+func HashDone(variant int, elapsed time.Duration) {
+	if o := get(); o != nil {
+		o.OnHashDone(variant, elapsed)
+	}
+}
+
+// ShareFound reports a hash meeting its target difficulty.
+func ShareFound(hash []byte, target uint64) {
+	if o := get(); o != nil {
+		o.OnShareFound(hash, target)
+	}
+}
+
+// Error reports an error. A nil err is ignored.
+func Error(err error) {
+	if o := get(); o != nil && err != nil {
+		o.OnError(err)
+	}
+}
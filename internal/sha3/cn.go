@@ -1,12 +1,36 @@
 package sha3
 
+import "encoding/binary"
+
+// Keccak1600State sets st to the state of keccak after absorbing data, with the
+// rate and padding of the original Keccak-256, as CryptoNight does. Unlike
+// hashing with NewLegacyKeccak256, it does not allocate.
 func Keccak1600State(st *[25]uint64, data []byte) {
-	s := &state{rate: 136}
-	s.Write(data)
-	s.padAndPermute(0x01)
-	*st = s.a
+	const rate = 136
+
+	*st = [25]uint64{}
+	for len(data) >= rate {
+		xorInWords(st, data[:rate])
+		keccakF1600(st)
+		data = data[rate:]
+	}
+
+	var last [rate]byte
+	copy(last[:], data)
+	last[len(data)] ^= 0x01
+	last[rate-1] ^= 0x80
+	xorInWords(st, last[:])
+	keccakF1600(st)
 }
 
+// Keccak1600Permute applies the Keccak-f[1600] permutation to st.
 func Keccak1600Permute(st *[25]uint64) {
 	keccakF1600(st)
 }
+
+// xorInWords xors b, of a length multiple of 8, into the first words of st.
+func xorInWords(st *[25]uint64, b []byte) {
+	for i := 0; i < len(b)/8; i++ {
+		st[i] ^= binary.LittleEndian.Uint64(b[8*i:])
+	}
+}
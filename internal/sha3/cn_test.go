@@ -0,0 +1,30 @@
+package sha3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestKeccak1600State(t *testing.T) {
+	data := sequentialBytes(3 * 136)
+	for size := 0; size <= len(data); size++ {
+		var st [25]uint64
+		Keccak1600State(&st, data[:size])
+
+		var got [32]byte
+		for i := 0; i < 4; i++ {
+			binary.LittleEndian.PutUint64(got[8*i:], st[i])
+		}
+		h := NewLegacyKeccak256()
+		h.Write(data[:size])
+		if want := h.Sum(nil); !bytes.Equal(got[:], want) {
+			t.Fatalf("size %d: expected %x, got %x", size, want, got)
+		}
+	}
+
+	var st [25]uint64
+	if n := testing.AllocsPerRun(10, func() { Keccak1600State(&st, data) }); n != 0 {
+		t.Errorf("Keccak1600State allocates %v times", n)
+	}
+}
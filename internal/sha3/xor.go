@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// +build !amd64,!386,!ppc64le appengine
+// +build !amd64,!386,!ppc64le appengine purego tinygo
 
 package sha3
 
@@ -0,0 +1,27 @@
+// +build go1.18
+
+package share
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// FuzzVerify checks that no share submitted by a miner can make Verify panic.
+func FuzzVerify(f *testing.F) {
+	f.Add(`{"blob":"` + strings.Repeat("07", 76) + `","nonce":"deadbeef","variant":1,"target":1}`)
+	f.Add(`{"blob":"5468697320697320612074657374","variant":0,"target":1,"result":"a084f01d1437a09c6985401b60d43554ae105802c5f5d8a9b3253649c0be6605"}`)
+	f.Add(`{"blob":"00","nonce":"00000000","variant":2}`)
+
+	f.Fuzz(func(t *testing.T, s string) {
+		var sh Share
+		if json.Unmarshal([]byte(s), &sh) != nil {
+			return
+		}
+
+		if v := Verify(&sh); v.Valid && v.Error != "" {
+			t.Errorf("valid share with error %q", v.Error)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+// Package share verifies mining shares submitted for a CryptoNight hashing
+// blob. It is shared by the commands of this module.
+package share // import "ekyu.moe/cryptonight/internal/share"
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"ekyu.moe/cryptonight"
+	"ekyu.moe/cryptonight/cnutil"
+	"ekyu.moe/cryptonight/internal/observe"
+)
+
+// NonceOffset is the offset of the 4-byte nonce in a Monero hashing blob.
+const NonceOffset = cnutil.NonceOffset
+
+// Share is a share to verify.
+type Share struct {
+	ID      json.RawMessage `json:"id,omitempty"`     // echoed back as is
+	Blob    string          `json:"blob"`             // hashing blob in hex
+	Nonce   string          `json:"nonce,omitempty"`  // 4 bytes in hex, replacing the one in blob
+	Variant int             `json:"variant"`          // CryptoNight variant
+	Height  uint64          `json:"height,omitempty"` // block height, for variant 4
+	Target  uint64          `json:"target"`           // difficulty to meet
+	Result  string          `json:"result,omitempty"` // hash claimed by the miner in hex
+}
+
+// Verdict is the result of a verification.
+type Verdict struct {
+	ID         json.RawMessage `json:"id,omitempty"`
+	Hash       string          `json:"hash,omitempty"`
+	Difficulty uint64          `json:"difficulty"`
+	Valid      bool            `json:"valid"`
+	Error      string          `json:"error,omitempty"` // why the share is invalid
+	Err        error           `json:"-"`               // the error behind Error
+}
+
+// ErrResultMismatch is reported when the hash claimed by the miner is wrong.
+var ErrResultMismatch = errors.New("result mismatch")
+
+// variantAlgorithms are the algorithms of the variants Validate accepts.
+var variantAlgorithms = map[int]cryptonight.Algorithm{
+	0: cryptonight.CNv0,
+	1: cryptonight.CNv1,
+	2: cryptonight.CNv2,
+	4: cryptonight.CNR,
+}
+
+// Verify hashes s and checks it against its target and claimed result. The
+// outcome is reported to the Observer of package cryptonight, if any.
+func Verify(s *Share) *Verdict {
+	sum, err := Hash(s)
+	return verdict(s, sum, err)
+}
+
+// VerifyPool is Verify hashing with a cache of p, once one is free.
+func VerifyPool(s *Share, p *cryptonight.Pool) *Verdict {
+	sum, err := HashPool(s, p)
+	return verdict(s, sum, err)
+}
+
+// verdict checks the hash sum of s, or reports err.
+func verdict(s *Share, sum []byte, err error) *Verdict {
+	v := &Verdict{ID: s.ID}
+	if err != nil {
+		v.Err, v.Error = err, err.Error()
+		observe.Error(v.Err)
+		return v
+	}
+	v.Hash = hex.EncodeToString(sum)
+	v.Difficulty = cryptonight.Difficulty(sum)
+
+	if s.Result != "" && !strings.EqualFold(s.Result, v.Hash) {
+		v.Err, v.Error = ErrResultMismatch, ErrResultMismatch.Error()
+		observe.Error(v.Err)
+		return v
+	}
+	if !cryptonight.CheckHash(sum, s.Target) {
+		v.Err = cryptonight.ErrLowDifficulty
+		v.Error = fmt.Sprintf("difficulty %d does not meet target %d", v.Difficulty, s.Target)
+		observe.Error(v.Err)
+		return v
+	}
+	v.Valid = true
+	observe.ShareFound(sum, s.Target)
+
+	return v
+}
+
+// Hash places the nonce of s in its blob and returns the hash of it.
+func Hash(s *Share) ([]byte, error) {
+	blob, err := hashingBlob(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return cryptonight.SumHeight(blob, s.Variant, s.Height), nil
+}
+
+// HashPool is Hash with a cache of p, once one is free.
+func HashPool(s *Share, p *cryptonight.Pool) ([]byte, error) {
+	blob, err := hashingBlob(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.SumHeight(blob, variantAlgorithms[s.Variant], s.Height), nil
+}
+
+// hashingBlob returns the blob of s with its nonce in place, once checked that
+// it can be hashed.
+func hashingBlob(s *Share) ([]byte, error) {
+	blob, err := hex.DecodeString(s.Blob)
+	if err != nil {
+		return nil, errors.New("decode blob: " + err.Error())
+	}
+
+	if s.Nonce != "" {
+		nonce, err := hex.DecodeString(s.Nonce)
+		if err != nil || len(nonce) != 4 {
+			return nil, errors.New("nonce must be 4 bytes in hex")
+		}
+		if len(blob) < NonceOffset+4 {
+			return nil, errors.New("blob too short to hold a nonce")
+		}
+		copy(blob[NonceOffset:], nonce)
+	}
+
+	if err := cryptonight.Validate(blob, s.Variant); err != nil && err != cryptonight.ErrHeightRequired {
+		return nil, err
+	}
+
+	return blob, nil
+}
@@ -0,0 +1,10 @@
+// +build amd64,!purego,!tinygo
+
+package jh
+
+// e8 runs E8 with SSE2, which is part of amd64, each row of the state being
+// one 128-bit register.
+func (s *state) e8() { e8SSE2(&s.x, &e8BitsliceRoundconstant) }
+
+//go:noescape
+func e8SSE2(x *[8][2]uint64, c *[42][4]uint64)
@@ -0,0 +1,141 @@
+// +build amd64,!purego,!tinygo
+
+#include "textflag.h"
+
+// The rows x[0] to x[7] of the state are kept in X0 to X7. The Sbox and MDS
+// layers are those of sboxMDS, the even rows being m0 to m3 and the odd rows
+// m4 to m7, with the round constant in X8 for the former and X9 for the
+// latter. X15 is all ones, to negate.
+
+DATA swapMasks<>+0x00(SB)/8, $0x5555555555555555
+DATA swapMasks<>+0x08(SB)/8, $0x5555555555555555
+DATA swapMasks<>+0x10(SB)/8, $0x3333333333333333
+DATA swapMasks<>+0x18(SB)/8, $0x3333333333333333
+DATA swapMasks<>+0x20(SB)/8, $0x0f0f0f0f0f0f0f0f
+DATA swapMasks<>+0x28(SB)/8, $0x0f0f0f0f0f0f0f0f
+DATA swapMasks<>+0x30(SB)/8, $0x00ff00ff00ff00ff
+DATA swapMasks<>+0x38(SB)/8, $0x00ff00ff00ff00ff
+DATA swapMasks<>+0x40(SB)/8, $0x0000ffff0000ffff
+DATA swapMasks<>+0x48(SB)/8, $0x0000ffff0000ffff
+DATA swapMasks<>+0x50(SB)/8, $0x00000000ffffffff
+DATA swapMasks<>+0x58(SB)/8, $0x00000000ffffffff
+GLOBL swapMasks<>(SB), RODATA, $0x60
+
+// SBOX computes one of the two Sboxes of sboxMDS, with temp for its temp0.
+#define SBOX(m0, m1, m2, m3, cc, temp) \
+	PXOR  X15, m3;  \
+	MOVO  m2, X12;  \
+	PANDN cc, X12;  \
+	PXOR  X12, m0;  \
+	MOVO  m0, temp; \
+	PAND  m1, temp; \
+	PXOR  cc, temp; \
+	MOVO  m2, X12;  \
+	PAND  m3, X12;  \
+	PXOR  X12, m0;  \
+	MOVO  m1, X12;  \
+	PANDN m2, X12;  \
+	PXOR  X12, m3;  \
+	MOVO  m0, X12;  \
+	PAND  m2, X12;  \
+	PXOR  X12, m1;  \
+	MOVO  m3, X12;  \
+	PANDN m0, X12;  \
+	PXOR  X12, m2;  \
+	MOVO  m1, X12;  \
+	POR   m3, X12;  \
+	PXOR  X12, m0;  \
+	MOVO  m1, X12;  \
+	PAND  m2, X12;  \
+	PXOR  X12, m3;  \
+	PXOR  temp, m2; \
+	PAND  m0, temp; \
+	PXOR  temp, m1
+
+#define MDS \
+	PXOR X2, X1; \
+	PXOR X4, X3; \
+	PXOR X0, X5; \
+	PXOR X6, X5; \
+	PXOR X0, X7; \
+	PXOR X3, X0; \
+	PXOR X5, X2; \
+	PXOR X1, X4; \
+	PXOR X7, X4; \
+	PXOR X1, X6
+
+// SWAP is swap of one odd row, for the mask in X14 and the shift k. As the
+// mask selects the lower halves, (x &^ mask) >> k is (x >> k) & mask.
+#define SWAP(x, k) \
+	MOVO  x, X12;   \
+	PAND  X14, X12; \
+	PSLLQ $k, X12;  \
+	PSRLQ $k, x;    \
+	PAND  X14, x;   \
+	POR   X12, x
+
+// ROUND is a round whose swapping layer is n < 6, of shift k.
+#define ROUND(n, k) \
+	MOVOU 0(SI), X8;                       \
+	MOVOU 16(SI), X9;                      \
+	ADDQ  $32, SI;                         \
+	SBOX(X0, X2, X4, X6, X8, X10);         \
+	SBOX(X1, X3, X5, X7, X9, X11);         \
+	MDS;                                   \
+	MOVOU swapMasks<>+(16*n)(SB), X14;     \
+	SWAP(X1, k);                           \
+	SWAP(X3, k);                           \
+	SWAP(X5, k);                           \
+	SWAP(X7, k)
+
+// ROUND6 is a round whose swapping layer swaps the two halves of the odd rows.
+#define ROUND6 \
+	MOVOU  0(SI), X8;               \
+	MOVOU  16(SI), X9;              \
+	ADDQ   $32, SI;                 \
+	SBOX(X0, X2, X4, X6, X8, X10);  \
+	SBOX(X1, X3, X5, X7, X9, X11);  \
+	MDS;                            \
+	PSHUFD $0x4e, X1, X1;           \
+	PSHUFD $0x4e, X3, X3;           \
+	PSHUFD $0x4e, X5, X5;           \
+	PSHUFD $0x4e, X7, X7
+
+// func e8SSE2(x *[8][2]uint64, c *[42][4]uint64)
+TEXT ·e8SSE2(SB), NOSPLIT, $0
+	MOVQ x+0(FP), DI
+	MOVQ c+8(FP), SI
+
+	MOVOU 0(DI), X0
+	MOVOU 16(DI), X1
+	MOVOU 32(DI), X2
+	MOVOU 48(DI), X3
+	MOVOU 64(DI), X4
+	MOVOU 80(DI), X5
+	MOVOU 96(DI), X6
+	MOVOU 112(DI), X7
+	PCMPEQL X15, X15
+
+	// 42 rounds, 7 at a time for the 7 swapping layers
+	MOVQ $6, CX
+
+loop:
+	ROUND(0, 1)
+	ROUND(1, 2)
+	ROUND(2, 4)
+	ROUND(3, 8)
+	ROUND(4, 16)
+	ROUND(5, 32)
+	ROUND6
+	DECQ CX
+	JNZ  loop
+
+	MOVOU X0, 0(DI)
+	MOVOU X1, 16(DI)
+	MOVOU X2, 32(DI)
+	MOVOU X3, 48(DI)
+	MOVOU X4, 64(DI)
+	MOVOU X5, 80(DI)
+	MOVOU X6, 96(DI)
+	MOVOU X7, 112(DI)
+	RET
@@ -0,0 +1,5 @@
+// +build !amd64 purego tinygo
+
+package jh
+
+func (s *state) e8() { e8Generic(&s.x) }
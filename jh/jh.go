@@ -1,7 +1,7 @@
-// HEAD_PLACEHOLDER
-// +build ignore
-
-// Package jh implements JH-256 algorithm.
+// Package jh implements the JH-256 hash algorithm, as submitted to the NIST
+// SHA-3 competition, in its final round version. It is one of the final
+// hashes of CryptoNight, and can be used on its own through New256, a
+// hash.Hash accepting input in any number of writes.
 //
 // This Go implementation is a port of the original C implementation which is
 // included in Monero as follows:
@@ -14,78 +14,13 @@ package jh // import "ekyu.moe/cryptonight/jh"
 import (
 	"encoding/binary"
 	"hash"
-	"unsafe"
 )
 
-// This field is for macro definitions.
-// We define it in a literal string so that it can trick gofmt(1).
-//
-// It should be empty after they are expanded by cpp(1).
-const _ = `
-#undef build
-#undef ignore
-
-/* swapping bit 2i with bit 2i+1 of 64-bit x */
-#define SWAP1(x) \
-	(x) = ((((x) & 0x5555555555555555) << 1) | (((x) & 0xaaaaaaaaaaaaaaaa) >> 1))
-
-/* swapping bits 4i||4i+1 with bits 4i+2||4i+3 of 64-bit x */
-#define SWAP2(x) \
-	(x) = ((((x) & 0x3333333333333333) << 2) | (((x) & 0xcccccccccccccccc) >> 2))
-
-/* swapping bits 8i||8i+1||8i+2||8i+3 with bits 8i+4||8i+5||8i+6||8i+7 of 64-bit x */
-#define SWAP4(x) \
-	(x) = ((((x) & 0x0f0f0f0f0f0f0f0f) << 4) | (((x) & 0xf0f0f0f0f0f0f0f0) >> 4))
-
-/* swapping bits 16i||16i+1||......||16i+7  with bits 16i+8||16i+9||......||16i+15 of 64-bit x */
-#define SWAP8(x) \
-	(x) = ((((x) & 0x00ff00ff00ff00ff) << 8) | (((x) & 0xff00ff00ff00ff00) >> 8))
-
-/* swapping bits 32i||32i+1||......||32i+15 with bits 32i+16||32i+17||......||32i+31 of 64-bit x */
-#define SWAP16(x) \
-	(x) = ((((x) & 0x0000ffff0000ffff) << 16) | (((x) & 0xffff0000ffff0000) >> 16))
-
-/* swapping bits 64i||64i+1||......||64i+31 with bits 64i+32||64i+33||......||64i+63 of 64-bit x */
-#define SWAP32(x) \
-	(x) = (((x) << 32) | ((x) >> 32))
-
-/* Two Sboxes are computed in parallel, each Sbox implements S0 and S1, selected by a constant bit
-   The reason to compute two Sboxes in parallel is to try to fully utilize the parallel processing power */
-#define SS(m0, m1, m2, m3, m4, m5, m6, m7, cc0, cc1) \
-	m3 = ^(m3);						\
-	m7 = ^(m7);						\
-	m0 ^= ((^(m2)) & (cc0));		\
-	m4 ^= ((^(m6)) & (cc1));		\
-	temp0 = (cc0) ^ ((m0) & (m1));	\
-	temp1 = (cc1) ^ ((m4) & (m5));	\
-	m0 ^= ((m2) & (m3));			\
-	m4 ^= ((m6) & (m7));			\
-	m3 ^= ((^(m1)) & (m2));			\
-	m7 ^= ((^(m5)) & (m6));			\
-	m1 ^= ((m0) & (m2));			\
-	m5 ^= ((m4) & (m6));			\
-	m2 ^= ((m0) & (^(m3)));			\
-	m6 ^= ((m4) & (^(m7)));			\
-	m0 ^= ((m1) | (m3));			\
-	m4 ^= ((m5) | (m7));			\
-	m3 ^= ((m1) & (m2));			\
-	m7 ^= ((m5) & (m6));			\
-	m1 ^= (temp0 & (m0));			\
-	m5 ^= (temp1 & (m4));			\
-	m2 ^= temp0;					\
-	m6 ^= temp1;
-
-/* The MDS transform */
-#define L(m0, m1, m2, m3, m4, m5, m6, m7) \
-	(m4) ^= (m1);			\
-	(m5) ^= (m2);			\
-	(m6) ^= (m0) ^ (m3);	\
-	(m7) ^= (m0);			\
-	(m0) ^= (m5);			\
-	(m1) ^= (m6);			\
-	(m2) ^= (m4) ^ (m7);	\
-	(m3) ^= (m4);
-`
+// Size is the size of a JH-256 digest in bytes.
+const Size = 32
+
+// BlockSize is the block size of JH-256 in bytes.
+const BlockSize = 64
 
 // For memset
 var zeroBuf64Byte [64]byte
@@ -98,6 +33,7 @@ type state struct {
 	buffer           [64]byte     // the 512-bit message block to be hashed
 }
 
+// Sum256 returns the JH-256 digest of b.
 func Sum256(b []byte) []byte {
 	h := New256()
 	h.Write(b)
@@ -105,10 +41,12 @@ func Sum256(b []byte) []byte {
 	return h.Sum(nil)
 }
 
+// New256 returns a new hash.Hash computing JH-256.
 func New256() hash.Hash {
 	return &state{hashbitlen: 256, x: jh256H0}
 }
 
+// Reset resets the state to the one of New256.
 func (s *state) Reset() {
 	s.hashbitlen = 256
 	s.databitlen = 0
@@ -116,61 +54,49 @@ func (s *state) Reset() {
 	s.x = jh256H0
 }
 
-func (s *state) Size() int      { return 32 }
-func (s *state) BlockSize() int { return 64 }
+func (s *state) Size() int      { return Size }
+func (s *state) BlockSize() int { return BlockSize }
 
-// hash each 512-bit message block, except the last partial block
-func (s *state) Write(data []byte) (n int, err error) {
-	index := uint64(0) // the starting address of the data to be compressed
-	databitlen := uint64(len(data)) * 8
-	s.databitlen += databitlen
+// Write hashes each 512-bit message block, except the last partial block,
+// which is kept in the buffer. It never returns an error.
+func (s *state) Write(data []byte) (int, error) {
+	n := len(data)
+	s.databitlen += uint64(n) * 8
 
 	// if there is remaining data in the buffer, fill it to a full message block first
-	// we assume that the size of the data in the buffer is the multiple of 8 bits if it is not at the end of a message
-
-	// There is data in the buffer, but the incoming data is insufficient for a full block
-	if s.datasizeInBuffer > 0 && s.datasizeInBuffer+databitlen < 512 {
-		if databitlen&7 == 0 {
-			copy(s.buffer[s.datasizeInBuffer>>3:], data[:64-(s.datasizeInBuffer>>3)])
-		} else {
-			copy(s.buffer[s.datasizeInBuffer>>3:], data[:64-(s.datasizeInBuffer>>3)+1])
+	if s.datasizeInBuffer > 0 {
+		m := copy(s.buffer[s.datasizeInBuffer>>3:], data)
+		s.datasizeInBuffer += uint64(m) * 8
+		data = data[m:]
+		if s.datasizeInBuffer < 512 {
+			return n, nil
 		}
-		s.datasizeInBuffer += databitlen
-		databitlen = 0
-	}
-
-	// There is data in the buffer, and the incoming data is sufficient for a full block
-	if s.datasizeInBuffer > 0 && s.datasizeInBuffer+databitlen >= 512 {
-		copy(s.buffer[s.datasizeInBuffer>>3:], data[:64-(s.datasizeInBuffer>>3)])
-		index = 64 - (s.datasizeInBuffer >> 3)
-		databitlen -= 512 - s.datasizeInBuffer
 		s.f8()
 		s.datasizeInBuffer = 0
 	}
 
 	// hash the remaining full message blocks
-	for databitlen >= 512 {
-		copy(s.buffer[:], data[index:index+64])
+	for len(data) >= 64 {
+		copy(s.buffer[:], data[:64])
 		s.f8()
-		index += 64
-		databitlen -= 512
+		data = data[64:]
 	}
 
-	// store the partial block into buffer, assume that -- if part of the last byte is not part of the message, then that part consists of bits*/
-	if databitlen > 0 {
-		if databitlen&7 == 0 {
-			copy(s.buffer[:((databitlen&0x1ff)>>3)], data[index:])
-		} else {
-			copy(s.buffer[:((databitlen&0x1ff)>>3)+1], data[index:])
-		}
-		s.datasizeInBuffer = databitlen
-	}
+	// store the partial block into buffer
+	s.datasizeInBuffer = uint64(copy(s.buffer[:], data)) * 8
 
-	return len(data), nil
+	return n, nil
 }
 
-// Sum pads the message, process the padded block(s), truncate the hash value H to obtain the message digest
+// Sum appends the digest of the data written so far to b. It does not change
+// the underlying hash state.
 func (s *state) Sum(b []byte) []byte {
+	d := *s
+	return d.sum(b)
+}
+
+// sum pads the message, process the padded block(s), truncate the hash value H to obtain the message digest
+func (s *state) sum(b []byte) []byte {
 	var i uint64
 
 	if s.databitlen&0x1ff == 0 {
@@ -214,7 +140,13 @@ func (s *state) Sum(b []byte) []byte {
 		s.f8()
 	}
 
-	return append(b, (*[32]byte)(unsafe.Pointer(&s.x[6][0]))[:]...)
+	var out [32]byte
+	binary.LittleEndian.PutUint64(out[0:], s.x[6][0])
+	binary.LittleEndian.PutUint64(out[8:], s.x[6][1])
+	binary.LittleEndian.PutUint64(out[16:], s.x[7][0])
+	binary.LittleEndian.PutUint64(out[24:], s.x[7][1])
+
+	return append(b, out[:]...)
 }
 
 // The compression function F8.
@@ -235,81 +167,91 @@ func (s *state) f8() {
 	}
 }
 
-// The bijective function E8, in bitslice form.
-func (s *state) e8() {
-	var i, roundnumber, temp0, temp1 uint64
-
-	for roundnumber = 0; roundnumber < 42; roundnumber += 7 {
-		// round 7*roundnumber+0: Sbox, MDS and Swapping layers
-		for i = 0; i < 2; i++ {
-			SS(s.x[0][i], s.x[2][i], s.x[4][i], s.x[6][i], s.x[1][i], s.x[3][i], s.x[5][i], s.x[7][i], e8BitsliceRoundconstant[roundnumber+0][i], e8BitsliceRoundconstant[roundnumber+0][i+2])
-			L(s.x[0][i], s.x[2][i], s.x[4][i], s.x[6][i], s.x[1][i], s.x[3][i], s.x[5][i], s.x[7][i])
-			SWAP1(s.x[1][i])
-			SWAP1(s.x[3][i])
-			SWAP1(s.x[5][i])
-			SWAP1(s.x[7][i])
-		}
-
-		// round 7*roundnumber+1: Sbox, MDS and Swapping layers
-		for i = 0; i < 2; i++ {
-			SS(s.x[0][i], s.x[2][i], s.x[4][i], s.x[6][i], s.x[1][i], s.x[3][i], s.x[5][i], s.x[7][i], e8BitsliceRoundconstant[roundnumber+1][i], e8BitsliceRoundconstant[roundnumber+1][i+2])
-			L(s.x[0][i], s.x[2][i], s.x[4][i], s.x[6][i], s.x[1][i], s.x[3][i], s.x[5][i], s.x[7][i])
-			SWAP2(s.x[1][i])
-			SWAP2(s.x[3][i])
-			SWAP2(s.x[5][i])
-			SWAP2(s.x[7][i])
+// e8Generic is the bijective function E8, in bitslice form. Each of its 42
+// rounds is the Sbox and MDS layers, then one of the 7 swapping layers.
+func e8Generic(x *[8][2]uint64) {
+	for roundnumber := 0; roundnumber < 42; roundnumber++ {
+		c := &e8BitsliceRoundconstant[roundnumber]
+		for i := 0; i < 2; i++ {
+			x[0][i], x[2][i], x[4][i], x[6][i], x[1][i], x[3][i], x[5][i], x[7][i] = sboxMDS(
+				x[0][i], x[2][i], x[4][i], x[6][i], x[1][i], x[3][i], x[5][i], x[7][i], c[i], c[i+2])
 		}
 
-		// round 7*roundnumber+2: Sbox, MDS and Swapping layers
-		for i = 0; i < 2; i++ {
-			SS(s.x[0][i], s.x[2][i], s.x[4][i], s.x[6][i], s.x[1][i], s.x[3][i], s.x[5][i], s.x[7][i], e8BitsliceRoundconstant[roundnumber+2][i], e8BitsliceRoundconstant[roundnumber+2][i+2])
-			L(s.x[0][i], s.x[2][i], s.x[4][i], s.x[6][i], s.x[1][i], s.x[3][i], s.x[5][i], s.x[7][i])
-			SWAP4(s.x[1][i])
-			SWAP4(s.x[3][i])
-			SWAP4(s.x[5][i])
-			SWAP4(s.x[7][i])
-		}
-
-		// round 7*roundnumber+3: Sbox, MDS and Swapping layers
-		for i = 0; i < 2; i++ {
-			SS(s.x[0][i], s.x[2][i], s.x[4][i], s.x[6][i], s.x[1][i], s.x[3][i], s.x[5][i], s.x[7][i], e8BitsliceRoundconstant[roundnumber+3][i], e8BitsliceRoundconstant[roundnumber+3][i+2])
-			L(s.x[0][i], s.x[2][i], s.x[4][i], s.x[6][i], s.x[1][i], s.x[3][i], s.x[5][i], s.x[7][i])
-			SWAP8(s.x[1][i])
-			SWAP8(s.x[3][i])
-			SWAP8(s.x[5][i])
-			SWAP8(s.x[7][i])
+		if n := roundnumber % 7; n < 6 {
+			for i := 1; i < 8; i += 2 {
+				x[i][0] = swap(x[i][0], n)
+				x[i][1] = swap(x[i][1], n)
+			}
+		} else {
+			// swapping the two 64-bit halves of the odd rows
+			for i := 1; i < 8; i += 2 {
+				x[i][0], x[i][1] = x[i][1], x[i][0]
+			}
 		}
+	}
+}
 
-		// round 7*roundnumber+4: Sbox, MDS and Swapping layers
-		for i = 0; i < 2; i++ {
-			SS(s.x[0][i], s.x[2][i], s.x[4][i], s.x[6][i], s.x[1][i], s.x[3][i], s.x[5][i], s.x[7][i], e8BitsliceRoundconstant[roundnumber+4][i], e8BitsliceRoundconstant[roundnumber+4][i+2])
-			L(s.x[0][i], s.x[2][i], s.x[4][i], s.x[6][i], s.x[1][i], s.x[3][i], s.x[5][i], s.x[7][i])
-			SWAP16(s.x[1][i])
-			SWAP16(s.x[3][i])
-			SWAP16(s.x[5][i])
-			SWAP16(s.x[7][i])
-		}
+// swapMasks select the lower halves of the bit groups exchanged by the
+// swapping layer n, which are 1<<n bits long:
+//   - 0: swapping bit 2i with bit 2i+1 of 64-bit x
+//   - 1: swapping bits 4i||4i+1 with bits 4i+2||4i+3 of 64-bit x
+//   - 2: swapping bits 8i||8i+1||8i+2||8i+3 with bits 8i+4||8i+5||8i+6||8i+7 of 64-bit x
+//   - 3: swapping bits 16i||16i+1||......||16i+7  with bits 16i+8||16i+9||......||16i+15 of 64-bit x
+//   - 4: swapping bits 32i||32i+1||......||32i+15 with bits 32i+16||32i+17||......||32i+31 of 64-bit x
+//   - 5: swapping bits 64i||64i+1||......||64i+31 with bits 64i+32||64i+33||......||64i+63 of 64-bit x
+var swapMasks = [6]uint64{
+	0x5555555555555555,
+	0x3333333333333333,
+	0x0f0f0f0f0f0f0f0f,
+	0x00ff00ff00ff00ff,
+	0x0000ffff0000ffff,
+	0x00000000ffffffff,
+}
 
-		// round 7*roundnumber+5: Sbox, MDS and Swapping layers
-		for i = 0; i < 2; i++ {
-			SS(s.x[0][i], s.x[2][i], s.x[4][i], s.x[6][i], s.x[1][i], s.x[3][i], s.x[5][i], s.x[7][i], e8BitsliceRoundconstant[roundnumber+5][i], e8BitsliceRoundconstant[roundnumber+5][i+2])
-			L(s.x[0][i], s.x[2][i], s.x[4][i], s.x[6][i], s.x[1][i], s.x[3][i], s.x[5][i], s.x[7][i])
-			SWAP32(s.x[1][i])
-			SWAP32(s.x[3][i])
-			SWAP32(s.x[5][i])
-			SWAP32(s.x[7][i])
-		}
+// swap is the swapping layer n of x.
+func swap(x uint64, n int) uint64 {
+	m, k := swapMasks[n], uint(1)<<uint(n)
+	return (x&m)<<k | (x&^m)>>k
+}
 
-		// round 7*roundnumber+6: Sbox and MDS layers
-		for i = 0; i < 2; i++ {
-			SS(s.x[0][i], s.x[2][i], s.x[4][i], s.x[6][i], s.x[1][i], s.x[3][i], s.x[5][i], s.x[7][i], e8BitsliceRoundconstant[roundnumber+6][i], e8BitsliceRoundconstant[roundnumber+6][i+2])
-			L(s.x[0][i], s.x[2][i], s.x[4][i], s.x[6][i], s.x[1][i], s.x[3][i], s.x[5][i], s.x[7][i])
-		}
-		// round 7*roundnumber+6: swapping layer
-		for i = 1; i < 8; i = i + 2 {
-			temp0 = s.x[i][0]
-			s.x[i][0] = s.x[i][1]
-			s.x[i][1] = temp0
-		}
-	}
+// sboxMDS computes two Sboxes in parallel, each Sbox implements S0 and S1,
+// selected by a constant bit, then the MDS transform. The reason to compute
+// two Sboxes in parallel is to try to fully utilize the parallel processing
+// power.
+func sboxMDS(m0, m1, m2, m3, m4, m5, m6, m7, cc0, cc1 uint64) (uint64, uint64, uint64, uint64, uint64, uint64, uint64, uint64) {
+	// Sbox
+	m3 = ^m3
+	m7 = ^m7
+	m0 ^= ^m2 & cc0
+	m4 ^= ^m6 & cc1
+	temp0 := cc0 ^ (m0 & m1)
+	temp1 := cc1 ^ (m4 & m5)
+	m0 ^= m2 & m3
+	m4 ^= m6 & m7
+	m3 ^= ^m1 & m2
+	m7 ^= ^m5 & m6
+	m1 ^= m0 & m2
+	m5 ^= m4 & m6
+	m2 ^= m0 & ^m3
+	m6 ^= m4 & ^m7
+	m0 ^= m1 | m3
+	m4 ^= m5 | m7
+	m3 ^= m1 & m2
+	m7 ^= m5 & m6
+	m1 ^= temp0 & m0
+	m5 ^= temp1 & m4
+	m2 ^= temp0
+	m6 ^= temp1
+
+	// MDS
+	m4 ^= m1
+	m5 ^= m2
+	m6 ^= m0 ^ m3
+	m7 ^= m0
+	m0 ^= m5
+	m1 ^= m6
+	m2 ^= m4 ^ m7
+	m3 ^= m4
+
+	return m0, m1, m2, m3, m4, m5, m6, m7
 }
@@ -0,0 +1,83 @@
+package jh
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+var jhSpecs = []struct {
+	input  string
+	output string
+}{
+	// as computed by the reference implementation of the NIST submission
+	{"", "46e64619c18bb0a92a5e87185a47eef83ca747b8fcc8e1412921357e326df434"},
+	{"The quick brown fox jumps over the lazy dog", "6a049fed5fc6874acfdc4a08b568a4f8cbac27de933496f031015b38961608a0"},
+}
+
+func TestSum256(t *testing.T) {
+	for i, v := range jhSpecs {
+		if out := hex.EncodeToString(Sum256([]byte(v.input))); out != v.output {
+			t.Errorf("\n[%d] expected:\n\t%s\ngot:\n\t%s\n", i, v.output, out)
+		}
+	}
+}
+
+func TestNew256(t *testing.T) {
+	in := make([]byte, 3*BlockSize+7)
+	for i := range in {
+		in[i] = byte(i)
+	}
+	want := Sum256(in)
+
+	h := New256()
+	if h.Size() != Size || h.BlockSize() != BlockSize {
+		t.Fatalf("unexpected sizes %d, %d", h.Size(), h.BlockSize())
+	}
+
+	// written in chunks of every size, across block boundaries
+	for n := 1; n <= 2*BlockSize; n++ {
+		h.Reset()
+		for p := in; len(p) > 0; {
+			m := n
+			if m > len(p) {
+				m = len(p)
+			}
+			h.Write(p[:m])
+			p = p[m:]
+		}
+		if sum := h.Sum(nil); !bytes.Equal(sum, want) {
+			t.Fatalf("chunks of %d bytes:\nexpected:\n\t%x\ngot:\n\t%x\n", n, want, sum)
+		}
+	}
+
+	// Sum does not change the state
+	h.Reset()
+	h.Write(in[:10])
+	h.Sum(nil)
+	h.Write(in[10:])
+	prefix := []byte("prefix")
+	if sum := h.Sum(prefix); !bytes.Equal(sum[:len(prefix)], prefix) || !bytes.Equal(sum[len(prefix):], want) {
+		t.Errorf("\nexpected:\n\t%x\ngot:\n\t%x\n", want, sum[len(prefix):])
+	}
+}
+
+func TestE8(t *testing.T) {
+	s := New256().(*state)
+	for i := 0; i < 100; i++ {
+		x := s.x
+		e8Generic(&x)
+		s.e8()
+		if s.x != x {
+			t.Fatalf("[%d] e8 and e8Generic disagree", i)
+		}
+	}
+}
+
+func BenchmarkSum256(b *testing.B) {
+	in := make([]byte, 200)
+	b.SetBytes(int64(len(in)))
+	for i := 0; i < b.N; i++ {
+		Sum256(in)
+	}
+}
@@ -0,0 +1,48 @@
+// Package keccak implements the original Keccak-256, which CryptoNote calls
+// cn_fast_hash and uses to hash transactions and build tree hashes, as well as
+// the Keccak-f[1600] permutation and the 200 bytes state CryptoNight starts
+// from.
+//
+// Keccak-256 differs from SHA3-256 in its padding, so their digests differ.
+// It shares the permutation code of CryptoNight, including its assembly.
+package keccak // import "ekyu.moe/cryptonight/keccak"
+
+import (
+	"encoding/binary"
+	"hash"
+
+	"ekyu.moe/cryptonight/internal/sha3"
+)
+
+// Size is the size of a Keccak-256 digest in bytes.
+const Size = 32
+
+// Sum256 returns the Keccak-256 digest of b, i.e. cn_fast_hash.
+func Sum256(b []byte) []byte {
+	var st [25]uint64
+	sha3.Keccak1600State(&st, b)
+
+	sum := make([]byte, Size)
+	for i := 0; i < Size/8; i++ {
+		binary.LittleEndian.PutUint64(sum[8*i:], st[i])
+	}
+
+	return sum
+}
+
+// New256 returns a new hash.Hash computing Keccak-256.
+func New256() hash.Hash {
+	return sha3.NewLegacyKeccak256()
+}
+
+// State sets st to the state of Keccak after absorbing b, with the rate and
+// padding of Keccak-256, which is the first step of CryptoNight. Its first 4
+// words are the Keccak-256 digest of b in little endian.
+func State(st *[25]uint64, b []byte) {
+	sha3.Keccak1600State(st, b)
+}
+
+// Permute applies the Keccak-f[1600] permutation to st.
+func Permute(st *[25]uint64) {
+	sha3.Keccak1600Permute(st)
+}
@@ -0,0 +1,48 @@
+package keccak
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+var keccakSpecs = []struct {
+	input  string
+	output string
+}{
+	{"", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+	{"abc", "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+}
+
+func TestSum256(t *testing.T) {
+	long := bytes.Repeat([]byte{0xa5}, 1000) // several blocks
+
+	for i, v := range keccakSpecs {
+		if out := hex.EncodeToString(Sum256([]byte(v.input))); out != v.output {
+			t.Errorf("\n[%d] expected:\n\t%s\ngot:\n\t%s\n", i, v.output, out)
+		}
+	}
+
+	for _, in := range [][]byte{nil, []byte("This is a test"), long[:135], long[:136], long} {
+		h := New256()
+		h.Write(in[:len(in)/2])
+		h.Write(in[len(in)/2:])
+		if sum := h.Sum(nil); !bytes.Equal(sum, Sum256(in)) {
+			t.Errorf("%d bytes: New256 and Sum256 disagree:\n\t%x\n\t%x", len(in), sum, Sum256(in))
+		}
+	}
+}
+
+func TestPermute(t *testing.T) {
+	// the first word of Keccak-f[1600] of the zero state
+	var st [25]uint64
+	Permute(&st)
+	if st[0] != 0xf1258f7940e1dde7 {
+		t.Errorf("unexpected state %#x", st[0])
+	}
+
+	State(&st, []byte("abc"))
+	if st[0] != 0x4fa945ea7a65034e {
+		t.Errorf("unexpected state %#x", st[0])
+	}
+}
@@ -0,0 +1,121 @@
+package cryptonight
+
+import (
+	"sync"
+	"time"
+)
+
+// meterSeconds is the longest window of a HashrateMeter, in seconds.
+const meterSeconds = 15 * 60
+
+// HashrateMeter counts hashes, and reports the hashrate averaged over the last
+// 10 seconds, 60 seconds and 15 minutes, as most miners display it. It is safe
+// for concurrent use, so that the threads of a miner can share one, and its
+// zero value is ready to use.
+//
+// Cache.Mine and Cache.MineHeight record their hashes in the meter set by
+// Cache.SetHashrateMeter.
+type HashrateMeter struct {
+	mu     sync.Mutex
+	start  time.Time            // time of the first Record
+	sec    int64                // Unix time of the second counted in counts[sec%meterSeconds]
+	counts [meterSeconds]uint64 // hashes of each of the last seconds
+	total  uint64               // hashes since start
+	now    func() time.Time     // clock, time.Now if nil
+}
+
+// Record counts n hashes done now.
+func (m *HashrateMeter) Record(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock()
+	if m.start.IsZero() {
+		m.start = now
+		m.sec = now.Unix()
+	}
+	m.advance(now.Unix())
+	m.counts[m.sec%meterSeconds] += uint64(n)
+	m.total += uint64(n)
+}
+
+// Rate returns the hashrate in H/s averaged over the last d, rounded to a
+// second and at most 15 minutes, or over the time since the first Record if it
+// is shorter. It is 0 before the first Record.
+func (m *HashrateMeter) Rate(d time.Duration) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.start.IsZero() {
+		return 0
+	}
+	now := m.clock()
+	m.advance(now.Unix())
+
+	n := int64((d + time.Second/2) / time.Second)
+	if n < 1 {
+		n = 1
+	}
+	if n > meterSeconds {
+		n = meterSeconds
+	}
+	// the current second is only partly elapsed
+	span := time.Duration(n-1)*time.Second + time.Duration(now.Nanosecond())
+	if since := now.Sub(m.start); since < span {
+		span = since
+	}
+	if span <= 0 {
+		return 0
+	}
+
+	var sum uint64
+	for i := int64(0); i < n; i++ {
+		sum += m.counts[(m.sec-i)%meterSeconds]
+	}
+
+	return float64(sum) / span.Seconds()
+}
+
+// Rates returns the hashrate in H/s averaged over the last 10 seconds, 60
+// seconds and 15 minutes. See Rate.
+func (m *HashrateMeter) Rates() (r10s, r60s, r15m float64) {
+	return m.Rate(10 * time.Second), m.Rate(60 * time.Second), m.Rate(15 * time.Minute)
+}
+
+// Total returns the number of hashes recorded.
+func (m *HashrateMeter) Total() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.total
+}
+
+func (m *HashrateMeter) clock() time.Time {
+	if m.now != nil {
+		return m.now()
+	}
+
+	return time.Now()
+}
+
+// advance moves the current second of m to sec, zeroing the counts of the
+// seconds skipped since the last call.
+func (m *HashrateMeter) advance(sec int64) {
+	if sec <= m.sec {
+		return
+	}
+	if sec-m.sec >= meterSeconds {
+		m.counts = [meterSeconds]uint64{}
+	} else {
+		for s := m.sec + 1; s <= sec; s++ {
+			m.counts[s%meterSeconds] = 0
+		}
+	}
+	m.sec = sec
+}
+
+// SetHashrateMeter makes Cache.Mine and Cache.MineHeight record every hash
+// they compute with cc in m. A nil m removes it.
+func (cc *Cache) SetHashrateMeter(m *HashrateMeter) {
+	cc.meter = m
+}
@@ -0,0 +1,98 @@
+package cryptonight
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHashrateMeter(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	m := &HashrateMeter{now: func() time.Time { return now }}
+	if r10s, r60s, r15m := m.Rates(); r10s != 0 || r60s != 0 || r15m != 0 {
+		t.Errorf("expected no hashrate before the first Record, got %v %v %v", r10s, r60s, r15m)
+	}
+
+	// 100 H/s for 20 minutes, then 10 H/s for 30 seconds
+	for i := 0; i < 20*60; i++ {
+		m.Record(100)
+		now = now.Add(time.Second)
+	}
+	for i := 0; i < 30; i++ {
+		m.Record(10)
+		now = now.Add(time.Second)
+	}
+	for i, v := range []struct {
+		d    time.Duration
+		rate float64
+	}{
+		// now is at the start of a second, which is not counted
+		{10 * time.Second, 10},
+		{60 * time.Second, (29*100 + 30*10) / 59.0},
+		{15 * time.Minute, (869*100 + 30*10) / 899.0},
+		{time.Hour, (869*100 + 30*10) / 899.0},
+	} {
+		if got := m.Rate(v.d); math.Abs(got-v.rate) > 1e-9 {
+			t.Errorf("[%d] expected %v H/s over %v, got %v", i, v.rate, v.d, got)
+		}
+	}
+	if n := m.Total(); n != 20*60*100+30*10 {
+		t.Errorf("expected %d hashes, got %d", 20*60*100+30*10, n)
+	}
+
+	// the meter only averages over the time since the first Record, and
+	// forgets the seconds without hashes
+	now = time.Unix(2000000, 0)
+	m = &HashrateMeter{now: func() time.Time { return now }}
+	m.Record(50)
+	now = now.Add(2 * time.Second)
+	if r10s, r60s, _ := m.Rates(); r10s != 25 || r60s != 25 {
+		t.Errorf("expected 25 H/s after 2 seconds, got %v %v", r10s, r60s)
+	}
+	now = now.Add(20 * time.Minute)
+	if r10s, _, r15m := m.Rates(); r10s != 0 || r15m != 0 {
+		t.Errorf("expected no hashrate after 20 idle minutes, got %v %v", r10s, r15m)
+	}
+}
+
+func TestHashrateMeterConcurrent(t *testing.T) {
+	var (
+		m  HashrateMeter
+		wg sync.WaitGroup
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				m.Record(1)
+				m.Rates()
+			}
+		}()
+	}
+	wg.Wait()
+	if n := m.Total(); n != 8000 {
+		t.Errorf("expected 8000 hashes, got %d", n)
+	}
+}
+
+func TestMineHashrateMeter(t *testing.T) {
+	blob := make([]byte, 76)
+	copy(blob, []byte{7, 7, 0xe3, 0xd5, 0xdb, 0xe5, 0x05})
+
+	var m HashrateMeter
+	cc := new(Cache)
+	cc.SetHashrateMeter(&m)
+	nonce, _, err := cc.Mine(context.Background(), blob, CNPico, 5, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := m.Total(); n != uint64(nonce)+1 {
+		t.Errorf("expected %d hashes, got %d", nonce+1, n)
+	}
+	if m.Rate(time.Second) <= 0 {
+		t.Error("expected a positive hashrate")
+	}
+}
@@ -0,0 +1,69 @@
+package cryptonight
+
+import (
+	"context"
+	"encoding/binary"
+
+	"ekyu.moe/cryptonight/cnutil"
+	"ekyu.moe/cryptonight/internal/observe"
+)
+
+// Mine searches for a nonce of the hashing blob such that its hash of algo
+// meets target, trying startNonce, startNonce+step, and so on, with cc. It
+// returns the nonce and the hash as soon as one is found, ctx.Err() if ctx is
+// done first, and ErrNoncesExhausted once the nonce would wrap around. Miners
+// running several goroutines can give each its own Cache, a different
+// startNonce and the number of goroutines as step. A step of 0 is taken as 1.
+//
+// blob is not modified, the nonces are placed in a copy of it, at the offset
+// found by cnutil.ParseHeader. Mine returns the error of cnutil.ParseHeader if
+// blob is not a hashing blob, ErrHeightRequired if algo is CNR, which needs
+// MineHeight, and the errors of ValidateAlgorithm. It panics with
+// ErrCacheInUse if cc is used by another goroutine. Every hash is recorded in
+// the HashrateMeter of cc, if any.
+func (cc *Cache) Mine(ctx context.Context, blob []byte, algo Algorithm, target uint64, startNonce, step uint32) (nonce uint32, hash []byte, err error) {
+	if algo == CNR {
+		observe.Error(ErrHeightRequired)
+		return 0, nil, ErrHeightRequired
+	}
+
+	return cc.MineHeight(ctx, blob, algo, 0, target, startNonce, step)
+}
+
+// MineHeight is like Mine, but also accepts CNR, which hashes with height.
+// height is ignored by the other algorithms.
+func (cc *Cache) MineHeight(ctx context.Context, blob []byte, algo Algorithm, height, target uint64, startNonce, step uint32) (nonce uint32, hash []byte, err error) {
+	h, err := cnutil.ParseHeader(blob)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := ValidateAlgorithm(blob, algo); err != nil {
+		observe.Error(err)
+		return 0, nil, err
+	}
+	if step == 0 {
+		step = 1
+	}
+
+	data := append([]byte(nil), blob...)
+	var sum [32]byte
+	for nonce = startNonce; ; nonce += step {
+		if err := ctx.Err(); err != nil {
+			return 0, nil, err
+		}
+
+		binary.LittleEndian.PutUint32(data[h.NonceOffset:], nonce)
+		cc.SumInto(&sum, data, algo, height)
+		if cc.meter != nil {
+			cc.meter.Record(1)
+		}
+		if CheckHash(sum[:], target) {
+			observe.ShareFound(sum[:], target)
+			return nonce, sum[:], nil
+		}
+
+		if nonce+step < nonce {
+			return 0, nil, ErrNoncesExhausted
+		}
+	}
+}
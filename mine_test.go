@@ -0,0 +1,66 @@
+package cryptonight
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"ekyu.moe/cryptonight/cnutil"
+)
+
+func TestMine(t *testing.T) {
+	// a hashing blob of version 7, whose timestamp takes 5 bytes
+	blob := make([]byte, 76)
+	copy(blob, []byte{7, 7, 0xe3, 0xd5, 0xdb, 0xe5, 0x05})
+	orig := append([]byte(nil), blob...)
+
+	cc := new(Cache)
+	nonce, sum, err := cc.Mine(context.Background(), blob, CNPico, 50, 1000, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nonce < 1000 || (nonce-1000)%3 != 0 {
+		t.Errorf("unexpected nonce %d", nonce)
+	}
+	if !bytes.Equal(blob, orig) {
+		t.Error("Mine modified blob")
+	}
+	binary.LittleEndian.PutUint32(blob[cnutil.NonceOffset:], nonce)
+	if want := SumAlgorithm(blob, CNPico, 0); !bytes.Equal(sum, want) {
+		t.Errorf("\nexpected:\n\t%x\ngot:\n\t%x\n", want, sum)
+	}
+	if !CheckHash(sum, 50) {
+		t.Errorf("hash %x does not meet the target", sum)
+	}
+
+	// the last nonces, none of which meets the target
+	if _, _, err := cc.Mine(context.Background(), orig, CNPico, math.MaxUint64, math.MaxUint32-1, 0); err != ErrNoncesExhausted {
+		t.Errorf("expected ErrNoncesExhausted, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := cc.Mine(ctx, orig, CNPico, 1, 0, 1); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	for i, v := range []struct {
+		blob []byte
+		algo Algorithm
+		err  error
+	}{
+		{orig, CNR, ErrHeightRequired},
+		{orig, Algorithm(-1), ErrUnknownAlgorithm},
+		{orig[:40], CNPico, cnutil.ErrShortBlob},
+	} {
+		if _, _, err := cc.Mine(context.Background(), v.blob, v.algo, 1, 0, 1); err != v.err {
+			t.Errorf("[%d] expected %v, got %v", i, v.err, err)
+		}
+	}
+
+	if _, sum, err := cc.MineHeight(context.Background(), orig, CNR, 1806260, 1, 0, 1); err != nil || !CheckHash(sum, 1) {
+		t.Errorf("unexpected %x, %v", sum, err)
+	}
+}
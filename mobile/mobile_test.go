@@ -0,0 +1,52 @@
+package mobile
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestSum(t *testing.T) {
+	for i, v := range []struct {
+		in      string
+		variant int
+		out     string
+		err     bool
+	}{
+		{"This is a test", 0, "a084f01d1437a09c6985401b60d43554ae105802c5f5d8a9b3253649c0be6605", false},
+		{"variant 1 requires at least 43 bytes of input.", 1, "261124c5a6dca5d4aa3667d328a94ead9a819ae714e1f1dc113ceeb14f1ecf99", false},
+		{"This is a test", 1, "", true},
+		{"This is a test", 3, "", true},
+		{"This is a test", -1, "", true},
+	} {
+		h := NewHasher()
+		for _, sum := range []func([]byte, int) ([]byte, error){Sum, h.Sum} {
+			result, err := sum([]byte(v.in), v.variant)
+			if (err != nil) != v.err {
+				t.Errorf("[%d] unexpected error: %v", i, err)
+				continue
+			}
+			if out := hex.EncodeToString(result); out != v.out {
+				t.Errorf("\n[%d] expected:\n\t%s\ngot:\n\t%s\n", i, v.out, out)
+			}
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	in := []byte("This is a test")
+	sum, _ := Sum(in, 0)
+	diff := Difficulty(sum)
+	if diff <= 0 {
+		t.Fatalf("unexpected difficulty %d", diff)
+	}
+
+	if ok, err := Verify(in, 0, diff); !ok || err != nil {
+		t.Errorf("expected %d to be met, got %v, %v", diff, ok, err)
+	}
+	if ok, err := Verify(in, 0, diff+1); ok || err != nil {
+		t.Errorf("expected %d not to be met, got %v, %v", diff+1, ok, err)
+	}
+	if _, err := Verify(in, 0, -1); err == nil {
+		t.Error("expected error for negative target")
+	}
+}
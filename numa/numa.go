@@ -0,0 +1,92 @@
+// Package numa places the scratchpad of a cryptonight.Cache on a given NUMA
+// node, and pins threads to the CPUs of a node, for servers with several
+// sockets where a hash is much slower with its scratchpad in the memory of
+// another socket.
+//
+// A miner on such a server typically starts one goroutine per CPU of each
+// node, which calls PinNode and then hashes with a Cache from NewCacheOnNode
+// for the same node.
+//
+// It is only implemented on Linux, without the purego build tag or TinyGo;
+// elsewhere its functions return ErrUnsupported.
+package numa // import "ekyu.moe/cryptonight/numa"
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"ekyu.moe/cryptonight"
+)
+
+var (
+	// ErrUnsupported is returned on systems where NUMA placement is not
+	// implemented.
+	ErrUnsupported = errors.New("numa: not supported on this system")
+
+	// ErrNoNode is returned for a node which does not exist or is offline.
+	ErrNoNode = errors.New("numa: no such node")
+
+	errBadList = errors.New("numa: malformed list")
+)
+
+// Allocator is a cryptonight.Allocator of memory bound to the node Node: the
+// pages of the memory it returns are only ever taken from the memory of Node.
+type Allocator struct {
+	Node int
+}
+
+// NewCacheOnNode returns a new Cache whose scratchpad is bound to the memory
+// of node, with an Allocator for node. As for cryptonight.NewCacheWithAllocator,
+// the scratchpad is only allocated by the first hash, and must be released by
+// Close.
+//
+// Binding only decides where the memory is, not where the hashes run: pin the
+// threads using the Cache to the same node with PinNode.
+func NewCacheOnNode(node int) (*cryptonight.Cache, error) {
+	if err := checkNode(node); err != nil {
+		return nil, err
+	}
+
+	return cryptonight.NewCacheWithAllocator(Allocator{Node: node})
+}
+
+// PinNode locks the calling goroutine to its OS thread, and restricts the
+// thread to the CPUs of node. See PinThread.
+func PinNode(node int) error {
+	cpus, err := CPUs(node)
+	if err != nil {
+		return err
+	}
+
+	return PinThread(cpus)
+}
+
+// parseList parses a list of integers in the format of the kernel, such as
+// "0-3,8,10-11" in /sys/devices/system/node/node0/cpulist.
+func parseList(s string) ([]int, error) {
+	var l []int
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return l, nil
+	}
+	for _, r := range strings.Split(s, ",") {
+		lo, hi := r, r
+		if i := strings.IndexByte(r, '-'); i >= 0 {
+			lo, hi = r[:i], r[i+1:]
+		}
+		a, err := strconv.Atoi(lo)
+		if err != nil || a < 0 {
+			return nil, errBadList
+		}
+		b, err := strconv.Atoi(hi)
+		if err != nil || b < a {
+			return nil, errBadList
+		}
+		for i := a; i <= b; i++ {
+			l = append(l, i)
+		}
+	}
+
+	return l, nil
+}
@@ -0,0 +1,116 @@
+// +build !purego,!tinygo
+
+package numa
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/bits"
+	"os"
+	"runtime"
+	"strconv"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const sysNode = "/sys/devices/system/node/"
+
+// mpolBind is the MPOL_BIND policy of mbind(2).
+const mpolBind = 2
+
+// Alloc maps n bytes of memory and binds it to a.Node with mbind(2). The pages
+// are only taken when first written to, i.e. by the first hash.
+func (a Allocator) Alloc(n int) ([]byte, error) {
+	if err := checkNode(a.Node); err != nil {
+		return nil, err
+	}
+	mem, err := unix.Mmap(-1, 0, n, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE|unix.MAP_ANONYMOUS)
+	if err != nil {
+		return nil, errors.New("numa: mmap: " + err.Error())
+	}
+
+	// a bitmask of nodes in unsigned longs, of which the kernel reads one bit
+	// less than it is told
+	mask := make([]uint, a.Node/bits.UintSize+1)
+	mask[a.Node/bits.UintSize] = 1 << uint(a.Node%bits.UintSize)
+	if _, _, e := unix.Syscall6(unix.SYS_MBIND, uintptr(unsafe.Pointer(&mem[0])), uintptr(len(mem)),
+		mpolBind, uintptr(unsafe.Pointer(&mask[0])), uintptr(len(mask)*bits.UintSize+1), 0); e != 0 {
+		unix.Munmap(mem)
+		return nil, errors.New("numa: mbind: " + e.Error())
+	}
+
+	return mem, nil
+}
+
+// Free unmaps mem.
+func (a Allocator) Free(mem []byte) error {
+	return unix.Munmap(mem)
+}
+
+// Nodes returns the NUMA nodes which are online, in ascending order. It
+// returns ErrUnsupported if the kernel was built without NUMA support.
+func Nodes() ([]int, error) {
+	b, err := ioutil.ReadFile(sysNode + "online")
+	if os.IsNotExist(err) {
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return parseList(string(b))
+}
+
+// CPUs returns the CPUs of node, in ascending order. It is empty for a node
+// with memory only.
+func CPUs(node int) ([]int, error) {
+	if err := checkNode(node); err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(sysNode + "node" + strconv.Itoa(node) + "/cpulist")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseList(string(b))
+}
+
+// PinThread locks the calling goroutine to its OS thread, as
+// runtime.LockOSThread does, and restricts the thread to run on cpus only.
+//
+// The thread keeps its affinity after runtime.UnlockOSThread, so a goroutine
+// which pinned its thread should rather return without unlocking it, in which
+// case the runtime terminates the thread.
+func PinThread(cpus []int) error {
+	if len(cpus) == 0 {
+		return errors.New("numa: no CPU to pin to")
+	}
+	var set unix.CPUSet
+	set.Zero()
+	for _, c := range cpus {
+		set.Set(c)
+	}
+
+	runtime.LockOSThread()
+	if err := unix.SchedSetaffinity(0, &set); err != nil {
+		runtime.UnlockOSThread()
+		return errors.New("numa: sched_setaffinity: " + err.Error())
+	}
+
+	return nil
+}
+
+func checkNode(node int) error {
+	nodes, err := Nodes()
+	if err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		if n == node {
+			return nil
+		}
+	}
+
+	return ErrNoNode
+}
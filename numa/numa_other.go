@@ -0,0 +1,20 @@
+// +build !linux purego tinygo
+
+package numa
+
+// Alloc returns ErrUnsupported.
+func (a Allocator) Alloc(n int) ([]byte, error) { return nil, ErrUnsupported }
+
+// Free returns ErrUnsupported.
+func (a Allocator) Free(mem []byte) error { return ErrUnsupported }
+
+// Nodes returns ErrUnsupported.
+func Nodes() ([]int, error) { return nil, ErrUnsupported }
+
+// CPUs returns ErrUnsupported.
+func CPUs(node int) ([]int, error) { return nil, ErrUnsupported }
+
+// PinThread returns ErrUnsupported.
+func PinThread(cpus []int) error { return ErrUnsupported }
+
+func checkNode(node int) error { return ErrUnsupported }
@@ -0,0 +1,90 @@
+package numa
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"ekyu.moe/cryptonight"
+)
+
+func TestParseList(t *testing.T) {
+	for i, v := range []struct {
+		in  string
+		out []int
+		err bool
+	}{
+		{"0\n", []int{0}, false},
+		{"0-3,8,10-11\n", []int{0, 1, 2, 3, 8, 10, 11}, false},
+		{"\n", nil, false},
+		{"3-1", nil, true},
+		{"-1", nil, true},
+		{"0,,1", nil, true},
+		{"a-b", nil, true},
+	} {
+		out, err := parseList(v.in)
+		if (err != nil) != v.err {
+			t.Errorf("%d: expected error %v, got %v", i, v.err, err)
+			continue
+		}
+		if len(out) != 0 || len(v.out) != 0 {
+			if !reflect.DeepEqual(out, v.out) {
+				t.Errorf("%d: expected %v, got %v", i, v.out, out)
+			}
+		}
+	}
+}
+
+func TestNewCacheOnNode(t *testing.T) {
+	nodes, err := Nodes()
+	if err == ErrUnsupported {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, node := range []int{-1, 1 << 20} {
+		if _, err := NewCacheOnNode(node); err != ErrNoNode {
+			t.Errorf("node %d: expected ErrNoNode, got %v", node, err)
+		}
+	}
+
+	cc, err := NewCacheOnNode(nodes[0])
+	if err == cryptonight.ErrAllocatorUnsupported {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+
+	data := []byte("This is a test")
+	if expected, got := cryptonight.Sum(data, 0), cc.Sum(data, 0); !bytes.Equal(got, expected) {
+		t.Errorf("expected %x, got %x", expected, got)
+	}
+}
+
+func TestPinNode(t *testing.T) {
+	nodes, err := Nodes()
+	if err == ErrUnsupported {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	cpus, err := CPUs(nodes[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cpus) == 0 {
+		t.Skipf("node %d has no CPU", nodes[0])
+	}
+
+	// the pinned thread is terminated once the goroutine returns
+	done := make(chan error)
+	go func() { done <- PinNode(nodes[0]) }()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
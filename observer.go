@@ -0,0 +1,29 @@
+package cryptonight
+
+import (
+	"time"
+
+	"ekyu.moe/cryptonight/internal/observe"
+)
+
+// Observer receives events from the hashing and verifying code of this module,
+// so that host applications such as wallet GUIs and farm managers can follow
+// them without scraping logs. Methods may be called concurrently from many
+// goroutines, and should return quickly as they run on the hashing path.
+type Observer interface {
+	// OnHashDone is called after each hash computed by Sum or Cache.Sum.
+	OnHashDone(variant int, elapsed time.Duration)
+
+	// OnShareFound is called when a verifier finds a hash meeting its target
+	// difficulty. hash must not be modified.
+	OnShareFound(hash []byte, target uint64)
+
+	// OnError is called when hashing or verifying fails, right before the
+	// error is returned or panicked with.
+	OnError(err error)
+}
+
+// SetObserver sets the Observer of the whole module. A nil o removes it.
+func SetObserver(o Observer) {
+	observe.Set(o)
+}
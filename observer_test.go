@@ -0,0 +1,63 @@
+package cryptonight
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	hashes []int
+	shares int
+	errs   []error
+}
+
+func (r *recorder) OnHashDone(variant int, elapsed time.Duration) {
+	r.mu.Lock()
+	r.hashes = append(r.hashes, variant)
+	r.mu.Unlock()
+}
+
+func (r *recorder) OnShareFound(hash []byte, target uint64) {
+	r.mu.Lock()
+	r.shares++
+	r.mu.Unlock()
+}
+
+func (r *recorder) OnError(err error) {
+	r.mu.Lock()
+	r.errs = append(r.errs, err)
+	r.mu.Unlock()
+}
+
+func TestObserver(t *testing.T) {
+	r := new(recorder)
+	SetObserver(r)
+	defer SetObserver(nil)
+
+	Sum(nil, 0)
+	new(Cache).Sum(make([]byte, 43), 1)
+	func() {
+		defer func() {
+			if p := recover(); p != ErrShortInput {
+				t.Fatalf("expected to panic with ErrShortInput, got %v.", p)
+			}
+		}()
+
+		Sum(nil, 1)
+	}()
+
+	if len(r.hashes) != 2 || r.hashes[0] != 0 || r.hashes[1] != 1 {
+		t.Errorf("expected hashes of variant 0 and 1, got %v", r.hashes)
+	}
+	if len(r.errs) != 1 || r.errs[0] != ErrShortInput {
+		t.Errorf("expected ErrShortInput, got %v", r.errs)
+	}
+
+	SetObserver(nil)
+	Sum(nil, 0)
+	if len(r.hashes) != 2 {
+		t.Errorf("observer called after being removed")
+	}
+}
@@ -0,0 +1,93 @@
+package cryptonight
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"ekyu.moe/cryptonight/internal/sha3"
+)
+
+// phase is one phase of a hash, which expects cc to hold the result of the
+// previous ones.
+type phase struct {
+	name string
+	run  func(cc *Cache, data []byte, p params, height uint64)
+}
+
+// goPhases are the phases of sumGo.
+var goPhases = []phase{
+	{"keccak", func(cc *Cache, data []byte, p params, height uint64) {
+		sha3.Keccak1600State(&cc.finalState, data)
+	}},
+	{"explode", func(cc *Cache, data []byte, p params, height uint64) {
+		cc.explode(cc.pad(p.memory), p)
+	}},
+	{"loop", func(cc *Cache, data []byte, p params, height uint64) {
+		cc.memhard(cc.pad(p.memory), data, p, height)
+	}},
+	{"implode", func(cc *Cache, data []byte, p params, height uint64) {
+		cc.implode(cc.pad(p.memory), p)
+	}},
+	{"final", func(cc *Cache, data []byte, p params, height uint64) {
+		cc.finalHash()
+	}},
+}
+
+// phasesOf are the phases of each backend the host can run, see
+// sum_amd64_test.go for the assembly.
+var phasesOf = map[string][]phase{"go": goPhases}
+
+// TestPhases checks that the phases of each backend add up to its hash.
+func TestPhases(t *testing.T) {
+	for backend, phases := range phasesOf {
+		for _, variant := range []int{0, 1, 2, 4} {
+			if backend != "go" && variant > 2 {
+				continue
+			}
+			cc := new(Cache)
+			for _, ph := range phases {
+				ph.run(cc, benchData[0], standard(variant), 1806260)
+			}
+			if expected := SumHeight(benchData[0], variant, 1806260); string(cc.digest[:]) != string(expected) {
+				t.Errorf("[%s v%d] expected %x, got %x", backend, variant, expected, cc.digest)
+			}
+		}
+	}
+}
+
+// BenchmarkPhases times each phase of a hash on its own, for every backend and
+// standard variant, along with the whole hash in H/s. The memory hard loop
+// should take most of it.
+func BenchmarkPhases(b *testing.B) {
+	for _, backend := range []string{"go", "amd64-aes"} {
+		phases, ok := phasesOf[backend]
+		if !ok {
+			continue
+		}
+		for _, variant := range []int{0, 1, 2, 4} {
+			if backend != "go" && variant > 2 {
+				continue
+			}
+			p := standard(variant)
+			cc := new(Cache)
+			for _, ph := range phases {
+				ph := ph
+				b.Run(fmt.Sprintf("%s/v%d/%s", backend, variant, ph.name), func(b *testing.B) {
+					for i := 0; i < b.N; i++ {
+						ph.run(cc, benchData[0], p, 1806260)
+					}
+				})
+			}
+			b.Run(fmt.Sprintf("%s/v%d/total", backend, variant), func(b *testing.B) {
+				start := time.Now()
+				for i := 0; i < b.N; i++ {
+					for _, ph := range phases {
+						ph.run(cc, benchData[i&0x03], p, 1806260)
+					}
+				}
+				reportHashrate(b, start, 1)
+			})
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package cryptonight
+
+import (
+	"runtime"
+
+	"ekyu.moe/cryptonight/internal/observe"
+)
+
+// Pool hashes with a bounded number of Cache, which caps the memory taken by
+// concurrent hashes at 2 MiB each, or 4 MiB once CNHeavy was hashed. Unlike the
+// pool behind the package-level functions, which grows with the number of
+// concurrent callers, a Pool makes callers wait for a free Cache, which suits
+// servers hashing on behalf of untrusted clients.
+//
+// A Pool is safe for concurrent use by multiple goroutines. It must be created
+// with NewPool.
+type Pool struct {
+	// caches holds the free caches, or nil for those not allocated yet.
+	caches chan *Cache
+}
+
+// NewPool returns a Pool of at most max caches, allocated on demand. If max is
+// not positive, it is runtime.GOMAXPROCS(0), as more concurrent hashes than
+// CPUs only take memory.
+func NewPool(max int) *Pool {
+	if max <= 0 {
+		max = runtime.GOMAXPROCS(0)
+	}
+
+	p := &Pool{caches: make(chan *Cache, max)}
+	for i := 0; i < max; i++ {
+		p.caches <- nil
+	}
+
+	return p
+}
+
+// Cap returns the maximum number of caches of p.
+func (p *Pool) Cap() int { return cap(p.caches) }
+
+// get waits for a free cache.
+func (p *Pool) get() *Cache {
+	cc := <-p.caches
+	if cc == nil {
+		cc = new(Cache)
+	}
+
+	return cc
+}
+
+func (p *Pool) put(cc *Cache) { p.caches <- cc }
+
+// Sum calculate a hash digest of algo with a cache of p, waiting for one to be
+// free. It panics the same way as SumAlgorithm does, and with
+// ErrHeightRequired if algo is CNR, which needs SumHeight.
+func (p *Pool) Sum(data []byte, algo Algorithm) []byte {
+	if algo == CNR {
+		observe.Error(ErrHeightRequired)
+		panic(ErrHeightRequired)
+	}
+
+	return p.SumHeight(data, algo, 0)
+}
+
+// SumHeight is like Sum, but also accepts CNR, which hashes with height.
+// height is ignored by the other algorithms.
+func (p *Pool) SumHeight(data []byte, algo Algorithm, height uint64) []byte {
+	if !algo.valid() {
+		observe.Error(ErrUnknownAlgorithm)
+		panic(ErrUnknownAlgorithm)
+	}
+
+	cc := p.get()
+	defer p.put(cc)
+
+	return cc.SumAlgorithm(data, algo, height)
+}
+
+// SumChecked is like SumHeight, but returns the error of ValidateAlgorithm
+// instead of panicking with it, which makes it suitable for untrusted input.
+func (p *Pool) SumChecked(data []byte, algo Algorithm, height uint64) ([]byte, error) {
+	if err := ValidateAlgorithm(data, algo); err != nil {
+		observe.Error(err)
+		return nil, err
+	}
+
+	return p.SumHeight(data, algo, height), nil
+}
@@ -0,0 +1,76 @@
+package cryptonight
+
+import (
+	"encoding/hex"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestPool(t *testing.T) {
+	if c := NewPool(0).Cap(); c != runtime.GOMAXPROCS(0) {
+		t.Errorf("unexpected default capacity %d", c)
+	}
+
+	p := NewPool(2)
+	var wg sync.WaitGroup
+	for g := 0; g < 6; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+
+			v := hashSpecsV2[g%len(hashSpecsV2)]
+			in, _ := hex.DecodeString(v.input)
+			if result := p.Sum(in, CNv2); hex.EncodeToString(result) != v.output {
+				t.Errorf("\n[goroutine %d] expected:\n\t%s\ngot:\n\t%x\n", g, v.output, result)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	// caches are allocated on demand, and never more than the capacity
+	allocated := 0
+	for i := 0; i < p.Cap(); i++ {
+		if cc := <-p.caches; cc != nil {
+			allocated++
+		}
+	}
+	if allocated == 0 || allocated > 2 {
+		t.Errorf("unexpected number of caches %d", allocated)
+	}
+
+	v := hashSpecsV4[0]
+	in, _ := hex.DecodeString(v.input)
+	if result := NewPool(1).SumHeight(in, CNR, v.height); hex.EncodeToString(result) != v.output {
+		t.Errorf("\n[%s] expected:\n\t%s\ngot:\n\t%x\n", CNR, v.output, result)
+	}
+
+	if _, err := NewPool(1).SumChecked(make([]byte, 42), CNv1, 0); err != ErrShortInput {
+		t.Errorf("expected ErrShortInput, got %v", err)
+	}
+
+	for algo, err := range map[Algorithm]error{
+		CNR:           ErrHeightRequired,
+		Algorithm(-1): ErrUnknownAlgorithm,
+		CNv1:          ErrShortInput,
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r != err {
+					t.Errorf("%s: expected panic with %v, got %v", algo, err, r)
+				}
+			}()
+			NewPool(1).Sum(nil, algo)
+		}()
+	}
+
+	// a panic must give the cache back
+	p = NewPool(1)
+	func() {
+		defer func() { recover() }()
+		p.Sum(nil, CNv1)
+	}()
+	if len(p.caches) != 1 {
+		t.Error("the cache was not given back after a panic")
+	}
+}
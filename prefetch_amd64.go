@@ -0,0 +1,6 @@
+// +build amd64,!purego,!noprefetch,!tinygo
+
+package cryptonight
+
+// prefetch enables the PREFETCH hints of the assembly, see sum_defs_amd64.h.
+const prefetch = 1
@@ -0,0 +1,283 @@
+package cryptonight
+
+import (
+	"encoding/binary"
+	"math/bits"
+
+	"ekyu.moe/cryptonight/blake256"
+)
+
+// This file implements the random math of variant 4, also known as CN-R, as
+// per monero: src/crypto/variant4_random_math.h. A program of 60 to 70
+// instructions is generated from the block height, and it is run on 9 32-bit
+// registers in every iteration of the memory hard loop.
+
+// opcodes of the random math, in the order of monero
+const (
+	opMul = iota // a*b
+	opAdd        // a+b + C, C is an unsigned 32-bit constant
+	opSub        // a-b
+	opRor        // rotate right "a" by "b & 31" bits
+	opRol        // rotate left "a" by "b & 31" bits
+	opXor        // a^b
+	opRet        // finish execution
+
+	opCount = opRet
+)
+
+const (
+	// minimal theoretical latency of a program, 15 multiplications
+	rmTotalLatency = 15 * 3
+
+	rmMinInstructions = 60
+	rmMaxInstructions = 70 // final opRet is not counted
+
+	rmALUCountMul = 1
+	rmALUCount    = 3
+)
+
+var (
+	// latencies for Intel CPUs starting from Sandy Bridge
+	rmOpLatency = [opCount]int{3, 2, 1, 2, 2, 1}
+
+	// latencies for a theoretical ASIC
+	rmASICOpLatency = [opCount]int{3, 1, 1, 1, 1, 1}
+
+	// available ALUs for each opcode
+	rmOpALUs = [opCount]int{rmALUCountMul, rmALUCount, rmALUCount, rmALUCount, rmALUCount, rmALUCount}
+)
+
+// rmInstruction is an instruction of the random math. dst indexes one of the 4
+// variable registers, src any of the 9 registers.
+type rmInstruction struct {
+	opcode, dst, src uint8
+	c                uint32
+}
+
+// rmProgram is a generated program, terminated by opRet.
+type rmProgram [rmMaxInstructions + 1]rmInstruction
+
+// rmSeed feeds the generator with random bytes, rehashing its 32 bytes of data
+// with blake256 whenever they run out.
+type rmSeed struct {
+	data  [32]byte
+	index int
+}
+
+func (s *rmSeed) next(n int) []byte {
+	if s.index+n > len(s.data) {
+		h := blake256.New()
+		h.Write(s.data[:])
+		h.Sum(s.data[:0])
+		s.index = 0
+	}
+	b := s.data[s.index : s.index+n]
+	s.index += n
+
+	return b
+}
+
+// generate fills p with the program of height, and returns its length without
+// the final opRet. It simulates the latencies of a CPU and of an ASIC, so that
+// the program is as long as possible on both, and retries until register 8 is
+// used as a source.
+func (p *rmProgram) generate(height uint64) int {
+	seed := rmSeed{index: 32}
+	binary.LittleEndian.PutUint64(seed.data[:], height)
+	seed.data[20] = 0xda // change seed
+
+	var (
+		size   int
+		r8Used bool
+	)
+	for !r8Used || size < rmMinInstructions || size > rmMaxInstructions {
+		var (
+			latency, asicLatency [9]int
+
+			// Tracks the previous instruction and the value of the source
+			// operand of registers 0 to 3: the low byte is the current value of
+			// the destination, then the opcode, then the value of the source.
+			// Registers 4 to 8 are constant, and treated as having the same
+			// value.
+			instData = [9]uint32{0, 1, 2, 3, 0xffffff, 0xffffff, 0xffffff, 0xffffff, 0xffffff}
+
+			aluBusy     [rmTotalLatency + 1][rmALUCount]bool
+			rotated     [4]bool
+			rotateCount int
+			numRetries  int
+		)
+		size = 0
+		r8Used = false
+
+		for total := 0; (latency[0] < rmTotalLatency || latency[1] < rmTotalLatency ||
+			latency[2] < rmTotalLatency || latency[3] < rmTotalLatency) && numRetries < 64; {
+			// fail-safe to guarantee termination
+			if total++; total > 256 {
+				break
+			}
+
+			c := seed.next(1)[0]
+
+			// MUL = 0-2, ADD = 3, SUB = 4, ROR/ROL = 5, XOR = 6-7
+			opcode := c & 7
+			switch {
+			case opcode == 5:
+				if seed.next(1)[0] < 0x80 {
+					opcode = opRor
+				} else {
+					opcode = opRol
+				}
+			case opcode >= 6:
+				opcode = opXor
+			case opcode <= 2:
+				opcode = opMul
+			default:
+				opcode -= 2
+			}
+
+			dst := c >> 3 & 3
+			src := c >> 5 & 7
+			a, b := int(dst), int(src)
+
+			// don't do ADD/SUB/XOR with the same register, use register 8
+			// as source instead
+			if (opcode == opAdd || opcode == opSub || opcode == opXor) && a == b {
+				b, src = 8, 8
+			}
+
+			// don't rotate the same destination twice, it's a single rotation
+			rotation := opcode == opRor || opcode == opRol
+			if rotation && rotated[a] {
+				continue
+			}
+
+			// don't do the same instruction (but MUL) with the same source
+			// value twice, as they can be optimized into a single one
+			if opcode != opMul && instData[a]&0xffff00 == uint32(opcode)<<8+(instData[b]&0xff)<<16 {
+				continue
+			}
+
+			// find which ALU is available, and when, for this instruction
+			next := latency[a]
+			if latency[b] > next {
+				next = latency[b]
+			}
+			alu := -1
+			for ; next < rmTotalLatency; next++ {
+				for i := rmOpALUs[opcode] - 1; i >= 0; i-- {
+					if aluBusy[next][i] {
+						continue
+					}
+					// ADD is two 1-cycle instructions on a real CPU
+					if opcode == opAdd && aluBusy[next+1][i] {
+						continue
+					}
+					// a rotation only starts when the previous one is done
+					if rotation && next < rotateCount*rmOpLatency[opcode] {
+						continue
+					}
+					alu = i
+					break
+				}
+				if alu >= 0 {
+					break
+				}
+			}
+
+			// don't leave a register unchanged for more than 7 cycles
+			if next > latency[a]+7 {
+				continue
+			}
+
+			next += rmOpLatency[opcode]
+			if next > rmTotalLatency {
+				numRetries++
+				continue
+			}
+
+			if rotation {
+				rotateCount++
+			}
+
+			// ALUs are fully pipelined, they are only busy for the first cycle
+			aluBusy[next-rmOpLatency[opcode]][alu] = true
+			latency[a] = next
+
+			// an ASIC runs as many independent instructions per cycle as it
+			// can
+			if asicLatency[b] > asicLatency[a] {
+				asicLatency[a] = asicLatency[b]
+			}
+			asicLatency[a] += rmASICOpLatency[opcode]
+
+			rotated[a] = rotation
+			instData[a] = uint32(size) + uint32(opcode)<<8 + (instData[b]&0xff)<<16
+
+			p[size] = rmInstruction{opcode: opcode, dst: dst, src: src}
+			if src == 8 {
+				r8Used = true
+			}
+			if opcode == opAdd {
+				aluBusy[next-rmOpLatency[opcode]+1][alu] = true
+				p[size].c = binary.LittleEndian.Uint32(seed.next(4))
+			}
+
+			if size++; size >= rmMinInstructions {
+				break
+			}
+		}
+
+		// An ASIC extracts as much parallelism as possible, so add a few more
+		// MUL and ROR to reach the latency on it for at least 1 register.
+		prevSize := size
+		for size < rmMaxInstructions && asicLatency[0] < rmTotalLatency && asicLatency[1] < rmTotalLatency &&
+			asicLatency[2] < rmTotalLatency && asicLatency[3] < rmTotalLatency {
+			minIdx, maxIdx := 0, 0
+			for i := 1; i < 4; i++ {
+				if asicLatency[i] < asicLatency[minIdx] {
+					minIdx = i
+				}
+				if asicLatency[i] > asicLatency[maxIdx] {
+					maxIdx = i
+				}
+			}
+
+			opcode := [3]uint8{opRor, opMul, opMul}[(size-prevSize)%3]
+			latency[minIdx] = latency[maxIdx] + rmOpLatency[opcode]
+			asicLatency[minIdx] = asicLatency[maxIdx] + rmASICOpLatency[opcode]
+
+			p[size] = rmInstruction{opcode: opcode, dst: uint8(minIdx), src: uint8(maxIdx)}
+			size++
+		}
+	}
+
+	p[size] = rmInstruction{opcode: opRet}
+
+	return size
+}
+
+// run executes p on r, of which r[0:4] are the variable registers and r[4:9]
+// the constant ones.
+func (p *rmProgram) run(r *[9]uint32) {
+	for i := range p {
+		op := &p[i]
+		src := r[op.src]
+		dst := &r[op.dst]
+		switch op.opcode {
+		case opMul:
+			*dst *= src
+		case opAdd:
+			*dst += src + op.c
+		case opSub:
+			*dst -= src
+		case opRor:
+			*dst = bits.RotateLeft32(*dst, -int(src%32))
+		case opRol:
+			*dst = bits.RotateLeft32(*dst, int(src%32))
+		case opXor:
+			*dst ^= src
+		default:
+			return
+		}
+	}
+}
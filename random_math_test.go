@@ -0,0 +1,51 @@
+package cryptonight
+
+import (
+	"testing"
+)
+
+func TestRandomMathGenerate(t *testing.T) {
+	var p rmProgram
+	for height := uint64(1806260); height < 1806260+2000; height++ {
+		size := p.generate(height)
+		if size < rmMinInstructions || size > rmMaxInstructions {
+			t.Fatalf("[%d] %d instructions generated", height, size)
+		}
+		if p[size].opcode != opRet {
+			t.Fatalf("[%d] program not terminated", height)
+		}
+
+		r8Used := false
+		for _, op := range p[:size] {
+			if op.opcode >= opRet || op.dst > 3 || op.src > 8 {
+				t.Fatalf("[%d] invalid instruction %+v", height, op)
+			}
+			if op.c != 0 && op.opcode != opAdd {
+				t.Fatalf("[%d] constant on %+v", height, op)
+			}
+			r8Used = r8Used || op.src == 8
+		}
+		if !r8Used {
+			t.Fatalf("[%d] register 8 not used", height)
+		}
+	}
+}
+
+func TestRandomMathRun(t *testing.T) {
+	p := rmProgram{
+		{opcode: opMul, dst: 0, src: 4},
+		{opcode: opAdd, dst: 1, src: 5, c: 0xffffffff},
+		{opcode: opSub, dst: 2, src: 6},
+		{opcode: opRor, dst: 3, src: 7},
+		{opcode: opRol, dst: 0, src: 8},
+		{opcode: opXor, dst: 1, src: 0},
+		{opcode: opRet},
+		{opcode: opXor, dst: 2, src: 0}, // never run
+	}
+	r := [9]uint32{3, 5, 7, 0x80000001, 0x10000, 2, 8, 33, 4}
+	p.run(&r)
+
+	if expected := [9]uint32{0x300000, 0x300006, 0xffffffff, 0xc0000000, 0x10000, 2, 8, 33, 4}; r != expected {
+		t.Errorf("\nexpected:\n\t%x\ngot:\n\t%x\n", expected, r)
+	}
+}
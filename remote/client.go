@@ -0,0 +1,455 @@
+package remote
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ekyu.moe/cryptonight"
+	"ekyu.moe/cryptonight/internal/observe"
+)
+
+// Config contains optional parameters of a Client. A zero field means its
+// default value.
+type Config struct {
+	DialTimeout    time.Duration // timeout of connecting to a worker, default 5s
+	Retries        int           // retries on other workers after a failure, default 2
+	HealthInterval time.Duration // interval of health checks, default 10s
+}
+
+// Request is a hash to compute.
+type Request struct {
+	Data      []byte
+	Algorithm cryptonight.Algorithm
+	Height    uint64 // for CNR
+}
+
+// Result is the result of a Request in a batch.
+type Result struct {
+	Hash []byte
+	Err  error
+}
+
+// Client dispatches requests to a set of workers. Workers failing a request or
+// a health check are skipped until they pass a health check again. A Client
+// is safe for concurrent use.
+type Client struct {
+	conf    Config
+	workers []*worker
+	next    uint32 // round robin counter
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewClient returns a Client dispatching requests to the workers at addrs.
+// conf may be nil.
+func NewClient(addrs []string, conf *Config) *Client {
+	c := &Client{done: make(chan struct{})}
+	if conf != nil {
+		c.conf = *conf
+	}
+	if c.conf.DialTimeout <= 0 {
+		c.conf.DialTimeout = 5 * time.Second
+	}
+	if c.conf.Retries <= 0 {
+		c.conf.Retries = 2
+	}
+	if c.conf.HealthInterval <= 0 {
+		c.conf.HealthInterval = 10 * time.Second
+	}
+
+	for _, addr := range addrs {
+		c.workers = append(c.workers, &worker{addr: addr, healthy: 1})
+	}
+	go c.checkHealth()
+
+	return c
+}
+
+// Close stops health checks and closes all connections.
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		for _, w := range c.workers {
+			w.mu.Lock()
+			if w.cn != nil {
+				w.cn.close(ErrUnavailable)
+				w.cn = nil
+			}
+			w.mu.Unlock()
+		}
+	})
+
+	return nil
+}
+
+// variantAlgorithms are the algorithms of the variants Sum accepts.
+var variantAlgorithms = map[int]cryptonight.Algorithm{
+	0: cryptonight.CNv0,
+	1: cryptonight.CNv1,
+	2: cryptonight.CNv2,
+}
+
+// Sum calculates a CryptoNight hash digest of variant on a worker, retrying on
+// other workers if it fails to respond. Like cryptonight.Sum, it does not take
+// variant 4, for which SumAlgorithm hashes CNR at a height.
+func (c *Client) Sum(ctx context.Context, data []byte, variant int) ([]byte, error) {
+	algo, ok := variantAlgorithms[variant]
+	if !ok {
+		if variant == 4 {
+			return nil, cryptonight.ErrHeightRequired
+		}
+		return nil, cryptonight.ErrUnknownVariant
+	}
+
+	return c.SumAlgorithm(ctx, data, algo, 0)
+}
+
+// SumAlgorithm calculates a hash digest of algo on a worker, the same way as
+// Sum does. height is only used by CNR.
+func (c *Client) SumAlgorithm(ctx context.Context, data []byte, algo cryptonight.Algorithm, height uint64) ([]byte, error) {
+	q, err := newSumRequest(data, algo, height)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.do(ctx, q)
+}
+
+// Verify calculates a CryptoNight hash digest on a worker and checks its
+// difficulty against target.
+func (c *Client) Verify(ctx context.Context, data []byte, variant int, target uint64) (bool, error) {
+	sum, err := c.Sum(ctx, data, variant)
+	if err != nil {
+		return false, err
+	}
+	if !cryptonight.CheckHash(sum, target) {
+		return false, nil
+	}
+	observe.ShareFound(sum, target)
+
+	return true, nil
+}
+
+// SumBatch computes many hashes at once. Requests are spread over the healthy
+// workers and pipelined on their connections. Requests of a worker failing in
+// the middle are retried individually as by Sum.
+func (c *Client) SumBatch(ctx context.Context, reqs []Request) []Result {
+	results := make([]Result, len(reqs))
+
+	// group requests by worker
+	groups := make(map[*worker][]int)
+	qs := make([]*request, len(reqs))
+	for i, r := range reqs {
+		q, err := newSumRequest(r.Data, r.Algorithm, r.Height)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		qs[i] = q
+		w := c.pick()
+		if w == nil {
+			results[i].Err = ErrUnavailable
+			continue
+		}
+		groups[w] = append(groups[w], i)
+	}
+
+	var wg sync.WaitGroup
+	for w, idx := range groups {
+		wg.Add(1)
+		go func(w *worker, idx []int) {
+			defer wg.Done()
+
+			group := make([]*request, len(idx))
+			for j, i := range idx {
+				group[j] = qs[i]
+			}
+			chs, cn, err := w.send(ctx, c.conf.DialTimeout, group)
+			for j, i := range idx {
+				if err == nil {
+					var p *response
+					if p, err = cn.wait(ctx, group[j].id, chs[j]); err == nil {
+						results[i].Hash, results[i].Err = parseResponse(p)
+						continue
+					}
+					if ctx.Err() != nil {
+						results[i].Err = ctx.Err()
+						continue
+					}
+					w.drop(cn)
+				}
+				results[i].Hash, results[i].Err = c.do(ctx, qs[i])
+			}
+		}(w, idx)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// do sends q to a worker, retrying on others on failure.
+func (c *Client) do(ctx context.Context, q *request) ([]byte, error) {
+	for i := 0; i <= c.conf.Retries; i++ {
+		w := c.pick()
+		if w == nil {
+			break
+		}
+
+		chs, cn, err := w.send(ctx, c.conf.DialTimeout, []*request{q})
+		if err == nil {
+			var p *response
+			if p, err = cn.wait(ctx, q.id, chs[0]); err == nil {
+				return parseResponse(p)
+			}
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		w.drop(cn)
+	}
+	observe.Error(ErrUnavailable)
+
+	return nil, ErrUnavailable
+}
+
+// pick returns the next healthy worker in round robin, or simply the next
+// worker if none of them is healthy. It returns nil if there is no worker.
+func (c *Client) pick() *worker {
+	n := uint32(len(c.workers))
+	if n == 0 {
+		return nil
+	}
+	start := atomic.AddUint32(&c.next, 1)
+	for i := uint32(0); i < n; i++ {
+		if w := c.workers[(start+i)%n]; atomic.LoadInt32(&w.healthy) == 1 {
+			return w
+		}
+	}
+
+	return c.workers[start%n]
+}
+
+// checkHealth pings every worker at each interval, until c is closed.
+func (c *Client) checkHealth() {
+	t := time.NewTicker(c.conf.HealthInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-t.C:
+		}
+
+		for _, w := range c.workers {
+			go c.ping(w)
+		}
+	}
+}
+
+func (c *Client) ping(w *worker) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.conf.DialTimeout)
+	defer cancel()
+
+	q := &request{op: opPing}
+	chs, cn, err := w.send(ctx, c.conf.DialTimeout, []*request{q})
+	if err == nil {
+		_, err = cn.wait(ctx, q.id, chs[0])
+	}
+	if err != nil {
+		w.drop(cn)
+		return
+	}
+	atomic.StoreInt32(&w.healthy, 1)
+}
+
+func newSumRequest(data []byte, algo cryptonight.Algorithm, height uint64) (*request, error) {
+	if len(data) > MaxBlobSize {
+		return nil, errBlobTooLarge
+	}
+	if algo < 0 || algo > 255 {
+		return nil, cryptonight.ErrUnknownAlgorithm
+	}
+
+	return &request{op: opSum, algo: uint8(algo), height: height, blob: data}, nil
+}
+
+func parseResponse(p *response) ([]byte, error) {
+	if p.status != statusOK {
+		return nil, ServerError(p.payload)
+	}
+	if len(p.payload) != 32 {
+		return nil, errors.New("remote: malformed response")
+	}
+
+	return p.payload, nil
+}
+
+// worker is a remote worker with at most one connection at a time.
+type worker struct {
+	addr    string
+	healthy int32 // 1 if healthy, accessed atomically
+
+	mu sync.Mutex
+	cn *conn
+}
+
+// send sends qs on the connection to w, dialing it if needed. It assigns the
+// request IDs and returns a channel for each of them.
+func (w *worker) send(ctx context.Context, timeout time.Duration, qs []*request) ([]chan *response, *conn, error) {
+	w.mu.Lock()
+	cn := w.cn
+	if cn == nil {
+		d := net.Dialer{Timeout: timeout}
+		nc, err := d.DialContext(ctx, "tcp", w.addr)
+		if err != nil {
+			w.mu.Unlock()
+			atomic.StoreInt32(&w.healthy, 0)
+			return nil, nil, err
+		}
+		cn = newConn(nc)
+		w.cn = cn
+	}
+	w.mu.Unlock()
+
+	chs, err := cn.send(qs)
+
+	return chs, cn, err
+}
+
+// drop marks w unhealthy and closes cn if it is still the connection of w.
+// cn is nil if w failed to dial.
+func (w *worker) drop(cn *conn) {
+	atomic.StoreInt32(&w.healthy, 0)
+	if cn == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	cn.close(ErrUnavailable)
+	if cn == w.cn {
+		w.cn = nil
+	}
+}
+
+// conn is a connection with pipelined requests.
+type conn struct {
+	nc net.Conn
+	w  frameWriter
+
+	mu      sync.Mutex
+	lastID  uint32
+	pending map[uint32]chan *response
+	err     error // set once the connection is broken
+}
+
+func newConn(nc net.Conn) *conn {
+	cn := &conn{
+		nc:      nc,
+		w:       frameWriter{w: bufio.NewWriter(nc)},
+		pending: make(map[uint32]chan *response),
+	}
+	go cn.readLoop()
+
+	return cn
+}
+
+// send registers and writes qs, then flushes them at once.
+func (cn *conn) send(qs []*request) ([]chan *response, error) {
+	chs := make([]chan *response, len(qs))
+
+	cn.mu.Lock()
+	if cn.err != nil {
+		cn.mu.Unlock()
+		return nil, cn.err
+	}
+	for i, q := range qs {
+		cn.lastID++
+		q.id = cn.lastID
+		chs[i] = make(chan *response, 1)
+		cn.pending[q.id] = chs[i]
+	}
+	cn.mu.Unlock()
+
+	cn.w.mu.Lock()
+	var err error
+	for _, q := range qs {
+		if err = writeRequest(cn.w.w, q); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = cn.w.w.Flush()
+	}
+	cn.w.mu.Unlock()
+
+	if err != nil {
+		cn.close(err)
+		return nil, err
+	}
+
+	return chs, nil
+}
+
+// wait waits for the response of request id on ch.
+func (cn *conn) wait(ctx context.Context, id uint32, ch chan *response) (*response, error) {
+	select {
+	case p, ok := <-ch:
+		if !ok {
+			cn.mu.Lock()
+			err := cn.err
+			cn.mu.Unlock()
+			return nil, err
+		}
+		return p, nil
+
+	case <-ctx.Done():
+		cn.mu.Lock()
+		delete(cn.pending, id)
+		cn.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (cn *conn) readLoop() {
+	r := bufio.NewReader(cn.nc)
+	for {
+		p, err := readResponse(r)
+		if err != nil {
+			cn.close(err)
+			return
+		}
+
+		cn.mu.Lock()
+		ch := cn.pending[p.id]
+		delete(cn.pending, p.id)
+		cn.mu.Unlock()
+		if ch != nil {
+			ch <- p
+		}
+	}
+}
+
+// close closes the connection with err, failing all pending requests.
+func (cn *conn) close(err error) {
+	cn.mu.Lock()
+	defer cn.mu.Unlock()
+
+	if cn.err != nil {
+		return
+	}
+	cn.err = err
+	cn.nc.Close()
+	for id, ch := range cn.pending {
+		close(ch)
+		delete(cn.pending, id)
+	}
+}
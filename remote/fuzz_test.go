@@ -0,0 +1,56 @@
+// +build go1.18
+
+package remote
+
+import (
+	"bytes"
+	"testing"
+)
+
+// FuzzReadRequest checks that readRequest never panics on arbitrary input, and
+// that whatever it accepts is written back unchanged by writeRequest.
+func FuzzReadRequest(f *testing.F) {
+	var buf bytes.Buffer
+	writeRequest(&buf, &request{id: 1, op: opSum, algo: 3, height: 1806260, blob: testIn})
+	f.Add(buf.Bytes())
+	f.Add([]byte{0, 0, 0, 0, opPing, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	f.Add([]byte{0, 0, 0, 0, opSum, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		q, err := readRequest(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := writeRequest(&buf, q); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), data[:buf.Len()]) {
+			t.Errorf("\nexpected:\n\t%x\ngot:\n\t%x\n", data[:buf.Len()], buf.Bytes())
+		}
+	})
+}
+
+// FuzzReadResponse is FuzzReadRequest for responses.
+func FuzzReadResponse(f *testing.F) {
+	var buf bytes.Buffer
+	writeResponse(&buf, &response{id: 1, payload: make([]byte, 32)})
+	f.Add(buf.Bytes())
+	f.Add([]byte{0, 0, 0, 0, statusErr, 0xff, 0xff, 0xff, 0xff})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p, err := readResponse(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := writeResponse(&buf, p); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), data[:buf.Len()]) {
+			t.Errorf("\nexpected:\n\t%x\ngot:\n\t%x\n", data[:buf.Len()], buf.Bytes())
+		}
+	})
+}
@@ -0,0 +1,150 @@
+// Package remote offloads CryptoNight hashing to a fleet of worker machines
+// over TCP, for front-ends, e.g. stratum servers of a pool, which are too
+// small to hash locally.
+//
+// The protocol is a sequence of length-prefixed binary frames over a plain
+// TCP connection. A request is an 18 bytes header followed by the blob: the
+// request ID in uint32, the operation in uint8, the cryptonight.Algorithm in
+// uint8, the block height in uint64 and the length of the blob in uint32. A
+// response is a 9 bytes header followed by the
+// payload: the request ID in uint32, the status in uint8 and the length of the
+// payload in uint32. The payload is the 32 bytes hash when status is 0, or an
+// error message otherwise. All integers are little endian.
+//
+// Requests on a connection are served concurrently, so responses may come
+// back in any order and are matched by their request ID. A client can thus
+// pipeline requests on a single connection, which is how batches are sent. A
+// server only reads a request once fewer than its number of workers are in
+// flight on the connection.
+package remote // import "ekyu.moe/cryptonight/remote"
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+)
+
+// MaxBlobSize limits the size of a blob in a request.
+const MaxBlobSize = 1 << 20
+
+// Operations of a request.
+const (
+	opPing = 0 // health check, replied with an empty payload
+	opSum  = 1
+)
+
+// Status of a response.
+const (
+	statusOK  = 0
+	statusErr = 1
+)
+
+var (
+	// ErrUnavailable is returned when no worker could serve a request within
+	// the configured retries.
+	ErrUnavailable = errors.New("remote: no worker available")
+
+	errBlobTooLarge = errors.New("remote: blob too large")
+)
+
+// ServerError is an error reported by a worker, e.g. for an invalid algorithm.
+// It is never retried, since any other worker would report it as well.
+type ServerError string
+
+func (e ServerError) Error() string { return "remote: " + string(e) }
+
+type request struct {
+	id     uint32
+	op     uint8
+	algo   uint8
+	height uint64
+	blob   []byte
+}
+
+type response struct {
+	id      uint32
+	status  uint8
+	payload []byte
+}
+
+func readRequest(r io.Reader) (*request, error) {
+	var hdr [18]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+
+	n := binary.LittleEndian.Uint32(hdr[14:])
+	if n > MaxBlobSize {
+		return nil, errBlobTooLarge
+	}
+	q := &request{
+		id:     binary.LittleEndian.Uint32(hdr[0:]),
+		op:     hdr[4],
+		algo:   hdr[5],
+		height: binary.LittleEndian.Uint64(hdr[6:]),
+		blob:   make([]byte, n),
+	}
+	if _, err := io.ReadFull(r, q.blob); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+func writeRequest(w io.Writer, q *request) error {
+	var hdr [18]byte
+	binary.LittleEndian.PutUint32(hdr[0:], q.id)
+	hdr[4] = q.op
+	hdr[5] = q.algo
+	binary.LittleEndian.PutUint64(hdr[6:], q.height)
+	binary.LittleEndian.PutUint32(hdr[14:], uint32(len(q.blob)))
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(q.blob)
+
+	return err
+}
+
+func readResponse(r io.Reader) (*response, error) {
+	var hdr [9]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+
+	n := binary.LittleEndian.Uint32(hdr[5:])
+	if n > MaxBlobSize {
+		return nil, errBlobTooLarge
+	}
+	p := &response{
+		id:      binary.LittleEndian.Uint32(hdr[0:]),
+		status:  hdr[4],
+		payload: make([]byte, n),
+	}
+	if _, err := io.ReadFull(r, p.payload); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func writeResponse(w io.Writer, p *response) error {
+	var hdr [9]byte
+	binary.LittleEndian.PutUint32(hdr[0:], p.id)
+	hdr[4] = p.status
+	binary.LittleEndian.PutUint32(hdr[5:], uint32(len(p.payload)))
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(p.payload)
+
+	return err
+}
+
+// frameWriter serializes frames written by multiple goroutines.
+type frameWriter struct {
+	mu sync.Mutex
+	w  *bufio.Writer
+}
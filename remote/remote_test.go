@@ -0,0 +1,234 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ekyu.moe/cryptonight"
+)
+
+var (
+	testIn  = []byte("This is a test")
+	testOut = "a084f01d1437a09c6985401b60d43554ae105802c5f5d8a9b3253649c0be6605"
+)
+
+func startServer(t *testing.T) (addr string, stop func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{ErrorLog: log.New(ioutil.Discard, "", 0)}
+	go s.Serve(l)
+
+	return l.Addr().String(), func() { l.Close() }
+}
+
+// deadAddr returns an address nobody listens on.
+func deadAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	return addr
+}
+
+func TestSum(t *testing.T) {
+	addr, stop := startServer(t)
+	defer stop()
+
+	c := NewClient([]string{addr}, nil)
+	defer c.Close()
+
+	sum, err := c.Sum(context.Background(), testIn, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(sum) != testOut {
+		t.Errorf("\nexpected:\n\t%s\ngot:\n\t%x\n", testOut, sum)
+	}
+
+	if _, err := c.Sum(context.Background(), testIn, 1); err == nil {
+		t.Error("expected error for short variant 1 input")
+	} else if _, ok := err.(ServerError); !ok {
+		t.Errorf("expected ServerError, got %T: %v", err, err)
+	}
+	if _, err := c.Sum(context.Background(), testIn, 7); err == nil {
+		t.Error("expected error for unknown variant")
+	}
+
+	if ok, err := c.Verify(context.Background(), testIn, 0, 1); !ok || err != nil {
+		t.Errorf("expected target 1 to be met, got %v, %v", ok, err)
+	}
+}
+
+func TestSumAlgorithm(t *testing.T) {
+	addr, stop := startServer(t)
+	defer stop()
+
+	c := NewClient([]string{addr}, nil)
+	defer c.Close()
+
+	in := bytes.Repeat(testIn, 4) // long enough for Chukwa
+	for _, a := range []cryptonight.Algorithm{cryptonight.CNR, cryptonight.CNPico, cryptonight.Chukwa} {
+		want := cryptonight.SumAlgorithm(in, a, 1806260)
+		sum, err := c.SumAlgorithm(context.Background(), in, a, 1806260)
+		if err != nil {
+			t.Errorf("%v: %v", a, err)
+		} else if !bytes.Equal(sum, want) {
+			t.Errorf("\n%v: expected:\n\t%x\ngot:\n\t%x\n", a, want, sum)
+		}
+	}
+
+	if _, err := c.SumAlgorithm(context.Background(), testIn, 200, 0); err == nil {
+		t.Error("expected error for unknown algorithm")
+	} else if _, ok := err.(ServerError); !ok {
+		t.Errorf("expected ServerError, got %T: %v", err, err)
+	}
+	if _, err := c.Sum(context.Background(), testIn, 4); err != cryptonight.ErrHeightRequired {
+		t.Errorf("expected ErrHeightRequired for variant 4, got %v", err)
+	}
+}
+
+// TestServerConn checks that the requests pipelined beyond the workers of a
+// server are all served, and that a stalled request times out.
+func TestServerConn(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go (&Server{Workers: 1, ReadTimeout: 100 * time.Millisecond, ErrorLog: log.New(ioutil.Discard, "", 0)}).Serve(l)
+
+	nc, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	var buf bytes.Buffer
+	for i := uint32(1); i <= 4; i++ {
+		writeRequest(&buf, &request{id: i, op: opSum, blob: testIn})
+	}
+	buf.Write([]byte{5, 0, 0, 0, opSum}) // stalled in the middle of its header
+	if _, err := nc.Write(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	nc.SetReadDeadline(time.Now().Add(10 * time.Second))
+	seen := make(map[uint32]bool)
+	for i := 0; i < 4; i++ {
+		p, err := readResponse(nc)
+		if err != nil {
+			t.Fatalf("[%d] %v", i, err)
+		}
+		if hex.EncodeToString(p.payload) != testOut {
+			t.Errorf("[%d] unexpected response %d %q", p.id, p.status, p.payload)
+		}
+		seen[p.id] = true
+	}
+	if len(seen) != 4 {
+		t.Errorf("expected 4 distinct responses, got %v", seen)
+	}
+	if _, err := readResponse(nc); err != io.EOF {
+		t.Errorf("expected the stalled connection to be closed, got %v", err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	addr, stop := startServer(t)
+	defer stop()
+
+	// the dead worker comes first in round robin at least once
+	c := NewClient([]string{deadAddr(t), addr, deadAddr(t)}, &Config{Retries: 3})
+	defer c.Close()
+
+	for i := 0; i < 3; i++ {
+		sum, err := c.Sum(context.Background(), testIn, 0)
+		if err != nil {
+			t.Fatalf("[%d] %v", i, err)
+		}
+		if hex.EncodeToString(sum) != testOut {
+			t.Errorf("\n[%d] expected:\n\t%s\ngot:\n\t%x\n", i, testOut, sum)
+		}
+	}
+
+	c = NewClient([]string{deadAddr(t)}, nil)
+	defer c.Close()
+	if _, err := c.Sum(context.Background(), testIn, 0); err != ErrUnavailable {
+		t.Errorf("expected ErrUnavailable, got %v", err)
+	}
+}
+
+func TestSumBatch(t *testing.T) {
+	addr1, stop1 := startServer(t)
+	defer stop1()
+	addr2, stop2 := startServer(t)
+	defer stop2()
+
+	c := NewClient([]string{addr1, addr2, deadAddr(t)}, nil)
+	defer c.Close()
+
+	reqs := make([]Request, 8)
+	for i := range reqs {
+		reqs[i] = Request{Data: testIn}
+	}
+	reqs[3].Algorithm = cryptonight.CNv1
+
+	want, _ := hex.DecodeString(testOut)
+	for i, r := range c.SumBatch(context.Background(), reqs) {
+		if i == 3 {
+			if r.Err == nil {
+				t.Errorf("[%d] expected error for short variant 1 input", i)
+			}
+			continue
+		}
+		if r.Err != nil {
+			t.Errorf("[%d] %v", i, r.Err)
+		} else if !bytes.Equal(r.Hash, want) {
+			t.Errorf("\n[%d] expected:\n\t%x\ngot:\n\t%x\n", i, want, r.Hash)
+		}
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	addr, stop := startServer(t)
+	defer stop()
+
+	c := NewClient([]string{addr}, &Config{HealthInterval: 10 * time.Millisecond})
+	defer c.Close()
+
+	w := c.workers[0]
+	w.drop(nil)
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&w.healthy) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("worker not marked healthy by health check")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	addr, stop := startServer(t)
+	defer stop()
+
+	c := NewClient([]string{addr}, nil)
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Sum(ctx, testIn, 0); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package remote
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"ekyu.moe/cryptonight"
+)
+
+// Server serves hashing requests of clients. The zero value of Server is
+// ready to use.
+type Server struct {
+	// Workers is the maximum number of concurrent hashes across all
+	// connections, each taking a 2 MiB cache, and of requests in flight on
+	// a single connection. Zero means GOMAXPROCS.
+	Workers int
+
+	// ReadTimeout is the maximum time to wait for the next request of a
+	// connection, blob included, once a worker is free for it. Zero means 2
+	// minutes, which idle clients outlast with their health checks.
+	ReadTimeout time.Duration
+
+	// WriteTimeout is the maximum time to write a response. Zero means 30
+	// seconds.
+	WriteTimeout time.Duration
+
+	// ErrorLog logs errors of connections. Nil means the standard logger of
+	// package log.
+	ErrorLog *log.Logger
+
+	once sync.Once
+	pool *cryptonight.Pool
+}
+
+func (s *Server) init() {
+	s.pool = cryptonight.NewPool(s.Workers)
+	if s.ReadTimeout <= 0 {
+		s.ReadTimeout = 2 * time.Minute
+	}
+	if s.WriteTimeout <= 0 {
+		s.WriteTimeout = 30 * time.Second
+	}
+}
+
+func (s *Server) logf(format string, v ...interface{}) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, v...)
+	} else {
+		log.Printf(format, v...)
+	}
+}
+
+// ListenAndServe listens on the TCP address addr and then calls Serve.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l and serves each of them in a new goroutine.
+// It always returns a non-nil error, once l fails.
+func (s *Server) Serve(l net.Listener) error {
+	defer l.Close()
+
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				s.logf("remote: accept: %v", err)
+				time.Sleep(50 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		go s.ServeConn(c)
+	}
+}
+
+// ServeConn serves requests on c until the client closes it or times out,
+// then closes c.
+func (s *Server) ServeConn(c net.Conn) {
+	s.once.Do(s.init)
+	defer c.Close()
+
+	r := bufio.NewReader(c)
+	w := &frameWriter{w: bufio.NewWriter(c)}
+
+	// inflight bounds the requests of c being served, and so the blobs held
+	inflight := make(chan struct{}, s.pool.Cap())
+	var wg sync.WaitGroup
+	for {
+		inflight <- struct{}{}
+		c.SetReadDeadline(time.Now().Add(s.ReadTimeout))
+		q, err := readRequest(r)
+		if err != nil {
+			if err != io.EOF {
+				s.logf("remote: read request from %s: %v", c.RemoteAddr(), err)
+			}
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-inflight
+				wg.Done()
+			}()
+
+			p := s.handle(q)
+			w.mu.Lock()
+			c.SetWriteDeadline(time.Now().Add(s.WriteTimeout))
+			err := writeResponse(w.w, p)
+			if err == nil {
+				err = w.w.Flush()
+			}
+			w.mu.Unlock()
+			if err != nil {
+				s.logf("remote: write response to %s: %v", c.RemoteAddr(), err)
+				c.Close()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *Server) handle(q *request) *response {
+	p := &response{id: q.id}
+
+	switch q.op {
+	case opPing:
+		return p
+
+	case opSum:
+		sum, err := s.pool.SumChecked(q.blob, cryptonight.Algorithm(q.algo), q.height)
+		if err != nil {
+			p.status = statusErr
+			p.payload = []byte(err.Error())
+			return p
+		}
+		p.payload = sum
+		return p
+
+	default:
+		p.status = statusErr
+		p.payload = []byte("unknown operation")
+		return p
+	}
+}
@@ -0,0 +1,13 @@
+package cryptonight
+
+import (
+	"hash"
+
+	"ekyu.moe/cryptonight/skein"
+)
+
+// newSkein256 returns Skein-512-256 of package skein, which is free of unsafe
+// and, unlike github.com/aead/skein, does not allocate in Sum.
+func newSkein256() hash.Hash {
+	return skein.New256(nil)
+}
@@ -0,0 +1,247 @@
+// Package skein implements Skein-512 hash function with arbitrary output
+// length, along with the UBI (Unique Block Iteration) chaining mode it is
+// built on.
+//
+// This Go implementation follows "The Skein Hash Function Family" version 1.3,
+// which is the version used by CryptoNote's final hash:
+//     src/crypto/skein.c
+//     src/crypto/skein_port.h
+//
+// Besides plain hashing, the optional parameters of the specification (key,
+// personalization, public key, key identifier and nonce) are supported via
+// Config, so it can serve as a MAC or a KDF as well.
+package skein // import "ekyu.moe/cryptonight/skein"
+
+import (
+	"encoding/binary"
+	"hash"
+)
+
+const (
+	// BlockSize is the block size of Skein-512 in bytes.
+	BlockSize = 64
+
+	// Size256 is the size of a Skein-512-256 checksum in bytes.
+	Size256 = 32
+
+	// Size512 is the size of a Skein-512-512 checksum in bytes.
+	Size512 = 64
+)
+
+// Type values of UBI, as per section 3.5.1 of the specification.
+const (
+	typeKey       uint64 = 0
+	typeConfig    uint64 = 4
+	typePersonal  uint64 = 8
+	typePublicKey uint64 = 12
+	typeKeyID     uint64 = 16
+	typeNonce     uint64 = 20
+	typeMessage   uint64 = 48
+	typeOutput    uint64 = 63
+)
+
+// Flags of the second tweak word.
+const (
+	flagFirst = 1 << 62
+	flagFinal = 1 << 63
+)
+
+// Config contains optional parameters for Skein-512. A nil or empty field is
+// simply skipped. When non-empty, each field is processed by its own UBI
+// invocation in the order defined by the specification.
+type Config struct {
+	Key       []byte // secret key for MAC/KDF usage
+	Personal  []byte // personalization string
+	PublicKey []byte // public key, e.g. for signature hashing
+	KeyID     []byte // key identifier for KDF usage
+	Nonce     []byte // nonce for stream cipher or randomized hashing
+}
+
+// ubi is the state of a UBI chaining computation over one type of input.
+type ubi struct {
+	h     [8]uint64       // chaining value
+	tweak [2]uint64       // position and flags
+	buf   [BlockSize]byte // pending input
+	nbuf  int             // valid bytes in buf
+}
+
+// reset starts a new UBI computation of type typ, keeping the chaining value.
+func (u *ubi) reset(typ uint64) {
+	u.tweak[0] = 0
+	u.tweak[1] = typ<<56 | flagFirst
+	u.nbuf = 0
+}
+
+// write absorbs p. The last block is always kept in buf, since it has to be
+// processed with the final flag set.
+func (u *ubi) write(p []byte) {
+	for len(p) > 0 {
+		if u.nbuf == BlockSize {
+			u.block(u.buf[:], BlockSize)
+			u.nbuf = 0
+		}
+		n := copy(u.buf[u.nbuf:], p)
+		u.nbuf += n
+		p = p[n:]
+	}
+}
+
+// final pads and processes the last (possibly empty) block.
+func (u *ubi) final() {
+	for i := u.nbuf; i < BlockSize; i++ {
+		u.buf[i] = 0
+	}
+	u.tweak[1] |= flagFinal
+	u.block(u.buf[:], uint64(u.nbuf))
+}
+
+// block processes one block, of which n bytes are actual input.
+func (u *ubi) block(b []byte, n uint64) {
+	var m [8]uint64
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint64(b[8*i:])
+	}
+
+	u.tweak[0] += n
+	c := threefish(&u.h, &u.tweak, &m)
+	for i := range u.h {
+		u.h[i] = c[i] ^ m[i]
+	}
+	u.tweak[1] &^= flagFirst
+}
+
+// process runs a whole UBI computation of type typ over p.
+func (u *ubi) process(typ uint64, p []byte) {
+	u.reset(typ)
+	u.write(p)
+	u.final()
+}
+
+type state struct {
+	size int       // output size in bytes
+	iv   [8]uint64 // chaining value after all parameters are processed
+	u    ubi
+}
+
+// New returns a new hash.Hash computing the Skein-512 checksum with size bytes
+// of output. conf may be nil. It panics if size is not positive.
+func New(size int, conf *Config) hash.Hash {
+	if size <= 0 {
+		panic("skein: invalid output size")
+	}
+
+	s := &state{size: size}
+	s.init(conf)
+	s.Reset()
+
+	return s
+}
+
+// New256 returns a new hash.Hash computing the Skein-512-256 checksum. If key
+// is not empty, the returned hash.Hash computes a MAC.
+func New256(key []byte) hash.Hash {
+	return New(Size256, &Config{Key: key})
+}
+
+// New512 returns a new hash.Hash computing the Skein-512-512 checksum. If key
+// is not empty, the returned hash.Hash computes a MAC.
+func New512(key []byte) hash.Hash {
+	return New(Size512, &Config{Key: key})
+}
+
+// Sum256 returns the Skein-512-256 checksum of the data.
+func Sum256(data []byte) []byte {
+	h := New256(nil)
+	h.Write(data)
+
+	return h.Sum(nil)
+}
+
+// Sum512 returns the Skein-512-512 checksum of the data.
+func Sum512(data []byte) []byte {
+	h := New512(nil)
+	h.Write(data)
+
+	return h.Sum(nil)
+}
+
+// init computes the chaining value from the key, the configuration block and
+// the other optional parameters.
+func (s *state) init(conf *Config) {
+	if conf == nil {
+		conf = new(Config)
+	}
+
+	// as per section 3.5.2, K' = 0 if there is no key
+	if len(conf.Key) > 0 {
+		s.u.process(typeKey, conf.Key)
+	}
+
+	// configuration string, as per section 3.5.2
+	var cfg [32]byte
+	copy(cfg[:4], "SHA3")
+	binary.LittleEndian.PutUint16(cfg[4:], 1) // version
+	binary.LittleEndian.PutUint64(cfg[8:], uint64(s.size)*8)
+	// cfg[16:19] are tree parameters, which are all zero for sequential hashing
+	s.u.process(typeConfig, cfg[:])
+
+	for _, p := range []struct {
+		typ  uint64
+		data []byte
+	}{
+		{typePersonal, conf.Personal},
+		{typePublicKey, conf.PublicKey},
+		{typeKeyID, conf.KeyID},
+		{typeNonce, conf.Nonce},
+	} {
+		if len(p.data) > 0 {
+			s.u.process(p.typ, p.data)
+		}
+	}
+
+	s.iv = s.u.h
+}
+
+func (s *state) Reset() {
+	s.u.h = s.iv
+	s.u.reset(typeMessage)
+}
+
+func (s *state) Size() int      { return s.size }
+func (s *state) BlockSize() int { return BlockSize }
+
+func (s *state) Write(data []byte) (n int, err error) {
+	s.u.write(data)
+
+	return len(data), nil
+}
+
+// Sum appends the checksum to b. It does not change the underlying state.
+func (s *state) Sum(b []byte) []byte {
+	msg := s.u
+	msg.final()
+
+	// output transformation, as per section 3.5.3
+	var (
+		out     ubi
+		counter [8]byte
+		block   [BlockSize]byte
+	)
+	for i, remain := uint64(0), s.size; remain > 0; i++ {
+		out.h = msg.h
+		binary.LittleEndian.PutUint64(counter[:], i)
+		out.process(typeOutput, counter[:])
+
+		for j, v := range out.h {
+			binary.LittleEndian.PutUint64(block[8*j:], v)
+		}
+		n := BlockSize
+		if remain < n {
+			n = remain
+		}
+		b = append(b, block[:n]...)
+		remain -= n
+	}
+
+	return b
+}
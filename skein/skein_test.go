@@ -0,0 +1,130 @@
+package skein
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+
+	"github.com/aead/skein"
+)
+
+type hashSpec struct {
+	input, output string // input in plain text, output in hex
+}
+
+var (
+	// From the Skein submission and Wikipedia.
+	hashSpecs256 = []hashSpec{
+		{"", "39ccc4554a8b31853b9de7a1fe638a24cce6b35a55f2431009e18780335d2621"},
+		{"The quick brown fox jumps over the lazy dog", "b3250457e05d3060b1a4bbc1428bc75a3f525ca389aeab96cfa34638d96e492a"},
+	}
+	hashSpecs512 = []hashSpec{
+		{"", "bc5b4c50925519c290cc634277ae3d6257212395cba733bbad37a4af0fa06af41fca7903d06564fea7a2d3730dbdb80c1f85562dfcc070334ea4d1d9e72cba7a"},
+		{"The quick brown fox jumps over the lazy dog", "94c2ae036dba8783d0b3f7d6cc111ff810702f5c77707999be7e1c9486ff238a7044de734293147359b4ac7e1d09cd247c351d69826b78dcddd951f0ef912713"},
+	}
+)
+
+func TestSum(t *testing.T) {
+	run := func(t *testing.T, hashSpecs []hashSpec, sum func([]byte) []byte) {
+		for i, v := range hashSpecs {
+			result := sum([]byte(v.input))
+			if hex.EncodeToString(result) != v.output {
+				t.Errorf("\n[%d] expected:\n\t%s\ngot:\n\t%x\n", i, v.output, result)
+			}
+		}
+	}
+
+	t.Run("256", func(t *testing.T) { run(t, hashSpecs256, Sum256) })
+	t.Run("512", func(t *testing.T) { run(t, hashSpecs512, Sum512) })
+}
+
+func TestStreaming(t *testing.T) {
+	data := make([]byte, 1000)
+	rand.New(rand.NewSource(0)).Read(data)
+
+	for _, size := range []int{1, 20, Size256, Size512, 100, 128} {
+		h := New(size, nil)
+		h.Write(data)
+		expected := h.Sum(nil)
+		if len(expected) != size {
+			t.Fatalf("expected %d bytes, got %d", size, len(expected))
+		}
+
+		// split at every block boundary and its neighbours
+		for _, split := range []int{0, 1, 63, 64, 65, 127, 128, 129, 999, 1000} {
+			h.Reset()
+			h.Write(data[:split])
+			h.Sum(nil) // must not affect the state
+			h.Write(data[split:])
+			if result := h.Sum(nil); !bytes.Equal(result, expected) {
+				t.Errorf("\n[size %d, split %d] expected:\n\t%x\ngot:\n\t%x\n", size, split, expected, result)
+			}
+		}
+	}
+}
+
+// TestCrossCheck compares Skein-512-256 against github.com/aead/skein, which
+// is used by the CryptoNight final hash.
+func TestCrossCheck(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < 200; i++ {
+		data := make([]byte, r.Intn(300))
+		r.Read(data)
+		var key []byte
+		if i&1 == 1 {
+			key = make([]byte, r.Intn(100)+1)
+			r.Read(key)
+		}
+
+		h := skein.New256(key)
+		h.Write(data)
+		expected := h.Sum(nil)
+
+		h = New256(key)
+		h.Write(data)
+		if result := h.Sum(nil); !bytes.Equal(result, expected) {
+			t.Errorf("\n[%d] expected:\n\t%x\ngot:\n\t%x\n", i, expected, result)
+		}
+	}
+}
+
+func TestConfig(t *testing.T) {
+	data := []byte("The quick brown fox jumps over the lazy dog")
+	results := make(map[string]bool)
+	for _, conf := range []*Config{
+		nil,
+		{Key: []byte("key")},
+		{Personal: []byte("20181016 ekyu.moe/cryptonight")},
+		{PublicKey: []byte("public key")},
+		{KeyID: []byte("key id")},
+		{Nonce: []byte("nonce")},
+		{Key: []byte("key"), Nonce: []byte("nonce")},
+	} {
+		h := New(Size256, conf)
+		h.Write(data)
+		results[hex.EncodeToString(h.Sum(nil))] = true
+	}
+
+	// each parameter must lead to a distinct chaining value
+	if len(results) != 7 {
+		t.Errorf("expected 7 distinct results, got %d", len(results))
+	}
+
+	// empty fields are skipped, hence the same as nil
+	h := New(Size256, &Config{Key: []byte{}})
+	h.Write(data)
+	if result := hex.EncodeToString(h.Sum(nil)); result != hashSpecs256[1].output {
+		t.Errorf("\nexpected:\n\t%s\ngot:\n\t%s\n", hashSpecs256[1].output, result)
+	}
+}
+
+func BenchmarkSum256(b *testing.B) {
+	// exactly 200 bytes, the size of CryptoNight's final state
+	in := make([]byte, 200)
+	b.SetBytes(int64(len(in)))
+
+	for i := 0; i < b.N; i++ {
+		Sum256(in)
+	}
+}
@@ -0,0 +1,68 @@
+package skein
+
+import (
+	"math/bits"
+)
+
+// c240 is the key schedule parity constant.
+const c240 = 0x1bd11bdaa9fc1a22
+
+// Rotation constants of Threefish-512, as per table 4 of the specification.
+var rot512 = [8][4]int{
+	{46, 36, 19, 37},
+	{33, 27, 14, 42},
+	{17, 49, 36, 39},
+	{44, 9, 54, 56},
+	{39, 30, 34, 24},
+	{13, 50, 10, 17},
+	{25, 29, 39, 43},
+	{8, 35, 56, 22},
+}
+
+// threefish encrypts block m with Threefish-512 under key k and tweak t.
+//
+// It has 72 rounds, with a subkey injected every 4 rounds.
+func threefish(k *[8]uint64, t *[2]uint64, m *[8]uint64) [8]uint64 {
+	var ks [9]uint64
+	ks[8] = c240
+	for i := 0; i < 8; i++ {
+		ks[i] = k[i]
+		ks[8] ^= k[i]
+	}
+	ts := [3]uint64{t[0], t[1], t[0] ^ t[1]}
+
+	x := *m
+	for d := 0; d < 72; d += 4 {
+		injectKey(&x, &ks, &ts, d/4)
+
+		for r := d; r < d+4; r++ {
+			rc := &rot512[r%8]
+
+			// MIX
+			x[0] += x[1]
+			x[1] = bits.RotateLeft64(x[1], rc[0]) ^ x[0]
+			x[2] += x[3]
+			x[3] = bits.RotateLeft64(x[3], rc[1]) ^ x[2]
+			x[4] += x[5]
+			x[5] = bits.RotateLeft64(x[5], rc[2]) ^ x[4]
+			x[6] += x[7]
+			x[7] = bits.RotateLeft64(x[7], rc[3]) ^ x[6]
+
+			// permute, as per table 3 of the specification
+			x[0], x[2], x[3], x[4], x[6], x[7] = x[2], x[4], x[7], x[6], x[0], x[3]
+		}
+	}
+	injectKey(&x, &ks, &ts, 18)
+
+	return x
+}
+
+// injectKey adds the s-th subkey to x.
+func injectKey(x *[8]uint64, ks *[9]uint64, ts *[3]uint64, s int) {
+	for i := 0; i < 8; i++ {
+		x[i] += ks[(s+i)%9]
+	}
+	x[5] += ts[s%3]
+	x[6] += ts[(s+1)%3]
+	x[7] += uint64(s)
+}
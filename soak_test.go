@@ -0,0 +1,166 @@
+package cryptonight_test
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ekyu.moe/cryptonight"
+	"ekyu.moe/cryptonight/internal/share"
+	"ekyu.moe/cryptonight/remote"
+)
+
+// The soak test is opt-in, as it is meant to run for hours, e.g.
+// "go test -run Soak -soak 4h -timeout 0".
+var (
+	soakDuration = flag.Duration("soak", 0, "run the soak test for this long")
+	soakInterval = flag.Duration("soak.interval", time.Minute, "interval of soak test samples")
+)
+
+// soakSample is a snapshot of the resources of the process.
+type soakSample struct {
+	rss        uint64 // resident set size in bytes, 0 if unknown
+	heap       uint64 // live heap after a GC
+	goroutines int
+	mallocs    uint64
+	hashes     uint64
+}
+
+func takeSoakSample(hashes uint64) soakSample {
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	return soakSample{readRSS(), ms.HeapAlloc, runtime.NumGoroutine(), ms.Mallocs, hashes}
+}
+
+// readRSS reads the resident set size from /proc/self/statm, which only exists
+// on Linux.
+func readRSS() uint64 {
+	b, err := ioutil.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) < 2 {
+		return 0
+	}
+	pages, _ := strconv.ParseUint(fields[1], 10, 64)
+
+	return pages * uint64(os.Getpagesize())
+}
+
+// TestSoak hashes continuously with the pool, with private caches, through the
+// share verifier and through a remote worker, sampling memory and goroutines
+// at each interval. It fails if they have grown between the first sample, taken
+// after warming up for an interval, and the last one.
+func TestSoak(t *testing.T) {
+	if *soakDuration <= 0 {
+		t.Skip("soak test is disabled, enable it with -soak")
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go (&remote.Server{ErrorLog: log.New(ioutil.Discard, "", 0)}).Serve(l)
+	client := remote.NewClient([]string{l.Addr().String()}, nil)
+	defer client.Close()
+
+	var (
+		hashes uint64
+		done   = make(chan struct{})
+		wg     sync.WaitGroup
+	)
+	blob := make([]byte, 76)
+	workers := []func(i int){
+		func(i int) { cryptonight.Sum(blob, i%3) },
+		func() func(int) {
+			cc := new(cryptonight.Cache)
+			return func(i int) { cc.Sum(blob, i%3) }
+		}(),
+		func(i int) {
+			share.Verify(&share.Share{Blob: strings.Repeat("00", 76), Nonce: "01020304", Variant: i % 3, Target: 1})
+		},
+		func(i int) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			if _, err := client.Sum(ctx, blob, i%3); err != nil {
+				t.Error(err)
+			}
+			cancel()
+		},
+	}
+	for _, work := range workers {
+		wg.Add(1)
+		go func(work func(int)) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				work(i)
+				atomic.AddUint64(&hashes, 1)
+			}
+		}(work)
+	}
+
+	tick := time.NewTicker(*soakInterval)
+	defer tick.Stop()
+	deadline := time.After(*soakDuration)
+
+	var (
+		first, prev soakSample
+		n           int
+	)
+	for ; ; n++ {
+		select {
+		case <-deadline:
+		case <-tick.C:
+			s := takeSoakSample(atomic.LoadUint64(&hashes))
+			if n == 0 {
+				first = s
+			} else {
+				t.Logf("rss %d MiB, heap %d MiB, %d goroutines, %.0f allocs/hash, %.2f H/s",
+					s.rss>>20, s.heap>>20, s.goroutines,
+					float64(s.mallocs-prev.mallocs)/float64(s.hashes-prev.hashes+1),
+					float64(s.hashes-prev.hashes)/soakInterval.Seconds())
+			}
+			prev = s
+			continue
+		}
+		break
+	}
+	close(done)
+	wg.Wait()
+
+	if n < 2 {
+		t.Skip("soak test too short, -soak must be at least twice -soak.interval")
+	}
+	last := prev
+
+	// Some slack is allowed for the pools, whose sizes depend on GC timing, and
+	// for the goroutines serving remote requests in flight.
+	const slack, goroutineSlack = 64 << 20, 8
+	if last.heap > first.heap+slack {
+		t.Errorf("heap grew from %d MiB to %d MiB", first.heap>>20, last.heap>>20)
+	}
+	if first.rss > 0 && last.rss > first.rss+slack {
+		t.Errorf("rss grew from %d MiB to %d MiB", first.rss>>20, last.rss>>20)
+	}
+	if last.goroutines > first.goroutines+goroutineSlack {
+		t.Errorf("goroutines grew from %d to %d", first.goroutines, last.goroutines)
+	}
+}
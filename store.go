@@ -0,0 +1,164 @@
+package cryptonight
+
+import (
+	"encoding/binary"
+	"io"
+
+	"ekyu.moe/cryptonight/internal/aes"
+)
+
+// PadStore holds the scratchpad of a Cache created by NewCacheWithStore, such
+// as a file, external flash or PSRAM behind a bus. Offsets are in bytes, and
+// the words of the scratchpad are stored in little endian.
+type PadStore interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// StorePageSize is the size in bytes of the reads and writes of a Cache to its
+// PadStore.
+const StorePageSize = 4096
+
+// pageWords is StorePageSize in words of the scratchpad.
+const pageWords = StorePageSize / 8
+
+// NewCacheWithStore returns a new Cache which keeps its scratchpad in s rather
+// than in memory, holding only window bytes of it at a time, rounded up to a
+// multiple of StorePageSize. It is meant for devices which cannot spare the
+// 2 MiB or more of the scratchpad, such as the ones TinyGo targets, and where
+// hashes may take much longer: the memory hard loop accesses the scratchpad at
+// random, so most of its steps are a page read from s and one written back.
+//
+// s must be as large as the Algorithm.Memory of the algorithms hashed with cc.
+// A hash panics with the error of s if a read or write fails, and with
+// ErrStoreUnsupported for CNGPU, Chukwa and ChukwaV2, whose scratchpads are not
+// accessed in chunks of 16 bytes.
+func NewCacheWithStore(s PadStore, window int) *Cache {
+	n := (window + StorePageSize - 1) / StorePageSize
+	if n < 1 {
+		n = 1
+	}
+	pp := &pagedPad{
+		store:  s,
+		window: make([]uint64, n*pageWords),
+		pages:  make([]int, n),
+		dirty:  make([]bool, n),
+	}
+	pp.reset()
+
+	return &Cache{store: pp}
+}
+
+// pagedPad is a scratchpad in a PadStore, of which the pages last accessed are
+// held in a direct mapped window.
+type pagedPad struct {
+	store  PadStore
+	window []uint64 // pages held, page i in the slot i%len(pages)
+	pages  []int    // page held by each slot of window, -1 for none
+	dirty  []bool   // whether each slot of window was written to
+	buf    [StorePageSize]byte
+}
+
+// reset empties the window of pp without writing it back.
+func (pp *pagedPad) reset() {
+	for i := range pp.pages {
+		pp.pages[i] = -1
+		pp.dirty[i] = false
+	}
+	for i := range pp.window {
+		pp.window[i] = 0
+	}
+}
+
+// page returns the page of the scratchpad which holds the word at addr, and
+// addr within it. The page is written back to the store once evicted.
+func (pp *pagedPad) page(addr uint64) ([]uint64, uint64) {
+	page := int(addr / pageWords)
+	slot := page % len(pp.pages)
+	sp := pp.window[slot*pageWords : (slot+1)*pageWords]
+	if pp.pages[slot] != page {
+		pp.evict(slot)
+		pp.read(sp, page)
+		pp.pages[slot] = page
+	}
+	pp.dirty[slot] = true
+
+	return sp, addr % pageWords
+}
+
+// evict writes the page in slot back to the store if it was written to, and
+// empties slot.
+func (pp *pagedPad) evict(slot int) {
+	if pp.dirty[slot] {
+		pp.write(pp.window[slot*pageWords:(slot+1)*pageWords], pp.pages[slot])
+		pp.dirty[slot] = false
+	}
+	pp.pages[slot] = -1
+}
+
+// explode fills the first memory words of the scratchpad as aes.CnExplode does.
+func (pp *pagedPad) explode(memory int, blocks []uint64, rkeys *[40]uint32) {
+	pp.reset()
+	sp := pp.window[:pageWords]
+	for page := 0; page*pageWords < memory; page++ {
+		aes.CnExplode(sp, blocks, rkeys)
+		pp.write(sp, page)
+	}
+}
+
+// each writes back the window of pp, then calls f with the first memory words
+// of the scratchpad, a page at a time.
+func (pp *pagedPad) each(memory int, f func(sp []uint64)) {
+	for slot := range pp.pages {
+		pp.evict(slot)
+	}
+	sp := pp.window[:pageWords]
+	for page := 0; page*pageWords < memory; page++ {
+		pp.read(sp, page)
+		f(sp)
+	}
+}
+
+func (pp *pagedPad) read(sp []uint64, page int) {
+	if n, err := pp.store.ReadAt(pp.buf[:], int64(page)*StorePageSize); n < len(pp.buf) {
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		panic(err)
+	}
+	for i := range sp {
+		sp[i] = binary.LittleEndian.Uint64(pp.buf[8*i:])
+	}
+}
+
+func (pp *pagedPad) write(sp []uint64, page int) {
+	for i, v := range sp {
+		binary.LittleEndian.PutUint64(pp.buf[8*i:], v)
+	}
+	if _, err := pp.store.WriteAt(pp.buf[:], int64(page)*StorePageSize); err != nil {
+		panic(err)
+	}
+}
+
+// line returns the memory the memory hard loop accesses at the word addr, and
+// addr within it: pad itself, or the page of the PadStore of cc which holds
+// addr. The accesses of one step all fall in the same 64 bytes, so in the same
+// page.
+func (cc *Cache) line(pad []uint64, addr uint64) ([]uint64, uint64) {
+	if cc.store == nil {
+		return pad, addr
+	}
+
+	return cc.store.page(addr)
+}
+
+// eachPage calls f with the first memory words of the scratchpad, which is pad
+// or the PadStore of cc, a page at a time.
+func (cc *Cache) eachPage(pad []uint64, memory int, f func(sp []uint64)) {
+	if cc.store == nil {
+		f(pad[:memory])
+		return
+	}
+
+	cc.store.each(memory, f)
+}
@@ -0,0 +1,89 @@
+package cryptonight
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// memStore is a PadStore in memory, which counts its writes and fails once
+// failAfter of them were made, if set.
+type memStore struct {
+	b         []byte
+	writes    int
+	failAfter int
+}
+
+func (s *memStore) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(s.b)) {
+		return 0, io.EOF
+	}
+	n := copy(p, s.b[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+func (s *memStore) WriteAt(p []byte, off int64) (int, error) {
+	s.writes++
+	if s.failAfter > 0 && s.writes > s.failAfter {
+		return 0, errors.New("write failed")
+	}
+	if end := int(off) + len(p); end > len(s.b) {
+		s.b = append(s.b, make([]byte, end-len(s.b))...)
+	}
+
+	return copy(s.b[off:], p), nil
+}
+
+func TestNewCacheWithStore(t *testing.T) {
+	s := new(memStore)
+	cc := NewCacheWithStore(s, 3*StorePageSize-1)
+	if m := cc.Memory(); m != 3*StorePageSize {
+		t.Errorf("expected a window of %d bytes, got %d", 3*StorePageSize, m)
+	}
+
+	for a := range algorithms {
+		algo := Algorithm(a)
+		p := algorithms[a].p
+		if p.gpu || p.argon2 {
+			func() {
+				defer func() {
+					if r := recover(); r != ErrStoreUnsupported {
+						t.Errorf("[%s] expected to panic with ErrStoreUnsupported, got %v", algo, r)
+					}
+				}()
+				cc.SumAlgorithm(benchData[0], algo, 0)
+			}()
+			continue
+		}
+
+		for i, data := range benchData[:1] {
+			if expected, got := SumAlgorithm(data, algo, 1806260), cc.SumAlgorithm(data, algo, 1806260); !bytes.Equal(got, expected) {
+				t.Errorf("[%s %d] expected %x, got %x", algo, i, expected, got)
+			}
+		}
+	}
+	if len(s.b) != heavy.memory {
+		t.Errorf("expected a store of %d bytes, got %d", heavy.memory, len(s.b))
+	}
+
+	// a failing store makes the hash panic, and the Cache is usable again
+	// once it works
+	s.writes, s.failAfter = 0, 10
+	func() {
+		defer func() {
+			if r, ok := recover().(error); !ok || r.Error() != "write failed" {
+				t.Errorf("expected to panic with the error of the store, got %v", r)
+			}
+		}()
+		cc.Sum(benchData[0], 2)
+	}()
+	s.failAfter = 0
+	if expected, got := Sum(benchData[0], 2), cc.Sum(benchData[0], 2); !bytes.Equal(got, expected) {
+		t.Errorf("expected %x after the failure, got %x", expected, got)
+	}
+}
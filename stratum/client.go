@@ -0,0 +1,313 @@
+package stratum
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"ekyu.moe/cryptonight"
+	"ekyu.moe/cryptonight/internal/observe"
+)
+
+// Config contains the parameters of a Client. A zero field means its default
+// value.
+type Config struct {
+	Login string // wallet address, or whatever the pool expects
+	Pass  string // default "x"
+	Agent string // default "ekyu.moe/cryptonight/stratum"
+
+	// Algorithms are advertised to the pool on login, as xmrig does. The
+	// first one is the algorithm of the jobs naming none. By default, none is
+	// advertised, and the algorithm of such jobs is the one of the major
+	// version of their blob in Monero, or CNv0.
+	Algorithms []cryptonight.Algorithm
+
+	DialTimeout time.Duration // timeout of connecting and logging in, default 10s
+	Keepalive   time.Duration // interval of keepalives, default 60s, negative to disable
+}
+
+// Client is a connection to a pool, logged in. It does not reconnect: once
+// the connection is lost, its Jobs channel is closed and Err tells why. A
+// Client is safe for concurrent use.
+type Client struct {
+	conf    Config
+	conn    net.Conn
+	session string
+	jobs    chan *Job
+
+	wmu sync.Mutex // serializes writes to conn
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan *message
+	err     error
+	jobID   string // of the latest job
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Dial connects to the pool at addr and logs in. conf may be nil.
+func Dial(ctx context.Context, addr string, conf *Config) (*Client, error) {
+	c := &Client{
+		jobs:    make(chan *Job, 1),
+		pending: make(map[uint64]chan *message),
+		done:    make(chan struct{}),
+	}
+	if conf != nil {
+		c.conf = *conf
+	}
+	if c.conf.Pass == "" {
+		c.conf.Pass = "x"
+	}
+	if c.conf.Agent == "" {
+		c.conf.Agent = "ekyu.moe/cryptonight/stratum"
+	}
+	if c.conf.DialTimeout <= 0 {
+		c.conf.DialTimeout = 10 * time.Second
+	}
+	if c.conf.Keepalive == 0 {
+		c.conf.Keepalive = 60 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.conf.DialTimeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
+	go c.read()
+
+	if err := c.login(ctx); err != nil {
+		c.close(err)
+		return nil, err
+	}
+	if c.conf.Keepalive > 0 {
+		go c.keepalive()
+	}
+
+	return c, nil
+}
+
+func (c *Client) login(ctx context.Context) error {
+	p := &loginParams{Login: c.conf.Login, Pass: c.conf.Pass, Agent: c.conf.Agent}
+	for _, a := range c.conf.Algorithms {
+		p.Algo = append(p.Algo, a.String())
+	}
+
+	raw, err := c.call(ctx, "login", p)
+	if err != nil {
+		return err
+	}
+	var res loginResult
+	if err := json.Unmarshal(raw, &res); err != nil || res.ID == "" || res.Job == nil {
+		return errors.New("stratum: malformed login response")
+	}
+	if _, err := res.Job.parse(c.conf.Algorithms); err != nil {
+		return err
+	}
+	c.session = res.ID
+
+	return nil
+}
+
+// Jobs returns the channel of the jobs of the pool. Only the latest job is
+// kept when it is not received in time, as the previous ones are stale. The
+// channel is closed once the connection is lost.
+func (c *Client) Jobs() <-chan *Job { return c.jobs }
+
+// Submit sends a share of job, i.e. a nonce and the hash of its blob with
+// that nonce, and waits for the pool to accept it. A rejected share is
+// reported as an *Error. A share of a job older than the latest one is not
+// sent, and cryptonight.ErrStaleJob is returned instead.
+func (c *Client) Submit(ctx context.Context, job *Job, nonce uint32, hash []byte) error {
+	c.mu.Lock()
+	stale := job.ID != c.jobID
+	c.mu.Unlock()
+	if stale {
+		return cryptonight.ErrStaleJob
+	}
+
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], nonce)
+
+	_, err := c.call(ctx, "submit", &submitParams{
+		ID:     c.session,
+		JobID:  job.ID,
+		Nonce:  hex.EncodeToString(n[:]),
+		Result: hex.EncodeToString(hash),
+	})
+	if err == nil {
+		observe.ShareFound(hash, job.Target)
+	}
+
+	return err
+}
+
+// Err returns why the connection was lost, or nil while it is up.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	c.close(ErrClosed)
+	return nil
+}
+
+func (c *Client) close(err error) {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		c.err = err
+		c.mu.Unlock()
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// call sends a request and waits for its response.
+func (c *Client) call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	ch := make(chan *message, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return nil, err
+	}
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	b, _ := json.Marshal(&request{id, "2.0", method, params})
+	c.wmu.Lock()
+	_, err := c.conn.Write(append(b, '\n'))
+	c.wmu.Unlock()
+	if err != nil {
+		c.close(err)
+		return nil, err
+	}
+
+	select {
+	case m := <-ch:
+		if m.Error != nil {
+			return nil, m.Error
+		}
+		return m.Result, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.done:
+		return nil, c.Err()
+	}
+}
+
+// read dispatches the messages of the pool until the connection is lost.
+func (c *Client) read() {
+	defer close(c.jobs)
+
+	sc := bufio.NewScanner(c.conn)
+	sc.Buffer(nil, maxLine)
+	for sc.Scan() {
+		var m message
+		if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+			c.close(errors.New("stratum: malformed message: " + err.Error()))
+			return
+		}
+
+		if m.Method == "job" {
+			var j job
+			if err := json.Unmarshal(m.Params, &j); err != nil {
+				c.close(errBadJob)
+				return
+			}
+			parsed, err := j.parse(c.conf.Algorithms)
+			if err != nil {
+				c.close(err)
+				return
+			}
+			c.pushJob(parsed)
+			continue
+		}
+
+		// the job of the login response comes before the ones pushed next
+		var res loginResult
+		if json.Unmarshal(m.Result, &res) == nil && res.Job != nil {
+			if j, err := res.Job.parse(c.conf.Algorithms); err == nil {
+				c.pushJob(j)
+			}
+		}
+
+		c.mu.Lock()
+		ch := c.pending[m.ID]
+		c.mu.Unlock()
+		if ch != nil {
+			select {
+			case ch <- &m:
+			default: // a duplicate response
+			}
+		}
+	}
+
+	err := sc.Err()
+	if err == nil {
+		err = errors.New("stratum: connection closed by the pool")
+	}
+	c.close(err)
+}
+
+// pushJob sends j on c.jobs, replacing the job not received yet, if any. It
+// is only called by read, which keeps the jobs in order.
+func (c *Client) pushJob(j *Job) {
+	c.mu.Lock()
+	c.jobID = j.ID
+	c.mu.Unlock()
+
+	for {
+		select {
+		case c.jobs <- j:
+			return
+		default:
+		}
+		select {
+		case <-c.jobs:
+		default:
+		}
+	}
+}
+
+func (c *Client) keepalive() {
+	t := time.NewTicker(c.conf.Keepalive)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			ctx, cancel := context.WithTimeout(context.Background(), c.conf.DialTimeout)
+			_, err := c.call(ctx, "keepalived", &keepaliveParams{c.session})
+			cancel()
+			if err == context.DeadlineExceeded {
+				c.close(errors.New("stratum: keepalive timed out"))
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Package stratum implements the client side of the stratum protocol of
+// CryptoNight pools, as spoken by xmrig and the Monero pools, so that a miner
+// can be built on ekyu.moe/cryptonight alone.
+//
+// The protocol is JSON-RPC 2.0 over a plain TCP connection, one message per
+// line. The miner logs in with its wallet address, and is given a job in the
+// response. The pool then pushes a new job whenever the previous one becomes
+// stale, with the "job" method. Shares are sent back with "submit", and an
+// idle connection is kept alive with "keepalived".
+package stratum // import "ekyu.moe/cryptonight/stratum"
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+
+	"ekyu.moe/cryptonight"
+	"ekyu.moe/cryptonight/cnutil"
+)
+
+// maxLine limits the size of a message from the pool.
+const maxLine = 1 << 20
+
+var (
+	// ErrClosed is returned by the calls on a closed Client.
+	ErrClosed = errors.New("stratum: client closed")
+
+	errBadJob = errors.New("stratum: malformed job")
+)
+
+// Error is an error reported by the pool, e.g. for a share it rejects.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return "stratum: " + e.Message + " (" + strconv.Itoa(e.Code) + ")"
+}
+
+// Job is a hashing blob to find a nonce of, in the form taken by the hashing
+// APIs, e.g.
+//     cc.MineHeight(ctx, job.Blob, job.Algorithm, job.Height, job.Target, 0, 1)
+type Job struct {
+	ID        string
+	Blob      []byte
+	Target    uint64                // difficulty a share must meet
+	Algorithm cryptonight.Algorithm // from the pool, the Config or the blob version
+	Height    uint64                // of the block, only needed by CNR
+}
+
+// job is a Job as sent by the pool.
+type job struct {
+	Blob   string `json:"blob"`
+	JobID  string `json:"job_id"`
+	Target string `json:"target"`
+	Algo   string `json:"algo"`
+	Height uint64 `json:"height"`
+}
+
+// parse converts j into a Job. The algorithm is the one named by the pool if
+// any, else the first of algos, else the one of the major version of the
+// blob, else CNv0.
+func (j *job) parse(algos []cryptonight.Algorithm) (*Job, error) {
+	blob, err := hex.DecodeString(j.Blob)
+	if err != nil || j.JobID == "" {
+		return nil, errBadJob
+	}
+	target, err := parseTarget(j.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	var algo cryptonight.Algorithm
+	switch {
+	case j.Algo != "":
+		if algo, err = cryptonight.ParseAlgorithm(j.Algo); err != nil {
+			return nil, err
+		}
+	case len(algos) > 0:
+		algo = algos[0]
+	default:
+		if v, err := cnutil.BlobVariant(blob); err == nil {
+			algo = variantAlgorithms[v]
+		}
+	}
+
+	return &Job{j.JobID, blob, target, algo, j.Height}, nil
+}
+
+// variantAlgorithms are the algorithms of the variants cnutil.Variant returns.
+var variantAlgorithms = map[int]cryptonight.Algorithm{
+	0: cryptonight.CNv0,
+	1: cryptonight.CNv1,
+	2: cryptonight.CNv2,
+	4: cryptonight.CNR,
+}
+
+// parseTarget returns the difficulty of a target, which is the highest hash
+// accepted in little endian hex, truncated to its 4 or 8 most significant
+// bytes.
+func parseTarget(s string) (uint64, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return 0, errBadJob
+	}
+
+	switch len(b) {
+	case 4:
+		if t := binary.LittleEndian.Uint32(b); t != 0 {
+			return math.MaxUint32 / uint64(t), nil
+		}
+	case 8:
+		if t := binary.LittleEndian.Uint64(b); t != 0 {
+			return math.MaxUint64 / t, nil
+		}
+	}
+
+	return 0, errBadJob
+}
+
+// request is a message to the pool.
+type request struct {
+	ID      uint64      `json:"id"`
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// message is a message from the pool, either the response to a request or a
+// call of the pool, which has no ID.
+type message struct {
+	ID     uint64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+type loginParams struct {
+	Login string   `json:"login"`
+	Pass  string   `json:"pass"`
+	Agent string   `json:"agent"`
+	Algo  []string `json:"algo,omitempty"`
+}
+
+type loginResult struct {
+	ID     string `json:"id"`
+	Job    *job   `json:"job"`
+	Status string `json:"status"`
+}
+
+type submitParams struct {
+	ID     string `json:"id"`
+	JobID  string `json:"job_id"`
+	Nonce  string `json:"nonce"`
+	Result string `json:"result"`
+}
+
+type keepaliveParams struct {
+	ID string `json:"id"`
+}
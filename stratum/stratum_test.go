@@ -0,0 +1,233 @@
+package stratum
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ekyu.moe/cryptonight"
+)
+
+// testBlob is a hashing blob of version 7.
+var testBlob = "0707" + "e3d5dbe505" + hex.EncodeToString(make([]byte, 69))
+
+// pool is a fake pool. It sends a second job right after the login, rejects
+// the shares of nonce 0 and hangs up on "bye".
+type pool struct {
+	l          net.Listener
+	keepalives int32
+}
+
+func startPool(t *testing.T) *pool {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &pool{l: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(conn)
+		}
+	}()
+
+	return p
+}
+
+func (p *pool) serve(conn net.Conn) {
+	defer conn.Close()
+
+	sc := bufio.NewScanner(conn)
+	for sc.Scan() {
+		var q struct {
+			ID     uint64          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		json.Unmarshal(sc.Bytes(), &q)
+
+		switch q.Method {
+		case "login":
+			fmt.Fprintf(conn, `{"id":%d,"jsonrpc":"2.0","error":null,"result":{"id":"session","job":{"blob":"%s","job_id":"1","target":"b88d0600"},"status":"OK"}}`+"\n", q.ID, testBlob)
+			fmt.Fprintf(conn, `{"jsonrpc":"2.0","method":"job","params":{"blob":"%s","job_id":"2","target":"ffffffffffffff00","algo":"cn/r","height":1806260}}`+"\n", testBlob)
+		case "submit":
+			var s submitParams
+			json.Unmarshal(q.Params, &s)
+			if s.ID != "session" || s.Nonce == "00000000" {
+				fmt.Fprintf(conn, `{"id":%d,"jsonrpc":"2.0","error":{"code":-1,"message":"Low difficulty share"}}`+"\n", q.ID)
+			} else {
+				fmt.Fprintf(conn, `{"id":%d,"jsonrpc":"2.0","error":null,"result":{"status":"OK"}}`+"\n", q.ID)
+			}
+		case "keepalived":
+			atomic.AddInt32(&p.keepalives, 1)
+			fmt.Fprintf(conn, `{"id":%d,"jsonrpc":"2.0","error":null,"result":{"status":"KEEPALIVED"}}`+"\n", q.ID)
+		case "bye":
+			return
+		}
+	}
+}
+
+func nextJob(t *testing.T, c *Client) *Job {
+	select {
+	case j := <-c.Jobs():
+		return j
+	case <-time.After(5 * time.Second):
+		t.Fatal("no job")
+		return nil
+	}
+}
+
+func TestClient(t *testing.T) {
+	p := startPool(t)
+	defer p.l.Close()
+
+	c, err := Dial(context.Background(), p.l.Addr().String(), &Config{
+		Login:     "wallet",
+		Keepalive: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j := nextJob(t, c)
+	if j.ID == "1" {
+		if j.Target != 10000 || j.Algorithm != cryptonight.CNv1 {
+			t.Errorf("unexpected job %+v", j)
+		}
+		j = nextJob(t, c)
+	}
+	if j.ID != "2" || j.Algorithm != cryptonight.CNR || j.Height != 1806260 || j.Target != 256 || len(j.Blob) != 76 {
+		t.Errorf("unexpected job %+v", j)
+	}
+
+	if err := c.Submit(context.Background(), j, 1, make([]byte, 32)); err != nil {
+		t.Errorf("share rejected: %v", err)
+	}
+	err = c.Submit(context.Background(), j, 0, make([]byte, 32))
+	if e, ok := err.(*Error); !ok || e.Code != -1 {
+		t.Errorf("expected a rejection, got %v", err)
+	}
+	if err := c.Submit(context.Background(), &Job{ID: "1"}, 1, make([]byte, 32)); err != cryptonight.ErrStaleJob {
+		t.Errorf("expected ErrStaleJob for the replaced job, got %v", err)
+	}
+
+	for i := 0; atomic.LoadInt32(&p.keepalives) == 0; i++ {
+		if i == 100 {
+			t.Fatal("no keepalive")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	c.Close()
+	if _, ok := <-c.Jobs(); ok {
+		t.Error("jobs not closed")
+	}
+	if err := c.Err(); err != ErrClosed {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+	if err := c.Submit(context.Background(), j, 1, make([]byte, 32)); err != ErrClosed {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestHangUp(t *testing.T) {
+	p := startPool(t)
+	defer p.l.Close()
+
+	c, err := Dial(context.Background(), p.l.Addr().String(), &Config{Keepalive: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	nextJob(t, c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := c.call(ctx, "bye", nil); err == nil || err == ctx.Err() {
+		t.Errorf("expected the connection to be lost, got %v", err)
+	}
+	if c.Err() == nil {
+		t.Error("no error after the connection was lost")
+	}
+}
+
+func TestConfigAlgorithm(t *testing.T) {
+	p := startPool(t)
+	defer p.l.Close()
+
+	// the blob of the login job is of Monero, yet the job names no algorithm
+	c, err := Dial(context.Background(), p.l.Addr().String(), &Config{
+		Algorithms: []cryptonight.Algorithm{cryptonight.CNLite1},
+		Keepalive:  -1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	j := nextJob(t, c)
+	if j.ID == "1" {
+		if j.Algorithm != cryptonight.CNLite1 {
+			t.Errorf("expected %s, got %s", cryptonight.CNLite1, j.Algorithm)
+		}
+		j = nextJob(t, c)
+	}
+	if j.Algorithm != cryptonight.CNR {
+		t.Errorf("expected %s named by the pool, got %s", cryptonight.CNR, j.Algorithm)
+	}
+}
+
+func TestParseJob(t *testing.T) {
+	for i, v := range []struct {
+		target string
+		diff   uint64
+	}{
+		{"b88d0600", 10000},
+		{"ffffffff", 1},
+		{"ffffffffffffff00", 256},
+		{"00000000", 0},
+		{"ff", 0},
+		{"zz", 0},
+	} {
+		diff, err := parseTarget(v.target)
+		if diff != v.diff || (err != nil) != (v.diff == 0) {
+			t.Errorf("[%d] %s: expected %d, got %d, %v", i, v.target, v.diff, diff, err)
+		}
+	}
+
+	// the algorithm of the pool, else of the Config, else of the blob version
+	lite := []cryptonight.Algorithm{cryptonight.CNLite1}
+	for i, v := range []struct {
+		j     job
+		algos []cryptonight.Algorithm
+		algo  cryptonight.Algorithm
+	}{
+		{job{Blob: testBlob, JobID: "1", Target: "ffffffff", Algo: "cn-pico"}, lite, cryptonight.CNPico},
+		{job{Blob: testBlob, JobID: "1", Target: "ffffffff"}, lite, cryptonight.CNLite1},
+		{job{Blob: testBlob, JobID: "1", Target: "ffffffff"}, nil, cryptonight.CNv1},
+		{job{Blob: "", JobID: "1", Target: "ffffffff"}, nil, cryptonight.CNv0},
+	} {
+		if j, err := v.j.parse(v.algos); err != nil || j.Algorithm != v.algo {
+			t.Errorf("[%d] expected %s, got %v, %v", i, v.algo, j, err)
+		}
+	}
+
+	for i, j := range []job{
+		{Blob: "zz", JobID: "1", Target: "ffffffff"},
+		{Blob: testBlob, Target: "ffffffff"},
+		{Blob: testBlob, JobID: "1", Target: "ffffffff", Algo: "rx/0"},
+	} {
+		if _, err := j.parse(nil); err == nil {
+			t.Errorf("[%d] expected an error", i)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package cryptonight
+
+import (
+	"sync/atomic"
+	"time"
+
+	"ekyu.moe/cryptonight/internal/observe"
+)
+
+// Sum2 calculates the digests of algo of dataA and dataB with cc, the same way
+// as Cache.SumAlgorithm does, and panics the same way. On amd64 with AES-NI,
+// the memory hard loops of CNv0, CNv1 and CNv2 run interleaved on a doubled
+// scratchpad, so that each hides part of the memory latency of the other,
+// which raises the hashrate of a thread. The other algorithms, and the builds
+// without the assembly, hash dataA and dataB one after the other.
+func (cc *Cache) Sum2(dataA, dataB []byte, algo Algorithm, height uint64) (out [2][32]byte) {
+	if !algo.valid() {
+		observe.Error(ErrUnknownAlgorithm)
+		panic(ErrUnknownAlgorithm)
+	}
+	if !atomic.CompareAndSwapUint32(&cc.inUse, 0, 1) {
+		observe.Error(ErrCacheInUse)
+		panic(ErrCacheInUse)
+	}
+	defer atomic.StoreUint32(&cc.inUse, 0)
+
+	p := algorithms[algo].p
+	cc.safeSum2(&out, dataA, dataB, p, height)
+	if cc.crossChecked() && algo <= CNR {
+		crossCheck(dataA, p.variant, out[0][:])
+		crossCheck(dataB, p.variant, out[1][:])
+	}
+
+	return out
+}
+
+// safeSum2 calls cc.sum2, wiping cc if it panics and reporting to the Observer,
+// as safeSum does.
+func (cc *Cache) safeSum2(out *[2][32]byte, dataA, dataB []byte, p params, height uint64) {
+	done := false
+	defer func() {
+		if done {
+			return
+		}
+		cc.wipe()
+		if observe.Enabled() {
+			r := recover()
+			if err, ok := r.(error); ok {
+				observe.Error(err)
+			}
+			panic(r)
+		}
+	}()
+
+	var start time.Time
+	if observe.Enabled() {
+		start = time.Now()
+	}
+	cc.sum2(out, dataA, dataB, p, height)
+	done = true
+	if !start.IsZero() {
+		elapsed := time.Since(start) / 2
+		observe.HashDone(p.variant, elapsed)
+		observe.HashDone(p.variant, elapsed)
+	}
+}
@@ -0,0 +1,260 @@
+// +build amd64,!purego,!tinygo
+
+// amd64 assembly implementation for the memory hard steps of two hashes at
+// once, with SSE2 and AES-NI. The iterations of the two hashes alternate, so
+// that the CPU overlaps the latency of the scratchpad of one with the other.
+// Each macro below is one iteration of sum_v*_amd64.s, for the scratchpad pad
+// and the registers of one hash; C, D and the temporaries are shared.
+// Each ends by prefetching the next chunk of its hash, which the iteration
+// of the other hash leaves time to load.
+
+#include "textflag.h"
+#include "sum_defs_amd64.h"
+
+#define PAD_A R8
+#define PAD_B R9
+#define COUNT R11
+
+// lane A in X0, X1 and X4, lane B in X5, X6 and X7
+#define A_A X0
+#define B_A X1
+#define E_A X4
+#define A_B X5
+#define B_B X6
+#define E_B X7
+
+// INIT loads a and b of the keccak state at state.
+#define INIT(state, a, b) \
+	MOVOU 0(state), a;      \
+	MOVOU 32(state), TMPX0; \
+	PXOR  TMPX0, a;         \
+	MOVOU 16(state), b;     \
+	MOVOU 48(state), TMPX0; \
+	PXOR  TMPX0, b
+
+#define ITER0(pad, a, b) \
+	MOVQ    a, AX;                  \
+	ANDQ    $0x1ffff0, AX;          \
+	LEAQ    0(pad)(AX*1), CHUNK;    \
+	MOVO    0(CHUNK), C;            \
+	AESENC  a, C;                   \
+	PREFETCH(pad, C, BX);           \
+	MOVO    b, TMPX0;               \
+	PXOR    C, TMPX0;               \
+	MOVO    TMPX0, 0(CHUNK);        \
+	MOVQ    C, AX;                  \
+	MOVQ    AX, BX;                 \
+	ANDQ    $0x1ffff0, BX;          \
+	LEAQ    0(pad)(BX*1), CHUNK;    \
+	MOVO    0(CHUNK), D;            \
+	MOVQ    D, BX;                  \
+	MULQ    BX;                     \
+	MOVQ    DX, TMPX0;              \
+	MOVQ    AX, TMPX1;              \
+	MOVLHPS TMPX1, TMPX0;           \
+	PADDQ   TMPX0, a;               \
+	MOVO    a, 0(CHUNK);            \
+	PXOR    D, a;                   \
+	MOVO    C, b;                   \
+	PREFETCH(pad, a, AX)
+
+#define ITER1(pad, a, b, tweak) \
+	MOVQ    a, AX;                  \
+	ANDQ    $0x1ffff0, AX;          \
+	LEAQ    0(pad)(AX*1), CHUNK;    \
+	MOVO    0(CHUNK), C;            \
+	AESENC  a, C;                   \
+	PREFETCH(pad, C, BX);           \
+	MOVO    b, TMPX0;               \
+	PXOR    C, TMPX0;               \
+	MOVO    TMPX0, 0(CHUNK);        \
+	MOVB    11(CHUNK), CL;          \
+	MOVB    CL, BL;                 \
+	SHRB    $3, CL;                 \
+	ANDB    $6, CL;                 \
+	ANDB    $1, BL;                 \
+	ORB     BL, CL;                 \
+	SHLB    $1, CL;                 \
+	MOVL    $0x75310, DX;           \
+	SHRL    CL, DX;                 \
+	ANDL    $0x30, DX;              \
+	XORL    DX, 11(CHUNK);          \
+	MOVQ    C, AX;                  \
+	MOVQ    AX, BX;                 \
+	ANDQ    $0x1ffff0, BX;          \
+	LEAQ    0(pad)(BX*1), CHUNK;    \
+	MOVO    0(CHUNK), D;            \
+	MOVQ    D, BX;                  \
+	MULQ    BX;                     \
+	MOVQ    DX, TMPX0;              \
+	MOVQ    AX, TMPX1;              \
+	MOVLHPS TMPX1, TMPX0;           \
+	PADDQ   TMPX0, a;               \
+	MOVO    a, TMPX0;               \
+	PXOR    tweak, TMPX0;           \
+	MOVO    TMPX0, 0(CHUNK);        \
+	PXOR    D, a;                   \
+	MOVO    C, b;                   \
+	PREFETCH(pad, a, AX)
+
+// SHUFFLE_ADD is VARIANT2_SHUFFLE_ADD at addr, and SHUFFLE_ADD_2 the same
+// with VARIANT2_2 on the product in TMPX3.
+#define SHUFFLE_ADD(pad, addr, a, b, e) \
+	MOVQ    addr, BX;               \
+	MOVQ    addr, CX;               \
+	MOVQ    addr, DX;               \
+	XORQ    $0x10, BX;              \
+	XORQ    $0x20, CX;              \
+	XORQ    $0x30, DX;              \
+	LEAQ    0(pad)(BX*1), BX;       \
+	LEAQ    0(pad)(CX*1), CX;       \
+	LEAQ    0(pad)(DX*1), DX;       \
+	MOVO    0(BX), TMPX0;           \
+	MOVO    0(CX), TMPX1;           \
+	MOVO    0(DX), TMPX2;           \
+	PADDQ   e, TMPX2;               \
+	PADDQ   b, TMPX0;               \
+	PADDQ   a, TMPX1;               \
+	MOVO    TMPX2, 0(BX);           \
+	MOVO    TMPX0, 0(CX);           \
+	MOVO    TMPX1, 0(DX)
+
+#define SHUFFLE_ADD_2(pad, addr, a, b, e) \
+	MOVQ    addr, BX;               \
+	MOVQ    addr, CX;               \
+	MOVQ    addr, DX;               \
+	XORQ    $0x10, BX;              \
+	XORQ    $0x20, CX;              \
+	XORQ    $0x30, DX;              \
+	LEAQ    0(pad)(BX*1), BX;       \
+	LEAQ    0(pad)(CX*1), CX;       \
+	LEAQ    0(pad)(DX*1), DX;       \
+	MOVO    0(BX), TMPX0;           \
+	MOVO    0(CX), TMPX1;           \
+	MOVO    0(DX), TMPX2;           \
+	PXOR    TMPX3, TMPX0;           \
+	PXOR    TMPX1, TMPX3;           \
+	PADDQ   e, TMPX2;               \
+	PADDQ   b, TMPX0;               \
+	PADDQ   a, TMPX1;               \
+	MOVO    TMPX2, 0(BX);           \
+	MOVO    TMPX0, 0(CX);           \
+	MOVO    TMPX1, 0(DX)
+
+#define ITER2(pad, a, b, e, div, sqrt) \
+	MOVQ    a, AX;                      \
+	ANDQ    $0x1ffff0, AX;              \
+	LEAQ    0(pad)(AX*1), CHUNK;        \
+	MOVO    0(CHUNK), C;                \
+	AESENC  a, C;                       \
+	PREFETCH(pad, C, TMP0);             \
+	SHUFFLE_ADD(pad, AX, a, b, e);      \
+	MOVO    b, TMPX0;                   \
+	PXOR    C, TMPX0;                   \
+	MOVO    TMPX0, 0(CHUNK);            \
+	MOVQ    C, TMP0;                    \
+	ANDQ    $0x1ffff0, TMP0;            \
+	LEAQ    0(pad)(TMP0*1), CHUNK;      \
+	MOVO    0(CHUNK), D;                \
+	MOVQ    sqrt, CX;                   \
+	SHLQ    $32, CX;                    \
+	XORQ    div, CX;                    \
+	MOVQ    CX, TMPX0;                  \
+	PXOR    TMPX0, D;                   \
+	MOVQ    C, BX;                      \
+	LEAL    0(BX)(sqrt*2), CX;          \
+	ORL     $0x80000001, CX;            \
+	MOVHLPS C, TMPX0;                   \
+	MOVQ    TMPX0, AX;                  \
+	XORQ    DX, DX;                     \
+	DIVQ    CX;                         \
+	SHLQ    $32, DX;                    \
+	MOVL    AX, AX;                     \
+	LEAQ    0(AX)(DX*1), div;           \
+	LEAQ    0(BX)(div*1), AX;           \
+	MOVQ    AX, 0(SP);                  \
+	CALL    ·v2Sqrt(SB);                \
+	MOVQ    8(SP), sqrt;                \
+	MOVQ    C, AX;                      \
+	MOVQ    D, BX;                      \
+	MULQ    BX;                         \
+	MOVQ    DX, TMPX3;                  \
+	MOVQ    AX, TMPX0;                  \
+	MOVLHPS TMPX0, TMPX3;               \
+	SHUFFLE_ADD_2(pad, TMP0, a, b, e);  \
+	PADDQ   TMPX3, a;                   \
+	MOVO    a, 0(CHUNK);                \
+	PXOR    D, a;                       \
+	MOVO    b, e;                       \
+	MOVO    C, b;                       \
+	PREFETCH(pad, a, AX)
+
+// func memhard0x2(spA *uint64, stateA *[25]uint64, spB *uint64, stateB *[25]uint64)
+TEXT ·memhard0x2(SB), NOSPLIT, $0
+	MOVQ spA+0(FP), PAD_A
+	MOVQ stateA+8(FP), AX
+	INIT(AX, A_A, B_A)
+	MOVQ spB+16(FP), PAD_B
+	MOVQ stateB+24(FP), AX
+	INIT(AX, A_B, B_B)
+
+	MOVQ $ITER, COUNT
+loop0:
+	ITER0(PAD_A, A_A, B_A)
+	ITER0(PAD_B, A_B, B_B)
+	DECQ COUNT
+	JNZ  loop0
+	RET
+
+// func memhard1x2(spA *uint64, stateA *[25]uint64, spB *uint64, stateB *[25]uint64, tweakA, tweakB uint64)
+TEXT ·memhard1x2(SB), NOSPLIT, $0
+	MOVQ    spA+0(FP), PAD_A
+	MOVQ    stateA+8(FP), AX
+	INIT(AX, A_A, B_A)
+	MOVQ    spB+16(FP), PAD_B
+	MOVQ    stateB+24(FP), AX
+	INIT(AX, A_B, B_B)
+
+	// VARIANT1_INIT of both
+	MOVQ    tweakA+32(FP), TMPX0
+	PXOR    X8, X8
+	MOVLHPS TMPX0, X8
+	MOVQ    tweakB+40(FP), TMPX0
+	PXOR    X9, X9
+	MOVLHPS TMPX0, X9
+
+	MOVQ $ITER, COUNT
+loop1:
+	ITER1(PAD_A, A_A, B_A, X8)
+	ITER1(PAD_B, A_B, B_B, X9)
+	DECQ COUNT
+	JNZ  loop1
+	RET
+
+// func memhard2x2(spA *uint64, stateA *[25]uint64, spB *uint64, stateB *[25]uint64)
+TEXT ·memhard2x2(SB), NOSPLIT, $24 // 0(SP) and 8(SP) for the v2Sqrt CALL, 16(SP) for the count
+	MOVQ  spA+0(FP), PAD_A
+	MOVQ  stateA+8(FP), AX
+	INIT(AX, A_A, B_A)
+	MOVOU 64(AX), E_A
+	MOVOU 80(AX), TMPX0
+	PXOR  TMPX0, E_A
+	MOVQ  96(AX), R11
+	MOVQ  104(AX), R12
+
+	MOVQ  spB+16(FP), PAD_B
+	MOVQ  stateB+24(FP), AX
+	INIT(AX, A_B, B_B)
+	MOVOU 64(AX), E_B
+	MOVOU 80(AX), TMPX0
+	PXOR  TMPX0, E_B
+	MOVQ  96(AX), DI
+	MOVQ  104(AX), SI
+
+	MOVQ $ITER, 16(SP)
+loop2:
+	ITER2(PAD_A, A_A, B_A, E_A, R11, R12)
+	ITER2(PAD_B, A_B, B_B, E_B, DI, SI)
+	DECQ 16(SP)
+	JNZ  loop2
+	RET
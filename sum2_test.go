@@ -0,0 +1,80 @@
+package cryptonight
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSum2(t *testing.T) {
+	cc := new(Cache)
+	for a := range algorithms {
+		algo := Algorithm(a)
+		for i := 0; i < len(benchData); i += 2 {
+			dataA, dataB := benchData[i], benchData[i+1]
+			sums := cc.Sum2(dataA, dataB, algo, 1806260)
+			for j, data := range [][]byte{dataA, dataB} {
+				if expected := SumAlgorithm(data, algo, 1806260); !bytes.Equal(sums[j][:], expected) {
+					t.Errorf("\n[%s %d] expected:\n\t%x\ngot:\n\t%x\n", algo, i+j, expected, sums[j])
+				}
+			}
+		}
+	}
+
+	// too short for variant 1, whichever of the two it is
+	for _, data := range [][][]byte{{make([]byte, 42), benchData[0]}, {benchData[0], make([]byte, 42)}} {
+		func() {
+			defer func() {
+				if r := recover(); r != ErrShortInput {
+					t.Errorf("expected to panic with ErrShortInput, got %v.", r)
+				}
+			}()
+			cc.Sum2(data[0], data[1], CNv1, 0)
+		}()
+	}
+}
+
+func BenchmarkSum2(b *testing.B) {
+	cc := new(Cache)
+	for _, algo := range []Algorithm{CNv0, CNv1, CNv2} {
+		b.Run(algo.String(), func(b *testing.B) {
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				cc.Sum2(benchData[0], benchData[1], algo, 0)
+			}
+			reportHashrate(b, start, 2)
+		})
+		b.Run(algo.String()+"-sequential", func(b *testing.B) {
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				cc.SumAlgorithm(benchData[0], algo, 0)
+				cc.SumAlgorithm(benchData[1], algo, 0)
+			}
+			reportHashrate(b, start, 2)
+		})
+	}
+}
+
+func BenchmarkSumMany(b *testing.B) {
+	cc := new(Cache)
+	blobs := benchData[:]
+	dst := make([][32]byte, len(blobs))
+	for _, algo := range []Algorithm{CNv0, CNv1, CNv2} {
+		b.Run(algo.String(), func(b *testing.B) {
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				cc.SumMany(dst, blobs, algo, 0)
+			}
+			reportHashrate(b, start, len(blobs))
+		})
+		b.Run(algo.String()+"-sequential", func(b *testing.B) {
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				for j, data := range blobs {
+					cc.SumInto(&dst[j], data, algo, 0)
+				}
+			}
+			reportHashrate(b, start, len(blobs))
+		})
+	}
+}
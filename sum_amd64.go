@@ -1,3 +1,5 @@
+// +build amd64,!purego,!tinygo
+
 package cryptonight
 
 import (
@@ -13,71 +15,176 @@ var (
 	hasAES = cpu.X86.HasAES
 )
 
-func (cc *cache) sum(data []byte, variant int) []byte {
-	if !hasAES {
-		return cc.sumGo(data, variant)
+func init() {
+	backends = append(backends, "amd64-aes")
+}
+
+func backend() string {
+	if hasAES {
+		return "amd64-aes"
+	}
+
+	return "go"
+}
+
+// cpuFeatures returns the detected features among the ones of interest to
+// CryptoNight, with their names in /proc/cpuinfo. Only aes is used for now.
+func cpuFeatures() []string {
+	var features []string
+	for _, f := range []struct {
+		name string
+		has  bool
+	}{
+		{"aes", cpu.X86.HasAES},
+		{"sse2", cpu.X86.HasSSE2},
+		{"ssse3", cpu.X86.HasSSSE3},
+		{"sse4_1", cpu.X86.HasSSE41},
+		{"avx", cpu.X86.HasAVX},
+		{"avx2", cpu.X86.HasAVX2},
+		{"bmi2", cpu.X86.HasBMI2},
+	} {
+		if f.has {
+			features = append(features, f.name)
+		}
 	}
-	return cc.sumAsm(data, variant)
+
+	return features
 }
 
-func (cc *cache) sumAsm(data []byte, variant int) []byte {
+func (cc *Cache) sum(data []byte, p params, height uint64) []byte {
+	// The assembly only implements the variants 0 to 2 of standard sizes, so
+	// the random math of variant 4 and the other members of the family run in
+	// Go for now, as do the Caches with a PadStore.
+	if !hasAES || p.variant > 2 || p != standard(p.variant) || cc.store != nil || !cc.fits(2*p.memory) {
+		return cc.sumGo(data, p, height)
+	}
+	return cc.sumAsm(data, p.variant)
+}
+
+func (cc *Cache) sumAsm(data []byte, variant int) []byte {
+	if variant < 0 || variant > 2 {
+		panic(ErrUnknownVariant)
+	}
+	if variant == 1 && len(data) < 43 {
+		panic(ErrShortInput)
+	}
+
+	sp := cc.pad(2 * 1024 * 1024)
+
 	//////////////////////////////////////////////////
 	// as per CNS008 sec.3 Scratchpad Initialization
 	sha3.Keccak1600State(&cc.finalState, data)
+	cc.explodeAsm(sp)
+
+	//////////////////////////////////////////////////
+	// as per CNS008 sec.4 Memory-Hard Loop
+	cc.memhardAsm(sp, data, variant)
+
+	//////////////////////////////////////////////////
+	// as per CNS008 sec.5 Result Calculation
+	cc.implodeAsm(sp)
+
+	return cc.finalHash()
+}
 
-	// scratchpad init
+// explodeAsm, memhardAsm and implodeAsm are the phases of sumAsm, like
+// explode, memhard and implode of sumGo.
+
+func (cc *Cache) explodeAsm(sp []uint64) {
 	aes.CnExpandKeyAsm(&cc.finalState[0], &cc.rkeys)
 	copy(cc.blocks[:], cc.finalState[8:24])
+	aes.CnExplodeAsm(&sp[0], len(sp)/16, &cc.blocks[0], &cc.rkeys)
+}
 
-	for i := 0; i < 2*1024*1024/8; i += 16 {
-		for j := 0; j < 16; j += 2 {
-			aes.CnRoundsAsm(&cc.blocks[j], &cc.blocks[j], &cc.rkeys)
-		}
-		copy(cc.scratchpad[i:i+16], cc.blocks[:16])
-	}
-
-	//////////////////////////////////////////////////
-	// as per CNS008 sec.4 Memory-Hard Loop
+func (cc *Cache) memhardAsm(sp []uint64, data []byte, variant int) {
 	switch variant {
 	default:
-		memhard0(cc)
+		memhard0(&sp[0], &cc.finalState)
 
 	case 1:
-		if len(data) < 43 {
-			panic("cryptonight: variant 2 requires at least 43 bytes of input")
-		}
 		tweak := cc.finalState[24] ^ binary.LittleEndian.Uint64(data[35:43])
-		memhard1(cc, tweak)
+		memhard1(&sp[0], &cc.finalState, tweak)
 
 	case 2:
-		memhard2(cc)
+		memhard2(&sp[0], &cc.finalState)
 	}
+}
 
-	//////////////////////////////////////////////////
-	// as per CNS008 sec.5 Result Calculation
+func (cc *Cache) implodeAsm(sp []uint64) {
 	aes.CnExpandKeyAsm(&cc.finalState[4], &cc.rkeys)
-	tmp := cc.finalState[8:24] // a temp pointer
+	aes.CnImplodeAsm(&cc.finalState[8], &sp[0], len(sp)/16, &cc.rkeys)
+	sha3.Keccak1600Permute(&cc.finalState)
+}
 
-	for i := 0; i < 2*1024*1024/8; i += 16 {
-		for j := 0; j < 16; j += 2 {
-			cc.scratchpad[i+j+0] ^= tmp[j+0]
-			cc.scratchpad[i+j+1] ^= tmp[j+1]
-			aes.CnRoundsAsm(&cc.scratchpad[i+j], &cc.scratchpad[i+j], &cc.rkeys)
-		}
-		tmp = cc.scratchpad[i : i+16]
+// The memory hard loops run on the 2 MiB scratchpad sp, starting from state.
+
+//go:noescape
+func memhard0(sp *uint64, state *[25]uint64)
+
+//go:noescape
+func memhard1(sp *uint64, state *[25]uint64, tweak uint64)
+
+//go:noescape
+func memhard2(sp *uint64, state *[25]uint64)
+
+func (cc *Cache) sum2(out *[2][32]byte, dataA, dataB []byte, p params, height uint64) {
+	if !hasAES || p.variant > 2 || p != standard(p.variant) || cc.store != nil || !cc.fits(2*p.memory) {
+		copy(out[0][:], cc.sum(dataA, p, height))
+		copy(out[1][:], cc.sum(dataB, p, height))
+		return
+	}
+	if p.variant == 1 && (len(dataA) < 43 || len(dataB) < 43) {
+		panic(ErrShortInput)
 	}
 
-	copy(cc.finalState[8:24], tmp)
-	sha3.Keccak1600Permute(&cc.finalState)
+	// the scratchpad is doubled, stateB being the keccak state of dataB
+	const memory = 2 * 1024 * 1024
+	sp := cc.pad(2 * memory)
+	spA, spB := sp[:memory/8], sp[memory/8:]
+	var stateB [25]uint64
+
+	sha3.Keccak1600State(&cc.finalState, dataA)
+	sha3.Keccak1600State(&stateB, dataB)
+	for _, v := range []struct {
+		sp    []uint64
+		state *[25]uint64
+	}{{spA, &cc.finalState}, {spB, &stateB}} {
+		aes.CnExpandKeyAsm(&v.state[0], &cc.rkeys)
+		copy(cc.blocks[:], v.state[8:24])
+		aes.CnExplodeAsm(&v.sp[0], len(v.sp)/16, &cc.blocks[0], &cc.rkeys)
+	}
 
-	return cc.finalHash()
+	switch p.variant {
+	case 0:
+		memhard0x2(&spA[0], &cc.finalState, &spB[0], &stateB)
+	case 1:
+		tweakA := cc.finalState[24] ^ binary.LittleEndian.Uint64(dataA[35:43])
+		tweakB := stateB[24] ^ binary.LittleEndian.Uint64(dataB[35:43])
+		memhard1x2(&spA[0], &cc.finalState, &spB[0], &stateB, tweakA, tweakB)
+	case 2:
+		memhard2x2(&spA[0], &cc.finalState, &spB[0], &stateB)
+	}
+
+	for i, v := range []struct {
+		sp    []uint64
+		state *[25]uint64
+	}{{spA, &cc.finalState}, {spB, &stateB}} {
+		aes.CnExpandKeyAsm(&v.state[4], &cc.rkeys)
+		aes.CnImplodeAsm(&v.state[8], &v.sp[0], len(v.sp)/16, &cc.rkeys)
+		sha3.Keccak1600Permute(v.state)
+		cc.finalState = *v.state
+		copy(out[i][:], cc.finalHash())
+	}
 }
 
+// The interleaved memory hard loops run on the 2 MiB scratchpads spA and spB,
+// starting from stateA and stateB respectively.
+
 //go:noescape
-func memhard0(cc *cache)
+func memhard0x2(spA *uint64, stateA *[25]uint64, spB *uint64, stateB *[25]uint64)
 
 //go:noescape
-func memhard1(cc *cache, tweak uint64)
+func memhard1x2(spA *uint64, stateA *[25]uint64, spB *uint64, stateB *[25]uint64, tweakA, tweakB uint64)
 
 //go:noescape
-func memhard2(cc *cache)
+func memhard2x2(spA *uint64, stateA *[25]uint64, spB *uint64, stateB *[25]uint64)
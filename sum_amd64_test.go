@@ -1,16 +1,41 @@
+// +build amd64,!purego,!tinygo
+
 package cryptonight
 
 import (
+	"bytes"
+	"math/rand"
 	"testing"
+	"time"
 )
 
+func init() {
+	if !hasAES {
+		return
+	}
+
+	phasesOf["amd64-aes"] = []phase{
+		goPhases[0],
+		{"explode", func(cc *Cache, data []byte, p params, height uint64) {
+			cc.explodeAsm(cc.pad(p.memory))
+		}},
+		{"loop", func(cc *Cache, data []byte, p params, height uint64) {
+			cc.memhardAsm(cc.pad(p.memory), data, p.variant)
+		}},
+		{"implode", func(cc *Cache, data []byte, p params, height uint64) {
+			cc.implodeAsm(cc.pad(p.memory))
+		}},
+		goPhases[4],
+	}
+}
+
 func TestSumWithoutAESNI(t *testing.T) {
 	if !hasAES {
 		t.Skip("host does not support AES-NI")
 	}
 
 	hasAES = false
-	testSum(t, new(cache).sum)
+	testSum(t, withoutHeight(withStandard(new(Cache).sum)))
 	hasAES = true
 }
 
@@ -19,7 +44,7 @@ func TestSumAsm(t *testing.T) {
 		t.Skip("host does not support AES-NI")
 	}
 
-	testSum(t, new(cache).sumAsm)
+	testSum(t, new(Cache).sumAsm)
 }
 
 func BenchmarkSumAsm(b *testing.B) {
@@ -29,51 +54,84 @@ func BenchmarkSumAsm(b *testing.B) {
 
 	b.Run("v0", func(b *testing.B) {
 		b.N = 100
+		start := time.Now()
 		for i := 0; i < b.N; i++ {
-			new(cache).sumAsm(benchData[i&0x03], 0)
+			new(Cache).sumAsm(benchData[i&0x03], 0)
 		}
+		reportHashrate(b, start, 1)
 	})
 	b.Run("v1", func(b *testing.B) {
 		b.N = 100
+		start := time.Now()
 		for i := 0; i < b.N; i++ {
-			new(cache).sumAsm(benchData[i&0x03], 1)
+			new(Cache).sumAsm(benchData[i&0x03], 1)
 		}
+		reportHashrate(b, start, 1)
 	})
 	b.Run("v2", func(b *testing.B) {
 		b.N = 100
+		start := time.Now()
 		for i := 0; i < b.N; i++ {
-			new(cache).sumAsm(benchData[i&0x03], 2)
+			new(Cache).sumAsm(benchData[i&0x03], 2)
 		}
+		reportHashrate(b, start, 1)
 	})
 
 	b.Run("v0-parallel", func(b *testing.B) {
 		b.N = 100
+		start := time.Now()
 		b.RunParallel(func(pb *testing.PB) {
 			i := 0
 			for pb.Next() {
-				new(cache).sumAsm(benchData[i&0x03], 0)
+				new(Cache).sumAsm(benchData[i&0x03], 0)
 				i++
 			}
 		})
+		reportHashrate(b, start, 1)
 	})
 	b.Run("v1-parallel", func(b *testing.B) {
 		b.N = 100
+		start := time.Now()
 		b.RunParallel(func(pb *testing.PB) {
 			i := 0
 			for pb.Next() {
-				new(cache).sumAsm(benchData[i&0x03], 1)
+				new(Cache).sumAsm(benchData[i&0x03], 1)
 				i++
 			}
 		})
+		reportHashrate(b, start, 1)
 	})
 	b.Run("v2-parallel", func(b *testing.B) {
 		b.N = 100
+		start := time.Now()
 		b.RunParallel(func(pb *testing.PB) {
 			i := 0
 			for pb.Next() {
-				new(cache).sumAsm(benchData[i&0x03], 2)
+				new(Cache).sumAsm(benchData[i&0x03], 2)
 				i++
 			}
 		})
+		reportHashrate(b, start, 1)
 	})
 }
+
+// TestSumAsmEquivalence checks sumAsm against sumGo bit by bit on random
+// inputs of random lengths, for every variant.
+func TestSumAsmEquivalence(t *testing.T) {
+	if !hasAES {
+		t.Skip("host does not support AES-NI")
+	}
+
+	r := rand.New(rand.NewSource(1))
+	asm, ref := new(Cache), new(Cache)
+	for i := 0; i < 60; i++ {
+		variant := i % 3
+		data := make([]byte, 43+r.Intn(300))
+		r.Read(data)
+
+		expected := ref.sumGo(data, standard(variant), 0)
+		if result := asm.sumAsm(data, variant); !bytes.Equal(result, expected) {
+			t.Errorf("\n[%d] variant %d on %x\nexpected:\n\t%x\ngot:\n\t%x\n", i, variant, data, expected, result)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package cryptonight
+
+import "ekyu.moe/cryptonight/internal/argon2"
+
+// chukwaSaltSize is the size of the salt of Chukwa, the first bytes of its
+// input.
+const chukwaSaltSize = 16
+
+// sumArgon2 calculates a Chukwa hash digest: Argon2id of data salted with its
+// first 16 bytes, in p.iterations passes over p.memory bytes of the scratchpad
+// and a single lane, as in TurtleCoin.
+func (cc *Cache) sumArgon2(data []byte, p params) []byte {
+	if len(data) < chukwaSaltSize {
+		panic(ErrShortSalt)
+	}
+
+	argon2.IDKey(cc.digest[:], data, data[:chukwaSaltSize], uint32(p.iterations), cc.pad(p.memory), 1)
+
+	return cc.digest[:]
+}
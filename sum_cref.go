@@ -0,0 +1,26 @@
+// +build cnref,cgo
+
+package cryptonight
+
+import (
+	"bytes"
+	"fmt"
+
+	"ekyu.moe/cryptonight/internal/cref"
+)
+
+// With the cnref tag, every result of Sum is checked against the reference
+// implementation from monero, see package cref for how to link it.
+func init() {
+	backends = append(backends, "cref")
+	crossCheck = func(data []byte, variant int, sum []byte) {
+		// the height is not passed down here, see package crosscheck for
+		// variant 4
+		if variant == 4 {
+			return
+		}
+		if ref := cref.Sum(data, variant); !bytes.Equal(sum, ref) {
+			panic(fmt.Sprintf("cryptonight: mismatch against reference for variant %d, input %x: expected %x, got %x", variant, data, ref, sum))
+		}
+	}
+}
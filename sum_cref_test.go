@@ -0,0 +1,36 @@
+// +build cnref,cgo
+
+package cryptonight
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"ekyu.moe/cryptonight/internal/cref"
+)
+
+// With the cnref tag, every call of Sum is checked against the reference as
+// well, so the whole test suite turns into a differential test. This adds
+// random inputs of various lengths on top of it.
+func TestSumRef(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < 300; i++ {
+		variant := i % 3
+		data := make([]byte, r.Intn(256))
+		if variant == 1 && len(data) < 43 {
+			data = make([]byte, 43+r.Intn(213))
+		}
+		r.Read(data)
+
+		expected := cref.Sum(data, variant)
+		for name, sum := range map[string]func([]byte, int) []byte{
+			"Sum":   Sum,
+			"sumGo": withoutHeight(withStandard(new(Cache).sumGo)),
+		} {
+			if result := sum(data, variant); !bytes.Equal(result, expected) {
+				t.Errorf("\n[%d] %s with variant %d on %x\nexpected:\n\t%x\ngot:\n\t%x\n", i, name, variant, data, expected, result)
+			}
+		}
+	}
+}
@@ -1,5 +1,4 @@
 // spec
-#define PAD_SIZE (2 << 20) // 2 MiB
 #define ITER     (1 << 19) // 524288
 
 // common
@@ -25,3 +24,15 @@
 #define E     X4
 #define DIV_RESULT  R11
 #define SQRT_RESULT R12
+
+// PREFETCH hints the chunk at x[0] of the scratchpad at pad into the cache,
+// unless built with the noprefetch tag.
+#include "go_asm.h"
+#ifdef const_prefetch
+#define PREFETCH(pad, x, tmp) \
+	MOVQ       x, tmp;            \
+	ANDQ       $0x1ffff0, tmp;    \
+	PREFETCHT0 0(pad)(tmp*1)
+#else
+#define PREFETCH(pad, x, tmp)
+#endif
@@ -1,7 +1,14 @@
-// +build !amd64
+// +build !amd64 purego tinygo
 
 package cryptonight
 
-func (cc *cache) sum(data []byte, variant int) []byte {
-	return cc.sumGo(data, variant)
+func backend() string { return "go" }
+
+func (cc *Cache) sum(data []byte, p params, height uint64) []byte {
+	return cc.sumGo(data, p, height)
+}
+
+func (cc *Cache) sum2(out *[2][32]byte, dataA, dataB []byte, p params, height uint64) {
+	copy(out[0][:], cc.sum(dataA, p, height))
+	copy(out[1][:], cc.sum(dataB, p, height))
 }
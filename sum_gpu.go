@@ -0,0 +1,201 @@
+package cryptonight
+
+import (
+	"encoding/binary"
+	"math"
+
+	"ekyu.moe/cryptonight/internal/aes"
+	"ekyu.moe/cryptonight/internal/sha3"
+)
+
+// This file implements CryptoNight-GPU, as per xmrig:
+// src/crypto/cn/gpu/cn_gpu_ssse3.cpp. The scratchpad is filled with keccak
+// instead of AES, and the memory hard loop is single precision floating point
+// math on 4 lanes of 32 bits. Every operation is lane-wise, so it is run here
+// one lane at a time, in the same order and rounding as SSE.
+
+// gpuOrders are the orders in which the 4 blocks of an iteration are passed to
+// gpuCompute, and gpuCounts the initial values of its feedback, for each block
+// and each of its 4 computations.
+var (
+	gpuOrders = [4][4][4]int{
+		{{0, 1, 2, 3}, {0, 2, 3, 1}, {0, 3, 1, 2}, {0, 3, 2, 1}},
+		{{1, 0, 2, 3}, {1, 2, 3, 0}, {1, 3, 0, 2}, {1, 3, 2, 0}},
+		{{2, 1, 0, 3}, {2, 0, 3, 1}, {2, 3, 1, 0}, {2, 3, 0, 1}},
+		{{3, 1, 2, 0}, {3, 2, 0, 1}, {3, 0, 1, 2}, {3, 0, 2, 1}},
+	}
+	gpuCounts = [4][4]float32{
+		{1.3437500, 1.2812500, 1.3593750, 1.3671875},
+		{1.4296875, 1.3984375, 1.3828125, 1.3046875},
+		{1.4140625, 1.2734375, 1.2578125, 1.2890625},
+		{1.3203125, 1.3515625, 1.3359375, 1.4609375},
+	}
+)
+
+// sumGPU calculates a CryptoNight-GPU hash digest. Unlike the other members of
+// the family, the digest is the first 32 bytes of the final keccak state.
+func (cc *Cache) sumGPU(data []byte, p params) []byte {
+	sp := cc.pad(p.memory)
+
+	sha3.Keccak1600State(&cc.finalState, data)
+	cc.explodeGPU(sp)
+	cc.innerGPU(sp, p.iterations, uint32(p.mask))
+
+	aes.CnExpandKey(cc.finalState[4:8], &cc.rkeys)
+	cc.implodeHeavy(sp, len(sp))
+	sha3.Keccak1600Permute(&cc.finalState)
+
+	for i := 0; i < 4; i++ {
+		binary.LittleEndian.PutUint64(cc.digest[8*i:], cc.finalState[i])
+	}
+
+	return cc.digest[:]
+}
+
+// explodeGPU fills sp with keccak: every 512 bytes are 3 permutations of the
+// keccak state with its first word xored with their index.
+func (cc *Cache) explodeGPU(sp []uint64) {
+	var st [25]uint64
+	for i := 0; i < len(sp); i += 64 {
+		st = cc.finalState
+		st[0] ^= uint64(i / 64)
+
+		sha3.Keccak1600Permute(&st)
+		copy(sp[i:i+20], st[:20])
+		sha3.Keccak1600Permute(&st)
+		copy(sp[i+20:i+42], st[:22])
+		sha3.Keccak1600Permute(&st)
+		copy(sp[i+42:i+64], st[:22])
+	}
+}
+
+// innerGPU is the memory hard loop of CryptoNight-GPU. Each iteration reads 4
+// blocks of 16 bytes, xors each of them with 4 computations on all of them,
+// and addresses the next ones with the sum of the computations.
+func (cc *Cache) innerGPU(sp []uint64, iterations int, mask uint32) {
+	var (
+		n    [4][4]float32 // blocks as floats, by block then lane
+		sum0 [4]float32    // feedback of the previous iteration
+	)
+
+	s := uint32(cc.finalState[0]) >> 8
+	for i := 0; i < iterations; i++ {
+		base := int(s&mask) >> 3
+		for j := 0; j < 4; j++ {
+			w0, w1 := sp[base+2*j], sp[base+2*j+1]
+			n[j] = [4]float32{
+				float32(int32(w0)), float32(int32(w0 >> 32)),
+				float32(int32(w1)), float32(int32(w1 >> 32)),
+			}
+		}
+
+		var (
+			sums [4][4]float32
+			out2 [2]uint64
+		)
+		for j := 0; j < 4; j++ {
+			var (
+				out        [2]uint64
+				suma, sumb [4]float32
+			)
+			for k, o := range gpuOrders[j] {
+				var r [4]uint32
+				for lane := range r {
+					f := gpuCompute(n[o[0]][lane], n[o[1]][lane], n[o[2]][lane], n[o[3]][lane], gpuCounts[j][k], sum0[lane])
+					switch k {
+					case 0:
+						suma[lane] = f
+					case 1:
+						suma[lane] += f
+					case 2:
+						sumb[lane] = f
+					case 3:
+						sumb[lane] += f
+					}
+					r[lane] = uint32(int32(f * 536870880))
+				}
+
+				// the k-th computation is rotated right by k bytes
+				lo, hi := uint64(r[0])|uint64(r[1])<<32, uint64(r[2])|uint64(r[3])<<32
+				if k > 0 {
+					b := uint(8 * k)
+					lo, hi = lo>>b|hi<<(64-b), hi>>b|lo<<(64-b)
+				}
+				out[0] ^= lo
+				out[1] ^= hi
+			}
+			for lane := range sums[j] {
+				sums[j][lane] = suma[lane] + sumb[lane]
+			}
+
+			sp[base+2*j] ^= out[0]
+			sp[base+2*j+1] ^= out[1]
+			out2[0] ^= out[0]
+			out2[1] ^= out[1]
+		}
+
+		var v [4]uint32
+		for lane := 0; lane < 4; lane++ {
+			f := (sums[0][lane] + sums[1][lane]) + (sums[2][lane] + sums[3][lane])
+			f = math.Float32frombits(math.Float32bits(f) & 0x7fffffff) // abs, between 0 and 64
+			v[lane] = uint32(int32(f * 16777216))
+			sum0[lane] = f / 64
+		}
+		v[0] ^= uint32(out2[0])
+		v[1] ^= uint32(out2[0] >> 32)
+		v[2] ^= uint32(out2[1])
+		v[3] ^= uint32(out2[1] >> 32)
+		s = v[0] ^ v[1] ^ v[2] ^ v[3]
+	}
+}
+
+// gpuCompute is single_compute of xmrig for a lane, except for the conversion
+// to an integer: 4 rounds of 8 sub rounds on n0 to n3, with c as feedback
+// starting from cnt and rc added to it. The result is between -4 and 4, with an
+// absolute value of at least 2.
+func gpuCompute(n0, n1, n2, n3, cnt, rc float32) float32 {
+	c := cnt
+	var r float32
+	for i := 0; i < 4; i++ {
+		var n, d float32
+		gpuSubRound(n0, n1, n2, n3, rc, &n, &d, &c)
+		gpuSubRound(n1, n2, n3, n0, rc, &n, &d, &c)
+		gpuSubRound(n2, n3, n0, n1, rc, &n, &d, &c)
+		gpuSubRound(n3, n0, n1, n2, rc, &n, &d, &c)
+		gpuSubRound(n3, n2, n1, n0, rc, &n, &d, &c)
+		gpuSubRound(n2, n1, n0, n3, rc, &n, &d, &c)
+		gpuSubRound(n1, n0, n3, n2, rc, &n, &d, &c)
+		gpuSubRound(n0, n3, n2, n1, rc, &n, &d, &c)
+
+		// make sure abs(d) >= 2, so that the division neither overflows nor
+		// divides by zero
+		d = math.Float32frombits(math.Float32bits(d)&0xff7fffff | 0x40000000)
+		r += n / d
+	}
+
+	// a quick fmod by setting the exponent to 1
+	return math.Float32frombits(math.Float32bits(r)&0x807fffff | 0x40000000)
+}
+
+func gpuSubRound(n0, n1, n2, n3, rc float32, n, d, c *float32) {
+	n1 += *c
+	nn := n0 * *c
+	nn = fmaBreak(n1 * (nn * nn))
+	*n += nn
+
+	n3 -= *c
+	dd := n2 * *c
+	dd = fmaBreak(n3 * (dd * dd))
+	*d += dd
+
+	// constant feedback
+	*c += rc
+	*c += 0.734375
+	*c += math.Float32frombits(math.Float32bits(nn+dd)&0x807fffff | 0x40000000)
+}
+
+// fmaBreak sets the low bits of the exponent of x to 01, which breaks the
+// dependency chain.
+func fmaBreak(x float32) float32 {
+	return math.Float32frombits(math.Float32bits(x)&0xfeffffff | 0x00800000)
+}
@@ -7,7 +7,69 @@ import (
 	"ekyu.moe/cryptonight/internal/sha3"
 )
 
-func (cc *cache) sumGo(data []byte, variant int) []byte {
+// sumGo is the portable implementation. The state and the scratchpad hold the
+// little-endian words of the bytes of the specification, only converted with
+// encoding/binary, so it is correct on big-endian platforms too.
+func (cc *Cache) sumGo(data []byte, p params, height uint64) []byte {
+	if (p.gpu || p.argon2) && cc.store != nil {
+		panic(ErrStoreUnsupported)
+	}
+	if p.gpu {
+		return cc.sumGPU(data, p)
+	}
+	if p.argon2 {
+		return cc.sumArgon2(data, p)
+	}
+
+	if !knownVariant(p.variant) {
+		panic(ErrUnknownVariant)
+	}
+	if p.variant == 1 && len(data) < 43 {
+		panic(ErrShortInput)
+	}
+	var pad []uint64
+	if cc.store == nil {
+		pad = cc.pad(p.memory)
+	}
+
+	//////////////////////////////////////////////////
+	// as per CNS008 sec.3 Scratchpad Initialization
+	sha3.Keccak1600State(&cc.finalState, data)
+	cc.explode(pad, p)
+
+	//////////////////////////////////////////////////
+	// as per CNS008 sec.4 Memory-Hard Loop
+	cc.memhard(pad, data, p, height)
+
+	//////////////////////////////////////////////////
+	// as per CNS008 sec.5 Result Calculation
+	cc.implode(pad, p)
+
+	return cc.finalHash()
+}
+
+// explode fills the scratchpad, pad or the PadStore of cc, from the Keccak
+// state of the input.
+func (cc *Cache) explode(pad []uint64, p params) {
+	aes.CnExpandKey(cc.finalState[:4], &cc.rkeys)
+	copy(cc.blocks[:], cc.finalState[8:24])
+
+	if p.heavy {
+		for i := 0; i < 16; i++ {
+			aes.CnExplode(cc.blocks[:], cc.blocks[:], &cc.rkeys)
+			mixBlocks(cc.blocks[:])
+		}
+	}
+	if cc.store != nil {
+		cc.store.explode(p.memory/8, cc.blocks[:], &cc.rkeys)
+	} else {
+		aes.CnExplode(pad[:p.memory/8], cc.blocks[:], &cc.rkeys)
+	}
+}
+
+// memhard runs the memory hard loop on the scratchpad filled by explode. data
+// is the input, of at least 43 bytes for variant 1.
+func (cc *Cache) memhard(pad []uint64, data []byte, p params, height uint64) {
 	//////////////////////////////////////////////////
 	// these variables never escape to heap
 	var (
@@ -17,84 +79,96 @@ func (cc *cache) sumGo(data []byte, variant int) []byte {
 		// for variant 1
 		v1Tweak uint64
 
-		// for variant 2
+		// for variant 2 and 4
 		e          [2]uint64
 		divResult  uint64
 		sqrtResult uint64
-	)
 
-	//////////////////////////////////////////////////
-	// as per CNS008 sec.3 Scratchpad Initialization
-	sha3.Keccak1600State(&cc.finalState, data)
+		// for variant 4
+		r    [9]uint32
+		code rmProgram
+
+		variant = p.variant
+		mask    = p.mask
+	)
 
 	if variant == 1 {
-		if len(data) < 43 {
-			panic("cryptonight: variant 2 requires at least 43 bytes of input")
-		}
 		v1Tweak = cc.finalState[24] ^ binary.LittleEndian.Uint64(data[35:43])
 	}
 
-	// scratchpad init
-	aes.CnExpandKeyGo(cc.finalState[:4], &cc.rkeys)
-	copy(cc.blocks[:], cc.finalState[8:24])
-
-	for i := 0; i < 2*1024*1024/8; i += 16 {
-		for j := 0; j < 16; j += 2 {
-			aes.CnRoundsGo(cc.blocks[j:j+2], cc.blocks[j:j+2], &cc.rkeys)
-		}
-		copy(cc.scratchpad[i:i+16], cc.blocks[:16])
-	}
-
-	//////////////////////////////////////////////////
-	// as per CNS008 sec.4 Memory-Hard Loop
 	a[0] = cc.finalState[0] ^ cc.finalState[4]
 	a[1] = cc.finalState[1] ^ cc.finalState[5]
 	b[0] = cc.finalState[2] ^ cc.finalState[6]
 	b[1] = cc.finalState[3] ^ cc.finalState[7]
-	if variant == 2 {
+	if variant >= 2 {
 		e[0] = cc.finalState[8] ^ cc.finalState[10]
 		e[1] = cc.finalState[9] ^ cc.finalState[11]
+	}
+	if variant == 2 {
 		divResult = cc.finalState[12]
 		sqrtResult = cc.finalState[13]
 	}
+	if variant == 4 {
+		// VARIANT4_RANDOM_MATH_INIT
+		r[0] = uint32(cc.finalState[12])
+		r[1] = uint32(cc.finalState[12] >> 32)
+		r[2] = uint32(cc.finalState[13])
+		r[3] = uint32(cc.finalState[13] >> 32)
+		code.generate(height)
+	}
 
-	for i := 0; i < 524288; i++ {
-		addr := (a[0] & 0x1ffff0) >> 3
-		aes.CnSingleRoundGo(c[:2], cc.scratchpad[addr:addr+2], &a)
+	idx := a[0]
+	for i := 0; i < p.iterations; i++ {
+		sp, addr := cc.line(pad, (idx&mask)>>3)
+		aes.CnSingleRound(c[:2], sp[addr:addr+2], &a)
 
-		if variant == 2 {
+		if variant >= 2 {
 			// since we use []uint64 instead of []uint8 as scratchpad, the offset applies too
 			offset0 := addr ^ 0x02
 			offset1 := addr ^ 0x04
 			offset2 := addr ^ 0x06
 
-			chunk0_0 := cc.scratchpad[offset0+0]
-			chunk0_1 := cc.scratchpad[offset0+1]
-			chunk1_0 := cc.scratchpad[offset1+0]
-			chunk1_1 := cc.scratchpad[offset1+1]
-			chunk2_0 := cc.scratchpad[offset2+0]
-			chunk2_1 := cc.scratchpad[offset2+1]
-
-			cc.scratchpad[offset0+0] = chunk2_0 + e[0]
-			cc.scratchpad[offset0+1] = chunk2_1 + e[1]
-			cc.scratchpad[offset2+0] = chunk1_0 + a[0]
-			cc.scratchpad[offset2+1] = chunk1_1 + a[1]
-			cc.scratchpad[offset1+0] = chunk0_0 + b[0]
-			cc.scratchpad[offset1+1] = chunk0_1 + b[1]
+			chunk0_0 := sp[offset0+0]
+			chunk0_1 := sp[offset0+1]
+			chunk1_0 := sp[offset1+0]
+			chunk1_1 := sp[offset1+1]
+			chunk2_0 := sp[offset2+0]
+			chunk2_1 := sp[offset2+1]
+
+			if p.reverse {
+				chunk0_0, chunk2_0 = chunk2_0, chunk0_0
+				chunk0_1, chunk2_1 = chunk2_1, chunk0_1
+			}
+
+			sp[offset0+0] = chunk2_0 + e[0]
+			sp[offset0+1] = chunk2_1 + e[1]
+			sp[offset2+0] = chunk1_0 + a[0]
+			sp[offset2+1] = chunk1_1 + a[1]
+			sp[offset1+0] = chunk0_0 + b[0]
+			sp[offset1+1] = chunk0_1 + b[1]
+
+			if variant == 4 {
+				c[0] ^= chunk0_0 ^ chunk1_0 ^ chunk2_0
+				c[1] ^= chunk0_1 ^ chunk1_1 ^ chunk2_1
+			}
 		}
 
-		cc.scratchpad[addr+0] = b[0] ^ c[0]
-		cc.scratchpad[addr+1] = b[1] ^ c[1]
+		sp[addr+0] = b[0] ^ c[0]
+		sp[addr+1] = b[1] ^ c[1]
 
 		if variant == 1 {
-			t := cc.scratchpad[addr+1] >> 24
-			t = ((^t)&1)<<4 | (((^t)&1)<<4&t)<<1 | (t&32)>>1
-			cc.scratchpad[addr+1] ^= t << 24
+			t := sp[addr+1] >> 24
+			if p.xtl {
+				t = 0x75310 >> ((t>>4&6 | t&1) << 1) & 0x30
+			} else {
+				t = ((^t)&1)<<4 | (((^t)&1)<<4&t)<<1 | (t&32)>>1
+			}
+			sp[addr+1] ^= t << 24
 		}
 
-		addr = (c[0] & 0x1ffff0) >> 3
-		d[0] = cc.scratchpad[addr]
-		d[1] = cc.scratchpad[addr+1]
+		sp, addr = cc.line(pad, (c[0]&mask)>>3)
+		d[0] = sp[addr]
+		d[1] = sp[addr+1]
 
 		if variant == 2 {
 			// equivalent to VARIANT2_PORTABLE_INTEGER_MATH in slow-hash.c
@@ -109,34 +183,62 @@ func (cc *cache) sumGo(data []byte, variant int) []byte {
 			sqrtResult = v2Sqrt(sqrtInput)
 		}
 
+		// the result of the random math is only added to a after the shuffle
+		a1 := a
+		if variant == 4 {
+			// VARIANT4_RANDOM_MATH
+			d[0] ^= uint64(r[0]+r[1]) | uint64(r[2]+r[3])<<32
+
+			r[4] = uint32(a[0])
+			r[5] = uint32(a[1])
+			r[6] = uint32(b[0])
+			r[7] = uint32(e[0])
+			r[8] = uint32(e[1])
+			code.run(&r)
+
+			a1[0] ^= uint64(r[2]) | uint64(r[3])<<32
+			a1[1] ^= uint64(r[0]) | uint64(r[1])<<32
+		}
+
 		// byteMul
 		lo, hi := mul128(c[0], d[0])
 
-		if variant == 2 {
+		if variant >= 2 {
 			// shuffle again, it's the same process as above
 			offset0 := addr ^ 0x02
 			offset1 := addr ^ 0x04
 			offset2 := addr ^ 0x06
 
-			chunk0_0 := cc.scratchpad[offset0+0]
-			chunk0_1 := cc.scratchpad[offset0+1]
-			chunk1_0 := cc.scratchpad[offset1+0]
-			chunk1_1 := cc.scratchpad[offset1+1]
-			chunk2_0 := cc.scratchpad[offset2+0]
-			chunk2_1 := cc.scratchpad[offset2+1]
-
-			// VARIANT2_2
-			chunk0_0 ^= hi
-			chunk0_1 ^= lo
-			hi ^= chunk1_0
-			lo ^= chunk1_1
-
-			cc.scratchpad[offset0+0] = chunk2_0 + e[0]
-			cc.scratchpad[offset0+1] = chunk2_1 + e[1]
-			cc.scratchpad[offset2+0] = chunk1_0 + a[0]
-			cc.scratchpad[offset2+1] = chunk1_1 + a[1]
-			cc.scratchpad[offset1+0] = chunk0_0 + b[0]
-			cc.scratchpad[offset1+1] = chunk0_1 + b[1]
+			chunk0_0 := sp[offset0+0]
+			chunk0_1 := sp[offset0+1]
+			chunk1_0 := sp[offset1+0]
+			chunk1_1 := sp[offset1+1]
+			chunk2_0 := sp[offset2+0]
+			chunk2_1 := sp[offset2+1]
+
+			if variant == 2 {
+				// VARIANT2_2
+				chunk0_0 ^= hi
+				chunk0_1 ^= lo
+				hi ^= chunk1_0
+				lo ^= chunk1_1
+			}
+			if p.reverse {
+				chunk0_0, chunk2_0 = chunk2_0, chunk0_0
+				chunk0_1, chunk2_1 = chunk2_1, chunk0_1
+			}
+
+			sp[offset0+0] = chunk2_0 + e[0]
+			sp[offset0+1] = chunk2_1 + e[1]
+			sp[offset2+0] = chunk1_0 + a[0]
+			sp[offset2+1] = chunk1_1 + a[1]
+			sp[offset1+0] = chunk0_0 + b[0]
+			sp[offset1+1] = chunk0_1 + b[1]
+
+			if variant == 4 {
+				c[0] ^= chunk0_0 ^ chunk1_0 ^ chunk2_0
+				c[1] ^= chunk0_1 ^ chunk1_1 ^ chunk2_1
+			}
 
 			// re-asign higher-order of b
 			e[0] = b[0]
@@ -144,14 +246,15 @@ func (cc *cache) sumGo(data []byte, variant int) []byte {
 		}
 
 		// byteAdd
+		a = a1
 		a[0] += hi
 		a[1] += lo
 
-		cc.scratchpad[addr+0] = a[0]
-		cc.scratchpad[addr+1] = a[1]
+		sp[addr+0] = a[0]
+		sp[addr+1] = a[1]
 
 		if variant == 1 {
-			cc.scratchpad[addr+1] ^= v1Tweak
+			sp[addr+1] ^= v1Tweak
 		}
 
 		a[0] ^= d[0]
@@ -159,24 +262,62 @@ func (cc *cache) sumGo(data []byte, variant int) []byte {
 
 		b[0] = c[0]
 		b[1] = c[1]
-	}
 
-	//////////////////////////////////////////////////
-	// as per CNS008 sec.5 Result Calculation
-	aes.CnExpandKeyGo(cc.finalState[4:8], &cc.rkeys)
-	tmp := cc.finalState[8:24] // a temp pointer
-
-	for i := 0; i < 2*1024*1024/8; i += 16 {
-		for j := 0; j < 16; j += 2 {
-			cc.scratchpad[i+j+0] ^= tmp[j+0]
-			cc.scratchpad[i+j+1] ^= tmp[j+1]
-			aes.CnRoundsGo(cc.scratchpad[i+j:i+j+2], cc.scratchpad[i+j:i+j+2], &cc.rkeys)
+		idx = a[0]
+		if p.heavy {
+			// the division step of CryptoNight-Heavy, which also moves the
+			// next address away from a
+			sp, addr = cc.line(pad, (idx&mask)>>3)
+			n := int64(sp[addr])
+			dv := int32(sp[addr+1])
+			q := n / int64(dv|5)
+			sp[addr] = uint64(n ^ q)
+			idx = uint64(int64(dv) ^ q)
 		}
-		tmp = cc.scratchpad[i : i+16]
 	}
+}
 
-	copy(cc.finalState[8:24], tmp)
+// implode folds the scratchpad back into the Keccak state, then permutes it.
+func (cc *Cache) implode(pad []uint64, p params) {
+	aes.CnExpandKey(cc.finalState[4:8], &cc.rkeys)
+	if p.heavy {
+		cc.implodeHeavy(pad, p.memory/8)
+	} else {
+		cc.eachPage(pad, p.memory/8, func(sp []uint64) {
+			aes.CnImplode(cc.finalState[8:24], sp, &cc.rkeys)
+		})
+	}
 	sha3.Keccak1600Permute(&cc.finalState)
+}
 
-	return cc.finalHash()
+// implodeHeavy is the result calculation of CryptoNight-Heavy over the first
+// memory words of the scratchpad, see eachPage. Blocks are mixed after each
+// round, and the scratchpad is read twice, so unlike the one of CNS008 it
+// cannot be done in place.
+func (cc *Cache) implodeHeavy(pad []uint64, memory int) {
+	copy(cc.blocks[:], cc.finalState[8:24])
+	for pass := 0; pass < 2; pass++ {
+		cc.eachPage(pad, memory, func(sp []uint64) {
+			for i := 0; i < len(sp); i += 16 {
+				aes.CnImplode(cc.blocks[:], sp[i:i+16], &cc.rkeys)
+				mixBlocks(cc.blocks[:])
+			}
+		})
+	}
+	for i := 0; i < 16; i++ {
+		aes.CnExplode(cc.blocks[:], cc.blocks[:], &cc.rkeys)
+		mixBlocks(cc.blocks[:])
+	}
+	copy(cc.finalState[8:24], cc.blocks[:])
+}
+
+// mixBlocks xors each of the 8 blocks of 16 bytes in b with the next one, the
+// last one with the first one, as mix_and_propagate of CryptoNight-Heavy does.
+func mixBlocks(b []uint64) {
+	b0, b1 := b[0], b[1]
+	for i := 0; i < 14; i++ {
+		b[i] ^= b[i+2]
+	}
+	b[14] ^= b0
+	b[15] ^= b1
 }
@@ -2,60 +2,82 @@ package cryptonight
 
 import (
 	"testing"
+	"time"
 )
 
 func TestSumGo(t *testing.T) {
-	testSum(t, new(cache).sumGo)
+	testSum(t, withoutHeight(withStandard(new(Cache).sumGo)))
+	testSumHeight(t, withStandard(new(Cache).sumGo))
 }
 
 func BenchmarkSumGo(b *testing.B) {
 	b.Run("v0", func(b *testing.B) {
 		b.N = 100
+		start := time.Now()
 		for i := 0; i < b.N; i++ {
-			new(cache).sumGo(benchData[i&0x03], 0)
+			new(Cache).sumGo(benchData[i&0x03], standard(0), 0)
 		}
+		reportHashrate(b, start, 1)
 	})
 	b.Run("v1", func(b *testing.B) {
 		b.N = 100
+		start := time.Now()
 		for i := 0; i < b.N; i++ {
-			new(cache).sumGo(benchData[i&0x03], 1)
+			new(Cache).sumGo(benchData[i&0x03], standard(1), 0)
 		}
+		reportHashrate(b, start, 1)
 	})
 	b.Run("v2", func(b *testing.B) {
 		b.N = 100
+		start := time.Now()
 		for i := 0; i < b.N; i++ {
-			new(cache).sumGo(benchData[i&0x03], 2)
+			new(Cache).sumGo(benchData[i&0x03], standard(2), 0)
 		}
+		reportHashrate(b, start, 1)
+	})
+	b.Run("v4", func(b *testing.B) {
+		b.N = 100
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			new(Cache).sumGo(benchData[i&0x03], standard(4), 1806260)
+		}
+		reportHashrate(b, start, 1)
 	})
 
 	b.Run("v0-parallel", func(b *testing.B) {
 		b.N = 100
+		start := time.Now()
 		b.RunParallel(func(pb *testing.PB) {
 			i := 0
 			for pb.Next() {
-				new(cache).sumGo(benchData[i&0x03], 0)
+				new(Cache).sumGo(benchData[i&0x03], standard(0), 0)
 				i++
 			}
 		})
+		reportHashrate(b, start, 1)
 	})
 	b.Run("v1-parallel", func(b *testing.B) {
 		b.N = 100
+		start := time.Now()
 		b.RunParallel(func(pb *testing.PB) {
 			i := 0
 			for pb.Next() {
-				new(cache).sumGo(benchData[i&0x03], 1)
+				new(Cache).sumGo(benchData[i&0x03], standard(1), 0)
 				i++
 			}
 		})
+		reportHashrate(b, start, 1)
 	})
 	b.Run("v2-parallel", func(b *testing.B) {
 		b.N = 100
+		start := time.Now()
 		b.RunParallel(func(pb *testing.PB) {
 			i := 0
 			for pb.Next() {
-				new(cache).sumGo(benchData[i&0x03], 2)
+				new(Cache).sumGo(benchData[i&0x03], standard(2), 0)
 				i++
 			}
 		})
+		reportHashrate(b, start, 1)
 	})
 }
@@ -1,20 +1,24 @@
+// +build amd64,!purego,!tinygo
+
 // amd64 assembly implementation for memory hard step of variant 1, with SSE2 and AES-NI.
 // We don't use extra stack at all, and of course no CALL is made.
 
 #include "textflag.h"
 #include "sum_defs_amd64.h"
 
-// func memhard1(cc *cache, tweak uint64)
+// func memhard1(sp *uint64, state *[25]uint64, tweak uint64)
 TEXT ·memhard1(SB), NOSPLIT, $0
-	MOVQ    cc+0(FP), STATE
-	LEAQ    PAD_SIZE(STATE), AX // *cc.finalState
+	MOVQ    sp+0(FP), STATE
+	MOVQ    state+8(FP), AX // cc.finalState
 
-	MOVO    0(AX), A
-	PXOR    32(AX), A           // a = cc.finalState[0:2] ^ cc.finalState[4:6]
-	MOVO    16(AX), B
-	PXOR    48(AX), B           // b = cc.finalState[2:4] ^ cc.finalState[6:8]
+	MOVOU   0(AX), A
+	MOVOU   32(AX), TMPX0
+	PXOR    TMPX0, A           // a = cc.finalState[0:2] ^ cc.finalState[4:6]
+	MOVOU   16(AX), B
+	MOVOU   48(AX), TMPX0
+	PXOR    TMPX0, B           // b = cc.finalState[2:4] ^ cc.finalState[6:8]
 	// <BEGIN> VARIANT1_INIT
-	MOVQ    tweak+8(FP), TMPX0
+	MOVQ    tweak+16(FP), TMPX0
 	PXOR    TWEAK, TWEAK
 	MOVLHPS TMPX0, TWEAK
 	// <END> VARIANT1_INIT
@@ -28,6 +32,7 @@ LOOP:
 	// single round of AES
 	MOVO    0(CHUNK), C
 	AESENC  A, C
+	PREFETCH(STATE, C, BX) // chunk of c[0], loaded below
 
 	MOVO    B, TMPX0
 	PXOR    C, TMPX0
@@ -1,20 +1,25 @@
+// +build amd64,!purego,!tinygo
+
 // amd64 assembly implementation for memory hard step of variant 2, with SSE2 and AES-NI.
 
 #include "textflag.h"
 #include "sum_defs_amd64.h"
 
-// func memhard2(cc *cache)
+// func memhard2(sp *uint64, state *[25]uint64)
 TEXT ·memhard2(SB), NOSPLIT, $16 // stack is used for the v2Sqrt CALL only
-	MOVQ    cc+0(FP), STATE
-	LEAQ    PAD_SIZE(STATE), AX  // *cc.finalState
-
-	MOVO    0(AX), A
-	PXOR    32(AX), A            // a = cc.finalState[0:2] ^ cc.finalState[4:6]
-	MOVO    16(AX), B
-	PXOR    48(AX), B            // b = cc.finalState[2:4] ^ cc.finalState[6:8]
+	MOVQ    sp+0(FP), STATE
+	MOVQ    state+8(FP), AX  // cc.finalState
+
+	MOVOU   0(AX), A
+	MOVOU   32(AX), TMPX0
+	PXOR    TMPX0, A            // a = cc.finalState[0:2] ^ cc.finalState[4:6]
+	MOVOU   16(AX), B
+	MOVOU   48(AX), TMPX0
+	PXOR    TMPX0, B            // b = cc.finalState[2:4] ^ cc.finalState[6:8]
 	// <BEGIN> VARIANT2_INIT
-	MOVO    64(AX), E
-	PXOR    80(AX), E            // e = cc.finalState[8:10] ^ cc.finalState[10:12]
+	MOVOU   64(AX), E
+	MOVOU   80(AX), TMPX0
+	PXOR    TMPX0, E            // e = cc.finalState[8:10] ^ cc.finalState[10:12]
 	MOVQ    96(AX), DIV_RESULT   // divResult = cc.finalState[12]
 	MOVQ    104(AX), SQRT_RESULT // sqrtResult = cc.finalState[13]
 	// <END> VARIANT2_INIT
@@ -28,6 +33,7 @@ LOOP:
 	// single round of AES
 	MOVO    0(CHUNK), C
 	AESENC  A, C
+	PREFETCH(STATE, C, TMP0) // chunk of c[0], loaded below
 
 	// <BEGIN> VARIANT2_SHUFFLE_ADD
 	MOVQ    AX, BX
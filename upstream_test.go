@@ -0,0 +1,270 @@
+package cryptonight
+
+import (
+	"bufio"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Test vectors can be imported straight from the source trees of other
+// implementations by passing them to go test with -monero and -xmrig, so that
+// coverage tracks upstream.
+var (
+	moneroDir = flag.String("monero", "", "monero source tree to run tests/hash/tests-slow*.txt from")
+	xmrigDir  = flag.String("xmrig", "", "xmrig source tree to run CryptoNight_test.h from")
+)
+
+func TestUpstream(t *testing.T) {
+	if *moneroDir == "" && *xmrigDir == "" {
+		t.Skip("neither -monero nor -xmrig is given")
+	}
+
+	var specs []upstreamSpec
+	if *moneroDir != "" {
+		s, err := loadMoneroVectors(*moneroDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		specs = append(specs, s...)
+	}
+	if *xmrigDir != "" {
+		s, err := loadXmrigVectors(*xmrigDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		specs = append(specs, s...)
+	}
+
+	skipped := 0
+	for i, v := range specs {
+		if !knownVariant(v.variant) || v.family == "lite" && v.variant > 1 {
+			skipped++
+			continue
+		}
+
+		in, _ := hex.DecodeString(v.input)
+		var out string
+		switch v.family {
+		case "lite":
+			out = hex.EncodeToString(SumAlgorithm(in, CNLite0+Algorithm(v.variant), 0))
+		case "heavy":
+			out = hex.EncodeToString(SumAlgorithm(in, CNHeavy, 0))
+		case "pico_trtl":
+			out = hex.EncodeToString(SumAlgorithm(in, CNPico, 0))
+		case "msr":
+			out = hex.EncodeToString(SumAlgorithm(in, CNFast, 0))
+		case "half":
+			out = hex.EncodeToString(SumAlgorithm(in, CNHalf, 0))
+		case "xtl":
+			out = hex.EncodeToString(SumAlgorithm(in, CNXTL, 0))
+		case "rwz":
+			out = hex.EncodeToString(SumAlgorithm(in, CNRWZ, 0))
+		case "zls":
+			out = hex.EncodeToString(SumAlgorithm(in, CNZLS, 0))
+		case "double":
+			out = hex.EncodeToString(SumAlgorithm(in, CNDouble, 0))
+		case "gpu":
+			out = hex.EncodeToString(SumAlgorithm(in, CNGPU, 0))
+		default:
+			out = hex.EncodeToString(SumHeight(in, v.variant, v.height))
+		}
+		if out != v.output {
+			t.Errorf("\n[v%d, %d] input %s\nexpected:\n\t%s\ngot:\n\t%s\n", v.variant, i, v.input, v.output, out)
+		}
+	}
+	t.Logf("%d vectors run, %d skipped for unsupported variants", len(specs)-skipped, skipped)
+}
+
+// upstreamSpec is a hashSpec with the height of the variants depending on it,
+// and the family of the algorithm.
+type upstreamSpec struct {
+	hashSpec
+	height uint64
+	family string // as in the names of xmrig, such as "lite", empty for CryptoNight itself
+}
+
+// loadMoneroVectors reads dir/tests/hash/tests-slow*.txt. Each line of them is
+// "hash input [height]" in hex, and the variant is the suffix of the file name,
+// tests-slow.txt being variant 0.
+func loadMoneroVectors(dir string) ([]upstreamSpec, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "tests", "hash", "tests-slow*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no tests-slow*.txt found in %s", dir)
+	}
+
+	var specs []upstreamSpec
+	for _, name := range names {
+		variant := 0
+		if s := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(name), "tests-slow"), ".txt"); s != "" {
+			if variant, err = strconv.Atoi(strings.TrimPrefix(s, "-")); err != nil {
+				return nil, fmt.Errorf("%s: unknown variant", name)
+			}
+		}
+
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		s, err := parseMoneroVectors(f, variant)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		specs = append(specs, s...)
+	}
+
+	return specs, nil
+}
+
+func parseMoneroVectors(r io.Reader, variant int) ([]upstreamSpec, error) {
+	var specs []upstreamSpec
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 || len(fields[0]) != 64 {
+			return nil, fmt.Errorf("line %d: malformed", line)
+		}
+		if _, err := hex.DecodeString(fields[1]); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		var height uint64
+		if len(fields) == 3 {
+			var err error
+			if height, err = strconv.ParseUint(fields[2], 10, 64); err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+		}
+
+		specs = append(specs, upstreamSpec{hashSpec{fields[1], strings.ToLower(fields[0]), variant}, height, ""})
+	}
+
+	return specs, scanner.Err()
+}
+
+var (
+	xmrigArray = regexp.MustCompile(`(?s)static\s+(?:const\s+)?uint8_t\s+(\w+)\s*\[\s*\d*\s*\]\s*=\s*\{(.*?)\}\s*;`)
+	xmrigByte  = regexp.MustCompile(`0[xX]([0-9a-fA-F]{2})`)
+	xmrigOut   = regexp.MustCompile(`^test_output_(?:v(\d+)(_lite)?|(heavy|pico_trtl|msr|half|xtl|rwz|zls|double|gpu))$`)
+)
+
+// loadXmrigVectors reads CryptoNight_test.h in dir, which has been placed in
+// different directories across xmrig versions.
+func loadXmrigVectors(dir string) ([]upstreamSpec, error) {
+	for _, name := range []string{
+		filepath.Join(dir, "src", "crypto", "cn", "CryptoNight_test.h"),
+		filepath.Join(dir, "src", "crypto", "CryptoNight_test.h"),
+	} {
+		b, err := ioutil.ReadFile(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		specs, err := parseXmrigVectors(string(b))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		return specs, nil
+	}
+
+	return nil, fmt.Errorf("no CryptoNight_test.h found in %s", dir)
+}
+
+// parseXmrigVectors pairs test_input, which is made of 76 bytes blobs, with
+// each test_output_vN, test_output_vN_lite, test_output_heavy and
+// test_output_pico_trtl, which are made of 32 bytes hashes. Other arrays, such as the ones of the variants of
+// CryptoNight-Heavy, are ignored.
+func parseXmrigVectors(src string) ([]upstreamSpec, error) {
+	arrays := make(map[string]string)
+	for _, m := range xmrigArray.FindAllStringSubmatch(src, -1) {
+		var buf strings.Builder
+		for _, b := range xmrigByte.FindAllStringSubmatch(m[2], -1) {
+			buf.WriteString(strings.ToLower(b[1]))
+		}
+		arrays[m[1]] = buf.String()
+	}
+
+	const inputSize = 76 * 2
+	input := arrays["test_input"]
+	if input == "" || len(input)%inputSize != 0 {
+		return nil, fmt.Errorf("test_input not found or malformed")
+	}
+
+	var specs []upstreamSpec
+	for name, output := range arrays {
+		m := xmrigOut.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		variant, _ := strconv.Atoi(m[1])
+		family := ""
+		switch {
+		case m[2] != "":
+			family = "lite"
+		case m[3] != "":
+			family = m[3]
+		}
+		for i := 0; i+64 <= len(output) && (i/64+1)*inputSize <= len(input); i += 64 {
+			specs = append(specs, upstreamSpec{
+				hashSpec: hashSpec{input[i/64*inputSize : (i/64+1)*inputSize], output[i : i+64], variant},
+				family:   family,
+			})
+		}
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no test_output_vN found")
+	}
+
+	return specs, nil
+}
+
+func TestParseUpstream(t *testing.T) {
+	specs, err := parseMoneroVectors(strings.NewReader(`
+2F8E3DF40BD11F9AC90C743CA8E32BB391DA4FB98612AA3B6CDC639EE00B31F5 6465206f6d6e69627573206475626974616e64756d
+bbec2cacf69866a8e740380fe7b818fc78f8571221742d729d9d02d7f8989b87 63617665617420656d70746f72 1806260
+`), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 2 || specs[0] != (upstreamSpec{hashSpecsV0[2], 0, ""}) || specs[1] != (upstreamSpec{hashSpecsV0[4], 1806260, ""}) {
+		t.Errorf("unexpected monero vectors: %v", specs)
+	}
+
+	input := strings.Repeat("0x00, ", 75) + "0x01,\n" + strings.Repeat("0x02, ", 76)
+	output := strings.Repeat("0xAB, ", 64)
+	specs, err = parseXmrigVectors(`
+const static uint8_t test_input[152] = {
+    ` + input + `
+};
+const static uint8_t test_output_v0[64] = {
+    ` + output + `
+};
+const static uint8_t test_output_v0_lite[32] = { 0x00 };
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 2 || specs[0].variant != 0 ||
+		specs[0].input != strings.Repeat("00", 75)+"01" ||
+		specs[1].input != strings.Repeat("02", 76) ||
+		specs[1].output != strings.Repeat("ab", 32) {
+		t.Errorf("unexpected xmrig vectors: %v", specs)
+	}
+}
@@ -0,0 +1,29 @@
+// +build go1.12
+
+package cryptonight
+
+import (
+	"runtime/debug"
+)
+
+// moduleVersion returns the version of this module in the build info of the
+// binary.
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, m := range info.Deps {
+		if m.Path == modulePath {
+			if m.Replace != nil {
+				return m.Replace.Version
+			}
+			return m.Version
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,9 @@
+// +build !go1.12
+
+package cryptonight
+
+// moduleVersion returns an empty string, as build info is only embedded since
+// Go 1.12.
+func moduleVersion() string {
+	return ""
+}